@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sarp_backend/model"
+	"sarp_backend/service"
+	"strings"
+)
+
+func banAdd(fs *flag.FlagSet) func(c *console, args []string) error {
+	days := fs.Uint("days", 0, "length of the ban in days, between 1 and 29")
+	reason := fs.String("reason", "", "reason shown to the player")
+
+	return func(c *console, args []string) error {
+		data := &model.BanAPI{
+			Username:  args[0],
+			Expire:    *days,
+			Reason:    *reason,
+			AdminName: c.opts.actor,
+		}
+		return c.ban(context.Background(), data)
+	}
+}
+
+func banRemove(fs *flag.FlagSet) func(c *console, args []string) error {
+	return func(c *console, args []string) error {
+		return c.unban(context.Background(), args[0])
+	}
+}
+
+func banList(fs *flag.FlagSet) func(c *console, args []string) error {
+	return func(c *console, args []string) error {
+		return c.bans(context.Background())
+	}
+}
+
+func (c *console) ban(ctx context.Context, data *model.BanAPI) error {
+	if err := c.users.Ban(ctx, data, c.audit(service.AuditBanCreate, data.Username, data)); err != nil {
+		return err
+	}
+	return c.done("%s banned for %d days", data.Username, data.Expire)
+}
+
+func (c *console) unban(ctx context.Context, name string) error {
+	data := &model.BanAPI{Username: name, AdminName: c.opts.actor}
+	if err := c.users.Unban(ctx, data, c.audit(service.AuditBanRevoke, name, data)); err != nil {
+		return err
+	}
+	return c.done("%s unbanned", name)
+}
+
+func (c *console) bans(ctx context.Context) error {
+	bans, err := c.users.BanList(ctx)
+	if err != nil {
+		return err
+	}
+	if bans == nil {
+		bans = []model.BanAPI{}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-24s %-24s %-6s %s", "ACCOUNT", "BANNED BY", "DAYS", "REASON")
+	for _, ban := range bans {
+		fmt.Fprintf(&b, "\n%-24s %-24s %-6d %s", ban.Username, ban.AdminName, ban.Expire, ban.Reason)
+	}
+	return c.print(bans, "%s", b.String())
+}
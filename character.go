@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sarp_backend/model"
+	"sarp_backend/service"
+	"time"
+)
+
+func characterAccept(fs *flag.FlagSet) func(c *console, args []string) error {
+	return func(c *console, args []string) error {
+		return c.acceptCharacter(context.Background(), args[0])
+	}
+}
+
+func characterReject(fs *flag.FlagSet) func(c *console, args []string) error {
+	reason := fs.String("reason", "", "reason sent to the player")
+
+	return func(c *console, args []string) error {
+		return c.rejectCharacter(context.Background(), args[0], *reason)
+	}
+}
+
+func (c *console) acceptCharacter(ctx context.Context, character string) error {
+	found, err := c.users.FetchCharacter(ctx, character)
+	if err != nil {
+		return err
+	}
+
+	data := model.CharacterAPI{Username: found.Username, CharacterName: found.CharacterName, AcceptedBy: c.opts.actor}
+	owner, err := c.chars.AcceptCharacter(ctx, data, c.audit(service.AuditCharacterAccept, character, data))
+	if err != nil {
+		return err
+	}
+
+	date := time.Now().Format("02/01/2006, 15:04")
+	c.notify(ctx, owner, service.AcceptCharacterEmail, service.OutboxKey(service.AcceptCharacterEmail, character, date), service.CharacterAcceptedData{
+		Username:  owner,
+		Character: character,
+		Date:      date,
+	})
+
+	return c.done("%s accepted", character)
+}
+
+func (c *console) rejectCharacter(ctx context.Context, character, reason string) error {
+	if reason == "" {
+		return model.ErrMissingFields
+	}
+
+	found, err := c.users.FetchCharacter(ctx, character)
+	if err != nil {
+		return err
+	}
+
+	data := model.RejectCharacterAPI{Username: found.Username, CharacterName: found.CharacterName, Reason: reason, RejectedBy: c.opts.actor}
+	owner, err := c.chars.DeclineCharacter(ctx, data, c.audit(service.AuditCharacterReject, character, data))
+	if err != nil {
+		return err
+	}
+
+	date := time.Now().Format("02/01/2006, 15:04")
+	c.notify(ctx, owner, service.DeclineCharacterEmail, service.OutboxKey(service.DeclineCharacterEmail, character, date), service.CharacterRejectedData{
+		Username:  owner,
+		Character: character,
+		Date:      date,
+		Reason:    reason,
+		Admin:     c.opts.actor,
+	})
+
+	return c.done("%s rejected", character)
+}
+
+// notify queues the email the staff panel would have sent. The decision is already
+// saved, so a failure is only reported.
+func (c *console) notify(ctx context.Context, name, template, key string, data interface{}) {
+	to, err := c.users.FetchMail(ctx, name)
+	if err == nil && to == "" {
+		err = model.ErrAccountNotFound
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: can't get the email of %s: %v\n", name, err)
+		return
+	}
+
+	email, err := c.templates.Render(c.locale(name), template, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: can't render email: %v\n", err)
+		return
+	}
+
+	if err = c.outbox.Enqueue(ctx, key, to, email); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: can't queue email: %v\n", err)
+	}
+}
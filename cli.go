@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	config "sarp_backend/config"
+	"sarp_backend/i18n"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"sarp_backend/service"
+	"time"
+)
+
+const usage = `usage: ucp [--config path] [--json] [--as name] <command> [arguments]
+
+commands:
+  serve                                      start the HTTP server (default)
+  migrate up | down [steps] | status         apply or revert the schema migrations
+  user activate <name>                       activate an account without the email link
+  user promote <name> --role admin|tester --level n
+  user demote <name> [--role admin|tester]   remove one rank, or both without --role
+  user reset-password <name> [--password p]  set a password, a random one is printed when omitted
+  ban add <name> --days n --reason text
+  ban remove <name>
+  ban list
+  character accept <character>
+  character reject <character> --reason text
+  config check                               validate the configuration and exit`
+
+// actorLimit is the size of ucp_audit.Actor and blacklist.BannedBy.
+const actorLimit = 24
+
+// options are the flags every command understands, they can be given before or
+// after the command name.
+type options struct {
+	configPath string
+	json       bool
+	actor      string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.configPath, "config", o.configPath, "path of the JSON configuration, UCP_* environment variables override it")
+	fs.BoolVar(&o.json, "json", o.json, "print the result as JSON")
+	fs.StringVar(&o.actor, "as", o.actor, "name written in the audit log and on bans")
+}
+
+func defaultOptions() *options {
+	actor := "console"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		actor = "console:" + u.Username
+	}
+	if len(actor) > actorLimit {
+		actor = actor[:actorLimit]
+	}
+	return &options{configPath: "./config.json", actor: actor}
+}
+
+// parseArgs parses fs and returns the positional arguments, flags may come after them.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// console runs the operator commands on the same services as the HTTP API, so the
+// business rules can't drift between the two.
+type console struct {
+	opts      *options
+	out       io.Writer
+	users     *service.UserService
+	chars     *service.CharacterService
+	outbox    *service.OutboxService
+	templates *service.EmailTemplates
+	catalog   *i18n.Catalog
+}
+
+func newConsole(cfg *config.Config, repo repository.Repository, opts *options, out io.Writer) (*console, error) {
+	hasher, err := service.NewPasswordHasher(cfg.PasswordHash)
+	if err != nil {
+		return nil, fmt.Errorf("error creating password hasher: %w", err)
+	}
+
+	catalog, err := i18n.Load()
+	if err != nil {
+		return nil, fmt.Errorf("error loading message catalog: %w", err)
+	}
+
+	templates, err := service.NewEmailTemplates(catalog, cfg.EmailTemplatesDir)
+	if err != nil {
+		return nil, fmt.Errorf("error loading email templates: %w", err)
+	}
+
+	return &console{
+		opts:  opts,
+		out:   out,
+		users: service.NewUserService(repo, hasher),
+		chars: service.NewCharacterService(repo, service.CharacterConfig{
+			ClaimLease:       time.Duration(cfg.ReviewClaimMinutes) * time.Minute,
+			ResubmitCooldown: time.Duration(cfg.ResubmitCooldownHours) * time.Hour,
+		}),
+		// The workers of the running server deliver what is queued here.
+		outbox:    service.NewOutboxService(repo, nil, service.OutboxConfig{}),
+		templates: templates,
+		catalog:   catalog,
+	}, nil
+}
+
+// openConsole reads the configuration and connects to the database, the returned
+// function closes the connection.
+func openConsole(opts *options) (*console, func(), error) {
+	cfg, err := config.Read(opts.configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading %s:\n%w", opts.configPath, err)
+	}
+
+	ucpRepo, err := repository.New(cfg.Dsn, time.Duration(cfg.QueryTimeout)*time.Second)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating repository: %w", err)
+	}
+
+	c, err := newConsole(cfg, ucpRepo, opts, os.Stdout)
+	if err != nil {
+		ucpRepo.DB.Close()
+		return nil, nil, err
+	}
+	return c, func() { ucpRepo.DB.Close() }, nil
+}
+
+// print writes data as JSON with --json, the human text otherwise.
+func (c *console) print(data interface{}, format string, args ...interface{}) error {
+	if c.opts.json {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	}
+	_, err := fmt.Fprintf(c.out, format+"\n", args...)
+	return err
+}
+
+// done is the result of commands that only change something.
+func (c *console) done(format string, args ...interface{}) error {
+	return c.print(model.BaseResponse{Error: false, Message: fmt.Sprintf(format, args...)}, format, args...)
+}
+
+func (c *console) audit(action, target string, payload interface{}) *model.AuditAPI {
+	summary, err := json.Marshal(payload)
+	if err != nil {
+		summary = []byte("{}")
+	}
+
+	return &model.AuditAPI{
+		Actor:   c.opts.actor,
+		Action:  action,
+		Target:  target,
+		Payload: string(summary),
+	}
+}
+
+// locale is the language of the emails sent to name.
+func (c *console) locale(name string) string {
+	locale, err := c.users.Locale(context.Background(), name)
+	if err != nil || c.catalog.Normalize(locale) == "" {
+		return i18n.Default
+	}
+	return c.catalog.Normalize(locale)
+}
+
+// fail prints err and returns the exit code. Domain errors keep their code in the
+// JSON output so scripts can tell them apart.
+func fail(opts *options, command string, err error) int {
+	if opts.json {
+		br := model.BaseResponse{Error: true, Message: err.Error()}
+		var e *model.Error
+		if errors.As(err, &e) {
+			br.Code = e.Code
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(br)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+	return 1
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	config "sarp_backend/config"
+	"sarp_backend/migrations"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"sarp_backend/service"
+	"testing"
+)
+
+const testAccount = "Operator"
+
+func testConsole(t *testing.T, jsonOutput bool) (*console, *repository.MemoryRepository, *bytes.Buffer) {
+	t.Helper()
+
+	repo := repository.NewMemoryRepository()
+	if err := repo.Create(context.Background(), &repository.UserDB{
+		Username:     testAccount,
+		Email:        "operator@app.ro",
+		Password:     "-",
+		RegisterDate: "2024-01-01 00:00:00",
+	}); err != nil {
+		t.Fatalf("Error creating account: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	c, err := newConsole(config.Default(), repo, &options{json: jsonOutput, actor: "console"}, out)
+	if err != nil {
+		t.Fatalf("Error creating console: %v", err)
+	}
+	return c, repo, out
+}
+
+func TestStaffLevel(t *testing.T) {
+	c, repo, out := testConsole(t, true)
+	ctx := context.Background()
+
+	if !assert.NoError(t, c.setStaffLevel(ctx, testAccount, "admin", 3)) {
+		return
+	}
+	var data model.StaffLevelAPI
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &data))
+	assert.Equal(t, model.StaffLevelAPI{Username: testAccount, Admin: 3, Tester: 0}, data)
+
+	assert.NoError(t, c.setStaffLevel(ctx, testAccount, "tester", 1))
+	assert.NoError(t, c.setStaffLevel(ctx, testAccount, "admin", 0), "Demoting one rank keeps the other")
+	tester, _ := repo.FetchTesterLevel(ctx, testAccount)
+	admin, _ := repo.FetchAdminLevel(ctx, testAccount)
+	assert.Equal(t, 0, admin)
+	assert.Equal(t, 1, tester)
+
+	assert.Error(t, c.setStaffLevel(ctx, testAccount, "", 2), "Promoting needs a role")
+	assert.ErrorIs(t, c.setStaffLevel(ctx, "Nobody", "admin", 1), model.ErrAccountNotFound)
+
+	entries, total, err := repo.FetchAudit(ctx, &repository.AuditFilterDB{Target: testAccount, Limit: 10})
+	if assert.NoError(t, err) && assert.Equal(t, 3, total) {
+		assert.Equal(t, "console", entries[0].Actor)
+		assert.ElementsMatch(t, []string{service.AuditUserPromote, service.AuditUserPromote, service.AuditUserDemote},
+			[]string{entries[0].Action, entries[1].Action, entries[2].Action})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	c, repo, out := testConsole(t, true)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, c.resetPassword(ctx, testAccount, "short"), model.ErrWeakPassword)
+
+	if !assert.NoError(t, c.resetPassword(ctx, testAccount, "")) {
+		return
+	}
+	var result struct {
+		Password string `json:"password"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.NoError(t, c.users.Verify(ctx, &model.LoginAPI{Username: testAccount, Password: result.Password}))
+
+	entries, _, _ := repo.FetchAudit(ctx, &repository.AuditFilterDB{Action: service.AuditPasswordReset, Limit: 10})
+	if assert.Len(t, entries, 1) {
+		assert.NotContains(t, entries[0].Payload, result.Password)
+	}
+}
+
+func TestRandomPassword(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		password, err := randomPassword(16)
+		if assert.NoError(t, err) {
+			assert.Len(t, password, 16)
+			assert.NoError(t, (&model.ResetPasswordAPI{Username: testAccount, NewPassword: password}).Validate())
+		}
+	}
+}
+
+func TestBanCommands(t *testing.T) {
+	c, _, out := testConsole(t, false)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, c.ban(ctx, &model.BanAPI{Username: testAccount, Expire: 40, Reason: "test", AdminName: "console"}), model.ErrInvalidExpire)
+	assert.NoError(t, c.ban(ctx, &model.BanAPI{Username: testAccount, Expire: 3, Reason: "test", AdminName: "console"}))
+
+	out.Reset()
+	assert.NoError(t, c.bans(ctx))
+	assert.Contains(t, out.String(), testAccount)
+
+	assert.NoError(t, c.unban(ctx, testAccount))
+	assert.ErrorIs(t, c.unban(ctx, "Nobody"), model.ErrNotBanned)
+}
+
+func TestPrintMigrations(t *testing.T) {
+	applied := []migrations.Migration{{Version: 1, Name: "initial"}, {Version: 2, Name: "sessions"}}
+
+	out := &bytes.Buffer{}
+	c := &console{opts: &options{}, out: out}
+	assert.NoError(t, c.printMigrations("applied", applied, "database is up to date"))
+	assert.Equal(t, "applied 0001_initial\napplied 0002_sessions\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, c.printMigrations("applied", nil, "database is up to date"))
+	assert.Equal(t, "database is up to date\n", out.String())
+
+	out.Reset()
+	c.opts.json = true
+	assert.NoError(t, c.printMigrations("reverted", applied[1:], "nothing to revert"))
+	var body map[string][]migrationResult
+	if err := json.Unmarshal(out.Bytes(), &body); err != nil {
+		t.Fatalf("Error decoding output: %v", err)
+	}
+	assert.Equal(t, map[string][]migrationResult{"reverted": {{Version: 2, Name: "sessions"}}}, body)
+}
+
+func TestFlatten(t *testing.T) {
+	problems := checkConfig("./missing.json")
+	assert.Len(t, problems, 1)
+
+	assert.Equal(t, []string{"a", "b", "c"}, flatten(errors.Join(errors.New("a"), errors.Join(errors.New("b"), errors.New("c")))))
+}
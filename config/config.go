@@ -1,82 +1,469 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/Jeffail/gabs/v2"
+	"github.com/go-sql-driver/mysql"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+// EnvPrefix starts every environment override. The variable is named after the path
+// of the setting in the file, tokens.secret is UCP_TOKENS_SECRET, and a _FILE suffix
+// reads the value from a file instead.
+const EnvPrefix = "UCP_"
+
+// Without a permissions section every admin keeps the rights the UCP always gave
+// them, testers can only review characters and the audit trail and the email
+// outbox need level 3.
+var (
+	defaultAdminPermissions = map[int][]string{
+		1: {"staff.panel", "character.review", "character.read", "ban.list", "ban.create", "ban.revoke", "ajail", "logs.read:*", "session.revoke", "lockout.clear", "namechange.review", "ck.review"},
+		3: {"audit.read", "email.manage", "questionnaire.manage"},
+	}
+	defaultTesterPermissions = map[int][]string{
+		1: {"staff.panel", "character.review"},
+	}
+)
+
+// Config is filled from the defaults, then the file, then the environment. The config
+// tag is the path of the setting in the file, fields without one are derived.
 type Config struct {
-	Version      string `json:"version"`
-	FEPath       string `json:"frontend_path"`
-	Dsn          string `json:"dsn"`
-	Port         string `json:"port"`
-	SMTPHost     string `json:"smtp_host"`
-	SMTPPort     int    `json:"smtp_port"`
-	SMTPUser     string `json:"smtp_user"`
-	SMTPPassword string `json:"smtp_password"`
-	SMTPFrom     string `json:"smtp_from"`
+	Version           string `config:"version"`
+	FEPath            string `config:"frontend_path"`
+	Dsn               string `config:"db.dsn"`
+	QueryTimeout      int    `config:"db.query_timeout_seconds"`
+	Port              string `config:"port"`
+	PublicURL         string `config:"public_url"`
+	DevMode           bool   `config:"dev_mode"`
+	SMTPHost          string `config:"email.smtp_host"`
+	SMTPPort          int    `config:"email.smtp_port"`
+	SMTPUser          string `config:"email.smtp_user"`
+	SMTPPassword      string `config:"email.smtp_password"`
+	SMTPFrom          string `config:"email.smtp_from"`
+	SMTPSecurity      string `config:"email.smtp_security"`
+	SMTPIdle          int    `config:"email.smtp_idle_seconds"`
+	EmailTransport    string `config:"email.transport"`
+	EmailFileDir      string `config:"email.file_dir"`
+	EmailTemplatesDir string `config:"email.templates_dir"`
+	PasswordHash      string `config:"security.password_hash"`
+
+	RequestTimeout int      `config:"request_timeout_seconds"`
+	CORSOrigins    []string `config:"http.cors_origins"`
+	TrustedProxies []string `config:"http.trusted_proxies"`
+	ProxyHeader    string   `config:"http.proxy_header"`
+
+	RateLimitMax           int `config:"rate_limit.max"`
+	RateLimitWindow        int `config:"rate_limit.window_seconds"`
+	ResendConfirmMax       int `config:"rate_limit.resend_confirmation_max"`
+	ResendConfirmWindow    int `config:"rate_limit.resend_confirmation_window_seconds"`
+	ConfirmEmailsPerMail   int `config:"rate_limit.confirmation_emails_per_address"`
+	ConfirmEmailsPerWindow int `config:"rate_limit.confirmation_emails_window_seconds"`
+
+	SessionExpiryHours int `config:"session.expiry_hours"`
+
+	TwoFactorIssuer        string `config:"two_factor.issuer"`
+	TwoFactorRequiredStaff bool   `config:"two_factor.required_for_staff"`
+
+	LoginFreeAttempts   int `config:"login_guard.free_attempts"`
+	LoginLockAttempts   int `config:"login_guard.lock_attempts"`
+	LoginIPLockAttempts int `config:"login_guard.ip_lock_attempts"`
+	LoginBaseDelay      int `config:"login_guard.base_delay_seconds"`
+	LoginMaxDelay       int `config:"login_guard.max_delay_seconds"`
+	LoginLockMinutes    int `config:"login_guard.lock_minutes"`
+	LoginResetMinutes   int `config:"login_guard.reset_minutes"`
+
+	OutboxWorkers     int `config:"outbox.workers"`
+	OutboxBatchSize   int `config:"outbox.batch_size"`
+	OutboxPoll        int `config:"outbox.poll_seconds"`
+	OutboxBaseDelay   int `config:"outbox.base_delay_seconds"`
+	OutboxMaxDelay    int `config:"outbox.max_delay_seconds"`
+	OutboxMaxAttempts int `config:"outbox.max_attempts"`
+	OutboxLease       int `config:"outbox.lease_seconds"`
+
+	TokenSecret         string `config:"tokens.secret"`
+	ConfirmTokenMinutes int    `config:"tokens.confirm_ttl_minutes"`
+	ResetTokenMinutes   int    `config:"tokens.reset_ttl_minutes"`
+	EmailTokenMinutes   int    `config:"tokens.email_change_ttl_minutes"`
+
+	ReviewClaimMinutes    int `config:"reviews.claim_minutes"`
+	ResubmitCooldownHours int `config:"reviews.resubmit_cooldown_hours"`
+
+	AdminPermissions  map[int][]string `config:"permissions.admin"`
+	TesterPermissions map[int][]string `config:"permissions.tester"`
 }
 
+// Default is the configuration used for every setting missing from the file and
+// the environment. The settings without a usable default are left empty.
+func Default() *Config {
+	return &Config{
+		Version:   "dev",
+		PublicURL: "https://app.ro",
+
+		QueryTimeout:   3,
+		RequestTimeout: 5,
+		CORSOrigins:    []string{"https://app.ro"},
+		TrustedProxies: []string{"127.0.0.1", "::1"},
+		ProxyHeader:    "X-Real-IP",
+
+		RateLimitMax:           500,
+		RateLimitWindow:        3600,
+		ResendConfirmMax:       5,
+		ResendConfirmWindow:    15 * 60,
+		ConfirmEmailsPerMail:   3,
+		ConfirmEmailsPerWindow: 3600,
+
+		SessionExpiryHours: 24,
+
+		EmailTransport: "smtp",
+		SMTPPort:       465,
+		SMTPIdle:       30,
+		EmailFileDir:   "./mail",
+		PasswordHash:   "whirlpool",
+
+		TwoFactorIssuer: "SA-RP",
+
+		LoginFreeAttempts:   3,
+		LoginLockAttempts:   10,
+		LoginIPLockAttempts: 50,
+		LoginBaseDelay:      2,
+		LoginMaxDelay:       300,
+		LoginLockMinutes:    30,
+		LoginResetMinutes:   60,
+
+		OutboxWorkers:     2,
+		OutboxBatchSize:   10,
+		OutboxPoll:        5,
+		OutboxBaseDelay:   30,
+		OutboxMaxDelay:    3600,
+		OutboxMaxAttempts: 8,
+		OutboxLease:       120,
+
+		ConfirmTokenMinutes: 24 * 60,
+		ResetTokenMinutes:   15,
+		EmailTokenMinutes:   24 * 60,
+
+		ReviewClaimMinutes:    30,
+		ResubmitCooldownHours: 24,
+
+		AdminPermissions:  defaultAdminPermissions,
+		TesterPermissions: defaultTesterPermissions,
+	}
+}
+
+// Read loads path on top of the defaults and applies the environment overrides. The
+// returned error lists every invalid setting, not only the first one.
 func Read(path string) (*Config, error) {
+	return read(path, os.LookupEnv)
+}
+
+func read(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
 	parsed, err := gabs.ParseJSONFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	dsn, ok := parsed.Path("db.dsn").Data().(string)
-	if !ok {
-		return nil, errors.New("error dsn cast to string")
+	cfg := Default()
+	var errs []error
+
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("config")
+		if key == "" {
+			continue
+		}
+		if err = load(v.Field(i), key, parsed, lookupEnv); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Port 465 is implicit TLS, the submission port 587 upgrades with STARTTLS.
+	if cfg.SMTPSecurity == "" {
+		cfg.SMTPSecurity = "starttls"
+		if cfg.SMTPPort == 465 {
+			cfg.SMTPSecurity = "tls"
+		}
+	}
+	cfg.PublicURL = strings.TrimRight(cfg.PublicURL, "/")
+
+	// A setting that failed to parse keeps its default, so validating the rest still
+	// makes sense and the operator gets the whole list in one run.
+	if err = cfg.Validate(); err != nil {
+		errs = append(errs, err)
 	}
 
-	port, ok := parsed.Path("port").Data().(string)
-	if !ok {
-		return nil, errors.New("error port cast to string")
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// load sets one field, each source overrides the previous one: the file value, the
+// file it points to with a <key>_file sibling, the environment and its _FILE variant.
+func load(field reflect.Value, key string, parsed *gabs.Container, lookupEnv func(string) (string, bool)) error {
+	if parsed.ExistsP(key) {
+		if err := setJSON(field, parsed.Path(key).Data()); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	if parsed.ExistsP(key + "_file") {
+		file, ok := parsed.Path(key + "_file").Data().(string)
+		if !ok {
+			return fmt.Errorf("%s_file: must be a string", key)
+		}
+		if err := setFile(field, file); err != nil {
+			return fmt.Errorf("%s_file: %w", key, err)
+		}
 	}
 
-	fe, ok := parsed.Path("frontend_path").Data().(string)
-	if !ok {
-		return nil, errors.New("error log cast to string")
+	env := EnvName(key)
+	if value, ok := lookupEnv(env); ok {
+		if err := setString(field, value); err != nil {
+			return fmt.Errorf("%s: %w", env, err)
+		}
 	}
 
-	version, ok := parsed.Path("version").Data().(string)
-	if !ok {
-		return nil, errors.New("error version cast to string")
+	if file, ok := lookupEnv(env + "_FILE"); ok {
+		if err := setFile(field, file); err != nil {
+			return fmt.Errorf("%s_FILE: %w", env, err)
+		}
 	}
 
-	smtpHost, ok := parsed.Path("email.smtp_host").Data().(string)
-	if !ok {
-		return nil, errors.New("error smtp host cast to string")
+	return nil
+}
+
+// EnvName is the environment variable overriding the setting at key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func setFile(field reflect.Value, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
 	}
+	// Secret files usually end with a newline nobody meant to be part of the value.
+	return setString(field, strings.TrimRight(string(data), "\r\n"))
+}
 
-	smtpPort, ok := parsed.Path("email.smtp_port").Data().(float64)
-	if !ok {
-		return nil, errors.New("error smtp port cast to string")
+func setJSON(field reflect.Value, data interface{}) error {
+	switch field.Interface().(type) {
+	case string:
+		value, ok := data.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		field.SetString(value)
+	case int:
+		value, ok := data.(float64)
+		if !ok || value != float64(int(value)) {
+			return errors.New("must be an integer")
+		}
+		field.SetInt(int64(value))
+	case bool:
+		value, ok := data.(bool)
+		if !ok {
+			return errors.New("must be true or false")
+		}
+		field.SetBool(value)
+	case []string:
+		list, ok := data.([]interface{})
+		if !ok {
+			return errors.New("must be a list of strings")
+		}
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			value, okItem := item.(string)
+			if !okItem {
+				return errors.New("must be a list of strings")
+			}
+			values = append(values, value)
+		}
+		field.Set(reflect.ValueOf(values))
+	case map[int][]string:
+		levels, ok := data.(map[string]interface{})
+		if !ok {
+			return errors.New("must map levels to lists of permissions")
+		}
+		return setPermissions(field, levels)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
 	}
+	return nil
+}
 
-	smtpUser, ok := parsed.Path("email.smtp_user").Data().(string)
-	if !ok {
-		return nil, errors.New("error smtp user cast to string")
+// setString parses a value coming from the environment or a file. Lists are comma
+// separated and permissions are written as the same JSON object as in the file.
+func setString(field reflect.Value, raw string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(raw)
+	case int:
+		value, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		field.SetInt(int64(value))
+	case bool:
+		value, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		field.SetBool(value)
+	case []string:
+		var values []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	case map[int][]string:
+		var levels map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &levels); err != nil {
+			return errors.New("must be a JSON object mapping levels to lists of permissions")
+		}
+		return setPermissions(field, levels)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
 	}
+	return nil
+}
+
+func setPermissions(field reflect.Value, data map[string]interface{}) error {
+	levels := make(map[int][]string)
+	for key, child := range data {
+		level, err := strconv.Atoi(key)
+		if err != nil || level < 1 {
+			return fmt.Errorf("%s is not a valid level", key)
+		}
 
-	smtpPwd, ok := parsed.Path("email.smtp_password").Data().(string)
-	if !ok {
-		return nil, errors.New("error smtp password cast to string")
+		list, ok := child.([]interface{})
+		if !ok {
+			return fmt.Errorf("level %s must be a list of permissions", key)
+		}
+		for _, perm := range list {
+			value, okPerm := perm.(string)
+			if !okPerm {
+				return fmt.Errorf("level %s must be a list of permissions", key)
+			}
+			levels[level] = append(levels[level], value)
+		}
 	}
+	field.Set(reflect.ValueOf(levels))
+	return nil
+}
 
-	smtpFrom, ok := parsed.Path("email.smtp_from").Data().(string)
-	if !ok {
-		return nil, errors.New("error smtp from cast to string")
+// Validate checks every setting and joins all the problems in one error.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]interface{}{key}, args...)...))
+		}
 	}
 
-	return &Config{
-		Dsn:          dsn,
-		Port:         port,
-		FEPath:       fe,
-		Version:      version,
-		SMTPHost:     smtpHost,
-		SMTPPort:     int(smtpPort),
-		SMTPUser:     smtpUser,
-		SMTPPassword: smtpPwd,
-		SMTPFrom:     smtpFrom,
-	}, nil
+	check(c.Version != "", "version", "is required")
+	check(c.FEPath != "", "frontend_path", "is required")
+
+	if c.Dsn == "" {
+		check(false, "db.dsn", "is required")
+	} else if _, err := mysql.ParseDSN(c.Dsn); err != nil {
+		check(false, "db.dsn", "%v", err)
+	}
+	check(c.QueryTimeout >= 0, "db.query_timeout_seconds", "can't be negative")
+
+	_, port, err := net.SplitHostPort(c.Port)
+	check(err == nil && port != "", "port", "must look like :3000 or host:3000, got %q", c.Port)
+
+	publicURL, err := url.Parse(c.PublicURL)
+	check(err == nil && (publicURL.Scheme == "https" || publicURL.Scheme == "http") && publicURL.Host != "", "public_url", "must be an absolute http or https URL, got %q", c.PublicURL)
+
+	check(c.RequestTimeout > 0, "request_timeout_seconds", "must be positive")
+	check(len(c.CORSOrigins) > 0, "http.cors_origins", "needs at least one origin")
+	for _, origin := range c.CORSOrigins {
+		u, errOrigin := url.Parse(origin)
+		check(origin == "*" || (errOrigin == nil && u.Scheme != "" && u.Host != "" && (u.Path == "" || u.Path == "/")), "http.cors_origins", "%q is not an origin", origin)
+	}
+	for _, proxy := range c.TrustedProxies {
+		_, _, errCIDR := net.ParseCIDR(proxy)
+		check(net.ParseIP(proxy) != nil || errCIDR == nil, "http.trusted_proxies", "%q is not an IP or a CIDR range", proxy)
+	}
+	check(c.ProxyHeader == "" || len(c.TrustedProxies) > 0, "http.proxy_header", "is only read from http.trusted_proxies, add them or leave it empty")
+
+	check(c.RateLimitMax > 0, "rate_limit.max", "must be positive")
+	check(c.RateLimitWindow > 0, "rate_limit.window_seconds", "must be positive")
+	check(c.ResendConfirmMax > 0, "rate_limit.resend_confirmation_max", "must be positive")
+	check(c.ResendConfirmWindow > 0, "rate_limit.resend_confirmation_window_seconds", "must be positive")
+	check(c.ConfirmEmailsPerMail > 0, "rate_limit.confirmation_emails_per_address", "must be positive")
+	check(c.ConfirmEmailsPerWindow > 0, "rate_limit.confirmation_emails_window_seconds", "must be positive")
+	check(c.SessionExpiryHours > 0, "session.expiry_hours", "must be positive")
+
+	if _, err = mail.ParseAddress(c.SMTPFrom); err != nil {
+		check(false, "email.smtp_from", "must be an email address, got %q", c.SMTPFrom)
+	}
+	check(c.SMTPIdle >= 0, "email.smtp_idle_seconds", "can't be negative")
+
+	switch c.EmailTransport {
+	case "smtp":
+		check(c.SMTPHost != "", "email.smtp_host", "is required by the smtp transport")
+		check(c.SMTPPort > 0 && c.SMTPPort < 65536, "email.smtp_port", "must be between 1 and 65535")
+		check(c.SMTPSecurity == "tls" || c.SMTPSecurity == "starttls", "email.smtp_security", "must be tls or starttls, got %q", c.SMTPSecurity)
+	case "file":
+		check(c.EmailFileDir != "", "email.file_dir", "is required by the file transport")
+	case "memory":
+		// Captured emails are readable by anyone who can reach /dev/mailbox.
+		check(c.DevMode, "email.transport", "memory needs dev_mode")
+	default:
+		check(false, "email.transport", "must be smtp, file or memory, got %q", c.EmailTransport)
+	}
+
+	check(c.PasswordHash == "whirlpool" || c.PasswordHash == "argon2id", "security.password_hash", "must be whirlpool or argon2id, got %q", c.PasswordHash)
+	check(c.TwoFactorIssuer != "", "two_factor.issuer", "is required")
+
+	for key, value := range map[string]int{
+		"login_guard.free_attempts":       c.LoginFreeAttempts,
+		"login_guard.lock_attempts":       c.LoginLockAttempts,
+		"login_guard.ip_lock_attempts":    c.LoginIPLockAttempts,
+		"login_guard.base_delay_seconds":  c.LoginBaseDelay,
+		"login_guard.max_delay_seconds":   c.LoginMaxDelay,
+		"login_guard.lock_minutes":        c.LoginLockMinutes,
+		"login_guard.reset_minutes":       c.LoginResetMinutes,
+		"outbox.workers":                  c.OutboxWorkers,
+		"outbox.batch_size":               c.OutboxBatchSize,
+		"outbox.poll_seconds":             c.OutboxPoll,
+		"outbox.base_delay_seconds":       c.OutboxBaseDelay,
+		"outbox.max_delay_seconds":        c.OutboxMaxDelay,
+		"outbox.max_attempts":             c.OutboxMaxAttempts,
+		"outbox.lease_seconds":            c.OutboxLease,
+		"tokens.confirm_ttl_minutes":      c.ConfirmTokenMinutes,
+		"tokens.reset_ttl_minutes":        c.ResetTokenMinutes,
+		"tokens.email_change_ttl_minutes": c.EmailTokenMinutes,
+		"reviews.claim_minutes":           c.ReviewClaimMinutes,
+		"reviews.resubmit_cooldown_hours": c.ResubmitCooldownHours,
+	} {
+		check(value > 0, key, "must be positive")
+	}
+	check(c.LoginFreeAttempts < c.LoginLockAttempts, "login_guard.free_attempts", "must be lower than login_guard.lock_attempts")
+	check(c.LoginBaseDelay <= c.LoginMaxDelay, "login_guard.base_delay_seconds", "can't be greater than login_guard.max_delay_seconds")
+	check(c.OutboxBaseDelay <= c.OutboxMaxDelay, "outbox.base_delay_seconds", "can't be greater than outbox.max_delay_seconds")
+
+	check(len(c.TokenSecret) >= 32, "tokens.secret", "must have at least 32 characters")
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	// Map iteration above is random, keep the report stable between runs.
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
 }
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Error writing %s: %v", name, err)
+	}
+	return path
+}
+
+func testEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestReadDefaults(t *testing.T) {
+	path := writeFile(t, "config.json", `{
+		"port": ":3000",
+		"frontend_path": "./build",
+		"db": {"dsn": "user:password@tcp(127.0.0.1:3306)/ucp"},
+		"email": {"smtp_host": "smtp.app.ro", "smtp_from": "ucp@app.ro"},
+		"tokens": {"secret": "`+testSecret+`"}
+	}`)
+
+	cfg, err := read(path, testEnv(nil))
+	if err != nil {
+		t.Fatalf("Error reading config: %v", err)
+	}
+
+	assert.Equal(t, "dev", cfg.Version)
+	assert.Equal(t, "https://app.ro", cfg.PublicURL)
+	assert.Equal(t, []string{"127.0.0.1", "::1"}, cfg.TrustedProxies)
+	assert.Equal(t, "X-Real-IP", cfg.ProxyHeader)
+	assert.Equal(t, 24, cfg.SessionExpiryHours)
+	assert.Equal(t, "tls", cfg.SMTPSecurity, "Port 465 defaults to implicit TLS")
+	assert.Equal(t, defaultAdminPermissions, cfg.AdminPermissions)
+}
+
+func TestReadOverrides(t *testing.T) {
+	secret := writeFile(t, "secret", testSecret+"\n")
+	password := writeFile(t, "smtp_password", "from-file")
+
+	path := writeFile(t, "config.json", `{
+		"port": ":3000",
+		"frontend_path": "./build",
+		"public_url": "https://ucp.app.ro/",
+		"db": {"dsn": "user:password@tcp(127.0.0.1:3306)/ucp"},
+		"email": {"smtp_host": "smtp.app.ro", "smtp_port": 587, "smtp_from": "ucp@app.ro", "smtp_password_file": "`+password+`"},
+		"tokens": {"secret": "short"}
+	}`)
+
+	cfg, err := read(path, testEnv(map[string]string{
+		"UCP_PORT":                    ":4000",
+		"UCP_DEV_MODE":                "true",
+		"UCP_HTTP_CORS_ORIGINS":       "https://app.ro, https://beta.app.ro",
+		"UCP_TOKENS_SECRET_FILE":      secret,
+		"UCP_PERMISSIONS_TESTER":      `{"2": ["staff.panel"]}`,
+		"UCP_OUTBOX_WORKERS":          "4",
+		"UCP_EMAIL_SMTP_IDLE_SECONDS": "0",
+	}))
+	if err != nil {
+		t.Fatalf("Error reading config: %v", err)
+	}
+
+	assert.Equal(t, ":4000", cfg.Port)
+	assert.True(t, cfg.DevMode)
+	assert.Equal(t, "https://ucp.app.ro", cfg.PublicURL)
+	assert.Equal(t, []string{"https://app.ro", "https://beta.app.ro"}, cfg.CORSOrigins)
+	assert.Equal(t, testSecret, cfg.TokenSecret, "The _FILE variable wins and the trailing newline is dropped")
+	assert.Equal(t, "from-file", cfg.SMTPPassword)
+	assert.Equal(t, map[int][]string{2: {"staff.panel"}}, cfg.TesterPermissions)
+	assert.Equal(t, 4, cfg.OutboxWorkers)
+	assert.Equal(t, "starttls", cfg.SMTPSecurity, "Other ports default to STARTTLS")
+}
+
+func TestReadReportsEveryError(t *testing.T) {
+	path := writeFile(t, "config.json", `{
+		"port": "3000",
+		"public_url": "app.ro",
+		"email": {"transport": "pigeon", "smtp_from": "nobody"},
+		"outbox": {"workers": "two"},
+		"tokens": {"secret": "short"}
+	}`)
+
+	_, err := read(path, testEnv(map[string]string{
+		"UCP_SESSION_EXPIRY_HOURS": "0",
+		"UCP_HTTP_TRUSTED_PROXIES": "127.0.0.1,proxy",
+	}))
+	if !assert.Error(t, err) {
+		return
+	}
+
+	for _, key := range []string{
+		"outbox.workers: must be an integer",
+		"db.dsn: is required",
+		"frontend_path: is required",
+		"port: must look like",
+		"public_url: must be an absolute",
+		"email.transport: must be smtp, file or memory",
+		"email.smtp_from: must be an email address",
+		"session.expiry_hours: must be positive",
+		`http.trusted_proxies: "proxy" is not an IP`,
+		"tokens.secret: must have at least 32 characters",
+	} {
+		assert.Contains(t, err.Error(), key)
+	}
+}
+
+func TestReadMissingSecretFile(t *testing.T) {
+	path := writeFile(t, "config.json", `{}`)
+
+	_, err := read(path, testEnv(map[string]string{"UCP_TOKENS_SECRET_FILE": filepath.Join(t.TempDir(), "missing")}))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "UCP_TOKENS_SECRET_FILE")
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "UCP_DB_DSN", EnvName("db.dsn"))
+	assert.Equal(t, "UCP_EMAIL_SMTP_PASSWORD", EnvName("email.smtp_password"))
+}
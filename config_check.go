@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	config "sarp_backend/config"
+	"sarp_backend/i18n"
+	"sarp_backend/service"
+	"strings"
+)
+
+// ConfigCheck validates the configuration the server would start with, including the
+// environment overrides and the email templates, without connecting to anything.
+func ConfigCheck(opts *options, args []string) error {
+	positional, err := parseArgs(global("config", opts), args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || positional[0] != "check" {
+		return errUsage
+	}
+
+	problems := checkConfig(opts.configPath)
+	c := &console{opts: opts, out: os.Stdout}
+
+	type result struct {
+		Valid  bool     `json:"valid"`
+		Errors []string `json:"errors"`
+	}
+	if len(problems) == 0 {
+		return c.print(result{Valid: true, Errors: []string{}}, "%s is valid", opts.configPath)
+	}
+
+	if err = c.print(result{Valid: false, Errors: problems}, "%s has %d errors:\n  %s", opts.configPath, len(problems), strings.Join(problems, "\n  ")); err != nil {
+		return err
+	}
+	return errInvalidConfig
+}
+
+// errInvalidConfig only sets the exit code, the errors are already printed.
+var errInvalidConfig = errors.New("invalid configuration")
+
+func checkConfig(path string) []string {
+	cfg, err := config.Read(path)
+	if err != nil {
+		return flatten(err)
+	}
+
+	catalog, err := i18n.Load()
+	if err != nil {
+		return []string{fmt.Sprintf("messages: %v", err)}
+	}
+	if _, err = service.NewEmailTemplates(catalog, cfg.EmailTemplatesDir); err != nil {
+		return []string{fmt.Sprintf("email.templates_dir: %v", err)}
+	}
+	return nil
+}
+
+// flatten lists the leaves of errors built with errors.Join.
+func flatten(err error) []string {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []string{err.Error()}
+	}
+
+	var list []string
+	for _, e := range joined.Unwrap() {
+		list = append(list, flatten(e)...)
+	}
+	return list
+}
@@ -0,0 +1,295 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"sarp_backend/i18n"
+	"sarp_backend/model"
+	"sarp_backend/service"
+)
+
+func (h *UserHandler) SetLocale(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "account.locale_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("SetLocale(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		return h.errorResponse(ctx, br, model.ErrNotAuthenticated)
+	}
+
+	var data model.LocaleAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("SetLocale(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	locale := h.I18n.Normalize(data.Locale)
+	if locale == "" {
+		return h.errorResponse(ctx, br, model.ErrInvalidLocale)
+	}
+
+	if err = h.User.SetLocale(ctx.UserContext(), name, locale); err != nil {
+		h.Logger.Exception(fmt.Sprintf("SetLocale(): error saving the locale of %s: %v", name, err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	ctx.Set(fiber.HeaderContentLanguage, locale)
+	ctx.SetUserContext(i18n.WithLocale(ctx.UserContext(), locale))
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
+
+// ChangePassword is the logged in counterpart of UpdatePassword. Every other session
+// of the account is closed and pending reset links stop working along with the old
+// password.
+func (h *UserHandler) ChangePassword(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "account.password_change_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangePassword(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		return h.errorResponse(ctx, br, model.ErrNotAuthenticated)
+	}
+
+	var data model.ChangePasswordAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangePassword(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = data.Validate(); err != nil {
+		return h.errorResponse(ctx, br, err)
+	}
+
+	session, err := h.Auth.SessionID(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangePassword(): error reading the session of %s: %v", name, err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if err = h.User.ChangePassword(ctx.UserContext(), name, session, &data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangePassword(): error changing the password of %s: %v", name, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	h.Logger.Info(fmt.Sprintf("ChangePassword(): %s changed the account password", name))
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
+
+func (h *UserHandler) Profile(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "stats.failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("Profile(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		return h.errorResponse(ctx, br, model.ErrNotAuthenticated)
+	}
+
+	profile, err := h.User.Profile(ctx.UserContext(), name)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("Profile(): error fetching the profile of %s: %v", name, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data *model.ProfileAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         profile,
+	})
+}
+
+func (h *UserHandler) UpdateProfile(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "account.profile_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("UpdateProfile(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		return h.errorResponse(ctx, br, model.ErrNotAuthenticated)
+	}
+
+	var data model.ProfileAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("UpdateProfile(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = data.Validate(); err != nil {
+		return h.errorResponse(ctx, br, err)
+	}
+
+	if err = h.User.UpdateProfile(ctx.UserContext(), name, &data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("UpdateProfile(): error saving the profile of %s: %v", name, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
+
+// ChangeEmail starts moving the account to a new address. The new address gets a
+// confirmation link and the current one a notice, accounts.Email only changes once
+// the link is opened.
+func (h *UserHandler) ChangeEmail(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "account.email_change_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangeEmail(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		return h.errorResponse(ctx, br, model.ErrNotAuthenticated)
+	}
+
+	var data model.ChangeEmailAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangeEmail(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = data.Validate(); err != nil {
+		return h.errorResponse(ctx, br, err)
+	}
+
+	if err = h.User.Verify(ctx.UserContext(), &model.LoginAPI{Username: name, Password: data.Password}); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangeEmail(): error verifying the password of %s: %v", name, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	taken, err := h.User.Fetch(ctx.UserContext(), "", data.Email)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangeEmail(): error checking for duplicate email: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if taken {
+		return h.errorResponse(ctx, br, model.ErrEmailTaken)
+	}
+
+	oldEmail, err := h.User.FetchMail(ctx.UserContext(), name)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangeEmail(): error fetching the email of %s: %v", name, err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	token, err := h.Tokens.Issue(ctx.UserContext(), service.TokenEmail, name, data.Email)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangeEmail(): error issuing email change token: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	locale := h.accountLocale(ctx, name, i18n.FromContext(ctx.UserContext()))
+	confirmationLink := h.link("verify-email", token)
+
+	confirmation, err := h.Templates.Render(locale, service.ChangeEmailEmail, service.ChangeEmailData{
+		Username: name,
+		Email:    data.Email,
+		Link:     confirmationLink,
+	})
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangeEmail(): error rendering confirmation email: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	notice, err := h.Templates.Render(locale, service.EmailChangedEmail, service.ChangeEmailData{
+		Username: name,
+		Email:    data.Email,
+	})
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangeEmail(): error rendering notice email: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	// The notice goes first, a link must never be sent without the owner being told.
+	if err = h.Outbox.Enqueue(ctx.UserContext(), service.OutboxKey(service.EmailChangedEmail, token), oldEmail, notice); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangeEmail(): error queueing notice email: %v", err))
+		br.Message = h.T(ctx, "email.send_failed")
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if err = h.Outbox.Enqueue(ctx.UserContext(), service.OutboxKey(service.ChangeEmailEmail, token), data.Email, confirmation); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ChangeEmail(): error queueing confirmation email: %v", err))
+		br.Message = h.T(ctx, "email.send_failed")
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
+
+// VerifyEmail is the link sent by ChangeEmail, it works with or without a session
+// since the new mailbox may be opened on another device.
+func (h *UserHandler) VerifyEmail(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "account.email_change_failed"),
+	}
+
+	token := ctx.Query("token")
+	if token == "" {
+		return h.errorResponse(ctx, br, model.ErrTokenInvalid)
+	}
+
+	data, err := h.Tokens.Consume(ctx.UserContext(), service.TokenEmail, token)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("VerifyEmail(): error consuming token: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	if err = h.User.ChangeEmail(ctx.UserContext(), data.Username, data.Email); err != nil {
+		h.Logger.Exception(fmt.Sprintf("VerifyEmail(): error changing the email of %s: %v", data.Username, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	h.Logger.Info(fmt.Sprintf("VerifyEmail(): %s changed the account email", data.Username))
+
+	return ctx.Redirect("/", fiber.StatusFound)
+}
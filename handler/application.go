@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"sarp_backend/model"
+)
+
+// validateApplication checks data against the current questionnaire. A form loaded
+// before staff saved new questions is refused, its answers would be stored against
+// the wrong version.
+func (h *UserHandler) validateApplication(ctx *fiber.Ctx, data *model.CharacterDataAPI) error {
+	questionnaire, err := h.Char.Questionnaire(ctx.UserContext())
+	if err != nil {
+		return fmt.Errorf("can't get the application questions: %w", err)
+	}
+
+	if data.QuestionnaireVersion != questionnaire.Version {
+		return fmt.Errorf("%w: answers for version %d, current is %d", model.ErrQuestionnaireChanged, data.QuestionnaireVersion, questionnaire.Version)
+	}
+	data.Questions = questionnaire.Questions
+
+	return data.Validate()
+}
+
+// CharacterApplication returns the last submission of a waiting or rejected character
+// of the player, with the rejection reason, to pre-fill the form.
+func (h *UserHandler) CharacterApplication(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "application.fetch_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("CharacterApplication(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("CharacterApplication(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var filter model.ApplicationFilterAPI
+	if err = ctx.QueryParser(&filter); err != nil {
+		h.Logger.Exception(fmt.Sprintf("CharacterApplication(): error parsing query: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	draft, err := h.Char.Application(ctx.UserContext(), name, filter.CharacterName)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("CharacterApplication(): can't fetch application of %s: %v", filter.CharacterName, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data *model.ApplicationDraftAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         draft,
+	})
+}
+
+// EditCharacter replaces the details and answers of a waiting character. The name
+// can't change, the player withdraws the character and creates it again instead.
+func (h *UserHandler) EditCharacter(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "application.update_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("EditCharacter(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("EditCharacter(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.CharacterDataAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("EditCharacter(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = h.validateApplication(ctx, &data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("EditCharacter(): error validating character data: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	data.Username = name
+	if err = h.Char.Edit(ctx.UserContext(), &data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("EditCharacter(): can't edit %s: %v", data.CharacterName, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
+
+// WithdrawCharacter deletes a waiting or rejected character of the player, freeing
+// its name and its slot.
+func (h *UserHandler) WithdrawCharacter(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "application.withdraw_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("WithdrawCharacter(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("WithdrawCharacter(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.WithdrawCharacterAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("WithdrawCharacter(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = h.Char.Withdraw(ctx.UserContext(), name, data.CharacterName); err != nil {
+		h.Logger.Exception(fmt.Sprintf("WithdrawCharacter(): can't withdraw %s: %v", data.CharacterName, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
+
+// ResubmitCharacter sends a rejected character back to the waiting list with new
+// answers, once the resubmission cooldown has passed.
+func (h *UserHandler) ResubmitCharacter(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "application.resubmit_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResubmitCharacter(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("ResubmitCharacter(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.CharacterDataAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResubmitCharacter(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = h.validateApplication(ctx, &data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResubmitCharacter(): error validating character data: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	data.Username = name
+	if err = h.Char.Resubmit(ctx.UserContext(), &data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResubmitCharacter(): can't resubmit %s: %v", data.CharacterName, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"sarp_backend/model"
+	"sarp_backend/service"
+)
+
+const auditPayloadLimit = 1000
+
+func auditEntry(ctx *fiber.Ctx, actor, action, target string, payload interface{}) *model.AuditAPI {
+	summary, err := json.Marshal(payload)
+	if err != nil {
+		summary = []byte("{}")
+	}
+
+	return &model.AuditAPI{
+		Actor:   actor,
+		Action:  action,
+		Target:  target,
+		Payload: model.Truncate(string(summary), auditPayloadLimit),
+		IP:      service.ClientIP(ctx),
+	}
+}
+
+func (h *UserHandler) AuditLog(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "audit.failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("AuditLog(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("AuditLog(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var filter model.AuditFilterAPI
+	if err = ctx.QueryParser(&filter); err != nil {
+		h.Logger.Exception(fmt.Sprintf("AuditLog(): error parsing query: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = filter.Validate(); err != nil {
+		return h.errorResponse(ctx, br, err)
+	}
+
+	page, err := h.Audit.List(ctx.UserContext(), &filter)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("AuditLog(): error fetching audit entries: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data *model.AuditPageAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         page,
+	})
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"sarp_backend/i18n"
+	"sarp_backend/model"
+	"sarp_backend/service"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sendCKEmail tells username about the decision on the request. Failures are only
+// logged, the decision is already stored.
+func (h *UserHandler) sendCKEmail(ctx *fiber.Ctx, caller, template, username string, request *model.CKAPI) {
+	email, err := h.User.FetchMail(ctx.UserContext(), username)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("%s(): can't get email of %s: %v", caller, username, err))
+		return
+	}
+
+	date := time.Now()
+	if request.ReviewedAt != nil {
+		date = *request.ReviewedAt
+	}
+
+	rendered, err := h.Templates.Render(h.accountLocale(ctx, username, i18n.Default), template, service.CKData{
+		Username:  username,
+		Character: request.CharacterName,
+		Date:      date.Format("02/01/2006, 15:04"),
+		Reason:    request.Reason,
+		Admin:     request.ReviewedBy,
+	})
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("%s(): can't render email: %v", caller, err))
+	} else if err = h.Outbox.Enqueue(ctx.UserContext(), service.OutboxKey(template, strconv.FormatInt(request.ID, 10), username), email, rendered); err != nil {
+		h.Logger.Exception(fmt.Sprintf("%s(): can't queue email: %v", caller, err))
+	}
+}
+
+// RequestCK files a CK against an accepted character. Players spend a CK voucher once
+// it is approved, staff with the review permission file it for free.
+func (h *UserHandler) RequestCK(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "ck.request_failed"),
+	}
+
+	name, adminLevel, testerLevel, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("RequestCK(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("RequestCK(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.CKRequestAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("RequestCK(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	staff := h.Perms.Allowed(adminLevel, testerLevel, service.PermCKReview)
+	id, err := h.Char.RequestCK(ctx.UserContext(), name, staff, &data)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("RequestCK(): can't file a CK against %s: %v", data.CharacterName, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data int64 `json:"data"`
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         id,
+	})
+}
+
+// CKRequests returns the CK requests filed by the player, newest first.
+func (h *UserHandler) CKRequests(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "ck.fetch_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("CKRequests(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("CKRequests(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	requests, err := h.Char.CKRequests(ctx.UserContext(), name, "")
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("CKRequests(): can't fetch requests of %s: %v", name, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data []model.CKAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         requests,
+	})
+}
+
+// CKQueue lists the requests of every player with their evidence, ?status= narrows
+// it down to pending, approved or denied ones.
+func (h *UserHandler) CKQueue(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "ck.fetch_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("CKQueue(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("CKQueue(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var filter model.CKFilterAPI
+	if err = ctx.QueryParser(&filter); err != nil {
+		h.Logger.Exception(fmt.Sprintf("CKQueue(): error parsing query: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = filter.Validate(); err != nil {
+		h.Logger.Exception(fmt.Sprintf("CKQueue(): invalid filter: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	requests, err := h.Char.CKRequests(ctx.UserContext(), "", filter.Status)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("CKQueue(): can't fetch requests: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data []model.CKAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         requests,
+	})
+}
+
+// ApproveCK marks the character dead and emails both the requester and the owner.
+func (h *UserHandler) ApproveCK(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "ck.approve_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ApproveCK(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("ApproveCK(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.CKDecisionAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ApproveCK(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = data.Validate(); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ApproveCK(): invalid request: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	audit := auditEntry(ctx, name, service.AuditCKApprove, strconv.FormatInt(data.ID, 10), data)
+	request, err := h.Char.ApproveCK(ctx.UserContext(), data.ID, name, audit)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ApproveCK(): can't approve request %d: %v", data.ID, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	h.sendCKEmail(ctx, "ApproveCK", service.CKApprovedEmail, request.Owner, request)
+	if !strings.EqualFold(request.Requester, request.Owner) {
+		h.sendCKEmail(ctx, "ApproveCK", service.CKApprovedEmail, request.Requester, request)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data *model.CKAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         request,
+	})
+}
+
+// DenyCK closes the request and emails the requester the reason. The owner isn't told,
+// the character keeps playing as if nothing was filed.
+func (h *UserHandler) DenyCK(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "ck.deny_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("DenyCK(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("DenyCK(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.CKDecisionAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("DenyCK(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	audit := auditEntry(ctx, name, service.AuditCKDeny, strconv.FormatInt(data.ID, 10), data)
+	request, err := h.Char.DenyCK(ctx.UserContext(), &data, name, audit)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("DenyCK(): can't deny request %d: %v", data.ID, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	h.sendCKEmail(ctx, "DenyCK", service.CKDeniedEmail, request.Requester, request)
+
+	type response struct {
+		model.BaseResponse
+		Data *model.CKAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         request,
+	})
+}
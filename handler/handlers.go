@@ -5,36 +5,66 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"net/http"
 	"net/mail"
+	"net/url"
+	"sarp_backend/i18n"
 	"sarp_backend/model"
 	"sarp_backend/service"
-	"strconv"
+	"strings"
 	"time"
 )
 
+// Settings are the deployment specific values of the handlers. A player can ask for
+// ConfirmEmailsLimit activation emails per address in every ConfirmEmailsWindow,
+// the one sent at registration included.
+type Settings struct {
+	PublicURL           string
+	ConfirmEmailsLimit  int
+	ConfirmEmailsWindow time.Duration
+}
+
 type UserHandler struct {
-	User        service.UserServiceInterface
-	Char        service.CharacterServiceInterface
-	Auth        service.AuthServiceInterface
-	Logger      service.LoggerInterface
-	Email       service.EmailInterface
-	EmailErrors chan error
+	User      service.UserServiceInterface
+	Char      service.CharacterServiceInterface
+	Auth      service.AuthServiceInterface
+	Logger    service.LoggerInterface
+	Outbox    service.OutboxServiceInterface
+	TwoFactor service.TwoFactorServiceInterface
+	Guard     service.LoginGuardServiceInterface
+	Tokens    service.TokenServiceInterface
+	Perms     service.PermissionServiceInterface
+	Audit     service.AuditServiceInterface
+	I18n      *i18n.Catalog
+	Templates *service.EmailTemplates
+	Settings  Settings
 }
 
-func New(userService service.UserServiceInterface, charService service.CharacterServiceInterface, authService service.AuthServiceInterface, logService service.LoggerInterface, emailService service.EmailInterface) *UserHandler {
+func New(userService service.UserServiceInterface, charService service.CharacterServiceInterface, authService service.AuthServiceInterface, logService service.LoggerInterface, outboxService service.OutboxServiceInterface, twoFactorService service.TwoFactorServiceInterface, guardService service.LoginGuardServiceInterface, tokenService service.TokenServiceInterface, permissionService service.PermissionServiceInterface, auditService service.AuditServiceInterface, catalog *i18n.Catalog, templates *service.EmailTemplates, settings Settings) *UserHandler {
 	return &UserHandler{
-		User:        userService,
-		Char:        charService,
-		Auth:        authService,
-		Logger:      logService,
-		Email:       emailService,
-		EmailErrors: make(chan error, 10),
+		User:      userService,
+		Char:      charService,
+		Auth:      authService,
+		Logger:    logService,
+		Outbox:    outboxService,
+		TwoFactor: twoFactorService,
+		Guard:     guardService,
+		Tokens:    tokenService,
+		Perms:     permissionService,
+		Audit:     auditService,
+		I18n:      catalog,
+		Templates: templates,
+		Settings:  settings,
 	}
 }
 
+// link is the public address of an API route carrying token.
+func (h *UserHandler) link(route, token string) string {
+	return fmt.Sprintf("%s/internal-ucp-api/v1/%s?token=%s", h.Settings.PublicURL, route, url.QueryEscape(token))
+}
+
 func (h *UserHandler) Register(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "A aparut o eroare interna.",
+		Message: h.T(ctx, "error.internal"),
 	}
 
 	var registerData model.RegisterAPI
@@ -46,47 +76,34 @@ func (h *UserHandler) Register(ctx *fiber.Ctx) error {
 
 	if err := registerData.Validate(); err != nil {
 		h.Logger.Exception(fmt.Sprintf("Register(): error validating data to register: %v", err))
-		br.Message = model.ErrorMsg(err.Error())
-		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+		return h.errorResponse(ctx, br, err)
 	}
 
-	fetched, err := h.User.Fetch(registerData.Username, registerData.Email)
+	fetched, err := h.User.Fetch(ctx.UserContext(), registerData.Username, registerData.Email)
 	if err != nil {
-		h.Logger.Exception(fmt.Sprintf("Register(): trying to duplicate register: %v", err))
-		br.Message = "Exista deja un cont cu acest nume sau aceasta adresa de mail."
-		return ctx.Status(fiber.StatusConflict).JSON(br)
+		h.Logger.Exception(fmt.Sprintf("Register(): error checking for duplicate account: %v", err))
+		return h.errorResponse(ctx, br, err)
 	}
 
 	if fetched {
 		h.Logger.Exception("Register(): trying to duplicate register")
-		br.Message = "Exista deja un cont cu acest nume sau aceasta adresa de mail."
-		return ctx.Status(fiber.StatusConflict).JSON(br)
+		return h.errorResponse(ctx, br, model.ErrAccountExists)
 	}
 
-	if err = h.User.Create(&registerData); err != nil {
+	if err = h.User.Create(ctx.UserContext(), &registerData); err != nil {
 		h.Logger.Exception(fmt.Sprintf("Register(): error creating account: %v", err))
-		return ctx.Status(http.StatusInternalServerError).JSON(br)
+		return h.errorResponse(ctx, br, err)
 	}
 
-	timestamp := time.Now().Unix()
-	token := service.GenerateToken(registerData.Email, timestamp)
-	confirmationLink := fmt.Sprintf("https://app.ro/internal-ucp-api/v1/confirm?email=%s&token=%s&timestamp=%d", registerData.Email, token, timestamp)
-	emailBody := fmt.Sprintf(service.ConfirmAccountEmail, registerData.Username, confirmationLink)
-
-	go func() {
-		if err = h.Email.SendEmail(registerData.Email, "Confirmare cont UCP", emailBody); err != nil {
-			h.Logger.Exception("Register(): Failed to send confirmation email: " + err.Error())
-		}
-
-		select {
-		case h.EmailErrors <- err:
-		default:
-			h.Logger.Exception("Register(): EmailErrors channel is full or unbuffered.")
-		}
-	}()
+	key, confirmation, err := h.confirmationEmail(ctx, registerData.Username, registerData.Email, i18n.FromContext(ctx.UserContext()))
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("Register(): %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
 
-	if err = <-h.EmailErrors; err != nil {
-		br.Message = "Nu a putut fi trimis mailul catre adresa oferita."
+	if err = h.Outbox.Enqueue(ctx.UserContext(), key, registerData.Email, confirmation); err != nil {
+		h.Logger.Exception(fmt.Sprintf("Register(): error queueing confirmation email: %v", err))
+		br.Message = h.T(ctx, "email.send_failed")
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
 	}
 
@@ -99,46 +116,120 @@ func (h *UserHandler) Register(ctx *fiber.Ctx) error {
 func (h *UserHandler) Confirm(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "A aparut o eroare interna.",
+		Message: h.T(ctx, "error.internal"),
 	}
 
-	email := ctx.Query("email")
 	token := ctx.Query("token")
-	timestampStr := ctx.Query("timestamp")
 
-	if email == "" || token == "" || timestampStr == "" {
-		br.Message = "Parametrii trebuie completati."
-		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	if token == "" {
+		return h.errorResponse(ctx, br, model.ErrTokenInvalid)
 	}
 
-	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+	data, err := h.Tokens.Consume(ctx.UserContext(), service.TokenConfirm, token)
 	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(br)
+		h.Logger.Exception(fmt.Sprintf("Confirm(): error consuming token: %v", err))
+		return h.errorResponse(ctx, br, err)
 	}
 
-	if !service.ValidateToken(email, token, timestamp) {
-		br.Message = "Token-ul este incorect."
-		return ctx.Status(fiber.StatusUnauthorized).JSON(br)
+	if err = h.User.ActivateAccount(ctx.UserContext(), data.Email); err != nil {
+		h.Logger.Exception("Confirm(): failed to activate account: " + err.Error())
+		br.Message = h.T(ctx, "account.activate_failed")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(br)
 	}
 
-	if time.Now().Unix()-timestamp > 15*60 {
-		br.Message = "Token-ul a expirat."
-		return ctx.Status(fiber.StatusUnauthorized).JSON(br)
+	return ctx.Redirect("/", fiber.StatusFound)
+}
+
+// ResendConfirmation sends a new activation link to an account that was never
+// confirmed. The answer is the same whether or not such an account exists.
+func (h *UserHandler) ResendConfirmation(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "error.internal"),
 	}
 
-	if err = h.User.ActivateAccount(email); err != nil {
-		h.Logger.Exception("Confirm(): failed to activate account: " + err.Error())
-		br.Message = "Contul nu poate fi activat."
-		return ctx.Status(fiber.StatusInternalServerError).JSON(br)
+	email := ctx.Query("email")
+
+	if _, err := mail.ParseAddress(email); err != nil {
+		return h.errorResponse(ctx, br, model.ErrInvalidEmail)
 	}
 
-	return ctx.Redirect("/", fiber.StatusFound)
+	ok := model.BaseResponse{
+		Error:   false,
+		Message: "",
+	}
+
+	name, err := h.User.FetchUsername(ctx.UserContext(), email)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResendConfirmation(): error fetching account: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		return ctx.Status(http.StatusOK).JSON(ok)
+	}
+
+	activated, err := h.User.CheckActivation(ctx.UserContext(), name)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResendConfirmation(): error checking for activation status: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if activated {
+		return ctx.Status(http.StatusOK).JSON(ok)
+	}
+
+	// The IP limiter doesn't stop someone from flooding one mailbox from many addresses.
+	issued, err := h.Tokens.Recent(ctx.UserContext(), service.TokenConfirm, email, h.Settings.ConfirmEmailsWindow)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResendConfirmation(): error counting confirmation tokens: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if issued >= h.Settings.ConfirmEmailsLimit {
+		h.Logger.Info(fmt.Sprintf("ResendConfirmation(): too many confirmation emails for %s", name))
+		return ctx.Status(http.StatusOK).JSON(ok)
+	}
+
+	key, confirmation, err := h.confirmationEmail(ctx, name, email, h.accountLocale(ctx, name, i18n.FromContext(ctx.UserContext())))
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResendConfirmation(): %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if err = h.Outbox.Enqueue(ctx.UserContext(), key, email, confirmation); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResendConfirmation(): error queueing confirmation email: %v", err))
+		br.Message = h.T(ctx, "email.send_failed")
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(ok)
+}
+
+// confirmationEmail issues an activation token and renders the email carrying it, the
+// returned key is the idempotency key to queue it with.
+func (h *UserHandler) confirmationEmail(ctx *fiber.Ctx, name, email, locale string) (string, *service.Email, error) {
+	token, err := h.Tokens.Issue(ctx.UserContext(), service.TokenConfirm, name, email)
+	if err != nil {
+		return "", nil, fmt.Errorf("error issuing confirmation token: %w", err)
+	}
+
+	confirmationLink := h.link("confirm", token)
+	confirmation, err := h.Templates.Render(locale, service.ConfirmAccountEmail, service.ConfirmAccountData{
+		Username: name,
+		Link:     confirmationLink,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("error rendering confirmation email: %w", err)
+	}
+
+	return service.OutboxKey(service.ConfirmAccountEmail, token), confirmation, nil
 }
 
 func (h *UserHandler) Login(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "Numele sau parola sunt gresite.",
+		Message: h.T(ctx, "error.invalid_credentials"),
 	}
 
 	var loginData model.LoginAPI
@@ -150,63 +241,110 @@ func (h *UserHandler) Login(ctx *fiber.Ctx) error {
 
 	if err := loginData.Validate(); err != nil {
 		h.Logger.Exception(fmt.Sprintf("Login(): error validating data: %v", err))
-		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+		return h.errorResponse(ctx, br, err)
 	}
 
-	banned, err := h.User.CheckForBan(loginData.Username)
+	ip := service.ClientIP(ctx)
+
+	retryAfter, err := h.Guard.Check(ctx.UserContext(), loginData.Username, ip)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("Login(): error checking lockout for user %s: %v", loginData.Username, err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if retryAfter > 0 {
+		return h.lockedResponse(ctx, br, retryAfter)
+	}
+
+	banned, err := h.User.CheckForBan(ctx.UserContext(), loginData.Username)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("Login(): error checking for ban for user %s: %v", loginData.Username, err))
-		return ctx.Status(http.StatusOK).JSON(br)
+		return h.errorResponse(ctx, br, err)
 	}
 
 	if banned {
-		br.Message = "You are banned."
-		return ctx.Status(http.StatusOK).JSON(br)
+		return h.errorResponse(ctx, br, model.ErrBanned)
 	}
 
-	fetched, err := h.User.Fetch(loginData.Username, "")
+	fetched, err := h.User.Fetch(ctx.UserContext(), loginData.Username, "")
 	if err != nil {
-		h.Logger.Exception("Login(): user doesn't exist " + err.Error())
-		return ctx.Status(fiber.StatusConflict).JSON(br)
+		h.Logger.Exception(fmt.Sprintf("Login(): error fetching account: %v", err))
+		return h.errorResponse(ctx, br, err)
 	}
 
 	if !fetched {
-		return ctx.Status(fiber.StatusConflict).JSON(br)
+		// Verify hashes a dummy password for unknown accounts, the answer must take as
+		// long as for a wrong password.
+		_ = h.User.Verify(ctx.UserContext(), &loginData)
+		if errFail := h.Guard.Fail(ctx.UserContext(), loginData.Username, ip); errFail != nil {
+			h.Logger.Exception(fmt.Sprintf("Login(): error recording failed login: %v", errFail))
+		}
+		return h.errorResponse(ctx, br, model.ErrInvalidCredentials)
 	}
 
-	activated, err := h.User.CheckActivation(loginData.Username)
+	activated, err := h.User.CheckActivation(ctx.UserContext(), loginData.Username)
 	if err != nil {
 		h.Logger.Exception("Login(): error checking for activation status:" + err.Error())
-		br.Message = "Contul nu este activat. Verifica adresa de email."
-		return ctx.Status(http.StatusConflict).JSON(br)
+		return h.errorResponse(ctx, br, err)
 	}
 
 	if !activated {
-		br.Message = "Contul nu este activat. Verifica adresa de email."
-		return ctx.Status(http.StatusConflict).JSON(br)
+		return h.errorResponse(ctx, br, model.ErrAccountNotActivated)
 	}
 
-	if err = h.User.Verify(&loginData); err != nil {
+	if err = h.User.Verify(ctx.UserContext(), &loginData); err != nil {
 		h.Logger.Exception(fmt.Sprintf("Login(): error fetching account: %v", err))
-		return ctx.Status(http.StatusUnauthorized).JSON(br)
+		if errFail := h.Guard.Fail(ctx.UserContext(), loginData.Username, ip); errFail != nil {
+			h.Logger.Exception(fmt.Sprintf("Login(): error recording failed login: %v", errFail))
+		}
+		return h.errorResponse(ctx, br, err)
 	}
 
-	isTester, errTester := h.User.IsTester(loginData.Username)
+	testerLevel, errTester := h.User.TesterLevel(ctx.UserContext(), loginData.Username)
 	if errTester != nil {
 		h.Logger.Exception(fmt.Sprintf("Login(): error fetching account: %v", errTester))
+		return ctx.SendStatus(http.StatusInternalServerError)
 	}
 
-	isAdmin, errAdmin := h.User.IsAdmin(loginData.Username)
+	adminLevel, errAdmin := h.User.AdminLevel(ctx.UserContext(), loginData.Username)
 	if errAdmin != nil {
 		h.Logger.Exception(fmt.Sprintf("Login(): error fetching account: %v", errAdmin))
 		return ctx.SendStatus(http.StatusInternalServerError)
 	}
 
-	if err = h.Auth.SaveSession(ctx, loginData.Username, isTester, isAdmin); err != nil {
+	twoFactor, err := h.TwoFactor.Enabled(ctx.UserContext(), loginData.Username)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("Login(): error checking two factor status: %v", err))
+		return ctx.SendStatus(http.StatusInternalServerError)
+	}
+
+	if twoFactor {
+		// The lockout is only reset once the second step succeeds.
+		if err = h.Auth.SaveSession(ctx, loginData.Username, 0, 0, service.TwoFactorPending); err != nil {
+			h.Logger.Exception(fmt.Sprintf("Login(): error saving session: %v", err))
+			return ctx.SendStatus(http.StatusInternalServerError)
+		}
+
+		type response struct {
+			model.BaseResponse
+			TwoFactorRequired bool `json:"two_factor_required"`
+		}
+
+		return ctx.Status(http.StatusAccepted).JSON(response{
+			BaseResponse:      model.BaseResponse{},
+			TwoFactorRequired: true,
+		})
+	}
+
+	if err = h.Auth.SaveSession(ctx, loginData.Username, adminLevel, testerLevel, service.TwoFactorNone); err != nil {
 		h.Logger.Exception(fmt.Sprintf("Login(): error saving session: %v", err))
 		return ctx.SendStatus(http.StatusInternalServerError)
 	}
 
+	if err = h.Guard.Reset(ctx.UserContext(), loginData.Username); err != nil {
+		h.Logger.Exception(fmt.Sprintf("Login(): error resetting failed logins: %v", err))
+	}
+
 	return ctx.Status(http.StatusAccepted).JSON(model.BaseResponse{
 		Error:   false,
 		Message: "",
@@ -225,37 +363,47 @@ func (h *UserHandler) Logout(ctx *fiber.Ctx) error {
 func (h *UserHandler) ResetRequest(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "A fost intampinata o eroare interna.",
+		Message: h.T(ctx, "error.internal"),
 	}
 
 	email := ctx.Query("email")
 
 	if _, err := mail.ParseAddress(email); err != nil {
-		br.Message = "Adresa de email este invalida."
-		return ctx.Status(http.StatusBadRequest).JSON(br)
+		return h.errorResponse(ctx, br, model.ErrInvalidEmail)
 	}
 
-	timestamp := time.Now().Unix()
-	token := service.GenerateToken(email, timestamp)
+	name, err := h.User.FetchUsername(ctx.UserContext(), email)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResetRequest(): error fetching account: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
 
-	confirmationLink := fmt.Sprintf("https://app.ro/internal-ucp-api/v1/confirm-reset?email=%s&token=%s&timestamp=%d", email, token, timestamp)
-	emailBody := fmt.Sprintf(service.ResetPasswordEmail, confirmationLink)
+	// Don't reveal whether an account uses this address.
+	if name == "" {
+		return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+			Error:   false,
+			Message: "",
+		})
+	}
 
-	go func() {
-		var err error
-		if err = h.Email.SendEmail(email, "Confirmare cont UCP", emailBody); err != nil {
-			h.Logger.Exception("Register(): Failed to send confirmation email: " + err.Error())
-		}
+	token, err := h.Tokens.Issue(ctx.UserContext(), service.TokenReset, name, email)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResetRequest(): error issuing reset token: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
 
-		select {
-		case h.EmailErrors <- err:
-		default:
-			h.Logger.Exception("Register(): EmailErrors channel is full or unbuffered.")
-		}
-	}()
+	confirmationLink := h.link("confirm-reset", token)
+	resetEmail, err := h.Templates.Render(h.accountLocale(ctx, name, i18n.FromContext(ctx.UserContext())), service.ResetPasswordEmail, service.ResetPasswordData{
+		Link: confirmationLink,
+	})
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResetRequest(): error rendering reset email: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
 
-	if err := <-h.EmailErrors; err != nil {
-		br.Message = "Nu a putut fi trimis mailul catre adresa oferita."
+	if err = h.Outbox.Enqueue(ctx.UserContext(), service.OutboxKey(service.ResetPasswordEmail, token), email, resetEmail); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ResetRequest(): error queueing reset email: %v", err))
+		br.Message = h.T(ctx, "email.send_failed")
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
 	}
 
@@ -268,39 +416,27 @@ func (h *UserHandler) ResetRequest(ctx *fiber.Ctx) error {
 func (h *UserHandler) ConfirmReset(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "A fost intampinata o eroare interna.",
-	}
-
-	resetPwd := model.UpdatePassword{
-		Email:       ctx.Query("email"),
-		Token:       ctx.Query("token"),
-		Timestamp:   int64(ctx.QueryInt("timestamp")),
-		NewPassword: "",
-	}
-
-	if _, err := mail.ParseAddress(resetPwd.Email); err != nil || resetPwd.Token == "" || resetPwd.Timestamp == 0 {
-		return ctx.Status(http.StatusBadRequest).JSON(br)
+		Message: h.T(ctx, "error.internal"),
 	}
 
-	ts := time.Now().Unix()
-	if ts-resetPwd.Timestamp > 15*60 {
-		br.Message = "Token-ul este expirat."
-		return ctx.Status(http.StatusBadRequest).JSON(br)
+	token := ctx.Query("token")
+	if token == "" {
+		return h.errorResponse(ctx, br, model.ErrTokenInvalid)
 	}
 
-	if !service.ValidateToken(resetPwd.Email, resetPwd.Token, resetPwd.Timestamp) {
-		br.Message = "Token-ul este invalid."
-		return ctx.Status(http.StatusBadRequest).JSON(br)
+	if _, err := h.Tokens.Peek(ctx.UserContext(), service.TokenReset, token); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ConfirmReset(): error checking token: %v", err))
+		return h.errorResponse(ctx, br, err)
 	}
 
-	url := fmt.Sprintf("/password-reset?email=%s&token=%s&timestamp=%d", resetPwd.Email, resetPwd.Token, resetPwd.Timestamp)
-	return ctx.Status(http.StatusFound).Redirect(url)
+	target := fmt.Sprintf("/password-reset?token=%s", url.QueryEscape(token))
+	return ctx.Status(http.StatusFound).Redirect(target)
 }
 
 func (h *UserHandler) UpdatePassword(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "A fost intampinata o eroare interna.",
+		Message: h.T(ctx, "error.internal"),
 	}
 
 	var resetPwd model.UpdatePassword
@@ -310,23 +446,17 @@ func (h *UserHandler) UpdatePassword(ctx *fiber.Ctx) error {
 	}
 
 	if err := resetPwd.Validate(); err != nil {
-		br.Message = model.ErrorMsg(err.Error())
-		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+		return h.errorResponse(ctx, br, err)
 	}
 
-	ts := time.Now().Unix()
-	if ts-resetPwd.Timestamp > 15*60 {
-		br.Message = "Token-ul este expirat."
-		return ctx.Status(http.StatusBadRequest).JSON(br)
-	}
-
-	if !service.ValidateToken(resetPwd.Email, resetPwd.Token, resetPwd.Timestamp) {
-		br.Message = "Token-ul este invalid."
-		return ctx.Status(http.StatusBadRequest).JSON(br)
+	data, err := h.Tokens.Consume(ctx.UserContext(), service.TokenReset, resetPwd.Token)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("UpdatePassword(): error checking token: %v", err))
+		return h.errorResponse(ctx, br, err)
 	}
 
-	if err := h.User.UpdatePassword(resetPwd.Email, resetPwd.NewPassword); err != nil {
-		h.Logger.Exception(fmt.Sprintf("ConfirmReset(): error updating new password: %v", err))
+	if err = h.User.UpdatePassword(ctx.UserContext(), data.Email, resetPwd.NewPassword); err != nil {
+		h.Logger.Exception(fmt.Sprintf("UpdatePassword(): error updating new password: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
 	}
 
@@ -339,7 +469,7 @@ func (h *UserHandler) UpdatePassword(ctx *fiber.Ctx) error {
 func (h *UserHandler) CheckAuth(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "you are not authenticated",
+		Message: h.T(ctx, "error.not_authenticated"),
 	}
 
 	name, _, _, err := h.Auth.CheckSession(ctx)
@@ -371,7 +501,7 @@ func (h *UserHandler) GetStats(ctx *fiber.Ctx) error {
 		return ctx.SendStatus(http.StatusUnauthorized)
 	}
 
-	data, err := h.User.GetStats(name)
+	data, err := h.User.GetStats(ctx.UserContext(), name)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("GetStats() error fetching stats: %v", err))
 		return ctx.SendStatus(http.StatusInternalServerError)
@@ -383,10 +513,10 @@ func (h *UserHandler) GetStats(ctx *fiber.Ctx) error {
 func (h *UserHandler) GetStaff(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "can't get data",
+		Message: h.T(ctx, "stats.failed"),
 	}
 
-	data, err := h.User.GetStaff()
+	data, err := h.User.GetStaff(ctx.UserContext())
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("GetStats() error fetching stats: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
@@ -406,10 +536,10 @@ func (h *UserHandler) GetStaff(ctx *fiber.Ctx) error {
 func (h *UserHandler) ServerStats(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "can't get data",
+		Message: h.T(ctx, "stats.failed"),
 	}
 
-	data, err := h.User.GetServerStats()
+	data, err := h.User.GetServerStats(ctx.UserContext())
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("ServerStats() error fetching stats: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
@@ -429,10 +559,10 @@ func (h *UserHandler) ServerStats(ctx *fiber.Ctx) error {
 func (h *UserHandler) CheckAdmin(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "you are not authenticated",
+		Message: h.T(ctx, "error.not_authenticated"),
 	}
 
-	name, admin, tester, err := h.Auth.CheckSession(ctx)
+	name, adminLevel, testerLevel, err := h.Auth.CheckSession(ctx)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("CheckAdmin(): error checking for admin session: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
@@ -443,22 +573,20 @@ func (h *UserHandler) CheckAdmin(ctx *fiber.Ctx) error {
 		return ctx.Status(http.StatusOK).JSON(br)
 	}
 
-	if !admin && !tester {
-		h.Logger.Exception("CheckAdmin(): invalid session: user doesn't have admin rights")
-		return ctx.Status(http.StatusOK).JSON(br)
-	}
-
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"user":      name,
-		"is_tester": tester,
-		"is_admin":  admin,
+		"user":         name,
+		"is_tester":    testerLevel > 0,
+		"is_admin":     adminLevel > 0,
+		"admin_level":  adminLevel,
+		"tester_level": testerLevel,
+		"permissions":  h.Perms.List(adminLevel, testerLevel),
 	})
 }
 
 func (h *UserHandler) CreateCharacter(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "A fost intampinata o eroare interna.",
+		Message: h.T(ctx, "error.internal"),
 	}
 
 	name, _, _, err := h.Auth.CheckSession(ctx)
@@ -478,7 +606,7 @@ func (h *UserHandler) CreateCharacter(ctx *fiber.Ctx) error {
 		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
 	}
 
-	stats, err := h.User.GetStats(name)
+	stats, err := h.User.GetStats(ctx.UserContext(), name)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("CreateCharacter(): can't get stats: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
@@ -487,22 +615,19 @@ func (h *UserHandler) CreateCharacter(ctx *fiber.Ctx) error {
 	if stats != nil {
 		if stats.Characters >= 5 {
 			h.Logger.Exception("CreateCharacter(): can't create more characters")
-			br.Message = "Ai atins numarul maxim de caractere."
-			return ctx.Status(http.StatusConflict).JSON(br)
+			return h.errorResponse(ctx, br, model.ErrCharacterLimit)
 		}
 	}
 
-	if err = createChar.Validate(); err != nil {
-		h.Logger.Exception("CreateCharacter(): error validating character data")
-		br.Message = model.ErrorMsg(err.Error())
-		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	if err = h.validateApplication(ctx, &createChar); err != nil {
+		h.Logger.Exception(fmt.Sprintf("CreateCharacter(): error validating character data: %v", err))
+		return h.errorResponse(ctx, br, err)
 	}
 
 	createChar.Username = name
-	if err = h.Char.Create(&createChar); err != nil {
+	if err = h.Char.Create(ctx.UserContext(), &createChar); err != nil {
 		h.Logger.Exception(fmt.Sprintf("CreateCharacter(): error creating character: %v", err))
-		br.Message = "Un caracter a fost deja creat cu acest nume."
-		return ctx.Status(http.StatusInternalServerError).JSON(br)
+		return h.errorResponse(ctx, br, err)
 	}
 
 	return ctx.Status(http.StatusCreated).JSON(model.BaseResponse{
@@ -512,7 +637,7 @@ func (h *UserHandler) CreateCharacter(ctx *fiber.Ctx) error {
 }
 
 func (h *UserHandler) WaitingList(ctx *fiber.Ctx) error {
-	name, isAdmin, isTester, err := h.Auth.CheckSession(ctx)
+	name, _, _, err := h.Auth.CheckSession(ctx)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("WaitingList(): error checking for session: %v", err))
 		return ctx.SendStatus(http.StatusInternalServerError)
@@ -523,12 +648,7 @@ func (h *UserHandler) WaitingList(ctx *fiber.Ctx) error {
 		return ctx.SendStatus(http.StatusUnauthorized)
 	}
 
-	if !isAdmin && !isTester {
-		h.Logger.Exception(fmt.Sprintf("WaitingList(): user %s doesn't have admin rights", name))
-		return ctx.SendStatus(http.StatusUnauthorized)
-	}
-
-	list, err := h.Char.FetchWaiting()
+	list, err := h.Char.FetchWaiting(ctx.UserContext())
 	if err != nil {
 		h.Logger.Exception("WaitingList(): session doesn't exist: user is not logged in")
 		return ctx.SendStatus(http.StatusUnauthorized)
@@ -540,10 +660,10 @@ func (h *UserHandler) WaitingList(ctx *fiber.Ctx) error {
 func (h *UserHandler) AcceptCharacter(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "Caracterul nu a putut fi acceptat.",
+		Message: h.T(ctx, "character.accept_failed"),
 	}
 
-	name, isAdmin, isTester, err := h.Auth.CheckSession(ctx)
+	name, _, _, err := h.Auth.CheckSession(ctx)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("AcceptCharacter(): error checking for session: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
@@ -554,11 +674,6 @@ func (h *UserHandler) AcceptCharacter(ctx *fiber.Ctx) error {
 		return ctx.Status(http.StatusUnauthorized).JSON(br)
 	}
 
-	if !isAdmin && !isTester {
-		h.Logger.Exception(fmt.Sprintf("AcceptCharacter(): user %s doesn't have admin rights", name))
-		return ctx.Status(http.StatusUnauthorized).JSON(br)
-	}
-
 	var acceptChar model.CharacterAPI
 	if err = ctx.BodyParser(&acceptChar); err != nil {
 		h.Logger.Exception(fmt.Sprintf("AcceptCharacter(): error parsing body request: %v", err))
@@ -567,24 +682,29 @@ func (h *UserHandler) AcceptCharacter(ctx *fiber.Ctx) error {
 
 	acceptChar.AcceptedBy = name
 
-	if err = h.Char.AcceptCharacter(acceptChar); err != nil {
+	owner, err := h.Char.AcceptCharacter(ctx.UserContext(), acceptChar, auditEntry(ctx, name, service.AuditCharacterAccept, acceptChar.CharacterName, acceptChar))
+	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("AcceptCharacter(): can't accept character: %v", err))
-		return ctx.Status(http.StatusInternalServerError).JSON(br)
+		return h.errorResponse(ctx, br, err)
 	}
 
-	email, err := h.User.FetchMail(acceptChar.Username)
+	email, err := h.User.FetchMail(ctx.UserContext(), owner)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("AcceptCharacter(): can't get email for character: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
 	}
 
-	emailBody := fmt.Sprintf(service.AcceptCharacterEmail, acceptChar.Username, acceptChar.CharacterName, time.Now().Format("02/01/2006, 15:04"))
-
-	go func() {
-		if err = h.Email.SendEmail(email, "SA-RP: Caracter acceptat", emailBody); err != nil {
-			h.Logger.Exception(fmt.Sprintf("AcceptCharacter(): can't send email: %v", err))
-		}
-	}()
+	date := time.Now().Format("02/01/2006, 15:04")
+	acceptedEmail, err := h.Templates.Render(h.accountLocale(ctx, owner, i18n.Default), service.AcceptCharacterEmail, service.CharacterAcceptedData{
+		Username:  owner,
+		Character: acceptChar.CharacterName,
+		Date:      date,
+	})
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("AcceptCharacter(): can't render email: %v", err))
+	} else if err = h.Outbox.Enqueue(ctx.UserContext(), service.OutboxKey(service.AcceptCharacterEmail, acceptChar.CharacterName, date), email, acceptedEmail); err != nil {
+		h.Logger.Exception(fmt.Sprintf("AcceptCharacter(): can't queue email: %v", err))
+	}
 
 	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
 		Error:   false,
@@ -595,10 +715,10 @@ func (h *UserHandler) AcceptCharacter(ctx *fiber.Ctx) error {
 func (h *UserHandler) RejectCharacter(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "Caracterul nu a putut fi refuzat",
+		Message: h.T(ctx, "character.reject_failed"),
 	}
 
-	name, isAdmin, isTester, err := h.Auth.CheckSession(ctx)
+	name, _, _, err := h.Auth.CheckSession(ctx)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("RejectCharacter(): error checking for session: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
@@ -609,36 +729,39 @@ func (h *UserHandler) RejectCharacter(ctx *fiber.Ctx) error {
 		return ctx.Status(http.StatusUnauthorized).JSON(br)
 	}
 
-	if !isAdmin && !isTester {
-		h.Logger.Exception(fmt.Sprintf("RejectCharacter(): user %s doesn't have admin rights", name))
-		return ctx.Status(http.StatusUnauthorized).JSON(br)
-	}
-
 	var declineChar model.RejectCharacterAPI
 	if err = ctx.BodyParser(&declineChar); err != nil {
 		h.Logger.Exception(fmt.Sprintf("RejectCharacter(): error parsing body request: %v", err))
 		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
 	}
 
-	if err = h.Char.DeclineCharacter(declineChar); err != nil {
-		h.Logger.Exception(fmt.Sprintf("RejectCharacter(): can't accept character: %v", err))
-		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	declineChar.RejectedBy = name
+
+	owner, err := h.Char.DeclineCharacter(ctx.UserContext(), declineChar, auditEntry(ctx, name, service.AuditCharacterReject, declineChar.CharacterName, declineChar))
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("RejectCharacter(): can't reject character: %v", err))
+		return h.errorResponse(ctx, br, err)
 	}
 
-	email, err := h.User.FetchMail(declineChar.Username)
+	email, err := h.User.FetchMail(ctx.UserContext(), owner)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("RejectCharacter(): can't get email for character: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
 	}
 
-	emailBody := fmt.Sprintf(service.DeclineCharacterEmail, declineChar.Username, declineChar.CharacterName, time.Now().Format("02/01/2006, 15:04"), declineChar.Reason, name)
-
-	go func() {
-		if err = h.Email.SendEmail(email, "SA-RP: Caracter refuzat", emailBody); err != nil {
-			h.Logger.Exception(fmt.Sprintf("RejectCharacter(): can't send email: %v", err))
-			return
-		}
-	}()
+	date := time.Now().Format("02/01/2006, 15:04")
+	rejectedEmail, err := h.Templates.Render(h.accountLocale(ctx, owner, i18n.Default), service.DeclineCharacterEmail, service.CharacterRejectedData{
+		Username:  owner,
+		Character: declineChar.CharacterName,
+		Date:      date,
+		Reason:    declineChar.Reason,
+		Admin:     name,
+	})
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("RejectCharacter(): can't render email: %v", err))
+	} else if err = h.Outbox.Enqueue(ctx.UserContext(), service.OutboxKey(service.DeclineCharacterEmail, declineChar.CharacterName, date), email, rejectedEmail); err != nil {
+		h.Logger.Exception(fmt.Sprintf("RejectCharacter(): can't queue email: %v", err))
+	}
 
 	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
 		Error:   false,
@@ -649,10 +772,10 @@ func (h *UserHandler) RejectCharacter(ctx *fiber.Ctx) error {
 func (h *UserHandler) FetchCharacter(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "Caracterul nu a putut fi gasit.",
+		Message: h.T(ctx, "error.character_not_found"),
 	}
 
-	name, isAdmin, _, err := h.Auth.CheckSession(ctx)
+	name, _, _, err := h.Auth.CheckSession(ctx)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("FetchCharacter(): error checking for session: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
@@ -663,21 +786,16 @@ func (h *UserHandler) FetchCharacter(ctx *fiber.Ctx) error {
 		return ctx.Status(http.StatusUnauthorized).JSON(br)
 	}
 
-	if !isAdmin {
-		h.Logger.Exception(fmt.Sprintf("FetchCharacter(): user %s doesn't have admin rights", name))
-		return ctx.Status(http.StatusUnauthorized).JSON(br)
-	}
-
 	var data model.CharacterAPI
 	if err = ctx.BodyParser(&data); err != nil {
 		h.Logger.Exception(fmt.Sprintf("FetchCharacter(): error parsing body request: %v", err))
 		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
 	}
 
-	fetchData, errFetch := h.User.FetchCharacter(data.CharacterName)
+	fetchData, errFetch := h.User.FetchCharacter(ctx.UserContext(), data.CharacterName)
 	if errFetch != nil {
 		h.Logger.Exception(fmt.Sprintf("FetchCharacter(): error fetching character: %v", errFetch))
-		return ctx.Status(http.StatusNotFound).JSON(br)
+		return h.errorResponse(ctx, br, errFetch)
 	}
 
 	type response struct {
@@ -695,7 +813,7 @@ func (h *UserHandler) FetchCharacter(ctx *fiber.Ctx) error {
 }
 
 func (h *UserHandler) BanList(ctx *fiber.Ctx) error {
-	name, isAdmin, _, err := h.Auth.CheckSession(ctx)
+	name, _, _, err := h.Auth.CheckSession(ctx)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("BanList(): error checking for session: %v", err))
 		return ctx.SendStatus(http.StatusInternalServerError)
@@ -706,12 +824,7 @@ func (h *UserHandler) BanList(ctx *fiber.Ctx) error {
 		return ctx.SendStatus(http.StatusUnauthorized)
 	}
 
-	if !isAdmin {
-		h.Logger.Exception(fmt.Sprintf("BanList(): user %s doesn't have admin rights", name))
-		return ctx.SendStatus(http.StatusUnauthorized)
-	}
-
-	bans, errBans := h.User.BanList()
+	bans, errBans := h.User.BanList(ctx.UserContext())
 	if errBans != nil {
 		h.Logger.Exception(fmt.Sprintf("BanList(): error fetching character: %v", errBans))
 		return ctx.SendStatus(http.StatusNotFound)
@@ -723,9 +836,9 @@ func (h *UserHandler) BanList(ctx *fiber.Ctx) error {
 func (h *UserHandler) Ban(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "Jucatorul nu a putut fi banat.",
+		Message: h.T(ctx, "ban.create_failed"),
 	}
-	name, isAdmin, _, err := h.Auth.CheckSession(ctx)
+	name, _, _, err := h.Auth.CheckSession(ctx)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("Ban(): error checking for session: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
@@ -736,11 +849,6 @@ func (h *UserHandler) Ban(ctx *fiber.Ctx) error {
 		return ctx.Status(http.StatusUnauthorized).JSON(br)
 	}
 
-	if !isAdmin {
-		h.Logger.Exception(fmt.Sprintf("Ban(): user %s doesn't have admin rights", name))
-		return ctx.Status(http.StatusUnauthorized).JSON(br)
-	}
-
 	var data model.BanAPI
 	if err = ctx.BodyParser(&data); err != nil {
 		h.Logger.Exception(fmt.Sprintf("Ban(): error parsing body request: %v", err))
@@ -749,9 +857,9 @@ func (h *UserHandler) Ban(ctx *fiber.Ctx) error {
 
 	data.AdminName = name
 
-	if errBan := h.User.Ban(&data); errBan != nil {
-		h.Logger.Exception(fmt.Sprintf("Ban(): error fetching character: %v", errBan))
-		return ctx.Status(http.StatusNotFound).JSON(br)
+	if errBan := h.User.Ban(ctx.UserContext(), &data, auditEntry(ctx, name, service.AuditBanCreate, data.Username, data)); errBan != nil {
+		h.Logger.Exception(fmt.Sprintf("Ban(): error banning account: %v", errBan))
+		return h.errorResponse(ctx, br, errBan)
 	}
 
 	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
@@ -763,10 +871,10 @@ func (h *UserHandler) Ban(ctx *fiber.Ctx) error {
 func (h *UserHandler) Unban(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "Jucatorul nu a putut fi debanat.",
+		Message: h.T(ctx, "ban.revoke_failed"),
 	}
 
-	name, isAdmin, _, err := h.Auth.CheckSession(ctx)
+	name, _, _, err := h.Auth.CheckSession(ctx)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("Unban(): error checking for session: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
@@ -777,11 +885,6 @@ func (h *UserHandler) Unban(ctx *fiber.Ctx) error {
 		return ctx.Status(http.StatusUnauthorized).JSON(br)
 	}
 
-	if !isAdmin {
-		h.Logger.Exception(fmt.Sprintf("Unban(): user %s doesn't have admin rights", name))
-		return ctx.Status(http.StatusUnauthorized).JSON(br)
-	}
-
 	var data model.BanAPI
 	if err = ctx.BodyParser(&data); err != nil {
 		h.Logger.Exception(fmt.Sprintf("Unban(): error parsing body request: %v", err))
@@ -792,12 +895,12 @@ func (h *UserHandler) Unban(ctx *fiber.Ctx) error {
 
 	if data.Username == "" {
 		h.Logger.Exception(fmt.Sprintf("Unban(): can't have name empty"))
-		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+		return h.errorResponse(ctx, br, model.ErrMissingFields)
 	}
 
-	if errUnban := h.User.Unban(&data); errUnban != nil {
-		h.Logger.Exception(fmt.Sprintf("Unban(): error fetching character: %v", errUnban))
-		return ctx.Status(http.StatusNotFound).JSON(br)
+	if errUnban := h.User.Unban(ctx.UserContext(), &data, auditEntry(ctx, name, service.AuditBanRevoke, data.Username, data)); errUnban != nil {
+		h.Logger.Exception(fmt.Sprintf("Unban(): error removing ban: %v", errUnban))
+		return h.errorResponse(ctx, br, errUnban)
 	}
 
 	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
@@ -809,7 +912,7 @@ func (h *UserHandler) Unban(ctx *fiber.Ctx) error {
 func (h *UserHandler) Ajail(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "Jucatorul nu a putut fi sanctionat.",
+		Message: h.T(ctx, "ajail.failed"),
 	}
 
 	name, _, _, err := h.Auth.CheckSession(ctx)
@@ -818,6 +921,11 @@ func (h *UserHandler) Ajail(ctx *fiber.Ctx) error {
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
 	}
 
+	if name == "" {
+		h.Logger.Exception("Ajail(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
 	var data model.AjailAPI
 	if err = ctx.BodyParser(&data); err != nil {
 		h.Logger.Exception(fmt.Sprintf("Ajail(): error parsing body request: %v", err))
@@ -828,13 +936,12 @@ func (h *UserHandler) Ajail(ctx *fiber.Ctx) error {
 
 	if data.Character == "" || data.Reason == "" || data.Time == 0 {
 		h.Logger.Exception(fmt.Sprintf("Ajail(): can't have empty fields"))
-		br.Message = "Unul sau mai multe campuri nu sunt completate."
-		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+		return h.errorResponse(ctx, br, model.ErrMissingFields)
 	}
 
-	if errAjail := h.User.Ajail(&data); errAjail != nil {
-		h.Logger.Exception(fmt.Sprintf("Ajail(): error fetching character: %v", errAjail))
-		return ctx.Status(http.StatusNotFound).JSON(br)
+	if errAjail := h.User.Ajail(ctx.UserContext(), &data, auditEntry(ctx, name, service.AuditAjail, data.Character, data)); errAjail != nil {
+		h.Logger.Exception(fmt.Sprintf("Ajail(): error jailing character: %v", errAjail))
+		return h.errorResponse(ctx, br, errAjail)
 	}
 
 	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
@@ -846,24 +953,51 @@ func (h *UserHandler) Ajail(ctx *fiber.Ctx) error {
 func (h *UserHandler) Logs(ctx *fiber.Ctx) error {
 	br := model.BaseResponse{
 		Error:   true,
-		Message: "Log-urile nu au putut fi obtinute.",
+		Message: h.T(ctx, "logs.failed"),
+	}
+
+	name, adminLevel, testerLevel, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("Logs(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("Logs(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
 	}
 
 	var data model.LogsAPI
-	if err := ctx.BodyParser(&data); err != nil {
+	if err = ctx.BodyParser(&data); err != nil {
 		h.Logger.Exception(fmt.Sprintf("Logs(): error parsing body request: %v", err))
 		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
 	}
 
-	logs, err := h.User.Logs(&data)
+	if err = data.Validate(); err != nil {
+		h.Logger.Exception(fmt.Sprintf("Logs(): error validating filters: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	permission := service.PermLogsRead + ":" + strings.ToLower(data.Type)
+	if !h.Perms.Allowed(adminLevel, testerLevel, permission) {
+		h.Logger.Exception(fmt.Sprintf("Logs(): user %s doesn't have permission %s", name, permission))
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	logs, err := h.User.Logs(ctx.UserContext(), &data)
 	if err != nil {
 		h.Logger.Exception(fmt.Sprintf("Logs(): error fetching logs: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	if err = h.Audit.Record(ctx.UserContext(), auditEntry(ctx, name, service.AuditLogsRead, data.Type, data)); err != nil {
+		h.Logger.Exception(fmt.Sprintf("Logs(): error recording audit entry: %v", err))
 		return ctx.Status(http.StatusInternalServerError).JSON(br)
 	}
 
 	type response struct {
 		model.BaseResponse
-		Logs []map[string]interface{} `json:"logs"`
+		*model.LogsPageAPI
 	}
 
 	return ctx.Status(http.StatusOK).JSON(response{
@@ -871,6 +1005,6 @@ func (h *UserHandler) Logs(ctx *fiber.Ctx) error {
 			Error:   false,
 			Message: "",
 		},
-		Logs: logs,
+		LogsPageAPI: logs,
 	})
 }
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,11 +9,18 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sarp_backend/i18n"
 	"sarp_backend/model"
+	"sarp_backend/repository"
 	"sarp_backend/service"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 )
 
 func TestRegister(t *testing.T) {
@@ -26,7 +34,7 @@ func TestRegister(t *testing.T) {
 		{
 			"Visitor registers new account",
 			func(auth *service.MockAuthService, email *service.MockEmailService, log *service.MockLoggerService) {
-				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.RegisterAPI{
 				Username: testUsername,
@@ -49,7 +57,8 @@ func TestRegister(t *testing.T) {
 			http.StatusUnprocessableEntity,
 			&model.BaseResponse{
 				Error:   true,
-				Message: "Parola trebuie sa aiba minim 8 caractere (litere, cifre si caractere speciale)",
+				Code:    "missing_fields",
+				Message: "Unul sau mai multe campuri nu sunt completate.",
 			},
 		},
 	}
@@ -65,7 +74,7 @@ func TestRegister(t *testing.T) {
 
 			tt.mockFunc(auth, email, log)
 
-			app := testServer(service.NewUserService(repo), auth, email, nil, log)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, log, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 			resp := testSendRequest(t, app, http.MethodPost, "/register", tt.data)
 
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected response HTTP status code for test: %s", tt.name)
@@ -83,53 +92,52 @@ func TestRegister(t *testing.T) {
 }
 
 func TestConfirm(t *testing.T) {
-	ts := time.Now().Unix()
-	token := service.GenerateToken(testEmail, ts)
-
 	tests := []struct {
 		name           string
 		mockFunc       func(*service.MockAuthService, *service.MockEmailService, *service.MockLoggerService)
-		email          string
-		token          string
+		issueToken     bool
+		reuseToken     bool
 		expectedStatus int
 		expectedBody   *model.BaseResponse
 	}{
 		{
 			"User confirms their registration",
 			func(auth *service.MockAuthService, email *service.MockEmailService, log *service.MockLoggerService) {
-				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
-			testEmail,
-			token,
+			true,
+			false,
 			http.StatusFound,
 			nil,
 		},
 		{
-			"Invalid email is passed to be confirmed",
+			"Token is used a second time",
 			func(auth *service.MockAuthService, email *service.MockEmailService, log *service.MockLoggerService) {
-				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 				log.On("Exception", mock.AnythingOfType("string")).Return()
 			},
-			"invalid",
-			token,
-			http.StatusUnauthorized,
+			true,
+			true,
+			http.StatusBadRequest,
 			&model.BaseResponse{
 				Error:   true,
-				Message: "Token-ul este incorect.",
+				Code:    "token_invalid",
+				Message: "Token-ul este invalid.",
 			},
 		},
 		{
 			"Invalid token is passed to be confirmed",
 			func(auth *service.MockAuthService, email *service.MockEmailService, log *service.MockLoggerService) {
-				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 				log.On("Exception", mock.AnythingOfType("string")).Return()
 			},
-			testEmail,
-			uuid.NewString(),
-			http.StatusUnauthorized,
+			false,
+			false,
+			http.StatusBadRequest,
 			&model.BaseResponse{
 				Error:   true,
-				Message: "Token-ul este incorect.",
+				Code:    "token_invalid",
+				Message: "Token-ul este invalid.",
 			},
 		},
 	}
@@ -145,10 +153,22 @@ func TestConfirm(t *testing.T) {
 
 			tt.mockFunc(auth, email, log)
 
-			app := testServer(service.NewUserService(repo), auth, email, nil, log)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, log, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 			registerAccount(t, app)
 
-			target := fmt.Sprintf("/confirm?email=%s&token=%s&timestamp=%d", tt.email, tt.token, ts)
+			token := uuid.NewString()
+			if tt.issueToken {
+				var err error
+				if token, err = testTokens(repo).Issue(context.Background(), service.TokenConfirm, testUsername, testEmail); err != nil {
+					t.Fatalf("Error issuing confirmation token: %v", err)
+				}
+			}
+
+			target := "/confirm?token=" + token
+			if tt.reuseToken {
+				testSendRequest(t, app, http.MethodGet, target, nil)
+			}
+
 			resp := testSendRequest(t, app, http.MethodGet, target, nil)
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected response HTTP status code for test: %s", tt.name)
 
@@ -163,6 +183,63 @@ func TestConfirm(t *testing.T) {
 	}
 }
 
+func TestResendConfirmation(t *testing.T) {
+	tests := []struct {
+		name           string
+		confirm        bool
+		address        string
+		requests       int
+		expectedStatus int
+		expectedCode   string
+		expectedEmails int
+	}{
+		{"Unconfirmed account gets a new link", false, testEmail, 1, http.StatusOK, "", 2},
+		{"Unknown address gets the same answer", false, "other@test.ro", 1, http.StatusOK, "", 1},
+		{"Confirmed account gets nothing", true, testEmail, 1, http.StatusOK, "", 1},
+		{"Too many links for the same address", false, testEmail, 4, http.StatusOK, "", 3},
+		{"Invalid address", false, "test", 1, http.StatusUnprocessableEntity, "invalid_email", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testRepository(t)
+			defer testCleanup(t, repo)
+
+			auth := new(service.MockAuthService)
+			email := new(service.MockEmailService)
+			log := new(service.MockLoggerService)
+
+			log.On("Info", mock.AnythingOfType("string")).Return()
+
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, log, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+			if tt.confirm {
+				registerAndConfirmAccount(t, app, repo)
+			} else {
+				registerAccount(t, app)
+			}
+
+			var resp *http.Response
+			for i := 0; i < tt.requests; i++ {
+				resp = testSendRequest(t, app, http.MethodGet, "/resend-confirmation?email="+url.QueryEscape(tt.address), nil)
+			}
+
+			var responseBody model.BaseResponse
+			if err := json.NewDecoder(resp.Body).Decode(&responseBody); err != nil {
+				t.Fatalf("Error decoding response body for test %s: %v", tt.name, err)
+			}
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
+			assert.Equal(t, tt.expectedCode, responseBody.Code, "Unexpected error code for test: %s", tt.name)
+
+			_, queued, err := repo.FetchEmails(context.Background(), &repository.EmailFilterDB{Limit: 10})
+			if err != nil {
+				t.Fatalf("Error fetching queued emails for test %s: %v", tt.name, err)
+			}
+			assert.Equal(t, tt.expectedEmails, queued, "Unexpected number of queued emails for test: %s", tt.name)
+		})
+	}
+}
+
 func TestLogin(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -174,8 +251,8 @@ func TestLogin(t *testing.T) {
 		{
 			"Visitor successfully authenticates",
 			func(auth *service.MockAuthService, email *service.MockEmailService, log *service.MockLoggerService) {
-				auth.On("SaveSession", mock.Anything, testUsername, false, false).Return(nil)
-				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string")).Return(nil)
+				auth.On("SaveSession", mock.Anything, testUsername, 0, 0, service.TwoFactorNone).Return(nil)
+				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			model.LoginAPI{
 				Username: testUsername,
@@ -190,7 +267,7 @@ func TestLogin(t *testing.T) {
 		{
 			"Invalid credentials are passed on login",
 			func(auth *service.MockAuthService, email *service.MockEmailService, log *service.MockLoggerService) {
-				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 				log.On("Exception", mock.AnythingOfType("string")).Return()
 			},
 			model.LoginAPI{
@@ -200,6 +277,7 @@ func TestLogin(t *testing.T) {
 			http.StatusUnauthorized,
 			model.BaseResponse{
 				Error:   true,
+				Code:    "invalid_credentials",
 				Message: "Numele sau parola sunt gresite.",
 			},
 		},
@@ -216,8 +294,8 @@ func TestLogin(t *testing.T) {
 
 			tt.mockFunc(auth, email, log)
 
-			app := testServer(service.NewUserService(repo), auth, email, nil, log)
-			registerAndConfirmAccount(t, app)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, log, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+			registerAndConfirmAccount(t, app, repo)
 
 			resp := testSendRequest(t, app, http.MethodPost, "/login", tt.data)
 
@@ -240,9 +318,9 @@ func TestLoginUnconfirmedAccount(t *testing.T) {
 	email := new(service.MockEmailService)
 	log := new(service.MockLoggerService)
 
-	email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string")).Return(nil)
+	email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 
-	app := testServer(service.NewUserService(repo), auth, email, nil, log)
+	app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, log, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 	registerAccount(t, app)
 
 	resp := testSendRequest(t, app, http.MethodPost, "/login", model.LoginAPI{
@@ -252,6 +330,7 @@ func TestLoginUnconfirmedAccount(t *testing.T) {
 
 	expectedBody := model.BaseResponse{
 		Error:   true,
+		Code:    "account_not_activated",
 		Message: "Contul nu este activat. Verifica adresa de email.",
 	}
 
@@ -264,6 +343,37 @@ func TestLoginUnconfirmedAccount(t *testing.T) {
 	assert.Equal(t, expectedBody, responseBody, "Unexpected response body for test: %s", t.Name())
 }
 
+func TestLoginLockout(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	auth := new(service.MockAuthService)
+	email := new(service.MockEmailService)
+	log := new(service.MockLoggerService)
+
+	email.On("SendEmail", testEmail, "Confirmare cont UCP", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	log.On("Exception", mock.AnythingOfType("string")).Return()
+
+	app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, log, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+	registerAndConfirmAccount(t, app, repo)
+
+	for i := 0; i < testGuardConfig.FreeAttempts; i++ {
+		resp := testSendRequest(t, app, http.MethodPost, "/login", model.LoginAPI{
+			Username: testUsername,
+			Password: "invalid",
+		})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "Unexpected response HTTP status code for attempt %d", i)
+	}
+
+	resp := testSendRequest(t, app, http.MethodPost, "/login", model.LoginAPI{
+		Username: testUsername,
+		Password: testPassword,
+	})
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "Unexpected response HTTP status code for locked account")
+	assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter), "Missing Retry-After header for locked account")
+}
+
 func TestLogout(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -293,7 +403,7 @@ func TestLogout(t *testing.T) {
 			log := new(service.MockLoggerService)
 
 			tt.mockFunc(auth, log)
-			app := testServer(nil, auth, nil, nil, log)
+			app := testServer(nil, auth, nil, nil, log, nil, nil, nil, nil)
 			resp := testSendRequest(t, app, http.MethodPost, "/logout", nil)
 
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
@@ -311,7 +421,7 @@ func TestCheckAuth(t *testing.T) {
 		{
 			name: "Authenticated user is verified",
 			mockFunc: func(auth *service.MockAuthService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody: fiber.Map{
@@ -322,7 +432,7 @@ func TestCheckAuth(t *testing.T) {
 		{
 			name: "User is not authenticated",
 			mockFunc: func(auth *service.MockAuthService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return("", false, false, nil)
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil)
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
 			},
 			expectedStatus: http.StatusOK,
@@ -337,7 +447,7 @@ func TestCheckAuth(t *testing.T) {
 
 			tt.mockFunc(auth, logger)
 
-			app := testServer(nil, auth, nil, nil, logger)
+			app := testServer(nil, auth, nil, nil, logger, nil, nil, nil, nil)
 			resp := testSendRequest(t, app, http.MethodGet, "/check-auth", nil)
 
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
@@ -363,8 +473,8 @@ func TestGetStats(t *testing.T) {
 		{
 			"User is authenticated and gets their stats retrieved",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			http.StatusOK,
 			&model.GetStatsAPI{
@@ -380,9 +490,9 @@ func TestGetStats(t *testing.T) {
 		{
 			"User is not authenticated",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return("", false, false, nil)
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil)
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			http.StatusUnauthorized,
 			nil,
@@ -400,9 +510,9 @@ func TestGetStats(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, nil, logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			resp := testSendRequest(t, app, http.MethodGet, "/get-data", nil)
 
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
@@ -429,7 +539,7 @@ func TestGetStaff(t *testing.T) {
 		{
 			"User is authenticated and gets staff list",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			http.StatusOK,
 			&model.GetStaffAPI{
@@ -450,9 +560,9 @@ func TestGetStaff(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, nil, logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			resp := testSendRequest(t, app, http.MethodGet, "/get-staff", nil)
 
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
@@ -484,7 +594,7 @@ func TestServerStats(t *testing.T) {
 		{
 			"User is authenticated and gets server stats",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			http.StatusOK,
 			&response{
@@ -507,9 +617,9 @@ func TestServerStats(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, nil, logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			resp := testSendRequest(t, app, http.MethodGet, "/server-stats", nil)
 
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
@@ -537,8 +647,8 @@ func TestCreateCharacter(t *testing.T) {
 		{
 			"User is authenticated and sends correct data",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.CharacterDataAPI{
 				Username:        testUsername,
@@ -553,9 +663,9 @@ func TestCreateCharacter(t *testing.T) {
 		{
 			"User is not authenticated",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return("", false, false, nil)
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil)
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			nil,
 			http.StatusUnauthorized,
@@ -564,9 +674,9 @@ func TestCreateCharacter(t *testing.T) {
 		{
 			"User provides invalid character data",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.CharacterDataAPI{
 				Username:        testUsername,
@@ -576,7 +686,11 @@ func TestCreateCharacter(t *testing.T) {
 				CharacterOrigin: "test",
 			},
 			http.StatusUnprocessableEntity,
-			nil,
+			&model.BaseResponse{
+				Error:   true,
+				Code:    "character_name_required",
+				Message: "Numele caracterului trebuie completat.",
+			},
 		},
 	}
 
@@ -591,9 +705,9 @@ func TestCreateCharacter(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, service.NewCharacterService(repo), logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			resp := testSendRequest(t, app, http.MethodPost, "/create-character", tt.data)
 
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
@@ -610,6 +724,45 @@ func TestCreateCharacter(t *testing.T) {
 	}
 }
 
+func TestCreateCharacterNameTaken(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	auth := new(service.MockAuthService)
+	email := new(service.MockEmailService)
+	logger := new(service.MockLoggerService)
+
+	auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil)
+	email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	logger.On("Exception", mock.AnythingOfType("string")).Return()
+
+	app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+	registerAndConfirmAccount(t, app, repo)
+
+	data := &model.CharacterDataAPI{
+		CharacterName:   "Test_Test",
+		CharacterAge:    18,
+		CharacterOrigin: "test",
+	}
+
+	resp := testSendRequest(t, app, http.MethodPost, "/create-character", data)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Unexpected status code for the first character")
+
+	resp = testSendRequest(t, app, http.MethodPost, "/create-character", data)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "Unexpected status code for a duplicate character name")
+
+	var respBody model.BaseResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		t.Fatalf("Error decoding response body: %v", err)
+	}
+
+	assert.Equal(t, model.BaseResponse{
+		Error:   true,
+		Code:    "character_name_taken",
+		Message: "Un caracter a fost deja creat cu acest nume.",
+	}, respBody)
+}
+
 func TestCheckAdmin(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -619,26 +772,17 @@ func TestCheckAdmin(t *testing.T) {
 		{
 			name: "User is authenticated and with correct privilege",
 			mockFunc: func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, true, false, nil).Once()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil).Once()
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			expectedStatus: http.StatusOK,
 		},
-		{
-			"User is authenticated but with wrong privilege",
-			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
-			},
-			http.StatusOK,
-		},
 		{
 			"User is not authenticated",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return("", false, false, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil).Once()
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			http.StatusOK,
 		},
@@ -655,9 +799,9 @@ func TestCheckAdmin(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, nil, logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			resp := testSendRequest(t, app, http.MethodGet, "/restricted/check", nil)
 
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
@@ -675,9 +819,9 @@ func TestWaitingList(t *testing.T) {
 		{
 			"User is authenticated and with correct privilege",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, true, false, nil).Once()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil).Once()
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			http.StatusOK,
 			[]model.CharacterDataAPI{
@@ -690,24 +834,13 @@ func TestWaitingList(t *testing.T) {
 				},
 			},
 		},
-		{
-			"User is authenticated but with wrong privilege",
-			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
-			},
-			http.StatusUnauthorized,
-			nil,
-		},
 		{
 			"User is not authenticated",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return("", false, false, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil).Once()
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			http.StatusUnauthorized,
 			nil,
@@ -725,9 +858,9 @@ func TestWaitingList(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, service.NewCharacterService(repo), logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			createCharacter(t, app)
 			resp := testSendRequest(t, app, http.MethodGet, "/restricted/waiting-list", nil)
 
@@ -754,9 +887,9 @@ func TestAcceptCharacter(t *testing.T) {
 		{
 			"User is authenticated and with correct privilege",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, true, false, nil).Once()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil).Once()
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.CharacterAPI{
 				Username:      testUsername,
@@ -766,27 +899,25 @@ func TestAcceptCharacter(t *testing.T) {
 			http.StatusOK,
 		},
 		{
-			"User is authenticated but with wrong privilege",
+			"The owner is read from the character, not the body",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil).Once()
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.CharacterAPI{
-				Username:      testUsername,
+				Username:      "Nobody",
 				CharacterName: "Test_Test",
-				AcceptedBy:    testUsername,
 			},
-			http.StatusUnauthorized,
+			http.StatusOK,
 		},
 		{
 			"User is not authenticated",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return("", false, false, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil).Once()
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.CharacterAPI{
 				Username:      testUsername,
@@ -808,9 +939,9 @@ func TestAcceptCharacter(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, service.NewCharacterService(repo), logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			createCharacter(t, app)
 			resp := testSendRequest(t, app, http.MethodPost, "/restricted/accept-character", tt.data)
 
@@ -829,9 +960,9 @@ func TestRejectCharacter(t *testing.T) {
 		{
 			"User is authenticated and with correct privilege",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, true, false, nil).Once()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil).Once()
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.CharacterAPI{
 				Username:      testUsername,
@@ -840,28 +971,13 @@ func TestRejectCharacter(t *testing.T) {
 			},
 			http.StatusOK,
 		},
-		{
-			"User is authenticated but with wrong privilege",
-			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
-			},
-			&model.CharacterAPI{
-				Username:      testUsername,
-				CharacterName: "Test_Test",
-				AcceptedBy:    testUsername,
-			},
-			http.StatusUnauthorized,
-		},
 		{
 			"User is not authenticated",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return("", false, false, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil).Once()
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.CharacterAPI{
 				Username:      testUsername,
@@ -883,9 +999,9 @@ func TestRejectCharacter(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, service.NewCharacterService(repo), logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			createCharacter(t, app)
 			resp := testSendRequest(t, app, http.MethodPost, "/restricted/reject-character", tt.data)
 
@@ -904,9 +1020,9 @@ func TestFetchCharacter(t *testing.T) {
 		{
 			"User is authenticated and with correct privilege",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, true, false, nil).Once()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil).Once()
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.CharacterAPI{
 				Username:      testUsername,
@@ -915,28 +1031,13 @@ func TestFetchCharacter(t *testing.T) {
 			},
 			http.StatusOK,
 		},
-		{
-			"User is authenticated but with wrong privilege",
-			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
-			},
-			&model.CharacterAPI{
-				Username:      testUsername,
-				CharacterName: "Test_Test",
-				AcceptedBy:    testUsername,
-			},
-			http.StatusUnauthorized,
-		},
 		{
 			"User is not authenticated",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return("", false, false, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil).Once()
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.CharacterAPI{
 				Username:      testUsername,
@@ -958,9 +1059,9 @@ func TestFetchCharacter(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, service.NewCharacterService(repo), logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			createCharacter(t, app)
 			resp := testSendRequest(t, app, http.MethodPost, "/restricted/fetch-character", tt.data)
 
@@ -978,29 +1079,19 @@ func TestBanList(t *testing.T) {
 		{
 			"User is authenticated and with correct privilege",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, true, false, nil).Once()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil).Once()
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			http.StatusOK,
 		},
-		{
-			"User is authenticated but with wrong privilege",
-			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
-			},
-			http.StatusUnauthorized,
-		},
 		{
 			"User is not authenticated",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return("", false, false, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil).Once()
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			http.StatusUnauthorized,
 		},
@@ -1017,9 +1108,9 @@ func TestBanList(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, service.NewCharacterService(repo), logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			createCharacter(t, app)
 			resp := testSendRequest(t, app, http.MethodGet, "/restricted/ban-list", nil)
 
@@ -1038,9 +1129,9 @@ func TestBan(t *testing.T) {
 		{
 			"User is authenticated and with correct privilege",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, true, false, nil).Once()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil).Once()
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.BanAPI{
 				Username:   testUsername,
@@ -1051,30 +1142,13 @@ func TestBan(t *testing.T) {
 			},
 			http.StatusOK,
 		},
-		{
-			"User is authenticated but with wrong privilege",
-			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
-			},
-			&model.BanAPI{
-				Username:   testUsername,
-				Expire:     1,
-				Reason:     "test",
-				AdminName:  testUsername,
-				Characters: nil,
-			},
-			http.StatusUnauthorized,
-		},
 		{
 			"User is not authenticated",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return("", false, false, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil).Once()
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.BanAPI{
 				Username:   testUsername,
@@ -1098,17 +1172,162 @@ func TestBan(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, service.NewCharacterService(repo), logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			createCharacter(t, app)
+
+			ctx := context.Background()
+			if err := repo.SaveSessionData(ctx, "banned", []byte("{}"), time.Now().Add(time.Hour).Unix()); err != nil {
+				t.Fatalf("Error saving session: %v", err)
+			}
+			if err := repo.TouchSession(ctx, "banned", testUsername, "127.0.0.1", "test"); err != nil {
+				t.Fatalf("Error touching session: %v", err)
+			}
+
 			resp := testSendRequest(t, app, http.MethodPost, "/restricted/ban", tt.data)
 
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
+
+			sessions, err := repo.FetchSessions(ctx, testUsername)
+			if err != nil {
+				t.Fatalf("Error fetching sessions: %v", err)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				assert.Empty(t, sessions, "The sessions of a banned account must be closed")
+			} else {
+				assert.Len(t, sessions, 1, "Unexpected sessions after test: %s", tt.name)
+			}
 		})
 	}
 }
 
+func TestLogs(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	for i := 0; i < 5; i++ {
+		insertBanLog(t, repo, testUsername, fmt.Sprintf("Player_%d", i), fmt.Sprintf("reason %d", i))
+	}
+
+	auth := new(service.MockAuthService)
+	logger := new(service.MockLoggerService)
+	auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil)
+
+	app := testServer(service.NewUserService(repo, testHasher), auth, nil, nil, logger, nil, nil, nil, service.NewAuditService(repo))
+
+	type page struct {
+		model.BaseResponse
+		model.LogsPageAPI
+	}
+
+	fetch := func(data model.LogsAPI) page {
+		resp := testSendRequest(t, app, http.MethodPost, "/restricted/logs", data)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code for logs")
+
+		var body page
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		return body
+	}
+
+	first := fetch(model.LogsAPI{Type: "logs_ban", Limit: 3})
+	assert.Len(t, first.Logs, 3)
+	assert.Equal(t, 5, first.Total)
+	assert.NotZero(t, first.NextCursor)
+
+	second := fetch(model.LogsAPI{Type: "logs_ban", Limit: 3, Cursor: first.NextCursor})
+	assert.Len(t, second.Logs, 2)
+	assert.Zero(t, second.NextCursor)
+
+	filtered := fetch(model.LogsAPI{Type: "logs_ban", Character: "Player_2"})
+	assert.Len(t, filtered.Logs, 1)
+
+	searched := fetch(model.LogsAPI{Type: "logs_ban", Search: "reason 4"})
+	if assert.Len(t, searched.Logs, 1) {
+		assert.Equal(t, "Player_4", searched.Logs[0]["Player"])
+		assert.Equal(t, "-", searched.Logs[0]["IP"])
+	}
+
+	logger.On("Exception", mock.AnythingOfType("string")).Return()
+	code := func(data model.LogsAPI) string {
+		resp := testSendRequest(t, app, http.MethodPost, "/restricted/logs", data)
+		var body model.BaseResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		return body.Code
+	}
+
+	assert.Equal(t, "invalid_filter", code(model.LogsAPI{Type: "logs_kick", Search: "reason"}), "Tables without declared columns can't be searched")
+	assert.Equal(t, "invalid_filter", code(model.LogsAPI{Type: "logs_kick", Character: "Player_2"}))
+	assert.Equal(t, "invalid_filter", code(model.LogsAPI{Type: "logs_kick", From: "2025-01-01"}))
+	assert.Equal(t, "invalid_log_type", code(model.LogsAPI{Type: "accounts"}))
+}
+
+func TestAuditEntryTruncation(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		reason := ctx.Query("prefix") + strings.Repeat("ă", auditPayloadLimit)
+		return ctx.SendString(auditEntry(ctx, testUsername, service.AuditBanCreate, "Test_Test", model.BanAPI{Reason: reason}).Payload)
+	})
+
+	// One of the prefixes puts the cut inside a two byte character.
+	for _, prefix := range []string{"", "a"} {
+		resp := testSendRequest(t, app, http.MethodGet, "/?prefix="+prefix, nil)
+		payload, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Error reading response body: %v", err)
+		}
+
+		assert.LessOrEqual(t, len(payload), auditPayloadLimit)
+		assert.True(t, utf8.Valid(payload), "The payload must not end inside a character")
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	auth := new(service.MockAuthService)
+	email := new(service.MockEmailService)
+	logger := new(service.MockLoggerService)
+
+	auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+	auth.On("CheckSession", mock.Anything).Return(testUsername, 3, 0, nil)
+	email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+
+	app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+
+	registerAndConfirmAccount(t, app, repo)
+	createCharacter(t, app)
+
+	resp := testSendRequest(t, app, http.MethodPost, "/restricted/ban", &model.BanAPI{
+		Username: testUsername,
+		Expire:   1,
+		Reason:   "test",
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code for ban")
+
+	resp = testSendRequest(t, app, http.MethodGet, "/restricted/audit?action="+service.AuditBanCreate, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code for audit")
+
+	var body struct {
+		model.BaseResponse
+		Data model.AuditPageAPI `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Error decoding response body: %v", err)
+	}
+
+	assert.Equal(t, 1, body.Data.Total)
+	if assert.Len(t, body.Data.Entries, 1) {
+		assert.Equal(t, testUsername, body.Data.Entries[0].Actor)
+		assert.Equal(t, testUsername, body.Data.Entries[0].Target)
+	}
+}
+
 func TestUnban(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -1119,9 +1338,9 @@ func TestUnban(t *testing.T) {
 		{
 			"User is authenticated and with correct privilege",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, true, false, nil)
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.BanAPI{
 				Username:   testUsername,
@@ -1132,32 +1351,14 @@ func TestUnban(t *testing.T) {
 			},
 			http.StatusOK,
 		},
-		{
-			"User is authenticated but with wrong privilege",
-			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, true, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
-			},
-			&model.BanAPI{
-				Username:   testUsername,
-				Expire:     1,
-				Reason:     "test",
-				AdminName:  testUsername,
-				Characters: nil,
-			},
-			http.StatusUnauthorized,
-		},
 		{
 			"User is not authenticated",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, true, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return("", false, false, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil).Once()
 				logger.On("Exception", mock.AnythingOfType("string")).Return()
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.BanAPI{
 				Username:   testUsername,
@@ -1181,9 +1382,9 @@ func TestUnban(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, service.NewCharacterService(repo), logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			createCharacter(t, app)
 
 			resp := testSendRequest(t, app, http.MethodPost, "/restricted/ban", tt.data)
@@ -1204,9 +1405,9 @@ func TestAjail(t *testing.T) {
 		{
 			"User is authenticated and with correct privilege",
 			func(auth *service.MockAuthService, email *service.MockEmailService, logger *service.MockLoggerService) {
-				auth.On("CheckSession", mock.Anything).Return(testUsername, false, false, nil).Once()
-				auth.On("CheckSession", mock.Anything).Return(testUsername, true, false, nil)
-				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil).Once()
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 1, 0, nil)
+				email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 			},
 			&model.AjailAPI{
 				Character: "Test_Test",
@@ -1229,9 +1430,9 @@ func TestAjail(t *testing.T) {
 
 			tt.mockFunc(auth, email, logger)
 
-			app := testServer(service.NewUserService(repo), auth, email, service.NewCharacterService(repo), logger)
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
 
-			registerAndConfirmAccount(t, app)
+			registerAndConfirmAccount(t, app, repo)
 			createCharacter(t, app)
 
 			resp := testSendRequest(t, app, http.MethodPost, "/restricted/ajail", tt.data)
@@ -1240,3 +1441,1046 @@ func TestAjail(t *testing.T) {
 		})
 	}
 }
+
+func TestSetLocale(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockFunc       func(*service.MockAuthService, *service.MockLoggerService)
+		data           *model.LocaleAPI
+		expectedStatus int
+		expectedCode   string
+		expectedLocale string
+	}{
+		{
+			"User saves a supported locale",
+			func(auth *service.MockAuthService, logger *service.MockLoggerService) {
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil)
+			},
+			&model.LocaleAPI{Locale: "en-US"},
+			http.StatusOK,
+			"",
+			i18n.English,
+		},
+		{
+			"User asks for an unsupported locale",
+			func(auth *service.MockAuthService, logger *service.MockLoggerService) {
+				auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil)
+			},
+			&model.LocaleAPI{Locale: "fr"},
+			http.StatusUnprocessableEntity,
+			"invalid_locale",
+			"",
+		},
+		{
+			"User is not authenticated",
+			func(auth *service.MockAuthService, logger *service.MockLoggerService) {
+				auth.On("CheckSession", mock.Anything).Return("", 0, 0, nil)
+			},
+			&model.LocaleAPI{Locale: "en"},
+			http.StatusUnauthorized,
+			"not_authenticated",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testRepository(t)
+			defer testCleanup(t, repo)
+
+			auth := new(service.MockAuthService)
+			email := new(service.MockEmailService)
+			logger := new(service.MockLoggerService)
+
+			email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+			tt.mockFunc(auth, logger)
+
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+
+			registerAndConfirmAccount(t, app, repo)
+
+			resp := testSendRequest(t, app, http.MethodPost, "/account/locale", tt.data)
+
+			var responseBody model.BaseResponse
+			if err := json.NewDecoder(resp.Body).Decode(&responseBody); err != nil {
+				t.Fatalf("Error decoding response body for test %s: %v", tt.name, err)
+			}
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
+			assert.Equal(t, tt.expectedCode, responseBody.Code, "Unexpected error code for test: %s", tt.name)
+
+			locale, err := repo.FetchLocale(context.Background(), testUsername)
+			if err != nil {
+				t.Fatalf("Error fetching locale for test %s: %v", tt.name, err)
+			}
+			assert.Equal(t, tt.expectedLocale, locale, "Unexpected saved locale for test: %s", tt.name)
+		})
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           *model.ChangeEmailAPI
+		expectedStatus int
+		expectedCode   string
+		expectedEmails int
+	}{
+		{"User asks for a new address", &model.ChangeEmailAPI{Email: "new@test.ro", Password: testPassword}, http.StatusOK, "", 3},
+		{"Wrong password", &model.ChangeEmailAPI{Email: "new@test.ro", Password: "wrong123."}, http.StatusUnauthorized, "invalid_credentials", 1},
+		{"Address is already used", &model.ChangeEmailAPI{Email: testEmail, Password: testPassword}, http.StatusConflict, "email_taken", 1},
+		{"Invalid address", &model.ChangeEmailAPI{Email: "new", Password: testPassword}, http.StatusUnprocessableEntity, "invalid_email", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testRepository(t)
+			defer testCleanup(t, repo)
+
+			auth := new(service.MockAuthService)
+			email := new(service.MockEmailService)
+			logger := new(service.MockLoggerService)
+
+			auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil)
+			logger.On("Exception", mock.AnythingOfType("string")).Return()
+
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+			registerAndConfirmAccount(t, app, repo)
+
+			resp := testSendRequest(t, app, http.MethodPost, "/account/email", tt.data)
+
+			var responseBody model.BaseResponse
+			if err := json.NewDecoder(resp.Body).Decode(&responseBody); err != nil {
+				t.Fatalf("Error decoding response body for test %s: %v", tt.name, err)
+			}
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
+			assert.Equal(t, tt.expectedCode, responseBody.Code, "Unexpected error code for test: %s", tt.name)
+
+			_, queued, err := repo.FetchEmails(context.Background(), &repository.EmailFilterDB{Limit: 10})
+			if err != nil {
+				t.Fatalf("Error fetching queued emails for test %s: %v", tt.name, err)
+			}
+			assert.Equal(t, tt.expectedEmails, queued, "Unexpected number of queued emails for test: %s", tt.name)
+
+			address, err := repo.FetchMail(context.Background(), testUsername)
+			if err != nil {
+				t.Fatalf("Error fetching email for test %s: %v", tt.name, err)
+			}
+			assert.Equal(t, testEmail, address, "The address must not change before it is confirmed")
+		})
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	auth := new(service.MockAuthService)
+	email := new(service.MockEmailService)
+	logger := new(service.MockLoggerService)
+
+	auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil)
+	logger.On("Exception", mock.AnythingOfType("string")).Return()
+	logger.On("Info", mock.AnythingOfType("string")).Return()
+
+	app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+	registerAndConfirmAccount(t, app, repo)
+
+	resp := testSendRequest(t, app, http.MethodPost, "/account/email", model.ChangeEmailAPI{Email: "new@test.ro", Password: testPassword})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code asking for a new address")
+
+	emails, _, err := repo.FetchEmails(context.Background(), &repository.EmailFilterDB{Limit: 10})
+	if err != nil {
+		t.Fatalf("Error fetching queued emails: %v", err)
+	}
+
+	var link string
+	recipients := map[string]string{}
+	for _, e := range emails {
+		recipients[e.Subject] = e.Recipient
+		if match := regexp.MustCompile(`verify-email\?token=(\S+)`).FindStringSubmatch(e.TextBody); match != nil {
+			link = "/verify-email?token=" + match[1]
+		}
+	}
+	assert.Equal(t, testEmail, recipients["SA-RP: Cerere de schimbare a adresei de mail"], "The old address must be notified")
+	assert.Equal(t, "new@test.ro", recipients["Schimbare adresa de mail UCP"], "The link must go to the new address")
+	if link == "" {
+		t.Fatalf("No confirmation link in the queued emails")
+	}
+
+	resp = testSendRequest(t, app, http.MethodGet, link, nil)
+	assert.Equal(t, http.StatusFound, resp.StatusCode, "Unexpected status code confirming the new address")
+
+	address, err := repo.FetchMail(context.Background(), testUsername)
+	if err != nil {
+		t.Fatalf("Error fetching email: %v", err)
+	}
+	assert.Equal(t, "new@test.ro", address)
+
+	resp = testSendRequest(t, app, http.MethodGet, link, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "A link can only be used once")
+}
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name             string
+		data             *model.ChangePasswordAPI
+		expectedStatus   int
+		expectedCode     string
+		expectedPassword string
+	}{
+		{"User changes the password", &model.ChangePasswordAPI{CurrentPassword: testPassword, NewPassword: "changed123."}, http.StatusOK, "", "changed123."},
+		{"Wrong current password", &model.ChangePasswordAPI{CurrentPassword: "wrong123.", NewPassword: "changed123."}, http.StatusUnauthorized, "invalid_credentials", testPassword},
+		{"Weak new password", &model.ChangePasswordAPI{CurrentPassword: testPassword, NewPassword: "changed"}, http.StatusUnprocessableEntity, "weak_password", testPassword},
+		{"Same password", &model.ChangePasswordAPI{CurrentPassword: testPassword, NewPassword: testPassword}, http.StatusUnprocessableEntity, "password_unchanged", testPassword},
+		{"Missing current password", &model.ChangePasswordAPI{NewPassword: "changed123."}, http.StatusUnprocessableEntity, "missing_fields", testPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testRepository(t)
+			defer testCleanup(t, repo)
+
+			auth := new(service.MockAuthService)
+			email := new(service.MockEmailService)
+			logger := new(service.MockLoggerService)
+
+			auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil)
+			auth.On("SessionID", mock.Anything).Return("current", nil)
+			logger.On("Exception", mock.AnythingOfType("string")).Return()
+			logger.On("Info", mock.AnythingOfType("string")).Return()
+
+			users := service.NewUserService(repo, testHasher)
+			app := testServer(users, auth, testOutbox(repo, email), nil, logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+			registerAndConfirmAccount(t, app, repo)
+
+			ctx := context.Background()
+			for _, id := range []string{"current", "other"} {
+				if err := repo.SaveSessionData(ctx, id, []byte("{}"), time.Now().Add(time.Hour).Unix()); err != nil {
+					t.Fatalf("Error saving session: %v", err)
+				}
+				if err := repo.TouchSession(ctx, id, testUsername, "127.0.0.1", "test"); err != nil {
+					t.Fatalf("Error touching session: %v", err)
+				}
+			}
+			resetToken, err := testTokens(repo).Issue(ctx, service.TokenReset, testUsername, testEmail)
+			if err != nil {
+				t.Fatalf("Error issuing reset token: %v", err)
+			}
+
+			resp := testSendRequest(t, app, http.MethodPost, "/account/password", tt.data)
+
+			var responseBody model.BaseResponse
+			if err := json.NewDecoder(resp.Body).Decode(&responseBody); err != nil {
+				t.Fatalf("Error decoding response body for test %s: %v", tt.name, err)
+			}
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
+			assert.Equal(t, tt.expectedCode, responseBody.Code, "Unexpected error code for test: %s", tt.name)
+			assert.NoError(t, users.Verify(context.Background(), &model.LoginAPI{Username: testUsername, Password: tt.expectedPassword}), "Unexpected password after test: %s", tt.name)
+
+			sessions, err := repo.FetchSessions(ctx, testUsername)
+			if err != nil {
+				t.Fatalf("Error fetching sessions: %v", err)
+			}
+			_, errToken := testTokens(repo).Peek(ctx, service.TokenReset, resetToken)
+			if tt.expectedStatus == http.StatusOK {
+				if assert.Len(t, sessions, 1, "Only the current session must be left") {
+					assert.Equal(t, "current", sessions[0].ID)
+				}
+				assert.Error(t, errToken, "Pending reset links must stop working")
+			} else {
+				assert.Len(t, sessions, 2, "Unexpected sessions after test: %s", tt.name)
+				assert.NoError(t, errToken, "Unexpected reset link state after test: %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           *model.ProfileAPI
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"User saves the profile", &model.ProfileAPI{ForumName: "Test Forum", Avatar: "https://i.imgur.com/test.png", Description: "Salut"}, http.StatusOK, ""},
+		{"Avatar is not https", &model.ProfileAPI{ForumName: "Test", Avatar: "http://i.imgur.com/test.png"}, http.StatusUnprocessableEntity, "invalid_avatar"},
+		{"Forum name has wrong characters", &model.ProfileAPI{ForumName: "<b>Test</b>", Avatar: "https://i.imgur.com/test.png"}, http.StatusUnprocessableEntity, "invalid_forum_name"},
+		{"Forum name is too long", &model.ProfileAPI{ForumName: strings.Repeat("a", 201), Avatar: "https://i.imgur.com/test.png"}, http.StatusUnprocessableEntity, "invalid_forum_name"},
+		{"Description is too long", &model.ProfileAPI{ForumName: "Test", Avatar: "https://i.imgur.com/test.png", Description: strings.Repeat("ă", 5001)}, http.StatusUnprocessableEntity, "description_too_long"},
+		{"Missing forum name", &model.ProfileAPI{Avatar: "https://i.imgur.com/test.png"}, http.StatusUnprocessableEntity, "missing_fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testRepository(t)
+			defer testCleanup(t, repo)
+
+			auth := new(service.MockAuthService)
+			email := new(service.MockEmailService)
+			logger := new(service.MockLoggerService)
+
+			auth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil)
+			logger.On("Exception", mock.AnythingOfType("string")).Return()
+
+			app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+			registerAndConfirmAccount(t, app, repo)
+
+			resp := testSendRequest(t, app, http.MethodPost, "/account/profile", tt.data)
+
+			var responseBody model.BaseResponse
+			if err := json.NewDecoder(resp.Body).Decode(&responseBody); err != nil {
+				t.Fatalf("Error decoding response body for test %s: %v", tt.name, err)
+			}
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code for test: %s", tt.name)
+			assert.Equal(t, tt.expectedCode, responseBody.Code, "Unexpected error code for test: %s", tt.name)
+
+			resp = testSendRequest(t, app, http.MethodGet, "/account/profile", nil)
+
+			var profile struct {
+				model.BaseResponse
+				Data model.ProfileAPI `json:"data"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+				t.Fatalf("Error decoding profile for test %s: %v", tt.name, err)
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, *tt.data, profile.Data, "Unexpected profile for test: %s", tt.name)
+			} else {
+				assert.Equal(t, testUsername, profile.Data.ForumName, "The profile must not change for test: %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestEmailOutbox(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	auth := new(service.MockAuthService)
+	email := new(service.MockEmailService)
+	logger := new(service.MockLoggerService)
+
+	auth.On("CheckSession", mock.Anything).Return(testUsername, 3, 0, nil)
+	email.On("SendEmail", testEmail, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(errors.New("smtp is down"))
+	logger.On("Exception", mock.AnythingOfType("string")).Return()
+	logger.On("Info", mock.AnythingOfType("string")).Return()
+
+	app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), nil, logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+
+	registerAccount(t, app)
+
+	listOutbox := func(status string) model.EmailPageAPI {
+		t.Helper()
+
+		resp := testSendRequest(t, app, http.MethodGet, "/restricted/email-outbox?status="+status, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code listing %s emails", status)
+
+		var body struct {
+			model.BaseResponse
+			Data model.EmailPageAPI `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		return body.Data
+	}
+
+	page := listOutbox("pending")
+	if !assert.Len(t, page.Entries, 1, "Register must queue the confirmation email") {
+		return
+	}
+	assert.Equal(t, testEmail, page.Entries[0].Recipient)
+	assert.Equal(t, "Confirmare cont UCP", page.Entries[0].Subject)
+
+	// One attempt is enough to dead-letter the email.
+	if _, err := service.NewOutboxService(repo, email, service.OutboxConfig{BatchSize: 10, MaxAttempts: 1}).Dispatch(context.Background()); err != nil {
+		t.Fatalf("Error dispatching emails: %v", err)
+	}
+
+	page = listOutbox("dead")
+	if !assert.Len(t, page.Entries, 1) {
+		return
+	}
+	assert.Equal(t, "smtp is down", page.Entries[0].LastError)
+
+	resp := testSendRequest(t, app, http.MethodPost, "/restricted/email-outbox/retry", model.RetryEmailAPI{ID: page.Entries[0].ID})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code for retry")
+	assert.Len(t, listOutbox("pending").Entries, 1, "A retried email must be pending again")
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/email-outbox/retry", model.RetryEmailAPI{ID: 999})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "Unexpected status code for an unknown email")
+
+	resp = testSendRequest(t, app, http.MethodGet, "/restricted/email-outbox?status=lost", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode, "Unexpected status code for an invalid status")
+}
+
+func TestQuestionnaire(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	auth := new(service.MockAuthService)
+	email := new(service.MockEmailService)
+	logger := new(service.MockLoggerService)
+
+	auth.On("CheckSession", mock.Anything).Return(testUsername, 3, 0, nil)
+	logger.On("Exception", mock.AnythingOfType("string")).Return()
+	logger.On("Info", mock.AnythingOfType("string")).Return()
+
+	app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+	registerAndConfirmAccount(t, app, repo)
+
+	decodeCode := func(resp *http.Response) string {
+		t.Helper()
+
+		var body model.BaseResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		return body.Code
+	}
+
+	resp := testSendRequest(t, app, http.MethodPost, "/restricted/questionnaire", model.QuestionnaireAPI{})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode, "A questionnaire without questions must be rejected")
+	assert.Equal(t, "invalid_questionnaire", decodeCode(resp))
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/questionnaire", model.QuestionnaireAPI{Questions: []model.QuestionAPI{
+		{Text: "Ce este metagaming-ul?", MinLength: 10, MaxLength: 100},
+		{Text: "Descrie caracterul.", MinLength: 0, MaxLength: 50},
+	}})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code saving the questions")
+
+	resp = testSendRequest(t, app, http.MethodGet, "/questionnaire", nil)
+	var current struct {
+		model.BaseResponse
+		Data model.QuestionnaireAPI `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		t.Fatalf("Error decoding questionnaire: %v", err)
+	}
+	assert.Equal(t, 1, current.Data.Version)
+	assert.Equal(t, testUsername, current.Data.CreatedBy)
+	assert.Len(t, current.Data.Questions, 2)
+
+	application := model.CharacterDataAPI{
+		CharacterName:   "Test_Test",
+		CharacterAge:    18,
+		CharacterOrigin: "test",
+		Answers:         []string{"Folosirea informatiilor OOC in IC.", ""},
+	}
+
+	resp = testSendRequest(t, app, http.MethodPost, "/create-character", application)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "Answers for an old version must be rejected")
+	assert.Equal(t, "questionnaire_changed", decodeCode(resp))
+
+	application.QuestionnaireVersion = 1
+	application.Answers[0] = "   scurt   "
+	resp = testSendRequest(t, app, http.MethodPost, "/create-character", application)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode, "Answers are trimmed before the length check")
+	assert.Equal(t, "answer_too_short", decodeCode(resp))
+
+	resp = testSendRequest(t, app, http.MethodPost, "/create-character", model.CharacterDataAPI{
+		CharacterName: "Test_Test", CharacterAge: 18, CharacterOrigin: "test", QuestionnaireVersion: 1, Answers: []string{"Folosirea informatiilor OOC in IC."},
+	})
+	assert.Equal(t, "invalid_answers", decodeCode(resp), "Every question needs an answer")
+
+	application.Answers[0] = "Folosirea informatiilor OOC in IC."
+	resp = testSendRequest(t, app, http.MethodPost, "/create-character", application)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Unexpected status code creating the character")
+
+	// A new version must not change what the pending application was asked.
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/questionnaire", model.QuestionnaireAPI{Questions: []model.QuestionAPI{
+		{Text: "Intrebare noua", MinLength: 1, MaxLength: 10},
+	}})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code saving the second version")
+
+	resp = testSendRequest(t, app, http.MethodGet, "/restricted/waiting-list", nil)
+	var waiting []model.CharacterDataAPI
+	if err := json.NewDecoder(resp.Body).Decode(&waiting); err != nil {
+		t.Fatalf("Error decoding waiting list: %v", err)
+	}
+	if assert.Len(t, waiting, 1) {
+		assert.Equal(t, 1, waiting[0].QuestionnaireVersion)
+		assert.Equal(t, []string{"Folosirea informatiilor OOC in IC.", ""}, waiting[0].Answers)
+		if assert.Len(t, waiting[0].Questions, 2) {
+			assert.Equal(t, "Ce este metagaming-ul?", waiting[0].Questions[0].Text)
+		}
+	}
+
+	entries, _, _ := repo.FetchAudit(context.Background(), &repository.AuditFilterDB{Action: service.AuditQuestionnaire, Limit: 10})
+	assert.Len(t, entries, 2, "Every saved version must be audited")
+}
+
+func TestReviewWorkflow(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	auth := new(service.MockAuthService)
+	email := new(service.MockEmailService)
+	logger := new(service.MockLoggerService)
+
+	auth.On("CheckSession", mock.Anything).Return(testUsername, 3, 0, nil)
+	logger.On("Exception", mock.AnythingOfType("string")).Return()
+	logger.On("Info", mock.AnythingOfType("string")).Return()
+
+	app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+	registerAndConfirmAccount(t, app, repo)
+	createCharacter(t, app)
+
+	decodeCode := func(resp *http.Response) string {
+		t.Helper()
+
+		var body model.BaseResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		return body.Code
+	}
+
+	ctx := context.Background()
+	if _, err := repo.ClaimApplication(ctx, "Test_Test", "Other_Admin", time.Minute); err != nil {
+		t.Fatalf("Error claiming application: %v", err)
+	}
+
+	claim := model.ClaimAPI{CharacterName: "Test_Test"}
+	resp := testSendRequest(t, app, http.MethodPost, "/restricted/reviews/claim", claim)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "An application claimed by someone else can't be claimed")
+	assert.Equal(t, "application_claimed", decodeCode(resp))
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/accept-character", model.CharacterAPI{Username: testUsername, CharacterName: "Test_Test"})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "Only the reviewer holding the claim can accept")
+	assert.Equal(t, "application_claimed", decodeCode(resp))
+
+	resp = testSendRequest(t, app, http.MethodGet, "/restricted/waiting-list", nil)
+	var waiting []model.CharacterDataAPI
+	if err := json.NewDecoder(resp.Body).Decode(&waiting); err != nil {
+		t.Fatalf("Error decoding waiting list: %v", err)
+	}
+	if assert.Len(t, waiting, 1) {
+		assert.Equal(t, "Other_Admin", waiting[0].ClaimedBy)
+		assert.NotNil(t, waiting[0].ClaimExpiresAt)
+	}
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/reviews/release", claim)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "Only the holder can release a claim")
+	assert.Equal(t, "application_not_claimed", decodeCode(resp))
+
+	if err := repo.ReleaseApplication(ctx, "Test_Test", "Other_Admin"); err != nil {
+		t.Fatalf("Error releasing application: %v", err)
+	}
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/reviews/claim", claim)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code claiming a released application")
+	var claimed struct {
+		model.BaseResponse
+		Data model.ClaimResultAPI `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&claimed); err != nil {
+		t.Fatalf("Error decoding claim: %v", err)
+	}
+	assert.Equal(t, testUsername, claimed.Data.ClaimedBy)
+	assert.True(t, claimed.Data.ClaimExpiresAt.After(time.Now()))
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/reviews/note", model.ReviewNoteAPI{CharacterName: "Test_Test", Note: strings.Repeat("a", 1001)})
+	assert.Equal(t, "note_too_long", decodeCode(resp))
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/reviews/note", model.ReviewNoteAPI{CharacterName: "Test_Test", Note: "Originea e prea vaga."})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code adding a note")
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/reject-character", model.RejectCharacterAPI{Username: testUsername, CharacterName: "Test_Test", Reason: "Origine vaga"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code rejecting the character")
+
+	_, err := repo.FetchCharacter(ctx, "Test_Test")
+	assert.NoError(t, err, "A rejected character must be kept")
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/reject-character", model.RejectCharacterAPI{Username: testUsername, CharacterName: "Test_Test", Reason: "Origine vaga"})
+	assert.Equal(t, "character_not_found", decodeCode(resp), "A rejected character can't be rejected again")
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/accept-character", model.CharacterAPI{Username: testUsername, CharacterName: "Test_Test"})
+	assert.Equal(t, "character_not_found", decodeCode(resp), "A rejected character can't be accepted")
+
+	resp = testSendRequest(t, app, http.MethodGet, "/restricted/waiting-list", nil)
+	waiting = nil
+	if err = json.NewDecoder(resp.Body).Decode(&waiting); err != nil {
+		t.Fatalf("Error decoding waiting list: %v", err)
+	}
+	assert.Empty(t, waiting)
+
+	resp = testSendRequest(t, app, http.MethodGet, "/restricted/reviews/history?character=Test_Test", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code fetching the history")
+	var history struct {
+		model.BaseResponse
+		Data model.ReviewHistoryAPI `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		t.Fatalf("Error decoding history: %v", err)
+	}
+	if assert.Len(t, history.Data.Applications, 1) {
+		application := history.Data.Applications[0]
+		assert.Equal(t, repository.ApplicationRejected, application.Status)
+		assert.Equal(t, testUsername, application.ReviewedBy)
+		assert.Equal(t, "Origine vaga", application.Reason)
+		assert.NotNil(t, application.ReviewedAt)
+	}
+	var actions []string
+	for _, e := range history.Data.Events {
+		actions = append(actions, e.Reviewer+":"+e.Action)
+	}
+	assert.Equal(t, []string{
+		"Other_Admin:" + repository.ReviewClaim,
+		"Other_Admin:" + repository.ReviewRelease,
+		testUsername + ":" + repository.ReviewClaim,
+		testUsername + ":" + repository.ReviewNote,
+		testUsername + ":" + repository.ReviewReject,
+	}, actions)
+
+	resp = testSendRequest(t, app, http.MethodGet, "/restricted/reviews/history?character=Nobody_Here", nil)
+	assert.Equal(t, "character_not_found", decodeCode(resp))
+}
+
+func TestCharacterSlotsAfterRejections(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	auth := new(service.MockAuthService)
+	email := new(service.MockEmailService)
+	logger := new(service.MockLoggerService)
+
+	auth.On("CheckSession", mock.Anything).Return(testUsername, 3, 0, nil)
+	logger.On("Exception", mock.AnythingOfType("string")).Return()
+	logger.On("Info", mock.AnythingOfType("string")).Return()
+
+	app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+	registerAndConfirmAccount(t, app, repo)
+
+	create := func(name string) *http.Response {
+		return testSendRequest(t, app, http.MethodPost, "/create-character", model.CharacterDataAPI{CharacterName: name, CharacterAge: 20, CharacterOrigin: "test"})
+	}
+
+	for _, name := range []string{"Test_Alpha", "Test_Bravo", "Test_Charlie", "Test_Delta", "Test_Echo", "Test_Foxtrot"} {
+		resp := create(name)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode, "Unexpected status code creating %s", name)
+		resp = testSendRequest(t, app, http.MethodPost, "/restricted/reject-character", model.RejectCharacterAPI{CharacterName: name, Reason: "Origine vaga"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code rejecting %s", name)
+	}
+
+	resp := create("Test_Golf")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Rejected characters must not take a slot")
+
+	stats, err := service.NewUserService(repo, testHasher).GetStats(context.Background(), testUsername)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, stats.Characters)
+		assert.Len(t, stats.CharacterList, 7, "Rejected characters stay listed for a resubmit")
+	}
+
+	if err = repo.DeleteExp(context.Background()); err != nil {
+		t.Fatalf("Error purging characters: %v", err)
+	}
+	_, err = repo.FetchCharacter(context.Background(), "Test_Alpha")
+	assert.NoError(t, err, "A recent rejection keeps the name for a resubmit")
+}
+
+func TestCharacterResubmission(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	auth := new(service.MockAuthService)
+	email := new(service.MockEmailService)
+	logger := new(service.MockLoggerService)
+
+	auth.On("CheckSession", mock.Anything).Return(testUsername, 3, 0, nil)
+	logger.On("Exception", mock.AnythingOfType("string")).Return()
+	logger.On("Info", mock.AnythingOfType("string")).Return()
+
+	app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+	registerAndConfirmAccount(t, app, repo)
+	createCharacter(t, app)
+
+	decodeCode := func(resp *http.Response) string {
+		t.Helper()
+
+		var body model.BaseResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		return body.Code
+	}
+	fetchDraft := func(app *fiber.App) model.ApplicationDraftAPI {
+		t.Helper()
+
+		resp := testSendRequest(t, app, http.MethodGet, "/character-application?character=Test_Test", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code fetching the application")
+		var draft struct {
+			model.BaseResponse
+			Data model.ApplicationDraftAPI `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&draft); err != nil {
+			t.Fatalf("Error decoding application: %v", err)
+		}
+		return draft.Data
+	}
+
+	edited := model.CharacterDataAPI{CharacterName: "Test_Test", CharacterAge: 25, CharacterOrigin: "Romania"}
+	resp := testSendRequest(t, app, http.MethodPost, "/edit-character", edited)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code editing a waiting character")
+
+	draft := fetchDraft(app)
+	assert.Equal(t, repository.ApplicationPending, draft.Status)
+	assert.Equal(t, 25, draft.CharacterAge)
+	assert.Equal(t, "Romania", draft.CharacterOrigin)
+	assert.Nil(t, draft.ResubmitAfter)
+
+	ctx := context.Background()
+	if _, err := repo.ClaimApplication(ctx, "Test_Test", "Other_Admin", time.Minute); err != nil {
+		t.Fatalf("Error claiming application: %v", err)
+	}
+	resp = testSendRequest(t, app, http.MethodPost, "/edit-character", edited)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "A claimed application can't be edited")
+	assert.Equal(t, "application_in_review", decodeCode(resp))
+	if err := repo.ReleaseApplication(ctx, "Test_Test", "Other_Admin"); err != nil {
+		t.Fatalf("Error releasing application: %v", err)
+	}
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/reject-character", model.RejectCharacterAPI{Username: testUsername, CharacterName: "Test_Test", Reason: "Origine vaga"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code rejecting the character")
+
+	draft = fetchDraft(app)
+	assert.Equal(t, repository.ApplicationRejected, draft.Status)
+	assert.Equal(t, "Origine vaga", draft.Reason)
+	assert.Equal(t, "Romania", draft.CharacterOrigin, "The previous submission is kept for the form")
+	if assert.NotNil(t, draft.ResubmitAfter) {
+		assert.True(t, draft.ResubmitAfter.After(time.Now()))
+	}
+
+	resp = testSendRequest(t, app, http.MethodPost, "/edit-character", edited)
+	assert.Equal(t, "character_not_found", decodeCode(resp), "A rejected character is resubmitted, not edited")
+
+	edited.CharacterOrigin = "Bucuresti"
+	resp = testSendRequest(t, app, http.MethodPost, "/resubmit-character", edited)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "Resubmitting during the cooldown must fail")
+	assert.Equal(t, "resubmit_cooldown", decodeCode(resp))
+
+	noCooldown := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), service.NewCharacterService(repo, service.CharacterConfig{ClaimLease: time.Minute}), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+	resp = testSendRequest(t, noCooldown, http.MethodPost, "/resubmit-character", edited)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Unexpected status code resubmitting the character")
+
+	draft = fetchDraft(noCooldown)
+	assert.Equal(t, repository.ApplicationPending, draft.Status)
+	assert.Empty(t, draft.Reason)
+	assert.Equal(t, "Bucuresti", draft.CharacterOrigin)
+
+	resp = testSendRequest(t, app, http.MethodGet, "/restricted/waiting-list", nil)
+	var waiting []model.CharacterDataAPI
+	if err := json.NewDecoder(resp.Body).Decode(&waiting); err != nil {
+		t.Fatalf("Error decoding waiting list: %v", err)
+	}
+	assert.Len(t, waiting, 1)
+
+	resp = testSendRequest(t, app, http.MethodPost, "/withdraw-character", model.WithdrawCharacterAPI{CharacterName: "Test_Test"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code withdrawing the character")
+
+	resp = testSendRequest(t, app, http.MethodPost, "/withdraw-character", model.WithdrawCharacterAPI{CharacterName: "Test_Test"})
+	assert.Equal(t, "character_not_found", decodeCode(resp))
+
+	applications, _, err := repo.FetchReviewHistory(ctx, "Test_Test")
+	if assert.NoError(t, err) && assert.Len(t, applications, 2) {
+		assert.Equal(t, repository.ApplicationRejected, applications[0].Status)
+		assert.Equal(t, repository.ApplicationWithdrawn, applications[1].Status)
+	}
+
+	// Withdrawing frees the name.
+	createCharacter(t, app)
+}
+
+func TestNameChange(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	auth := new(service.MockAuthService)
+	email := new(service.MockEmailService)
+	logger := new(service.MockLoggerService)
+
+	auth.On("CheckSession", mock.Anything).Return(testUsername, 3, 0, nil)
+	logger.On("Exception", mock.AnythingOfType("string")).Return()
+	logger.On("Info", mock.AnythingOfType("string")).Return()
+
+	app := testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+	registerAndConfirmAccount(t, app, repo)
+	createCharacter(t, app)
+
+	decode := func(resp *http.Response, data interface{}) string {
+		t.Helper()
+
+		body := struct {
+			model.BaseResponse
+			Data interface{} `json:"data"`
+		}{Data: data}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		return body.Code
+	}
+
+	request := model.NameChangeRequestAPI{CharacterName: "Test_Test", NewName: "New_Name"}
+	resp := testSendRequest(t, app, http.MethodPost, "/name-changes/request", request)
+	assert.Equal(t, "character_not_found", decode(resp, nil), "Only accepted characters can be renamed")
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/accept-character", model.CharacterAPI{Username: testUsername, CharacterName: "Test_Test"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code accepting the character")
+
+	resp = testSendRequest(t, app, http.MethodPost, "/name-changes/request", request)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "A request needs a voucher")
+	assert.Equal(t, "no_name_voucher", decode(resp, nil))
+
+	grantVouchers(t, repo, testUsername, 1, 0)
+
+	for _, name := range []string{"new_name", "New_", "Test_test", "Averyveryverylong_Namehere"} {
+		resp = testSendRequest(t, app, http.MethodPost, "/name-changes/request", model.NameChangeRequestAPI{CharacterName: "Test_Test", NewName: name})
+		assert.Equal(t, "invalid_character_name", decode(resp, nil), "Unexpected code for %s", name)
+	}
+
+	var id int64
+	resp = testSendRequest(t, app, http.MethodPost, "/name-changes/request", request)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Unexpected status code requesting a name change")
+	decode(resp, &id)
+
+	resp = testSendRequest(t, app, http.MethodPost, "/name-changes/request", model.NameChangeRequestAPI{CharacterName: "Test_Test", NewName: "Other_Name"})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "A character can't have two pending requests")
+	assert.Equal(t, "name_change_pending", decode(resp, nil))
+
+	var queue []model.NameChangeAPI
+	resp = testSendRequest(t, app, http.MethodGet, "/restricted/name-changes?status=pending", nil)
+	decode(resp, &queue)
+	if assert.Len(t, queue, 1) {
+		assert.Equal(t, id, queue[0].ID)
+		assert.Equal(t, "New_Name", queue[0].NewName)
+	}
+
+	resp = testSendRequest(t, app, http.MethodGet, "/restricted/name-changes?status=unknown", nil)
+	assert.Equal(t, "invalid_filter", decode(resp, nil))
+
+	var denied model.NameChangeAPI
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/name-changes/deny", model.NameChangeDecisionAPI{ID: id, Reason: "Nume nerealist"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code denying the request")
+	decode(resp, &denied)
+	assert.Equal(t, repository.NameChangeDenied, denied.Status)
+	assert.Equal(t, testUsername, denied.ReviewedBy)
+
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/name-changes/approve", model.NameChangeDecisionAPI{ID: id})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "A handled request can't be approved")
+	assert.Equal(t, "name_change_not_found", decode(resp, nil))
+
+	// Denying leaves the voucher unused.
+	resp = testSendRequest(t, app, http.MethodPost, "/name-changes/request", request)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Unexpected status code requesting the name change again")
+	decode(resp, &id)
+
+	var approved model.NameChangeAPI
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/name-changes/approve", model.NameChangeDecisionAPI{ID: id})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code approving the request")
+	decode(resp, &approved)
+	assert.Equal(t, repository.NameChangeApproved, approved.Status)
+	assert.NotNil(t, approved.ReviewedAt)
+
+	ctx := context.Background()
+	if _, err := repo.FetchCharacter(ctx, "Test_Test"); !errors.Is(err, model.ErrCharacterNotFound) {
+		t.Errorf("The old name must be free, got %v", err)
+	}
+	renamed, err := repo.FetchCharacter(ctx, "New_Name")
+	if assert.NoError(t, err) {
+		assert.Equal(t, testUsername, renamed.Username)
+	}
+
+	resp = testSendRequest(t, app, http.MethodPost, "/name-changes/request", model.NameChangeRequestAPI{CharacterName: "New_Name", NewName: "Other_Name"})
+	assert.Equal(t, "no_name_voucher", decode(resp, nil), "The voucher is spent on approval")
+
+	var history []model.NameChangeAPI
+	resp = testSendRequest(t, app, http.MethodGet, "/name-changes", nil)
+	decode(resp, &history)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, repository.NameChangeApproved, history[0].Status)
+		assert.Equal(t, "Nume nerealist", history[1].Reason)
+	}
+
+	var logs struct {
+		model.BaseResponse
+		model.LogsPageAPI
+	}
+	resp = testSendRequest(t, app, http.MethodPost, "/restricted/logs", model.LogsAPI{Type: "namechanges"})
+	if err = json.NewDecoder(resp.Body).Decode(&logs); err != nil {
+		t.Fatalf("Error decoding logs: %v", err)
+	}
+	assert.Equal(t, 1, logs.Total)
+
+	emails, _, err := repo.FetchEmails(ctx, &repository.EmailFilterDB{Limit: 10})
+	if err != nil {
+		t.Fatalf("Error fetching queued emails: %v", err)
+	}
+	subjects := map[string]bool{}
+	for _, e := range emails {
+		subjects[e.Subject] = true
+	}
+	assert.True(t, subjects[testCatalog.T(i18n.Default, "email.name_change_approved.subject")])
+	assert.True(t, subjects[testCatalog.T(i18n.Default, "email.name_change_denied.subject")])
+}
+
+func TestCKRequest(t *testing.T) {
+	repo := testRepository(t)
+	defer testCleanup(t, repo)
+
+	staffAuth := new(service.MockAuthService)
+	playerAuth := new(service.MockAuthService)
+	email := new(service.MockEmailService)
+	logger := new(service.MockLoggerService)
+
+	staffAuth.On("CheckSession", mock.Anything).Return(testUsername, 3, 0, nil)
+	playerAuth.On("CheckSession", mock.Anything).Return(testUsername, 0, 0, nil)
+	logger.On("Exception", mock.AnythingOfType("string")).Return()
+	logger.On("Info", mock.AnythingOfType("string")).Return()
+
+	server := func(auth *service.MockAuthService) *fiber.App {
+		return testServer(service.NewUserService(repo, testHasher), auth, testOutbox(repo, email), testCharacters(repo), logger, service.NewTwoFactorService(repo, testIssuer), service.NewLoginGuardService(repo, testOutbox(repo, email), testTemplates, testGuardConfig), testTokens(repo), service.NewAuditService(repo))
+	}
+	staff, player := server(staffAuth), server(playerAuth)
+	registerAndConfirmAccount(t, staff, repo)
+	createCharacter(t, staff)
+
+	decode := func(resp *http.Response, data interface{}) string {
+		t.Helper()
+
+		body := struct {
+			model.BaseResponse
+			Data interface{} `json:"data"`
+		}{Data: data}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		return body.Code
+	}
+
+	request := model.CKRequestAPI{
+		CharacterName: "Test_Test",
+		Evidence:      []string{"https://imgur.com/a/ck", "https://youtu.be/ck"},
+		Description:   "Moarte in roleplay la banca.",
+	}
+	resp := testSendRequest(t, player, http.MethodPost, "/ck-requests/request", request)
+	assert.Equal(t, "character_not_found", decode(resp, nil), "Only accepted characters can be killed")
+
+	resp = testSendRequest(t, staff, http.MethodPost, "/restricted/accept-character", model.CharacterAPI{Username: testUsername, CharacterName: "Test_Test"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code accepting the character")
+
+	for _, evidence := range [][]string{nil, {"ftp://files.ro/ck"}, {"imgur.com/a/ck"}} {
+		resp = testSendRequest(t, player, http.MethodPost, "/ck-requests/request", model.CKRequestAPI{CharacterName: "Test_Test", Evidence: evidence, Description: "test"})
+		assert.Equal(t, "invalid_evidence", decode(resp, nil), "Unexpected code for %v", evidence)
+	}
+
+	resp = testSendRequest(t, player, http.MethodPost, "/ck-requests/request", request)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "A player request needs a voucher")
+	assert.Equal(t, "no_ck_voucher", decode(resp, nil))
+
+	grantVouchers(t, repo, testUsername, 0, 1)
+
+	var id int64
+	resp = testSendRequest(t, player, http.MethodPost, "/ck-requests/request", request)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Unexpected status code filing a CK")
+	decode(resp, &id)
+
+	resp = testSendRequest(t, staff, http.MethodPost, "/ck-requests/request", request)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "A character can't have two pending requests")
+	assert.Equal(t, "ck_pending", decode(resp, nil))
+
+	var filed []model.CKAPI
+	resp = testSendRequest(t, player, http.MethodGet, "/ck-requests", nil)
+	decode(resp, &filed)
+	if assert.Len(t, filed, 1) {
+		assert.Equal(t, request.Evidence, filed[0].Evidence)
+		assert.Equal(t, testUsername, filed[0].Owner)
+		assert.False(t, filed[0].Staff)
+	}
+
+	var denied model.CKAPI
+	resp = testSendRequest(t, staff, http.MethodPost, "/restricted/ck-requests/deny", model.CKDecisionAPI{ID: id, Reason: "Dovezi insuficiente"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code denying the request")
+	decode(resp, &denied)
+	assert.Equal(t, repository.CKDenied, denied.Status)
+
+	resp = testSendRequest(t, staff, http.MethodPost, "/restricted/ck-requests/approve", model.CKDecisionAPI{ID: id})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "A handled request can't be approved")
+	assert.Equal(t, "ck_not_found", decode(resp, nil))
+
+	// Denying leaves the voucher unused.
+	resp = testSendRequest(t, player, http.MethodPost, "/ck-requests/request", request)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Unexpected status code filing the CK again")
+	decode(resp, &id)
+
+	var approved model.CKAPI
+	resp = testSendRequest(t, staff, http.MethodPost, "/restricted/ck-requests/approve", model.CKDecisionAPI{ID: id})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code approving the request")
+	decode(resp, &approved)
+	assert.Equal(t, repository.CKApproved, approved.Status)
+	assert.Equal(t, testUsername, approved.ReviewedBy)
+
+	resp = testSendRequest(t, player, http.MethodPost, "/ck-requests/request", request)
+	assert.Equal(t, "character_not_found", decode(resp, nil), "A dead character can't be killed again")
+
+	// Staff requests don't need a voucher, the player's one is spent.
+	resp = testSendRequest(t, staff, http.MethodPost, "/create-character", model.CharacterDataAPI{CharacterName: "Second_Test", CharacterAge: 20, CharacterOrigin: "test"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Unexpected status code creating the second character")
+	resp = testSendRequest(t, staff, http.MethodPost, "/restricted/accept-character", model.CharacterAPI{Username: testUsername, CharacterName: "Second_Test"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code accepting the second character")
+
+	second := model.CKRequestAPI{CharacterName: "Second_Test", Evidence: request.Evidence, Description: request.Description}
+	resp = testSendRequest(t, player, http.MethodPost, "/ck-requests/request", second)
+	assert.Equal(t, "no_ck_voucher", decode(resp, nil), "The voucher is spent on approval")
+
+	resp = testSendRequest(t, staff, http.MethodPost, "/ck-requests/request", second)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "Unexpected status code filing a staff CK")
+	decode(resp, &id)
+
+	var queue []model.CKAPI
+	resp = testSendRequest(t, staff, http.MethodGet, "/restricted/ck-requests?status=pending", nil)
+	decode(resp, &queue)
+	if assert.Len(t, queue, 1) {
+		assert.Equal(t, id, queue[0].ID)
+		assert.True(t, queue[0].Staff)
+	}
+
+	resp = testSendRequest(t, staff, http.MethodPost, "/restricted/ck-requests/approve", model.CKDecisionAPI{ID: id})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Unexpected status code approving the staff request")
+
+	var logs struct {
+		model.BaseResponse
+		model.LogsPageAPI
+	}
+	resp = testSendRequest(t, staff, http.MethodPost, "/restricted/logs", model.LogsAPI{Type: "logs_ck"})
+	if err := json.NewDecoder(resp.Body).Decode(&logs); err != nil {
+		t.Fatalf("Error decoding logs: %v", err)
+	}
+	assert.Equal(t, 2, logs.Total)
+
+	ctx := context.Background()
+	if err := repo.DeleteExp(ctx); err != nil {
+		t.Fatalf("Error purging dead characters: %v", err)
+	}
+	for _, name := range []string{"Test_Test", "Second_Test"} {
+		if _, err := repo.FetchCharacter(ctx, name); !errors.Is(err, model.ErrCharacterNotFound) {
+			t.Errorf("%s must be purged, got %v", name, err)
+		}
+	}
+
+	emails, _, err := repo.FetchEmails(ctx, &repository.EmailFilterDB{Limit: 20})
+	if err != nil {
+		t.Fatalf("Error fetching queued emails: %v", err)
+	}
+	subjects := map[string]int{}
+	for _, e := range emails {
+		subjects[e.Subject]++
+	}
+	// The requester owns the characters, each approval sends a single email.
+	assert.Equal(t, 2, subjects[testCatalog.T(i18n.Default, "email.ck_approved.subject")])
+	assert.Equal(t, 1, subjects[testCatalog.T(i18n.Default, "email.ck_denied.subject")])
+}
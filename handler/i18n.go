@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"sarp_backend/i18n"
+	"sarp_backend/model"
+)
+
+// T translates a message into the language picked for the request.
+func (h *UserHandler) T(ctx *fiber.Ctx, id string, args ...interface{}) string {
+	return h.I18n.T(i18n.FromContext(ctx.UserContext()), id, args...)
+}
+
+// accountLocale is the language emails sent to name are written in, accounts without
+// a saved preference get the fallback.
+func (h *UserHandler) accountLocale(ctx *fiber.Ctx, name, fallback string) string {
+	locale, err := h.User.Locale(ctx.UserContext(), name)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("accountLocale(): can't fetch the locale of %s: %v", name, err))
+	}
+
+	if locale = h.I18n.Normalize(locale); locale == "" {
+		return fallback
+	}
+	return locale
+}
+
+// errorResponse answers with the status, code and translated message of a domain error.
+// Anything else is an internal error and keeps the generic message of the handler.
+func (h *UserHandler) errorResponse(ctx *fiber.Ctx, br model.BaseResponse, err error) error {
+	e := model.AsError(err)
+	br.Code = e.Code
+	if e != model.ErrInternal {
+		br.Message = h.T(ctx, "error."+e.Code)
+	}
+	return ctx.Status(e.Status).JSON(br)
+}
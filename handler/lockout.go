@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"math"
+	"net/http"
+	"sarp_backend/model"
+	"sarp_backend/service"
+	"strconv"
+	"time"
+)
+
+func (h *UserHandler) lockedResponse(ctx *fiber.Ctx, br model.BaseResponse, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	br.Code = model.ErrTooManyAttempts.Code
+
+	if seconds >= 60 {
+		br.Message = h.T(ctx, "lockout.retry_minutes", int(math.Ceil(float64(seconds)/60)))
+	} else {
+		br.Message = h.T(ctx, "lockout.retry_seconds", seconds)
+	}
+
+	return ctx.Status(http.StatusTooManyRequests).JSON(br)
+}
+
+func (h *UserHandler) ClearLockout(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "lockout.clear_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ClearLockout(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("ClearLockout(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.BanAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ClearLockout(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if data.Username == "" {
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = h.Guard.Unlock(ctx.UserContext(), data.Username, auditEntry(ctx, name, service.AuditLockoutClear, data.Username, data)); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ClearLockout(): error clearing lockout for %s: %v", data.Username, err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	h.Logger.Info(fmt.Sprintf("ClearLockout(): %s cleared the lockout of %s", name, data.Username))
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
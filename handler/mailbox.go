@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/gofiber/fiber/v2"
+	"html/template"
+	"net/http"
+	"sarp_backend/service"
+	"strconv"
+)
+
+var mailboxTemplate = template.Must(template.New("mailbox").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="UTF-8">
+	<title>Dev mailbox</title>
+	<style>
+		body { font-family: sans-serif; margin: 2em; }
+		table { border-collapse: collapse; width: 100%; }
+		td, th { border-bottom: 1px solid #ddd; padding: .4em; text-align: left; }
+		iframe { width: 100%; height: 60vh; border: 1px solid #ddd; }
+		pre { background: #f5f5f5; padding: 1em; white-space: pre-wrap; }
+	</style>
+</head>
+<body>
+{{if .Message}}
+	<p><a href="/dev/mailbox">&larr; Mailbox</a></p>
+	<h1>{{.Message.Subject}}</h1>
+	<p>From {{.Message.From}} to {{.Message.To}}, {{.Message.SentAt.Format "02/01/2006 15:04:05"}}</p>
+	<iframe sandbox srcdoc="{{.Message.HTML}}"></iframe>
+	<pre>{{.Message.Text}}</pre>
+{{else}}
+	<h1>Dev mailbox</h1>
+	<table>
+		<tr><th>#</th><th>To</th><th>Subject</th><th>Sent</th></tr>
+		{{range .Messages}}
+		<tr>
+			<td>{{.ID}}</td>
+			<td>{{.To}}</td>
+			<td><a href="/dev/mailbox/{{.ID}}">{{.Subject}}</a></td>
+			<td>{{.SentAt.Format "02/01/2006 15:04:05"}}</td>
+		</tr>
+		{{else}}
+		<tr><td colspan="4">No emails were sent yet.</td></tr>
+		{{end}}
+	</table>
+{{end}}
+</body>
+</html>`))
+
+// MailboxHandler shows the emails captured by the memory transport. It is only
+// registered in dev mode, there is no session check.
+type MailboxHandler struct {
+	Mailbox *service.MemoryTransport
+}
+
+func NewMailboxHandler(mailbox *service.MemoryTransport) *MailboxHandler {
+	return &MailboxHandler{Mailbox: mailbox}
+}
+
+func (h *MailboxHandler) List(ctx *fiber.Ctx) error {
+	return h.render(ctx, map[string]interface{}{"Messages": h.Mailbox.Messages()})
+}
+
+func (h *MailboxHandler) Show(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return ctx.Status(http.StatusNotFound).SendString("Not Found")
+	}
+
+	message, ok := h.Mailbox.Message(id)
+	if !ok {
+		return ctx.Status(http.StatusNotFound).SendString("Not Found")
+	}
+
+	return h.render(ctx, map[string]interface{}{"Message": message})
+}
+
+func (h *MailboxHandler) render(ctx *fiber.Ctx, data map[string]interface{}) error {
+	var page bytes.Buffer
+	if err := mailboxTemplate.Execute(&page, data); err != nil {
+		return ctx.Status(http.StatusInternalServerError).SendString(err.Error())
+	}
+	return ctx.Type("html").Send(page.Bytes())
+}
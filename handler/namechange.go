@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"sarp_backend/i18n"
+	"sarp_backend/model"
+	"sarp_backend/service"
+	"strconv"
+	"time"
+)
+
+// sendNameChangeEmail tells the owner of the character about the decision. Failures
+// are only logged, the decision is already stored.
+func (h *UserHandler) sendNameChangeEmail(ctx *fiber.Ctx, caller, template string, request *model.NameChangeAPI) {
+	email, err := h.User.FetchMail(ctx.UserContext(), request.Username)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("%s(): can't get email of %s: %v", caller, request.Username, err))
+		return
+	}
+
+	date := time.Now()
+	if request.ReviewedAt != nil {
+		date = *request.ReviewedAt
+	}
+
+	rendered, err := h.Templates.Render(h.accountLocale(ctx, request.Username, i18n.Default), template, service.NameChangeData{
+		Username: request.Username,
+		OldName:  request.CharacterName,
+		NewName:  request.NewName,
+		Date:     date.Format("02/01/2006, 15:04"),
+		Reason:   request.Reason,
+		Admin:    request.ReviewedBy,
+	})
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("%s(): can't render email: %v", caller, err))
+	} else if err = h.Outbox.Enqueue(ctx.UserContext(), service.OutboxKey(template, strconv.FormatInt(request.ID, 10)), email, rendered); err != nil {
+		h.Logger.Exception(fmt.Sprintf("%s(): can't queue email: %v", caller, err))
+	}
+}
+
+// RequestNameChange queues a rename of an accepted character of the player for staff
+// approval. It needs an unused name change voucher.
+func (h *UserHandler) RequestNameChange(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "name_change.request_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("RequestNameChange(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("RequestNameChange(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.NameChangeRequestAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("RequestNameChange(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	id, err := h.Char.RequestNameChange(ctx.UserContext(), name, &data)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("RequestNameChange(): can't request %s for %s: %v", data.NewName, data.CharacterName, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data int64 `json:"data"`
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         id,
+	})
+}
+
+// NameChanges returns the name change requests of the player, newest first.
+func (h *UserHandler) NameChanges(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "name_change.fetch_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("NameChanges(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("NameChanges(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	requests, err := h.Char.NameChanges(ctx.UserContext(), name, "")
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("NameChanges(): can't fetch requests of %s: %v", name, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data []model.NameChangeAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         requests,
+	})
+}
+
+// NameChangeQueue lists the requests of every player, ?status= narrows it down to
+// pending, approved or denied ones.
+func (h *UserHandler) NameChangeQueue(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "name_change.fetch_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("NameChangeQueue(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("NameChangeQueue(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var filter model.NameChangeFilterAPI
+	if err = ctx.QueryParser(&filter); err != nil {
+		h.Logger.Exception(fmt.Sprintf("NameChangeQueue(): error parsing query: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = filter.Validate(); err != nil {
+		h.Logger.Exception(fmt.Sprintf("NameChangeQueue(): invalid filter: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	requests, err := h.Char.NameChanges(ctx.UserContext(), "", filter.Status)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("NameChangeQueue(): can't fetch requests: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data []model.NameChangeAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         requests,
+	})
+}
+
+// ApproveNameChange renames the character, spends the voucher and emails the player.
+func (h *UserHandler) ApproveNameChange(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "name_change.approve_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ApproveNameChange(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("ApproveNameChange(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.NameChangeDecisionAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ApproveNameChange(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = data.Validate(); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ApproveNameChange(): invalid request: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	audit := auditEntry(ctx, name, service.AuditNameChangeApprove, strconv.FormatInt(data.ID, 10), data)
+	request, err := h.Char.ApproveNameChange(ctx.UserContext(), data.ID, name, audit)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ApproveNameChange(): can't approve request %d: %v", data.ID, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	h.sendNameChangeEmail(ctx, "ApproveNameChange", service.NameChangeApprovedEmail, request)
+
+	type response struct {
+		model.BaseResponse
+		Data *model.NameChangeAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         request,
+	})
+}
+
+// DenyNameChange closes the request without spending the voucher and emails the player
+// the reason.
+func (h *UserHandler) DenyNameChange(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "name_change.deny_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("DenyNameChange(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("DenyNameChange(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.NameChangeDecisionAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("DenyNameChange(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	audit := auditEntry(ctx, name, service.AuditNameChangeDeny, strconv.FormatInt(data.ID, 10), data)
+	request, err := h.Char.DenyNameChange(ctx.UserContext(), &data, name, audit)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("DenyNameChange(): can't deny request %d: %v", data.ID, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	h.sendNameChangeEmail(ctx, "DenyNameChange", service.NameChangeDeniedEmail, request)
+
+	type response struct {
+		model.BaseResponse
+		Data *model.NameChangeAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         request,
+	})
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"sarp_backend/model"
+	"sarp_backend/service"
+	"strconv"
+)
+
+func (h *UserHandler) EmailOutbox(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "outbox.list_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("EmailOutbox(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("EmailOutbox(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var filter model.EmailFilterAPI
+	if err = ctx.QueryParser(&filter); err != nil {
+		h.Logger.Exception(fmt.Sprintf("EmailOutbox(): error parsing query: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = filter.Validate(); err != nil {
+		return h.errorResponse(ctx, br, err)
+	}
+
+	page, err := h.Outbox.List(ctx.UserContext(), &filter)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("EmailOutbox(): error fetching emails: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data *model.EmailPageAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         page,
+	})
+}
+
+func (h *UserHandler) RetryEmail(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "outbox.retry_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("RetryEmail(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("RetryEmail(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.RetryEmailAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("RetryEmail(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if data.ID <= 0 {
+		return h.errorResponse(ctx, br, model.ErrMissingFields)
+	}
+
+	if err = h.Outbox.Retry(ctx.UserContext(), data.ID, auditEntry(ctx, name, service.AuditEmailRetry, strconv.FormatInt(data.ID, 10), data)); err != nil {
+		h.Logger.Exception(fmt.Sprintf("RetryEmail(): error retrying email %d: %v", data.ID, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	h.Logger.Info(fmt.Sprintf("RetryEmail(): %s queued email %d again", name, data.ID))
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"sarp_backend/model"
+	"sarp_backend/service"
+)
+
+// Questionnaire returns the questions of the character application form. The version
+// must be sent back with the answers.
+func (h *UserHandler) Questionnaire(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "questionnaire.failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("Questionnaire(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("Questionnaire(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	questionnaire, err := h.Char.Questionnaire(ctx.UserContext())
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("Questionnaire(): error fetching questions: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data *model.QuestionnaireAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         questionnaire,
+	})
+}
+
+// UpdateQuestionnaire replaces the application questions with a new version.
+func (h *UserHandler) UpdateQuestionnaire(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "questionnaire.update_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("UpdateQuestionnaire(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("UpdateQuestionnaire(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.QuestionnaireAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("UpdateQuestionnaire(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = data.Validate(); err != nil {
+		h.Logger.Exception(fmt.Sprintf("UpdateQuestionnaire(): invalid questions: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	data.CreatedBy = name
+	saved, err := h.Char.UpdateQuestionnaire(ctx.UserContext(), &data, auditEntry(ctx, name, service.AuditQuestionnaire, "questionnaire", data.Questions))
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("UpdateQuestionnaire(): error saving questions: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	h.Logger.Info(fmt.Sprintf("UpdateQuestionnaire(): %s saved version %d of the application questions", name, saved.Version))
+
+	type response struct {
+		model.BaseResponse
+		Data *model.QuestionnaireAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         saved,
+	})
+}
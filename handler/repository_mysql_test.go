@@ -0,0 +1,113 @@
+//go:build mysql
+
+package handler
+
+import (
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"sarp_backend/config"
+	"sarp_backend/migrations"
+	"sarp_backend/repository"
+	"testing"
+	"time"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Version: "test",
+		Dsn:     "samp:password@tcp(127.0.0.1:3306)/test_schema?charset=utf8mb4&parseTime=True&loc=Local", // Modify credentials
+		Port:    ":3000",
+	}
+}
+
+func testRepository(t *testing.T) *repository.UserRepository {
+	t.Helper()
+
+	cfg := testConfig()
+
+	dsn, err := mysql.ParseDSN(cfg.Dsn)
+	if err != nil {
+		t.Fatalf("Error parsing test dsn: %v", err)
+		return nil
+	}
+	schema := dsn.DBName
+	dsn.DBName = ""
+
+	serverRepo, errServer := repository.New(dsn.FormatDSN(), 0)
+	if errServer != nil {
+		t.Fatalf("Error creating test repository: %v", errServer)
+		return nil
+	}
+	if _, err = serverRepo.DB.Exec("CREATE DATABASE IF NOT EXISTS " + schema); err != nil {
+		t.Fatalf("Error creating test database: %v", err)
+		return nil
+	}
+	_ = serverRepo.DB.Close()
+
+	ucpRepo, errRepo := repository.New(cfg.Dsn, 5*time.Second)
+	if errRepo != nil {
+		t.Fatalf("Error creating test repository: %v", errRepo)
+		return nil
+	}
+
+	migrator, errMigrator := migrations.New(ucpRepo.DB)
+	if errMigrator != nil {
+		t.Fatalf("Error loading migrations: %v", errMigrator)
+		return nil
+	}
+	if _, err = migrator.Up(); err != nil {
+		t.Fatalf("Error migrating test database: %v", err)
+		return nil
+	}
+
+	truncateTables(t, ucpRepo)
+
+	return ucpRepo
+}
+
+// truncateTables empties every table created by the migrations.
+func truncateTables(t *testing.T, repo *repository.UserRepository) {
+	t.Helper()
+
+	var tables []string
+	if err := repo.DB.Select(&tables, "SHOW TABLES"); err != nil {
+		t.Fatalf("Error listing tables: %v", err)
+	}
+
+	for _, table := range tables {
+		if table == "schema_migrations" {
+			continue
+		}
+		if _, err := repo.DB.Exec(fmt.Sprintf("TRUNCATE TABLE %s", table)); err != nil {
+			t.Fatalf("Error truncating table %s: %v", table, err)
+		}
+	}
+}
+
+func testCleanup(t *testing.T, repo *repository.UserRepository) {
+	t.Helper()
+
+	truncateTables(t, repo)
+
+	if err := repo.DB.Close(); err != nil {
+		t.Logf("Error closing database connection: %v", err)
+	}
+}
+
+func insertBanLog(t *testing.T, repo *repository.UserRepository, admin, player, reason string) {
+	t.Helper()
+
+	query := "INSERT INTO logs_ban (Admin, Player, Reason) VALUES (?, ?, ?)"
+	if _, err := repo.DB.Exec(query, admin, player, reason); err != nil {
+		t.Fatalf("Error inserting log row: %v", err)
+	}
+}
+
+func grantVouchers(t *testing.T, repo *repository.UserRepository, name string, nameChanges, ck int) {
+	t.Helper()
+
+	query := "UPDATE accounts SET VoucherName = VoucherName + ?, VoucherCK = VoucherCK + ? WHERE Username = ?"
+	if _, err := repo.DB.Exec(query, nameChanges, ck, name); err != nil {
+		t.Fatalf("Error granting vouchers: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build !mysql
+
+package handler
+
+import (
+	"sarp_backend/repository"
+	"testing"
+)
+
+// The handler tests run against the in-memory repository by default, run them
+// with -tags mysql to use a live database instead.
+func testRepository(t *testing.T) *repository.MemoryRepository {
+	t.Helper()
+
+	return repository.NewMemoryRepository()
+}
+
+func testCleanup(t *testing.T, repo *repository.MemoryRepository) {
+	t.Helper()
+}
+
+func insertBanLog(t *testing.T, repo *repository.MemoryRepository, admin, player, reason string) {
+	t.Helper()
+
+	repo.InsertLog("logs_ban", map[string]interface{}{"Admin": admin, "Player": player, "Reason": reason})
+}
+
+func grantVouchers(t *testing.T, repo *repository.MemoryRepository, name string, nameChanges, ck int) {
+	t.Helper()
+
+	repo.GrantVouchers(name, nameChanges, ck)
+}
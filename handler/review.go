@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"sarp_backend/model"
+)
+
+// ClaimApplication reserves a pending application for the reviewer calling it. Other
+// reviewers can't accept or reject it until it is released or the lease runs out.
+func (h *UserHandler) ClaimApplication(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "review.claim_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ClaimApplication(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("ClaimApplication(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.ClaimAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ClaimApplication(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	claim, err := h.Char.Claim(ctx.UserContext(), data.CharacterName, name)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ClaimApplication(): can't claim %s: %v", data.CharacterName, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data *model.ClaimResultAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         claim,
+	})
+}
+
+func (h *UserHandler) ReleaseApplication(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "review.release_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ReleaseApplication(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("ReleaseApplication(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.ClaimAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ReleaseApplication(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = h.Char.Release(ctx.UserContext(), data.CharacterName, name); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ReleaseApplication(): can't release %s: %v", data.CharacterName, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
+
+// AddReviewNote stores an internal note on the application, players never see them.
+func (h *UserHandler) AddReviewNote(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "review.note_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("AddReviewNote(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("AddReviewNote(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.ReviewNoteAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("AddReviewNote(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = data.Validate(); err != nil {
+		h.Logger.Exception(fmt.Sprintf("AddReviewNote(): invalid note: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	if err = h.Char.AddNote(ctx.UserContext(), &data, name); err != nil {
+		h.Logger.Exception(fmt.Sprintf("AddReviewNote(): can't add note to %s: %v", data.CharacterName, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
+
+// ReviewHistory returns every application of ?character= with its review events.
+func (h *UserHandler) ReviewHistory(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "review.history_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ReviewHistory(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("ReviewHistory(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var filter model.ReviewHistoryFilterAPI
+	if err = ctx.QueryParser(&filter); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ReviewHistory(): error parsing query: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	history, err := h.Char.History(ctx.UserContext(), filter.CharacterName)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ReviewHistory(): can't fetch history of %s: %v", filter.CharacterName, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data *model.ReviewHistoryAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         history,
+	})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"sarp_backend/model"
+	"sarp_backend/service"
+)
+
+func (h *UserHandler) Sessions(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "sessions.list_failed"),
+	}
+
+	sessions, err := h.Auth.ListSessions(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("Sessions(): error fetching sessions: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data []model.SessionAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         sessions,
+	})
+}
+
+func (h *UserHandler) RevokeSession(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "sessions.revoke_failed"),
+	}
+
+	var data model.RevokeSessionAPI
+	if err := ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("RevokeSession(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if data.ID == "" {
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err := h.Auth.RevokeSession(ctx, data.ID); err != nil {
+		h.Logger.Exception(fmt.Sprintf("RevokeSession(): error revoking session: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
+
+func (h *UserHandler) RevokeOtherSessions(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "sessions.revoke_others_failed"),
+	}
+
+	if err := h.Auth.RevokeOtherSessions(ctx); err != nil {
+		h.Logger.Exception(fmt.Sprintf("RevokeOtherSessions(): error revoking sessions: %v", err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
+
+func (h *UserHandler) ForceLogout(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "sessions.force_logout_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ForceLogout(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if name == "" {
+		h.Logger.Exception("ForceLogout(): session doesn't exist: user is not logged in")
+		return ctx.Status(http.StatusUnauthorized).JSON(br)
+	}
+
+	var data model.RevokeSessionAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ForceLogout(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if data.Username == "" {
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = h.Auth.RevokeUserSessions(ctx.UserContext(), data.Username, auditEntry(ctx, name, service.AuditSessionRevoke, data.Username, data)); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ForceLogout(): error revoking sessions for %s: %v", data.Username, err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	h.Logger.Info(fmt.Sprintf("ForceLogout(): %s logged out all sessions of %s", name, data.Username))
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"sarp_backend/model"
+	"sarp_backend/service"
+)
+
+func (h *UserHandler) VerifyTwoFactor(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "error.invalid_two_factor_code"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("VerifyTwoFactor(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	var data model.TwoFactorCodeAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("VerifyTwoFactor(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = data.Validate(); err != nil {
+		return h.errorResponse(ctx, br, err)
+	}
+
+	ip := service.ClientIP(ctx)
+
+	retryAfter, err := h.Guard.Check(ctx.UserContext(), name, ip)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("VerifyTwoFactor(): error checking lockout for user %s: %v", name, err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	if retryAfter > 0 {
+		return h.lockedResponse(ctx, br, retryAfter)
+	}
+
+	if err = h.TwoFactor.Verify(ctx.UserContext(), name, data.Code); err != nil {
+		h.Logger.Exception(fmt.Sprintf("VerifyTwoFactor(): invalid code for user %s: %v", name, err))
+		if errFail := h.Guard.Fail(ctx.UserContext(), name, ip); errFail != nil {
+			h.Logger.Exception(fmt.Sprintf("VerifyTwoFactor(): error recording failed login: %v", errFail))
+		}
+		return h.errorResponse(ctx, br, err)
+	}
+
+	testerLevel, errTester := h.User.TesterLevel(ctx.UserContext(), name)
+	if errTester != nil {
+		h.Logger.Exception(fmt.Sprintf("VerifyTwoFactor(): error fetching account: %v", errTester))
+		return ctx.SendStatus(http.StatusInternalServerError)
+	}
+
+	adminLevel, errAdmin := h.User.AdminLevel(ctx.UserContext(), name)
+	if errAdmin != nil {
+		h.Logger.Exception(fmt.Sprintf("VerifyTwoFactor(): error fetching account: %v", errAdmin))
+		return ctx.SendStatus(http.StatusInternalServerError)
+	}
+
+	if err = h.Auth.SaveSession(ctx, name, adminLevel, testerLevel, service.TwoFactorVerified); err != nil {
+		h.Logger.Exception(fmt.Sprintf("VerifyTwoFactor(): error saving session: %v", err))
+		return ctx.SendStatus(http.StatusInternalServerError)
+	}
+
+	if err = h.Guard.Reset(ctx.UserContext(), name); err != nil {
+		h.Logger.Exception(fmt.Sprintf("VerifyTwoFactor(): error resetting failed logins: %v", err))
+	}
+
+	return ctx.Status(http.StatusAccepted).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
+
+func (h *UserHandler) TwoFactorStatus(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "error.internal"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("TwoFactorStatus(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	enabled, err := h.TwoFactor.Enabled(ctx.UserContext(), name)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("TwoFactorStatus(): error fetching status: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Enabled bool `json:"enabled"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Enabled:      enabled,
+	})
+}
+
+func (h *UserHandler) EnrollTwoFactor(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "two_factor.enroll_failed"),
+	}
+
+	name, _, _, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("EnrollTwoFactor(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	data, err := h.TwoFactor.Enroll(ctx.UserContext(), name)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("EnrollTwoFactor(): error enrolling user %s: %v", name, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	type response struct {
+		model.BaseResponse
+		Data *model.TwoFactorEnrollAPI `json:"data"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse: model.BaseResponse{},
+		Data:         data,
+	})
+}
+
+func (h *UserHandler) ConfirmTwoFactor(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "error.invalid_two_factor_code"),
+	}
+
+	name, adminLevel, testerLevel, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ConfirmTwoFactor(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	var data model.TwoFactorCodeAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ConfirmTwoFactor(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = data.Validate(); err != nil {
+		return h.errorResponse(ctx, br, err)
+	}
+
+	codes, err := h.TwoFactor.Confirm(ctx.UserContext(), name, data.Code)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("ConfirmTwoFactor(): error confirming for user %s: %v", name, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	// The user just proved possession of the second factor, so the current session counts as verified.
+	if err = h.Auth.SaveSession(ctx, name, adminLevel, testerLevel, service.TwoFactorVerified); err != nil {
+		h.Logger.Exception(fmt.Sprintf("ConfirmTwoFactor(): error saving session: %v", err))
+		return ctx.SendStatus(http.StatusInternalServerError)
+	}
+
+	type response struct {
+		model.BaseResponse
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response{
+		BaseResponse:  model.BaseResponse{},
+		RecoveryCodes: codes,
+	})
+}
+
+func (h *UserHandler) DisableTwoFactor(ctx *fiber.Ctx) error {
+	br := model.BaseResponse{
+		Error:   true,
+		Message: h.T(ctx, "error.invalid_two_factor_code"),
+	}
+
+	name, adminLevel, testerLevel, err := h.Auth.CheckSession(ctx)
+	if err != nil {
+		h.Logger.Exception(fmt.Sprintf("DisableTwoFactor(): error checking for session: %v", err))
+		return ctx.Status(http.StatusInternalServerError).JSON(br)
+	}
+
+	var data model.TwoFactorCodeAPI
+	if err = ctx.BodyParser(&data); err != nil {
+		h.Logger.Exception(fmt.Sprintf("DisableTwoFactor(): error parsing body request: %v", err))
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(br)
+	}
+
+	if err = data.Validate(); err != nil {
+		return h.errorResponse(ctx, br, err)
+	}
+
+	if err = h.TwoFactor.Disable(ctx.UserContext(), name, data.Code); err != nil {
+		h.Logger.Exception(fmt.Sprintf("DisableTwoFactor(): error disabling for user %s: %v", name, err))
+		return h.errorResponse(ctx, br, err)
+	}
+
+	if err = h.Auth.SaveSession(ctx, name, adminLevel, testerLevel, service.TwoFactorNone); err != nil {
+		h.Logger.Exception(fmt.Sprintf("DisableTwoFactor(): error saving session: %v", err))
+		return ctx.SendStatus(http.StatusInternalServerError)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(model.BaseResponse{
+		Error:   false,
+		Message: "",
+	})
+}
@@ -0,0 +1,160 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed locales/*.json
+var files embed.FS
+
+const (
+	Romanian = "ro"
+	English  = "en"
+
+	// Default is used when nothing better is known and for messages missing from a bundle.
+	Default = Romanian
+)
+
+// Catalog holds one bundle of messages per locale, keyed by message id.
+type Catalog struct {
+	bundles map[string]map[string]string
+}
+
+// Load reads the bundles embedded in the binary.
+func Load() (*Catalog, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) (*Catalog, error) {
+	entries, err := fs.ReadDir(fsys, "locales")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{bundles: make(map[string]map[string]string)}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, errRead := fs.ReadFile(fsys, path.Join("locales", entry.Name()))
+		if errRead != nil {
+			return nil, errRead
+		}
+
+		var bundle map[string]string
+		if err = json.Unmarshal(data, &bundle); err != nil {
+			return nil, fmt.Errorf("bundle %s: %w", entry.Name(), err)
+		}
+		c.bundles[strings.TrimSuffix(entry.Name(), ".json")] = bundle
+	}
+
+	base, ok := c.bundles[Default]
+	if !ok {
+		return nil, fmt.Errorf("missing bundle for the default locale %s", Default)
+	}
+
+	// A message missing from a bundle would silently fall back to the default
+	// locale, so every bundle must translate exactly the ids of the default one.
+	for locale, bundle := range c.bundles {
+		for id := range base {
+			if _, ok = bundle[id]; !ok {
+				return nil, fmt.Errorf("bundle %s: missing message %s", locale, id)
+			}
+		}
+		for id := range bundle {
+			if _, ok = base[id]; !ok {
+				return nil, fmt.Errorf("bundle %s: unknown message %s", locale, id)
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// T returns the message in the given locale, formatted with args when there are any.
+// Unknown locales use the default one and unknown ids are returned as they are.
+func (c *Catalog) T(locale, id string, args ...interface{}) string {
+	msg, ok := c.bundles[locale][id]
+	if !ok {
+		if msg, ok = c.bundles[Default][id]; !ok {
+			return id
+		}
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+func (c *Catalog) Locales() []string {
+	var ret []string
+	for locale := range c.bundles {
+		ret = append(ret, locale)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Normalize maps a language tag such as en-US to a supported locale, it returns an
+// empty string when there is no bundle for the language.
+func (c *Catalog) Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+
+	if _, ok := c.bundles[tag]; !ok {
+		return ""
+	}
+	return tag
+}
+
+// Match picks the supported locale with the highest weight from an Accept-Language
+// header, ties are won by the language listed first.
+func (c *Catalog) Match(header string) string {
+	best, bestWeight := Default, 0.0
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+
+		locale := c.Normalize(tag)
+		if locale != "" && weight > bestWeight {
+			best, bestWeight = locale, weight
+		}
+	}
+
+	return best
+}
+
+type contextKey struct{}
+
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locale)
+}
+
+// FromContext returns the locale picked for the request, or the default one.
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(contextKey{}).(string); ok && locale != "" {
+		return locale
+	}
+	return Default
+}
@@ -0,0 +1,79 @@
+package i18n
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	c, err := Load()
+	if err != nil {
+		t.Fatalf("Error loading embedded bundles: %v", err)
+	}
+
+	assert.Equal(t, []string{English, Romanian}, c.Locales())
+	assert.Equal(t, "Token-ul a expirat.", c.T(Romanian, "error.token_expired"))
+	assert.Equal(t, "The token has expired.", c.T(English, "error.token_expired"))
+}
+
+func TestLoad(t *testing.T) {
+	_, err := load(fstest.MapFS{
+		"locales/ro.json": {Data: []byte(`{"a": "A", "b": "B"}`)},
+		"locales/en.json": {Data: []byte(`{"a": "A"}`)},
+	})
+	assert.Error(t, err, "A bundle missing a message must be rejected")
+
+	_, err = load(fstest.MapFS{
+		"locales/ro.json": {Data: []byte(`{"a": "A"}`)},
+		"locales/en.json": {Data: []byte(`{"a": "A", "b": "B"}`)},
+	})
+	assert.Error(t, err, "A bundle with unknown messages must be rejected")
+
+	_, err = load(fstest.MapFS{"locales/en.json": {Data: []byte(`{"a": "A"}`)}})
+	assert.Error(t, err, "The default bundle is required")
+}
+
+func TestT(t *testing.T) {
+	c, err := load(fstest.MapFS{
+		"locales/ro.json": {Data: []byte(`{"wait": "Asteapta %d secunde."}`)},
+		"locales/en.json": {Data: []byte(`{"wait": "Wait %d seconds."}`)},
+	})
+	if err != nil {
+		t.Fatalf("Error loading bundles: %v", err)
+	}
+
+	assert.Equal(t, "Wait 5 seconds.", c.T(English, "wait", 5))
+	assert.Equal(t, "Asteapta 5 secunde.", c.T("de", "wait", 5), "Unknown locales must use the default one")
+	assert.Equal(t, "missing", c.T(English, "missing"))
+}
+
+func TestMatch(t *testing.T) {
+	c, err := Load()
+	if err != nil {
+		t.Fatalf("Error loading embedded bundles: %v", err)
+	}
+
+	tests := map[string]string{
+		"":                          Default,
+		"en-US,en;q=0.9":            English,
+		"de-DE, ro;q=0.8, en;q=0.7": Romanian,
+		"de, en;q=0.5":              English,
+		"ro;q=0.5, en-GB;q=0.9":     English,
+		"en;q=bad":                  Default,
+		"fr":                        Default,
+	}
+
+	for header, expected := range tests {
+		assert.Equal(t, expected, c.Match(header), "Unexpected locale for %q", header)
+	}
+
+	assert.Equal(t, English, c.Normalize("EN_us"))
+	assert.Empty(t, c.Normalize("fr"))
+}
+
+func TestContext(t *testing.T) {
+	assert.Equal(t, Default, FromContext(context.Background()))
+	assert.Equal(t, English, FromContext(WithLocale(context.Background(), English)))
+}
@@ -1,5 +1,132 @@
 package main
 
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+var errUsage = errors.New("invalid arguments")
+
+// command is a subcommand of user, ban or character. setup declares its flags and
+// returns the function running it with the positional arguments.
+type command struct {
+	args  int
+	setup func(fs *flag.FlagSet) func(c *console, args []string) error
+}
+
+var commands = map[string]map[string]command{
+	"user": {
+		"activate":       {args: 1, setup: userActivate},
+		"promote":        {args: 1, setup: userPromote},
+		"demote":         {args: 1, setup: userDemote},
+		"reset-password": {args: 1, setup: userResetPassword},
+	},
+	"ban": {
+		"add":    {args: 1, setup: banAdd},
+		"remove": {args: 1, setup: banRemove},
+		"list":   {args: 0, setup: banList},
+	},
+	"character": {
+		"accept": {args: 1, setup: characterAccept},
+		"reject": {args: 1, setup: characterReject},
+	},
+}
+
 func main() {
-	StartServer()
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	opts := defaultOptions()
+
+	fs := flag.NewFlagSet("ucp", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	name, args := "serve", fs.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	var err error
+	switch name {
+	case "serve":
+		if _, err = parseArgs(global(name, opts), args); err == nil {
+			StartServer(opts.configPath)
+		}
+	case "migrate":
+		var positional []string
+		if positional, err = parseArgs(global(name, opts), args); err == nil {
+			err = Migrate(opts, positional)
+		}
+	case "config":
+		err = ConfigCheck(opts, args)
+	default:
+		group, ok := commands[name]
+		if !ok {
+			err = errUsage
+			break
+		}
+		err = runCommand(opts, name, group, args)
+		if len(args) > 0 {
+			name += " " + args[0]
+		}
+	}
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errInvalidConfig):
+		return 1
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	default:
+		return fail(opts, name, err)
+	}
+}
+
+// global is the flag set of commands without flags of their own.
+func global(name string, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet("ucp "+name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	opts.register(fs)
+	return fs
+}
+
+func runCommand(opts *options, name string, group map[string]command, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	cmd, ok := group[args[0]]
+	if !ok {
+		return errUsage
+	}
+
+	fs := global(name+" "+args[0], opts)
+	exec := cmd.setup(fs)
+	positional, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != cmd.args {
+		return errUsage
+	}
+	if len(opts.actor) > actorLimit {
+		opts.actor = opts.actor[:actorLimit]
+	}
+
+	c, closeDB, err := openConsole(opts)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	return exec(c, positional)
 }
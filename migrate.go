@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	config "sarp_backend/config"
+	"sarp_backend/migrations"
+	"sarp_backend/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const migrateUsage = "usage: ucp migrate up | down [steps] | status"
+
+// migrationResult is a migration applied or reverted by the command.
+type migrationResult struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+}
+
+// migrationStatus is a line of ucp migrate status, AppliedAt is left out for the
+// pending migrations.
+type migrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified"`
+}
+
+func Migrate(opts *options, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	cfg, err := config.Read(opts.configPath)
+	if err != nil {
+		return fmt.Errorf("error reading %s:\n%w", opts.configPath, err)
+	}
+
+	ucpRepo, err := repository.New(cfg.Dsn, 0)
+	if err != nil {
+		return fmt.Errorf("error creating repository: %w", err)
+	}
+	defer ucpRepo.DB.Close()
+
+	migrator, err := migrations.New(ucpRepo.DB)
+	if err != nil {
+		return err
+	}
+
+	c := &console{opts: opts, out: os.Stdout}
+
+	switch args[0] {
+	case "up":
+		applied, errUp := migrator.Up()
+		if len(applied) > 0 || errUp == nil {
+			if err = c.printMigrations("applied", applied, "database is up to date"); err != nil {
+				return err
+			}
+		}
+		return errUp
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+		reverted, errDown := migrator.Down(steps)
+		if len(reverted) > 0 || errDown == nil {
+			if err = c.printMigrations("reverted", reverted, "nothing to revert"); err != nil {
+				return err
+			}
+		}
+		return errDown
+	case "status":
+		list, errStatus := migrator.Status()
+		if errStatus != nil {
+			return errStatus
+		}
+
+		ret := []migrationStatus{}
+		var b strings.Builder
+		for i, s := range list {
+			status := migrationStatus{Version: s.Version, Name: s.Name, Applied: s.Applied, Modified: s.Modified}
+			state := "pending"
+			if s.Applied {
+				appliedAt := s.AppliedAt
+				status.AppliedAt = &appliedAt
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state += " (modified)"
+			}
+			ret = append(ret, status)
+
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "%04d_%-24s %s", s.Version, s.Name, state)
+		}
+		return c.print(ret, "%s", b.String())
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+// printMigrations prints the migrations that were applied or reverted, verb is the
+// word of the text output and the key of the JSON one.
+func (c *console) printMigrations(verb string, list []migrations.Migration, none string) error {
+	ret := []migrationResult{}
+	var lines []string
+	for _, m := range list {
+		ret = append(ret, migrationResult{Version: m.Version, Name: m.Name})
+		lines = append(lines, fmt.Sprintf("%s %04d_%s", verb, m.Version, m.Name))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, none)
+	}
+	return c.print(map[string][]migrationResult{verb: ret}, "%s", strings.Join(lines, "\n"))
+}
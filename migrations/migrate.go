@@ -0,0 +1,286 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+const (
+	lockName    = "ucp_schema_migrations"
+	lockTimeout = 30
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool
+}
+
+type appliedMigration struct {
+	Version   int       `db:"Version"`
+	Checksum  string    `db:"Checksum"`
+	AppliedAt time.Time `db:"AppliedAt"`
+}
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+func New(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, errRead := fs.ReadFile(fsys, "sql/"+entry.Name())
+		if errRead != nil {
+			return nil, errRead
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	var done []Migration
+
+	err := m.locked(func(conn *sqlx.Conn) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if a, ok := applied[mig.Version]; ok {
+				if a.Checksum != mig.Checksum {
+					return fmt.Errorf("checksum mismatch for applied migration %d_%s", mig.Version, mig.Name)
+				}
+				continue
+			}
+
+			if err = execScript(conn, mig.Up); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+
+			query := "INSERT INTO schema_migrations (Version, Name, Checksum) VALUES (?, ?, ?)"
+			if _, err = conn.ExecContext(context.Background(), query, mig.Version, mig.Name, mig.Checksum); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var done []Migration
+
+	err := m.locked(func(conn *sqlx.Conn) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			a, ok := applied[mig.Version]
+			if !ok {
+				continue
+			}
+			if a.Checksum != mig.Checksum {
+				return fmt.Errorf("checksum mismatch for applied migration %d_%s", mig.Version, mig.Name)
+			}
+
+			if err = execScript(conn, mig.Down); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+
+			if _, err = conn.ExecContext(context.Background(), "DELETE FROM schema_migrations WHERE Version = ?", mig.Version); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+func (m *Migrator) Status() ([]Status, error) {
+	var list []Status
+
+	err := m.locked(func(conn *sqlx.Conn) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			s := Status{Version: mig.Version, Name: mig.Name}
+			if a, ok := applied[mig.Version]; ok {
+				s.Applied = true
+				s.AppliedAt = a.AppliedAt
+				s.Modified = a.Checksum != mig.Checksum
+			}
+			list = append(list, s)
+		}
+		return nil
+	})
+
+	return list, err
+}
+
+// locked runs fn on a single connection holding a MySQL advisory lock, so two
+// instances started together don't run the same migration twice.
+func (m *Migrator) locked(fn func(conn *sqlx.Conn) error) error {
+	ctx := context.Background()
+
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var got sql.NullInt64
+	if err = conn.GetContext(ctx, &got, "SELECT GET_LOCK(?, ?)", lockName, lockTimeout); err != nil {
+		return err
+	}
+	if !got.Valid || got.Int64 != 1 {
+		return errors.New("another instance is running migrations")
+	}
+	defer conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", lockName)
+
+	query := "CREATE TABLE IF NOT EXISTS schema_migrations (" +
+		"Version int not null primary key, " +
+		"Name varchar(128) not null, " +
+		"Checksum char(64) not null, " +
+		"AppliedAt timestamp default CURRENT_TIMESTAMP not null" +
+		") charset = utf8mb4"
+	if _, err = conn.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) applied(conn *sqlx.Conn) (map[int]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := conn.SelectContext(context.Background(), &rows, "SELECT Version, Checksum, AppliedAt FROM schema_migrations"); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]appliedMigration, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// execScript runs the statements one by one, the DSN doesn't have to allow multiStatements.
+func execScript(conn *sqlx.Conn, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := conn.ExecContext(context.Background(), stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits on semicolons ending a line and drops comment lines.
+func splitStatements(script string) []string {
+	var stmts []string
+	var current []string
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current = append(current, line)
+		if strings.HasSuffix(trimmed, ";") {
+			stmt := strings.TrimSuffix(strings.TrimSpace(strings.Join(current, "\n")), ";")
+			stmts = append(stmts, stmt)
+			current = nil
+		}
+	}
+
+	if rest := strings.TrimSpace(strings.Join(current, "\n")); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
@@ -0,0 +1,57 @@
+//go:build mysql
+
+package migrations
+
+import (
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"testing"
+)
+
+// testDsn points to a scratch schema, it is dropped before and after the test.
+const testDsn = "samp:password@tcp(127.0.0.1:3306)/test_migrations?charset=utf8mb4&parseTime=True&loc=Local" // Modify credentials
+
+func TestScriptsExecute(t *testing.T) {
+	cfg, err := mysql.ParseDSN(testDsn)
+	if err != nil {
+		t.Fatalf("Error parsing test dsn: %v", err)
+	}
+	schema := cfg.DBName
+	cfg.DBName = ""
+
+	server, err := sqlx.Connect("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatalf("Error connecting to the test server: %v", err)
+	}
+	defer server.Close()
+
+	for _, query := range []string{"DROP DATABASE IF EXISTS " + schema, "CREATE DATABASE " + schema} {
+		if _, err = server.Exec(query); err != nil {
+			t.Fatalf("Error preparing test database: %v", err)
+		}
+	}
+	defer server.Exec("DROP DATABASE IF EXISTS " + schema)
+
+	db, err := sqlx.Connect("mysql", testDsn)
+	if err != nil {
+		t.Fatalf("Error connecting to the test database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+
+	// Every up and down script runs once, then the schema is built again on top of the
+	// game tables the down scripts keep.
+	if _, err = migrator.Up(); err != nil {
+		t.Fatalf("Error applying migrations: %v", err)
+	}
+	if _, err = migrator.Down(len(migrator.migrations)); err != nil {
+		t.Fatalf("Error reverting migrations: %v", err)
+	}
+	if _, err = migrator.Up(); err != nil {
+		t.Fatalf("Error applying migrations again: %v", err)
+	}
+}
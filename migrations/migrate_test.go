@@ -0,0 +1,149 @@
+package migrations
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	migrations, err := load(files)
+	if err != nil {
+		t.Fatalf("Error loading embedded migrations: %v", err)
+	}
+
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, "Migrations must be numbered without gaps")
+		assert.NotEmpty(t, m.Up, "Missing up script for %s", m.Name)
+		assert.NotEmpty(t, m.Down, "Missing down script for %s", m.Name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_second.up.sql":   {Data: []byte("create table b (id int);")},
+		"sql/0001_first.up.sql":    {Data: []byte("create table a (id int);")},
+		"sql/0001_first.down.sql":  {Data: []byte("drop table a;")},
+		"sql/0002_second.down.sql": {Data: []byte("drop table b;")},
+	}
+
+	migrations, err := load(fsys)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+
+	if assert.Len(t, migrations, 2) {
+		assert.Equal(t, "first", migrations[0].Name)
+		assert.Equal(t, "second", migrations[1].Name)
+		assert.Len(t, migrations[0].Checksum, 64)
+		assert.NotEqual(t, migrations[0].Checksum, migrations[1].Checksum)
+	}
+
+	_, err = load(fstest.MapFS{"sql/0001_first.down.sql": {Data: []byte("drop table a;")}})
+	assert.Error(t, err, "A migration without an up script must be rejected")
+
+	_, err = load(fstest.MapFS{"sql/first.up.sql": {Data: []byte("")}})
+	assert.Error(t, err, "Files without a version must be rejected")
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- comment
+create table a
+(
+    id int not null
+);
+
+create table b (id int);
+insert into b values (1)`
+
+	assert.Equal(t, []string{
+		"create table a\n(\n    id int not null\n)",
+		"create table b (id int)",
+		"insert into b values (1)",
+	}, splitStatements(script))
+	assert.Empty(t, splitStatements("-- nothing to do\n"))
+}
+
+var (
+	statementStart = regexp.MustCompile(`(?i)^(create|alter|drop|insert|update|delete|rename)\s`)
+	identifier     = regexp.MustCompile(`^[A-Za-z0-9_$]+$`)
+)
+
+// checkStatement catches what breaks a script before it reaches MySQL: unbalanced
+// quotes or parentheses and quoted identifiers that aren't identifiers, like the Go
+// string concatenation once pasted into the game schema.
+func checkStatement(stmt string) error {
+	if !statementStart.MatchString(stmt) {
+		return fmt.Errorf("unknown statement %.40q", stmt)
+	}
+
+	depth := 0
+	var quote rune
+	var quoted strings.Builder
+	runes := []rune(stmt)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if quote != 0 {
+			switch {
+			case c == '\\' && quote != '`':
+				i++
+			case c == quote && i+1 < len(runes) && runes[i+1] == quote:
+				i++
+			case c == quote:
+				if quote == '`' && !identifier.MatchString(quoted.String()) {
+					return fmt.Errorf("invalid identifier %q", quoted.String())
+				}
+				quote = 0
+			default:
+				quoted.WriteRune(c)
+			}
+			continue
+		}
+
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+			quoted.Reset()
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return fmt.Errorf("unbalanced parentheses in %.40q", stmt)
+			}
+		}
+	}
+
+	if quote != 0 {
+		return fmt.Errorf("unterminated %c quote in %.40q", quote, stmt)
+	}
+	if depth != 0 {
+		return fmt.Errorf("unbalanced parentheses in %.40q", stmt)
+	}
+	return nil
+}
+
+func TestEmbeddedScripts(t *testing.T) {
+	migrations, err := load(files)
+	if err != nil {
+		t.Fatalf("Error loading embedded migrations: %v", err)
+	}
+
+	for _, m := range migrations {
+		for direction, script := range map[string]string{"up": m.Up, "down": m.Down} {
+			for _, stmt := range splitStatements(script) {
+				assert.NoError(t, checkStatement(stmt), "%04d_%s.%s.sql", m.Version, m.Name, direction)
+			}
+		}
+	}
+}
+
+func TestCheckStatement(t *testing.T) {
+	assert.NoError(t, checkStatement("create table a (`Character` varchar(24) default 'it''s (' not null)"))
+	assert.Error(t, checkStatement("create table a (` + \"`Character`\" + ` varchar(24))"))
+	assert.Error(t, checkStatement("create table a (id int"))
+	assert.Error(t, checkStatement("insert into a values ('x)"))
+	assert.Error(t, checkStatement("selec 1"))
+}
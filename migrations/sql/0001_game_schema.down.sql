@@ -0,0 +1 @@
+-- The game tables are never dropped by the UCP.
@@ -0,0 +1,298 @@
+-- Tables owned by the game server. They already exist in production, the
+-- migration only creates them on an empty database such as the test one.
+create table if not exists accounts
+(
+    id                 int auto_increment
+        primary key,
+    Username           varchar(250)                                                            null,
+    Email              varchar(250)                                                            not null,
+    NumePrenume        varchar(50)                                                             not null,
+    NumeForum          varchar(200)                                                            not null,
+    Password           varchar(129)                                                            null,
+    Serial             varchar(128)                                                            not null,
+    RegisterDate       varchar(36)                                                             null,
+    LoginDate          int                           default 0                                 null,
+    IP                 varchar(16)                   default 'n/a'                             null,
+    secret_code        varchar(24)                                                             null,
+    Characters         int                           default 0                                 not null,
+    Tokens             float                         default 0                                 not null,
+    Admin              int                           default 0                                 not null,
+    Tester             int                           default 0                                 not null,
+    DonateRank         int                           default 0                                 not null,
+    DonateExpired      varchar(128)                  default '0000-00-00'                      not null,
+    VoucherPhone       int                           default 0                                 not null,
+    VoucherName        int                           default 0                                 not null,
+    VoucherCK          int                           default 0                                 not null,
+    VoucherForum       int                           default 0                                 not null,
+    ConthorizeAdmin    int                           default 0                                 not null,
+    ConthorizeTester   int                           default 0                                 not null,
+    AcceptedBy         varchar(500)                  default ''                                not null,
+    Ziua               int                           default 0                                 not null,
+    Luna               varchar(50)                   default '0'                               not null,
+    Anul               int                           default 0                                 not null,
+    Ora                int                           default 0                                 not null,
+    Minut              int                           default 0                                 not null,
+    Accepted           int                           default 0                                 not null,
+    Raspuns1           varchar(3000) charset utf8    default ''                                not null,
+    Raspuns2           varchar(3000) charset utf8    default ''                                not null,
+    Raspuns3           varchar(3000) charset utf8    default ''                                not null,
+    Raspuns4           varchar(3000) charset utf8    default ''                                not null,
+    Raspuns5           varchar(1000) charset utf8mb4 default ''                                not null,
+    Motiv              varchar(500) charset utf8     default '0'                               not null,
+    A1                 int                           default 0                                 not null,
+    A2                 int                           default 0                                 not null,
+    A3                 int                           default 0                                 not null,
+    A4                 int                           default 0                                 not null,
+    A5                 int                           default 0                                 not null,
+    A6                 int                           default 0                                 not null,
+    A7                 int                           default 0                                 not null,
+    B1                 varchar(100)                  default ''                                not null,
+    B2                 varchar(100)                  default ''                                not null,
+    B3                 varchar(100)                  default ''                                not null,
+    B4                 varchar(100)                  default ''                                not null,
+    B5                 varchar(100)                  default ''                                not null,
+    B6                 varchar(100)                  default ''                                not null,
+    B7                 varchar(100)                  default ''                                not null,
+    Activated          int                           default 0                                 not null,
+    ConturiAcceptate   int                           default 0                                 not null,
+    ConturiRefuzate    int                           default 0                                 not null,
+    CaractereAcceptate int                           default 0                                 not null,
+    CaractereRefuzate  int                           default 0                                 not null,
+    Avatar             varchar(250)                  default 'https://i.imgur.com/LXXYhKw.png' not null,
+    DescriereaMea      varchar(5000) charset utf8    default '-'                               not null
+)
+    charset = latin1;
+
+create table if not exists blacklist
+(
+    ID       int auto_increment
+        primary key,
+    IP       varchar(16)  default '0.0.0.0' null,
+    Username varchar(24)                    null,
+    BannedBy varchar(24)                    null,
+    Reason   varchar(128)                   null,
+    Date   varchar(36)                    null,
+    perm     int          default 1         not null,
+    Expire   varchar(250) default ''        null
+)
+    charset = latin1;
+
+create table if not exists characters
+(
+    ID               int auto_increment
+        primary key,
+    Username         varchar(24)                                                                             null,
+    `Character`      varchar(24)                                                                             null,
+    Created          int          default 0                                                                  null,
+    Age              int          default 4                                                                  not null,
+    Level            int          default 1                                                                  not null,
+    Experience       int          default 0                                                                  not null,
+    Gender           int          default 0                                                                  null,
+    Origin           varchar(32)  default 'Nespecificat'                                                     null,
+    Skin             int          default 299                                                                null,
+    Status           int          default 0                                                                  null,
+    PosX             float        default 0                                                                  null,
+    PosY             float        default 0                                                                  null,
+    PosZ             float        default 0                                                                  null,
+    PosA             float        default 0                                                                  null,
+    Interior         int          default 0                                                                  not null,
+    World            int          default 0                                                                  null,
+    Money            int          default 2500                                                               not null,
+    BankMoney        int          default 2500                                                               null,
+    Savings          int          default 0                                                                  null,
+    JailTime         int          default 0                                                                  null,
+    Muted            int          default 0                                                                  null,
+    MuteTime         int          default 0                                                                  not null,
+    CreateDate       varchar(250) default '0'                                                                null,
+    LastLogin        int          default 0                                                                  null,
+    Gun1             int          default 0                                                                  null,
+    Gun2             int          default 0                                                                  null,
+    Gun3             int          default 0                                                                  null,
+    Gun4             int          default 0                                                                  null,
+    Gun5             int          default 0                                                                  null,
+    Gun6             int          default 0                                                                  null,
+    Gun7             int          default 0                                                                  null,
+    Gun8             int          default 0                                                                  null,
+    Gun9             int          default 0                                                                  null,
+    Gun10            int          default 0                                                                  null,
+    Gun11            int          default 0                                                                  null,
+    Gun12            int          default 0                                                                  null,
+    Gun13            int          default 0                                                                  null,
+    Ammo1            int          default 0                                                                  null,
+    Ammo2            int          default 0                                                                  null,
+    Ammo3            int          default 0                                                                  null,
+    Ammo4            int          default 0                                                                  null,
+    Ammo5            int          default 0                                                                  null,
+    Ammo6            int          default 0                                                                  null,
+    Ammo7            int          default 0                                                                  null,
+    Ammo8            int          default 0                                                                  null,
+    Ammo9            int          default 0                                                                  null,
+    Ammo10           int          default 0                                                                  null,
+    Ammo11           int          default 0                                                                  null,
+    Ammo12           int          default 0                                                                  null,
+    Ammo13           int          default 0                                                                  null,
+    House            int          default -1                                                                 null,
+    Business         int          default -1                                                                 null,
+    Journey          int          default -1                                                                 not null,
+    Phone            int          default 0                                                                  null,
+    PlayingHours     int          default 0                                                                  null,
+    Minutes          int          default 0                                                                  null,
+    ArmorStatus      float        default 0                                                                  null,
+    Entrance         int          default 0                                                                  null,
+    Job              int          default 0                                                                  null,
+    Faction          int          default -1                                                                 null,
+    FactionRank      int          default 0                                                                  null,
+    Prisoned         int          default 0                                                                  null,
+    Injured          int          default 0                                                                  null,
+    Health           float        default 100                                                                null,
+    Warnings         int          default 0                                                                  null,
+    Warn1            varchar(32)                                                                             null,
+    Warn2            varchar(32)                                                                             null,
+    MaskID           int          default 0                                                                  null,
+    FactionMod       int          default 0                                                                  null,
+    PropertyMod      int          default 0                                                                  not null,
+    Capacity         int          default 35                                                                 null,
+    AdminHide        int          default 0                                                                  null,
+    SpawnPoint       int          default 0                                                                  not null,
+    StopJob          int          default 0                                                                  not null,
+    PayCheck         int          default 0                                                                  not null,
+    Abandon          int          default 0                                                                  not null,
+    Badge            int          default 0                                                                  not null,
+    PhoneRingtone    int          default 0                                                                  not null,
+    Wanteds          int          default 0                                                                  not null,
+    Timeout          int          default 0                                                                  not null,
+    SModel           int          default 0                                                                  not null,
+    OnDuty           int          default 0                                                                  not null,
+    TimeDuty         int          default 0                                                                  not null,
+    pCarKey          int          default 9999                                                               not null,
+    pDupKey          int          default 9999                                                               not null,
+    Drugs            varchar(216) default '0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00' not null,
+    DrugPerm         int          default 0                                                                  not null,
+    Ingredients      int          default 0                                                                  not null,
+    Addiction        float        default 0                                                                  null,
+    FightStyle       int          default 0                                                                  not null,
+    Talk             int          default 0                                                                  not null,
+    Walk             int          default 0                                                                  not null,
+    HouseSpawn       int          default -1                                                                 not null,
+    Online           int          default 0                                                                  not null,
+    Cell             int          default -1                                                                 not null,
+    Swat             int          default 0                                                                  not null,
+    GrantB           int          default -1                                                                 not null,
+    Hire             int          default -1                                                                 not null,
+    Unit             int          default -1                                                                 not null,
+    GraffitiText     varchar(128)                                                                            null,
+    GraffitiFont     int          default 0                                                                  not null,
+    GraffitiType     int          default 0                                                                  not null,
+    Clothes          varchar(128) default '-1|-1|-1|-1|-1'                                                   not null,
+    SprayPermission  int          default 0                                                                  not null,
+    LeoLicense       int          default 0                                                                  not null,
+    MarijuanaLicense int          default 0                                                                  not null,
+    Renting          int          default 0                                                                  not null,
+    RentKey          int          default -1                                                                 not null,
+    Biografie        varchar(5000) charset utf8                                                              null,
+    CanPry           int          default 0                                                                  not null,
+    PaperPerm        int          default 0                                                                  not null,
+    FakeID           int          default 0                                                                  not null,
+    FakeName         varchar(64)                                                                             null,
+    FakeSign         varchar(32)                                                                             null,
+    FakeAge          int          default 0                                                                  not null,
+    FakeOrigin       varchar(32)                                                                             null,
+    FakeSex          int          default 0                                                                  not null,
+    FakeLicense      int          default 0                                                                  not null,
+    FakeNameLicense  varchar(64)                                                                             null,
+    FakeSignLicense  varchar(64)                                                                             null,
+    FakeDriving      int          default 0                                                                  not null,
+    FakeWeapon       int          default 0                                                                  not null,
+    Channels         varchar(256) default '-1|-1|-1|-1|-1|-1|-1|-1|-1|-1'                                    not null,
+    Slots            varchar(256) default '-1|-1|-1|-1|-1|-1|-1|-1|-1|-1'                                    not null,
+    Cards1           int          default 0                                                                  not null,
+    Cards2           int          default 0                                                                  not null,
+    AcceptedBy       varchar(500)                                                                            null,
+    AutoLights       int          default 0                                                                  not null,
+    UseArmourDrug    int          default 0                                                                  not null,
+    UseHealthDrug    int          default 0                                                                  not null,
+    TimeBuy          int          default 0                                                                  not null
+)
+    charset = latin1;
+
+create table if not exists houses
+(
+    houseID           int auto_increment
+        primary key,
+    houseOwner        int          default 0                                                                    null,
+    housePrice        int          default 0                                                                    null,
+    houseAddress      varchar(32)                                                                               null,
+    housePosX         float        default 0                                                                    null,
+    housePosY         float        default 0                                                                    null,
+    housePosZ         float        default 0                                                                    null,
+    housePosA         float        default 0                                                                    null,
+    houseIntX         float        default 0                                                                    null,
+    houseIntY         float        default 0                                                                    null,
+    houseIntZ         float        default 0                                                                    null,
+    houseIntA         float        default 0                                                                    null,
+    houseInterior     int          default 0                                                                    null,
+    houseExterior     int          default 0                                                                    null,
+    houseExteriorVW   int          default 0                                                                    null,
+    houseLocked       int          default 0                                                                    null,
+    houseWeapon1      int          default 0                                                                    null,
+    houseAmmo1        int          default 0                                                                    null,
+    houseWeapon2      int          default 0                                                                    null,
+    houseAmmo2        int          default 0                                                                    null,
+    houseWeapon3      int          default 0                                                                    null,
+    houseAmmo3        int          default 0                                                                    null,
+    houseWeapon4      int          default 0                                                                    null,
+    houseAmmo4        int          default 0                                                                    null,
+    houseWeapon5      int          default 0                                                                    null,
+    houseAmmo5        int          default 0                                                                    null,
+    houseWeapon6      int          default 0                                                                    null,
+    houseAmmo6        int          default 0                                                                    null,
+    houseWeapon7      int          default 0                                                                    null,
+    houseAmmo7        int          default 0                                                                    null,
+    houseWeapon8      int          default 0                                                                    null,
+    houseAmmo8        int          default 0                                                                    null,
+    houseWeapon9      int          default 0                                                                    null,
+    houseAmmo9        int          default 0                                                                    null,
+    houseWeapon10     int          default 0                                                                    null,
+    houseAmmo10       int          default 0                                                                    null,
+    houseWeapon11     int          default 0                                                                    not null,
+    houseAmmo11       int          default 0                                                                    not null,
+    houseWeapon12     int          default 0                                                                    not null,
+    houseAmmo12       int          default 0                                                                    not null,
+    houseWeapon13     int          default 0                                                                    not null,
+    houseAmmo13       int          default 0                                                                    not null,
+    houseWeapon14     int          default 0                                                                    not null,
+    houseAmmo14       int          default 0                                                                    not null,
+    houseWeapon15     int          default 0                                                                    not null,
+    houseAmmo15       int          default 0                                                                    not null,
+    houseWeapon16     int          default 0                                                                    not null,
+    houseAmmo16       int          default 0                                                                    not null,
+    houseWeapon17     int          default 0                                                                    not null,
+    houseAmmo17       int          default 0                                                                    not null,
+    houseWeapon18     int          default 0                                                                    not null,
+    houseAmmo18       int          default 0                                                                    not null,
+    houseWeapon19     int          default 0                                                                    not null,
+    houseAmmo19       int          default 0                                                                    not null,
+    houseWeapon20     int          default 0                                                                    not null,
+    houseAmmo20       int          default 0                                                                    not null,
+    houseMoney        int          default 0                                                                    null,
+    housePrepare      int          default -1                                                                   not null,
+    housePrepareDrugs int          default -1                                                                   not null,
+    housePrepareTime  int          default -1                                                                   not null,
+    housePrepareID    int          default -1                                                                   not null,
+    houseDrugs        varchar(256) default '0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00|0.00\t' not null,
+    houseRentable     int          default 0                                                                    not null,
+    houseRentPrice    int          default 0                                                                    not null
+)
+    charset = latin1;
+
+create table if not exists logs_ban
+(
+    ID     int auto_increment
+        primary key,
+    Admin  varchar(24)                         not null,
+    Player varchar(24)                         not null,
+    Reason varchar(128)                        not null,
+    IP     varchar(45)  default ''             not null,
+    Date   timestamp    default CURRENT_TIMESTAMP not null
+)
+    charset = utf8mb4;
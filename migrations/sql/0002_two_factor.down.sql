@@ -0,0 +1,2 @@
+drop table if exists ucp_recovery_codes;
+drop table if exists ucp_two_factor;
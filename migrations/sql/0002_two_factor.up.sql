@@ -0,0 +1,21 @@
+create table if not exists ucp_two_factor
+(
+    Username  varchar(24)                         not null
+        primary key,
+    Secret    varchar(64)                         not null,
+    Enabled   tinyint(1) default 0                not null,
+    LastStep  bigint     default 0                not null,
+    CreatedAt timestamp  default CURRENT_TIMESTAMP not null
+)
+    charset = utf8mb4;
+
+create table if not exists ucp_recovery_codes
+(
+    ID       int auto_increment
+        primary key,
+    Username varchar(24) not null,
+    CodeHash char(64)    not null,
+    UsedAt   timestamp   null,
+    index idx_recovery_username (Username)
+)
+    charset = utf8mb4;
@@ -0,0 +1,15 @@
+create table if not exists ucp_sessions
+(
+    ID        varchar(64)                           not null
+        primary key,
+    Data      blob                                  not null,
+    ExpiresAt bigint       default 0                not null,
+    Username  varchar(24)  default ''               not null,
+    IP        varchar(45)  default ''               not null,
+    UserAgent varchar(255) default ''               not null,
+    CreatedAt timestamp    default CURRENT_TIMESTAMP not null,
+    LastSeen  timestamp    default CURRENT_TIMESTAMP not null,
+    index idx_sessions_username (Username),
+    index idx_sessions_expires (ExpiresAt)
+)
+    charset = utf8mb4;
@@ -0,0 +1 @@
+drop table if exists ucp_login_attempts;
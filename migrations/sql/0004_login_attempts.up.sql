@@ -0,0 +1,10 @@
+create table if not exists ucp_login_attempts
+(
+    Scope       varchar(8)                          not null,
+    Subject     varchar(250)                        not null,
+    Failures    int       default 0                 not null,
+    LastFailure timestamp default CURRENT_TIMESTAMP not null,
+    LockedUntil datetime                            null,
+    primary key (Scope, Subject)
+)
+    charset = utf8mb4;
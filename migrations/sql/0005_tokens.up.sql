@@ -0,0 +1,16 @@
+create table if not exists ucp_tokens
+(
+    ID         bigint auto_increment
+        primary key,
+    Purpose    varchar(16)                         not null,
+    TokenHash  char(64)                            not null,
+    Username   varchar(24)                         not null,
+    Email      varchar(250)                        not null,
+    ExpiresAt  datetime                            not null,
+    ConsumedAt datetime                            null,
+    CreatedAt  timestamp default CURRENT_TIMESTAMP not null,
+    constraint ucp_tokens_hash
+        unique (TokenHash),
+    index idx_tokens_email (Purpose, Email)
+)
+    charset = utf8mb4;
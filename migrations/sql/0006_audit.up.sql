@@ -0,0 +1,16 @@
+create table if not exists ucp_audit
+(
+    ID        bigint auto_increment
+        primary key,
+    Actor     varchar(24)                         not null,
+    Action    varchar(32)                         not null,
+    Target    varchar(64)  default ''             not null,
+    Payload   text                                not null,
+    IP        varchar(45)  default ''             not null,
+    CreatedAt timestamp    default CURRENT_TIMESTAMP not null,
+    index idx_audit_actor (Actor),
+    index idx_audit_action (Action),
+    index idx_audit_target (Target),
+    index idx_audit_created (CreatedAt)
+)
+    charset = utf8mb4;
@@ -0,0 +1,8 @@
+create table if not exists ucp_preferences
+(
+    Username  varchar(24)                         not null
+        primary key,
+    Locale    varchar(8)                          not null,
+    UpdatedAt timestamp default CURRENT_TIMESTAMP not null on update CURRENT_TIMESTAMP
+)
+    charset = utf8mb4;
@@ -0,0 +1,19 @@
+create table if not exists email_outbox
+(
+    ID             bigint auto_increment
+        primary key,
+    IdempotencyKey char(64)                                not null,
+    Recipient      varchar(250)                            not null,
+    Subject        varchar(250)                            not null,
+    Body           mediumtext                              not null,
+    Status         varchar(16)   default 'pending'         not null,
+    Attempts       int           default 0                 not null,
+    LastError      varchar(1000) default ''                not null,
+    NextAttemptAt  datetime      default CURRENT_TIMESTAMP not null,
+    SentAt         datetime                                null,
+    CreatedAt      timestamp     default CURRENT_TIMESTAMP not null,
+    constraint email_outbox_key
+        unique (IdempotencyKey),
+    index idx_outbox_due (Status, NextAttemptAt)
+)
+    charset = utf8mb4;
@@ -0,0 +1,2 @@
+alter table email_outbox
+    drop column TextBody;
@@ -0,0 +1,2 @@
+alter table email_outbox
+    add column TextBody mediumtext null after Subject;
@@ -0,0 +1,4 @@
+drop table if exists ucp_application_answers;
+drop table if exists ucp_applications;
+drop table if exists ucp_questions;
+drop table if exists ucp_questionnaires;
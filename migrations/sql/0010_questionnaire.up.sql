@@ -0,0 +1,40 @@
+create table if not exists ucp_questionnaires
+(
+    Version   int auto_increment
+        primary key,
+    CreatedBy varchar(24)                         not null,
+    CreatedAt timestamp default CURRENT_TIMESTAMP not null
+)
+    charset = utf8mb4;
+
+create table if not exists ucp_questions
+(
+    Version   int           not null,
+    Position  int           not null,
+    Text      varchar(500)  not null,
+    MinLength int default 0 not null,
+    MaxLength int           not null,
+    primary key (Version, Position)
+)
+    charset = utf8mb4;
+
+create table if not exists ucp_applications
+(
+    ID          bigint auto_increment
+        primary key,
+    Username    varchar(24)                         not null,
+    `Character` varchar(24)                         not null,
+    Version     int       default 0                 not null,
+    CreatedAt   timestamp default CURRENT_TIMESTAMP not null,
+    index idx_applications_character (`Character`)
+)
+    charset = utf8mb4;
+
+create table if not exists ucp_application_answers
+(
+    ApplicationID bigint not null,
+    Position      int    not null,
+    Answer        text   not null,
+    primary key (ApplicationID, Position)
+)
+    charset = utf8mb4;
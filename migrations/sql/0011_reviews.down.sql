@@ -0,0 +1,9 @@
+drop table if exists ucp_review_events;
+
+alter table ucp_applications
+    drop column Status,
+    drop column ClaimedBy,
+    drop column ClaimExpiresAt,
+    drop column ReviewedBy,
+    drop column Reason,
+    drop column ReviewedAt;
@@ -0,0 +1,29 @@
+alter table ucp_applications
+    add Status         varchar(16)  default 'pending' not null,
+    add ClaimedBy      varchar(24)  default ''        not null,
+    add ClaimExpiresAt datetime                       null,
+    add ReviewedBy     varchar(24)  default ''        not null,
+    add Reason         varchar(500) default ''        not null,
+    add ReviewedAt     datetime                       null;
+
+-- Characters waiting from before the questionnaire get an application so they can be
+-- claimed like the new ones.
+insert into ucp_applications (Username, `Character`, Version)
+select c.Username, c.`Character`, 0
+from characters c
+where c.Created = 0
+  and not exists (select 1 from ucp_applications a where a.`Character` = c.`Character`);
+
+create table if not exists ucp_review_events
+(
+    ID            bigint auto_increment
+        primary key,
+    ApplicationID bigint                              not null,
+    `Character`   varchar(24)                         not null,
+    Reviewer      varchar(24)                         not null,
+    Action        varchar(16)                         not null,
+    Note          varchar(1000) default ''            not null,
+    CreatedAt     timestamp default CURRENT_TIMESTAMP not null,
+    index idx_review_events_character (`Character`)
+)
+    charset = utf8mb4;
@@ -0,0 +1,2 @@
+-- namechanges is a game table and stays.
+drop table if exists ucp_name_changes;
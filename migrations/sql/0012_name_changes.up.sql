@@ -0,0 +1,29 @@
+-- namechanges belongs to the game server like the tables of 0001, it is only created
+-- here for empty databases such as the test one.
+create table if not exists namechanges
+(
+    ID      int auto_increment
+        primary key,
+    OldName varchar(24)                         not null,
+    NewName varchar(24)                         not null,
+    Admin   varchar(24)                         not null,
+    Date    timestamp default CURRENT_TIMESTAMP not null
+)
+    charset = utf8mb4;
+
+create table if not exists ucp_name_changes
+(
+    ID          bigint auto_increment
+        primary key,
+    Username    varchar(24)                           not null,
+    `Character` varchar(24)                           not null,
+    NewName     varchar(24)                           not null,
+    Status      varchar(16)  default 'pending'        not null,
+    ReviewedBy  varchar(24)  default ''               not null,
+    Reason      varchar(500) default ''               not null,
+    CreatedAt   timestamp    default CURRENT_TIMESTAMP not null,
+    ReviewedAt  datetime                              null,
+    index idx_name_changes_status (Status),
+    index idx_name_changes_username (Username)
+)
+    charset = utf8mb4;
@@ -0,0 +1,2 @@
+-- logs_ck is a game table and stays.
+drop table if exists ucp_ck_requests;
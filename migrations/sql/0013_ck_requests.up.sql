@@ -0,0 +1,34 @@
+-- logs_ck belongs to the game server like the tables of 0001, it is only created here
+-- for empty databases such as the test one.
+create table if not exists logs_ck
+(
+    ID     int auto_increment
+        primary key,
+    Admin  varchar(24)                         not null,
+    Player varchar(24)                         not null,
+    Reason varchar(128)                        not null,
+    Date   timestamp default CURRENT_TIMESTAMP not null
+)
+    charset = utf8mb4;
+
+-- Evidence holds one link per line. Staff requests don't need a CK voucher of the
+-- requester.
+create table if not exists ucp_ck_requests
+(
+    ID          bigint auto_increment
+        primary key,
+    Requester   varchar(24)                            not null,
+    Staff       tinyint(1)    default 0                 not null,
+    `Character` varchar(24)                            not null,
+    Owner       varchar(24)                            not null,
+    Evidence    text                                   not null,
+    Description text                                   not null,
+    Status      varchar(16)   default 'pending'         not null,
+    ReviewedBy  varchar(24)   default ''                not null,
+    Reason      varchar(500)  default ''                not null,
+    CreatedAt   timestamp     default CURRENT_TIMESTAMP not null,
+    ReviewedAt  datetime                               null,
+    index idx_ck_requests_status (Status),
+    index idx_ck_requests_requester (Requester)
+)
+    charset = utf8mb4;
@@ -1,24 +1,105 @@
 package model
 
-func ErrorMsg(s string) string {
-	switch s {
-	case "invalid email address":
-		return "Adresa de email folosita este invalida."
-	case "username can only contain letters and digits":
-		return "Numele contului nu poate contine spatii si caractere speciale."
-	case "name cannot be empty":
-		return "Numele caracterului trebuie completat."
-	case "origin cannot be empty":
-		return "Originea caracterului trebuie completata."
-	case "origin contains wrong characters":
-		return "Originea caracterului trebuie sa contina doar litere."
-	case "age must be between 12 and 80 years old":
-		return "Varsta caracterului trebuie sa fie intre 12 si 80 de ani."
-	case "invalid character name":
-		return "Numele caracterului trebuie sa fie de forma Prenume_Nume."
-	case "invalid length for character origin":
-		return "Originrea caracterului trebuie aiba minim 4 caractere."
-	default:
-		return "Parola trebuie sa aiba minim 8 caractere (litere, cifre si caractere speciale)"
+import (
+	"errors"
+	"net/http"
+)
+
+// Error is a failure the client is expected to handle. Code is stable and meant for
+// the frontend, the message shown to the player is translated from "error.<code>"
+// and the error text goes to the logs.
+type Error struct {
+	Code   string
+	Status int
+	text   string
+}
+
+func (e *Error) Error() string {
+	return e.text
+}
+
+func newError(code string, status int, text string) *Error {
+	return &Error{Code: code, Status: status, text: text}
+}
+
+var (
+	ErrInternal = newError("internal", http.StatusInternalServerError, "internal error")
+	ErrNotFound = newError("not_found", http.StatusNotFound, "not found")
+
+	ErrMissingFields     = newError("missing_fields", http.StatusUnprocessableEntity, "one or more fields are empty")
+	ErrInvalidUsername   = newError("invalid_username", http.StatusUnprocessableEntity, "username can only contain letters and digits")
+	ErrInvalidEmail      = newError("invalid_email", http.StatusUnprocessableEntity, "invalid email address")
+	ErrWeakPassword      = newError("weak_password", http.StatusUnprocessableEntity, "password is too weak")
+	ErrPasswordUnchanged = newError("password_unchanged", http.StatusUnprocessableEntity, "new password is the same as the current one")
+	ErrInvalidFilter     = newError("invalid_filter", http.StatusUnprocessableEntity, "invalid filter")
+	ErrInvalidLocale     = newError("invalid_locale", http.StatusUnprocessableEntity, "unsupported locale")
+	ErrInvalidLogType    = newError("invalid_log_type", http.StatusUnprocessableEntity, "invalid log type")
+	ErrInvalidStaffLevel = newError("invalid_staff_level", http.StatusUnprocessableEntity, "staff level can't be negative")
+
+	ErrInvalidForumName   = newError("invalid_forum_name", http.StatusUnprocessableEntity, "forum name contains wrong characters or is too long")
+	ErrInvalidAvatar      = newError("invalid_avatar", http.StatusUnprocessableEntity, "avatar must be an https link")
+	ErrDescriptionTooLong = newError("description_too_long", http.StatusUnprocessableEntity, "description is too long")
+
+	ErrAccountExists       = newError("account_exists", http.StatusConflict, "account already exists")
+	ErrEmailTaken          = newError("email_taken", http.StatusConflict, "email address is used by another account")
+	ErrAccountNotFound     = newError("account_not_found", http.StatusNotFound, "account not found")
+	ErrAccountNotActivated = newError("account_not_activated", http.StatusConflict, "account is not activated")
+	ErrInvalidCredentials  = newError("invalid_credentials", http.StatusUnauthorized, "invalid username or password")
+	ErrTooManyAttempts     = newError("too_many_attempts", http.StatusTooManyRequests, "too many failed attempts")
+	ErrNotAuthenticated    = newError("not_authenticated", http.StatusUnauthorized, "user is not logged in")
+	ErrForbidden           = newError("forbidden", http.StatusForbidden, "missing permission")
+
+	ErrBanned        = newError("banned", http.StatusForbidden, "account is banned")
+	ErrNotBanned     = newError("not_banned", http.StatusNotFound, "account is not banned")
+	ErrInvalidExpire = newError("invalid_expire", http.StatusUnprocessableEntity, "invalid expire value")
+
+	ErrCharacterNameRequired = newError("character_name_required", http.StatusUnprocessableEntity, "name cannot be empty")
+	ErrInvalidCharacterName  = newError("invalid_character_name", http.StatusUnprocessableEntity, "invalid character name")
+	ErrOriginRequired        = newError("character_origin_required", http.StatusUnprocessableEntity, "origin cannot be empty")
+	ErrInvalidOrigin         = newError("invalid_character_origin", http.StatusUnprocessableEntity, "origin contains wrong characters")
+	ErrOriginTooShort        = newError("character_origin_too_short", http.StatusUnprocessableEntity, "invalid length for character origin")
+	ErrInvalidAge            = newError("invalid_character_age", http.StatusUnprocessableEntity, "age must be between 12 and 80 years old")
+	ErrCharacterNameTaken    = newError("character_name_taken", http.StatusConflict, "character name is taken")
+	ErrCharacterLimit        = newError("character_limit", http.StatusConflict, "character limit reached")
+	ErrCharacterNotFound     = newError("character_not_found", http.StatusNotFound, "character not found")
+
+	ErrInvalidQuestionnaire  = newError("invalid_questionnaire", http.StatusUnprocessableEntity, "invalid application questions")
+	ErrQuestionnaireChanged  = newError("questionnaire_changed", http.StatusConflict, "application questions changed since the form was loaded")
+	ErrInvalidAnswers        = newError("invalid_answers", http.StatusUnprocessableEntity, "answers don't match the application questions")
+	ErrAnswerTooShort        = newError("answer_too_short", http.StatusUnprocessableEntity, "answer is too short")
+	ErrAnswerTooLong         = newError("answer_too_long", http.StatusUnprocessableEntity, "answer is too long")
+	ErrApplicationClaimed    = newError("application_claimed", http.StatusConflict, "application is claimed by another reviewer")
+	ErrApplicationNotClaimed = newError("application_not_claimed", http.StatusConflict, "application isn't claimed by this reviewer")
+	ErrNoteTooLong           = newError("note_too_long", http.StatusUnprocessableEntity, "review note is too long")
+	ErrReasonTooLong         = newError("reason_too_long", http.StatusUnprocessableEntity, "rejection reason is too long")
+	ErrApplicationInReview   = newError("application_in_review", http.StatusConflict, "application is being reviewed")
+	ErrResubmitCooldown      = newError("resubmit_cooldown", http.StatusTooManyRequests, "character was submitted too recently")
+	ErrNameChangePending     = newError("name_change_pending", http.StatusConflict, "character already has a pending name change")
+	ErrNameChangeNotFound    = newError("name_change_not_found", http.StatusNotFound, "name change request not found")
+	ErrNoNameVoucher         = newError("no_name_voucher", http.StatusForbidden, "no name change voucher left")
+	ErrCKPending             = newError("ck_pending", http.StatusConflict, "character already has a pending CK request")
+	ErrCKNotFound            = newError("ck_not_found", http.StatusNotFound, "CK request not found")
+	ErrNoCKVoucher           = newError("no_ck_voucher", http.StatusForbidden, "no CK voucher left")
+	ErrInvalidEvidence       = newError("invalid_evidence", http.StatusUnprocessableEntity, "invalid evidence link")
+
+	ErrTokenInvalid = newError("token_invalid", http.StatusBadRequest, "token is invalid")
+	ErrTokenExpired = newError("token_expired", http.StatusBadRequest, "token is expired")
+
+	ErrInvalidTwoFactorCode  = newError("invalid_two_factor_code", http.StatusUnauthorized, "invalid two factor code")
+	ErrTwoFactorEnabled      = newError("two_factor_enabled", http.StatusConflict, "two factor authentication is already enabled")
+	ErrTwoFactorNotEnabled   = newError("two_factor_not_enabled", http.StatusConflict, "two factor authentication is not enabled")
+	ErrTwoFactorNotEnrolling = newError("two_factor_not_enrolling", http.StatusConflict, "no pending two factor enrollment")
+	ErrSessionNotFound       = newError("session_not_found", http.StatusNotFound, "session not found")
+	ErrCurrentSession        = newError("current_session", http.StatusConflict, "can't revoke the current session, log out instead")
+
+	ErrEmailNotFound = newError("email_not_found", http.StatusNotFound, "email not found or already sent")
+)
+
+// AsError returns the domain error wrapped in err, anything unknown is an internal error.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
 	}
+	return ErrInternal
 }
@@ -1,14 +1,17 @@
 package model
 
 import (
-	"errors"
+	"fmt"
 	"net/mail"
 	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type BaseResponse struct {
 	Error   bool   `json:"error"`
+	Code    string `json:"code,omitempty"`
 	Message string `json:"message"`
 }
 
@@ -20,81 +23,160 @@ type RegisterAPI struct {
 
 func (r *RegisterAPI) Validate() error {
 	if r.Username == "" || r.Email == "" || r.Password == "" {
-		return errors.New("one or more fields are empty")
+		return ErrMissingFields
 	}
 
 	validUsername := regexp.MustCompile(`^[a-zA-Z0-9]+$`)
 	if !validUsername.MatchString(r.Username) {
-		return errors.New("username can only contain letters and digits")
+		return ErrInvalidUsername
 	}
 
 	if _, err := mail.ParseAddress(r.Email); err != nil {
-		return errors.New("invalid email address")
+		return ErrInvalidEmail
 	}
 
-	if len(r.Password) < 8 {
-		return errors.New("password length must be greater than 8")
-	}
+	return checkPassword(r.Password)
+}
 
-	if !containsLetter(r.Password) {
-		return errors.New("password must contain at least one letter")
-	}
+type LoginAPI struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
 
-	if !containsDigit(r.Password) {
-		return errors.New("password must contain at least one digit")
+func (l *LoginAPI) Validate() error {
+	if l.Username == "" || l.Password == "" {
+		return ErrMissingFields
 	}
 
-	if !containsSpecialChar(r.Password) {
-		return errors.New("password must contain at least one special character")
+	validUsername := regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	if !validUsername.MatchString(l.Username) {
+		return ErrInvalidUsername
 	}
 
 	return nil
 }
 
-type LoginAPI struct {
-	Username string `json:"username"`
+type ChangeEmailAPI struct {
+	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
-func (l *LoginAPI) Validate() error {
-	if l.Username == "" || l.Password == "" {
-		return errors.New("one or more fields are empty")
+func (c *ChangeEmailAPI) Validate() error {
+	if c.Email == "" || c.Password == "" {
+		return ErrMissingFields
 	}
 
-	validUsername := regexp.MustCompile(`^[a-zA-Z0-9]+$`)
-	if !validUsername.MatchString(l.Username) {
-		return errors.New("username can only contain letters and digits")
+	if _, err := mail.ParseAddress(c.Email); err != nil {
+		return ErrInvalidEmail
 	}
 
 	return nil
 }
 
 type UpdatePassword struct {
-	Email       string `json:"email"`
 	Token       string `json:"token"`
-	Timestamp   int64  `json:"timestamp"`
 	NewPassword string `json:"new_password"`
 }
 
+type TokenAPI struct {
+	Username string
+	Email    string
+}
+
 func (r *UpdatePassword) Validate() error {
-	if _, err := mail.ParseAddress(r.Email); err != nil {
-		return errors.New("invalid email address")
+	if r.Token == "" {
+		return ErrTokenInvalid
 	}
 
-	if len(r.NewPassword) < 8 {
-		return errors.New("password length must be greater than 8")
+	return checkPassword(r.NewPassword)
+}
+
+// ChangePasswordAPI is the authenticated change, the new password follows the same
+// rules as the one picked at registration or through a reset link.
+type ChangePasswordAPI struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (c *ChangePasswordAPI) Validate() error {
+	if c.CurrentPassword == "" || c.NewPassword == "" {
+		return ErrMissingFields
 	}
 
-	if !containsLetter(r.NewPassword) {
-		return errors.New("password must contain at least one letter")
+	if c.CurrentPassword == c.NewPassword {
+		return ErrPasswordUnchanged
 	}
 
-	if !containsDigit(r.NewPassword) {
-		return errors.New("password must contain at least one digit")
+	return checkPassword(c.NewPassword)
+}
+
+// ResetPasswordAPI is a reset done by an operator from the console, no token is
+// involved but the password rules are the same.
+type ResetPasswordAPI struct {
+	Username    string `json:"username"`
+	NewPassword string `json:"new_password"`
+}
+
+func (r *ResetPasswordAPI) Validate() error {
+	if r.Username == "" || r.NewPassword == "" {
+		return ErrMissingFields
+	}
+
+	return checkPassword(r.NewPassword)
+}
+
+// StaffLevelAPI holds both ranks of an account, 0 removes the rank.
+type StaffLevelAPI struct {
+	Username string `json:"username"`
+	Admin    int    `json:"admin"`
+	Tester   int    `json:"tester"`
+}
+
+func (s *StaffLevelAPI) Validate() error {
+	if s.Username == "" {
+		return ErrMissingFields
 	}
 
-	if !containsSpecialChar(r.NewPassword) {
-		return errors.New("password must contain at least one special character")
+	if s.Admin < 0 || s.Tester < 0 {
+		return ErrInvalidStaffLevel
+	}
+
+	return nil
+}
+
+// ProfileAPI holds the forum profile columns of accounts, the limits follow the
+// column sizes.
+type ProfileAPI struct {
+	ForumName   string `json:"forum_name"`
+	Avatar      string `json:"avatar"`
+	Description string `json:"description"`
+}
+
+func (p *ProfileAPI) Validate() error {
+	p.ForumName = strings.TrimSpace(p.ForumName)
+	p.Avatar = strings.TrimSpace(p.Avatar)
+	p.Description = strings.TrimSpace(p.Description)
+
+	if p.ForumName == "" || p.Avatar == "" {
+		return ErrMissingFields
+	}
+
+	validForumName := regexp.MustCompile(`^[a-zA-Z0-9 ._-]+$`)
+	if len(p.ForumName) > 200 || !validForumName.MatchString(p.ForumName) {
+		return ErrInvalidForumName
+	}
+
+	if len(p.Avatar) > 250 || !checkImageURL(p.Avatar) {
+		return ErrInvalidAvatar
+	}
+
+	// The column default, the game shows it for an empty description.
+	if p.Description == "" {
+		p.Description = "-"
+	}
+
+	if utf8.RuneCountInString(p.Description) > 5000 {
+		return ErrDescriptionTooLong
 	}
 
 	return nil
@@ -123,36 +205,99 @@ type GetStaffAPI struct {
 }
 
 type CharacterDataAPI struct {
-	Username        string `json:"username"`
-	CharacterName   string `json:"character_name"`
-	CharacterAge    int    `json:"character_age"`
-	CharacterGender int    `json:"character_gender"`
-	CharacterOrigin string `json:"character_origin"`
+	Username             string   `json:"username"`
+	CharacterName        string   `json:"character_name"`
+	CharacterAge         int      `json:"character_age"`
+	CharacterGender      int      `json:"character_gender"`
+	CharacterOrigin      string   `json:"character_origin"`
+	QuestionnaireVersion int      `json:"questionnaire_version"`
+	Answers              []string `json:"answers"`
+	// Questions are the ones Answers were given to, they are always filled by the
+	// server and never read from the request.
+	Questions []QuestionAPI `json:"questions,omitempty"`
+	// ClaimedBy is the reviewer handling the application, empty once the claim expired.
+	ClaimedBy      string     `json:"claimed_by,omitempty"`
+	ClaimExpiresAt *time.Time `json:"claim_expires_at,omitempty"`
 }
 
 func (c *CharacterDataAPI) Validate() error {
 	if c.CharacterName == "" {
-		return errors.New("name cannot be empty")
+		return ErrCharacterNameRequired
 	}
 
 	if !checkCharacterName(c.CharacterName) {
-		return errors.New("invalid character name")
+		return ErrInvalidCharacterName
 	}
 
 	if c.CharacterOrigin == "" {
-		return errors.New("origin cannot be empty")
+		return ErrOriginRequired
 	}
 
 	if !(c.CharacterAge > 12 && c.CharacterAge < 80) {
-		return errors.New("age must be between 12 and 80 years old")
+		return ErrInvalidAge
 	}
 
 	if containsDigit(c.CharacterOrigin) || containsSpecialChar(c.CharacterOrigin) {
-		return errors.New("origin contains wrong characters")
+		return ErrInvalidOrigin
 	}
 
 	if len(c.CharacterOrigin) < 4 {
-		return errors.New("invalid length for character origin")
+		return ErrOriginTooShort
+	}
+
+	if len(c.Answers) != len(c.Questions) {
+		return ErrInvalidAnswers
+	}
+
+	for i, q := range c.Questions {
+		c.Answers[i] = strings.TrimSpace(c.Answers[i])
+		length := utf8.RuneCountInString(c.Answers[i])
+		if length < q.MinLength {
+			return fmt.Errorf("%w: question %d needs %d characters", ErrAnswerTooShort, i+1, q.MinLength)
+		}
+		if length > q.MaxLength {
+			return fmt.Errorf("%w: question %d allows %d characters", ErrAnswerTooLong, i+1, q.MaxLength)
+		}
+	}
+
+	return nil
+}
+
+const (
+	maxQuestions      = 10
+	maxQuestionLength = 500
+	maxAnswerLength   = 5000
+)
+
+type QuestionAPI struct {
+	Text      string `json:"text"`
+	MinLength int    `json:"min_length"`
+	MaxLength int    `json:"max_length"`
+}
+
+// QuestionnaireAPI is a version of the application questions. Saving one never edits
+// the current version, it creates the next.
+type QuestionnaireAPI struct {
+	Version   int           `json:"version"`
+	Questions []QuestionAPI `json:"questions"`
+	CreatedBy string        `json:"created_by"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+func (q *QuestionnaireAPI) Validate() error {
+	if len(q.Questions) == 0 || len(q.Questions) > maxQuestions {
+		return fmt.Errorf("%w: between 1 and %d questions are allowed", ErrInvalidQuestionnaire, maxQuestions)
+	}
+
+	for i := range q.Questions {
+		question := &q.Questions[i]
+		question.Text = strings.TrimSpace(question.Text)
+		if question.Text == "" || utf8.RuneCountInString(question.Text) > maxQuestionLength {
+			return fmt.Errorf("%w: question %d must have between 1 and %d characters", ErrInvalidQuestionnaire, i+1, maxQuestionLength)
+		}
+		if question.MinLength < 0 || question.MaxLength < 1 || question.MaxLength > maxAnswerLength || question.MinLength > question.MaxLength {
+			return fmt.Errorf("%w: question %d needs 0 <= min_length <= max_length <= %d", ErrInvalidQuestionnaire, i+1, maxAnswerLength)
+		}
 	}
 
 	return nil
@@ -168,6 +313,242 @@ type RejectCharacterAPI struct {
 	Username      string `json:"username"`
 	CharacterName string `json:"character_name"`
 	Reason        string `json:"reason"`
+	RejectedBy    string `json:"-"`
+}
+
+const (
+	maxReasonLength = 500
+	maxNoteLength   = 1000
+)
+
+func (r *RejectCharacterAPI) Validate() error {
+	if r.CharacterName == "" {
+		return ErrCharacterNameRequired
+	}
+	if utf8.RuneCountInString(r.Reason) > maxReasonLength {
+		return fmt.Errorf("%w: at most %d characters", ErrReasonTooLong, maxReasonLength)
+	}
+	return nil
+}
+
+// ClaimAPI names the application a reviewer claims or releases.
+type ClaimAPI struct {
+	CharacterName string `json:"character_name"`
+}
+
+type ReviewNoteAPI struct {
+	CharacterName string `json:"character_name"`
+	Note          string `json:"note"`
+}
+
+func (r *ReviewNoteAPI) Validate() error {
+	r.Note = strings.TrimSpace(r.Note)
+	if r.CharacterName == "" || r.Note == "" {
+		return ErrMissingFields
+	}
+	if utf8.RuneCountInString(r.Note) > maxNoteLength {
+		return fmt.Errorf("%w: at most %d characters", ErrNoteTooLong, maxNoteLength)
+	}
+	return nil
+}
+
+type ClaimResultAPI struct {
+	CharacterName  string    `json:"character_name"`
+	ClaimedBy      string    `json:"claimed_by"`
+	ClaimExpiresAt time.Time `json:"claim_expires_at"`
+}
+
+// ReviewEventAPI is one step of a review: claim, release, note, accept or reject.
+type ReviewEventAPI struct {
+	Application int64     `json:"application"`
+	Reviewer    string    `json:"reviewer"`
+	Action      string    `json:"action"`
+	Note        string    `json:"note"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ApplicationReviewAPI struct {
+	ID                   int64         `json:"id"`
+	QuestionnaireVersion int           `json:"questionnaire_version"`
+	Questions            []QuestionAPI `json:"questions"`
+	Answers              []string      `json:"answers"`
+	Status               string        `json:"status"`
+	ReviewedBy           string        `json:"reviewed_by"`
+	Reason               string        `json:"reason"`
+	ReviewedAt           *time.Time    `json:"reviewed_at"`
+	CreatedAt            time.Time     `json:"created_at"`
+}
+
+type ReviewHistoryAPI struct {
+	CharacterName string                 `json:"character_name"`
+	Applications  []ApplicationReviewAPI `json:"applications"`
+	Events        []ReviewEventAPI       `json:"events"`
+}
+
+type ReviewHistoryFilterAPI struct {
+	CharacterName string `query:"character"`
+}
+
+// ApplicationDraftAPI is what the player last submitted for a waiting or rejected
+// character, the form is pre-filled with it. Questions are the ones Answers were given
+// to, they can be older than the current questionnaire.
+type ApplicationDraftAPI struct {
+	CharacterName        string        `json:"character_name"`
+	CharacterAge         int           `json:"character_age"`
+	CharacterGender      int           `json:"character_gender"`
+	CharacterOrigin      string        `json:"character_origin"`
+	Status               string        `json:"status"`
+	Reason               string        `json:"reason"`
+	QuestionnaireVersion int           `json:"questionnaire_version"`
+	Questions            []QuestionAPI `json:"questions"`
+	Answers              []string      `json:"answers"`
+	// ResubmitAfter is set for rejected characters, submitting earlier fails.
+	ResubmitAfter *time.Time `json:"resubmit_after,omitempty"`
+}
+
+type ApplicationFilterAPI struct {
+	CharacterName string `query:"character"`
+}
+
+type WithdrawCharacterAPI struct {
+	CharacterName string `json:"character_name"`
+}
+
+type NameChangeRequestAPI struct {
+	CharacterName string `json:"character_name"`
+	NewName       string `json:"new_name"`
+}
+
+func (r *NameChangeRequestAPI) Validate() error {
+	if r.CharacterName == "" || r.NewName == "" {
+		return ErrCharacterNameRequired
+	}
+	if !checkCharacterName(r.NewName) {
+		return ErrInvalidCharacterName
+	}
+	if strings.EqualFold(r.CharacterName, r.NewName) {
+		return fmt.Errorf("%w: the new name is the current one", ErrInvalidCharacterName)
+	}
+	return nil
+}
+
+type NameChangeAPI struct {
+	ID            int64      `json:"id"`
+	Username      string     `json:"username"`
+	CharacterName string     `json:"character_name"`
+	NewName       string     `json:"new_name"`
+	Status        string     `json:"status"`
+	ReviewedBy    string     `json:"reviewed_by"`
+	Reason        string     `json:"reason"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+}
+
+type NameChangeFilterAPI struct {
+	Status string `query:"status"`
+}
+
+func (r *NameChangeFilterAPI) Validate() error {
+	switch r.Status {
+	case "", "pending", "approved", "denied":
+		return nil
+	default:
+		return fmt.Errorf("%w: invalid status", ErrInvalidFilter)
+	}
+}
+
+// NameChangeDecisionAPI approves or denies a request, the reason is only used on denial.
+type NameChangeDecisionAPI struct {
+	ID     int64  `json:"id"`
+	Reason string `json:"reason"`
+}
+
+func (r *NameChangeDecisionAPI) Validate() error {
+	if r.ID < 1 {
+		return ErrMissingFields
+	}
+	if utf8.RuneCountInString(r.Reason) > maxReasonLength {
+		return fmt.Errorf("%w: at most %d characters", ErrReasonTooLong, maxReasonLength)
+	}
+	return nil
+}
+
+const maxEvidenceLinks = 10
+
+// CKRequestAPI files a character kill, Evidence holds links to screenshots or videos.
+type CKRequestAPI struct {
+	CharacterName string   `json:"character_name"`
+	Evidence      []string `json:"evidence"`
+	Description   string   `json:"description"`
+}
+
+func (r *CKRequestAPI) Validate() error {
+	r.Description = strings.TrimSpace(r.Description)
+
+	if r.CharacterName == "" {
+		return ErrCharacterNameRequired
+	}
+	if r.Description == "" {
+		return ErrMissingFields
+	}
+	if utf8.RuneCountInString(r.Description) > 5000 {
+		return ErrDescriptionTooLong
+	}
+
+	if len(r.Evidence) == 0 || len(r.Evidence) > maxEvidenceLinks {
+		return fmt.Errorf("%w: between 1 and %d links", ErrInvalidEvidence, maxEvidenceLinks)
+	}
+	for i, link := range r.Evidence {
+		r.Evidence[i] = strings.TrimSpace(link)
+		if len(r.Evidence[i]) > 250 || !checkEvidenceURL(r.Evidence[i]) {
+			return fmt.Errorf("%w: %q", ErrInvalidEvidence, link)
+		}
+	}
+	return nil
+}
+
+type CKAPI struct {
+	ID            int64      `json:"id"`
+	Requester     string     `json:"requester"`
+	Staff         bool       `json:"staff"`
+	CharacterName string     `json:"character_name"`
+	Owner         string     `json:"owner"`
+	Evidence      []string   `json:"evidence"`
+	Description   string     `json:"description"`
+	Status        string     `json:"status"`
+	ReviewedBy    string     `json:"reviewed_by"`
+	Reason        string     `json:"reason"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+}
+
+type CKFilterAPI struct {
+	Status string `query:"status"`
+}
+
+func (r *CKFilterAPI) Validate() error {
+	switch r.Status {
+	case "", "pending", "approved", "denied":
+		return nil
+	default:
+		return fmt.Errorf("%w: invalid status", ErrInvalidFilter)
+	}
+}
+
+// CKDecisionAPI approves or denies a CK request, the reason is only used on denial.
+type CKDecisionAPI struct {
+	ID     int64  `json:"id"`
+	Reason string `json:"reason"`
+}
+
+func (r *CKDecisionAPI) Validate() error {
+	if r.ID < 1 {
+		return ErrMissingFields
+	}
+	if utf8.RuneCountInString(r.Reason) > maxReasonLength {
+		return fmt.Errorf("%w: at most %d characters", ErrReasonTooLong, maxReasonLength)
+	}
+	return nil
 }
 
 type BanAPI struct {
@@ -195,7 +576,46 @@ type ServerStatsAPI struct {
 }
 
 type LogsAPI struct {
-	Type string `json:"type"`
+	Type      string `json:"type"`
+	Cursor    int64  `json:"cursor"`
+	Limit     int    `json:"limit"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Account   string `json:"account"`
+	Character string `json:"character"`
+	Search    string `json:"search"`
+}
+
+func (r *LogsAPI) Validate() error {
+	for _, date := range []string{r.From, r.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("%w: invalid date", ErrInvalidFilter)
+		}
+	}
+
+	if r.Cursor < 0 {
+		return fmt.Errorf("%w: invalid cursor", ErrInvalidFilter)
+	}
+
+	if len(r.Search) > 64 || len(r.Account) > 24 || len(r.Character) > 24 {
+		return fmt.Errorf("%w: filter is too long", ErrInvalidFilter)
+	}
+
+	if r.Limit < 1 || r.Limit > 200 {
+		r.Limit = 50
+	}
+
+	return nil
+}
+
+type LogsPageAPI struct {
+	Logs           []map[string]interface{} `json:"logs"`
+	NextCursor     int64                    `json:"next_cursor"`
+	Total          int                      `json:"total"`
+	TotalEstimated bool                     `json:"total_estimated"`
 }
 
 type LogEntry struct {
@@ -203,3 +623,139 @@ type LogEntry struct {
 	Data      interface{} `json:"data"`
 	Timestamp time.Time   `json:"timestamp"`
 }
+
+type TwoFactorEnrollAPI struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TwoFactorCodeAPI struct {
+	Code string `json:"code"`
+}
+
+func (t *TwoFactorCodeAPI) Validate() error {
+	t.Code = strings.TrimSpace(t.Code)
+	if t.Code == "" {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if len(t.Code) > 16 {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+type SessionAPI struct {
+	ID        string    `json:"id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	Current   bool      `json:"current"`
+}
+
+type RevokeSessionAPI struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+type LocaleAPI struct {
+	Locale string `json:"locale"`
+}
+
+type AuditAPI struct {
+	ID        int64     `json:"id"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	Payload   string    `json:"payload"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuditFilterAPI struct {
+	Actor   string `query:"actor"`
+	Action  string `query:"action"`
+	Target  string `query:"target"`
+	From    string `query:"from"`
+	To      string `query:"to"`
+	Page    int    `query:"page"`
+	PerPage int    `query:"per_page"`
+}
+
+func (r *AuditFilterAPI) Validate() error {
+	for _, date := range []string{r.From, r.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("%w: invalid date", ErrInvalidFilter)
+		}
+	}
+
+	if r.Page < 1 {
+		r.Page = 1
+	}
+
+	if r.PerPage < 1 || r.PerPage > 200 {
+		r.PerPage = 50
+	}
+
+	return nil
+}
+
+type AuditPageAPI struct {
+	Entries []AuditAPI `json:"entries"`
+	Page    int        `json:"page"`
+	PerPage int        `json:"per_page"`
+	Total   int        `json:"total"`
+}
+
+// EmailAPI leaves out the body on purpose, confirmation and reset emails carry tokens.
+type EmailAPI struct {
+	ID            int64      `json:"id"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type EmailFilterAPI struct {
+	Status  string `query:"status"`
+	Page    int    `query:"page"`
+	PerPage int    `query:"per_page"`
+}
+
+func (r *EmailFilterAPI) Validate() error {
+	switch r.Status {
+	case "", "pending", "sent", "dead":
+	default:
+		return fmt.Errorf("%w: invalid status", ErrInvalidFilter)
+	}
+
+	if r.Page < 1 {
+		r.Page = 1
+	}
+
+	if r.PerPage < 1 || r.PerPage > 200 {
+		r.PerPage = 50
+	}
+
+	return nil
+}
+
+type EmailPageAPI struct {
+	Entries []EmailAPI `json:"entries"`
+	Page    int        `json:"page"`
+	PerPage int        `json:"per_page"`
+	Total   int        `json:"total"`
+}
+
+type RetryEmailAPI struct {
+	ID int64 `json:"id"`
+}
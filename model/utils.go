@@ -1,10 +1,53 @@
 package model
 
 import (
+	"fmt"
+	"net/url"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
+// checkPassword holds the strength rules shared by every way of setting a password.
+func checkPassword(s string) error {
+	if len(s) < 8 {
+		return fmt.Errorf("%w: length must be greater than 8", ErrWeakPassword)
+	}
+
+	if !containsLetter(s) {
+		return fmt.Errorf("%w: must contain at least one letter", ErrWeakPassword)
+	}
+
+	if !containsDigit(s) {
+		return fmt.Errorf("%w: must contain at least one digit", ErrWeakPassword)
+	}
+
+	if !containsSpecialChar(s) {
+		return fmt.Errorf("%w: must contain at least one special character", ErrWeakPassword)
+	}
+
+	return nil
+}
+
+// checkImageURL accepts absolute https links only, the avatar is embedded by the forum.
+func checkImageURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "https" && u.Host != "" && u.User == nil
+}
+
+// checkEvidenceURL accepts links to screenshots and videos hosted anywhere, unlike
+// avatars they are only opened by staff.
+func checkEvidenceURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && u.User == nil
+}
+
 func containsLetter(s string) bool {
 	for _, c := range s {
 		if unicode.IsLetter(c) {
@@ -35,7 +78,14 @@ func containsSpecialChar(s string) bool {
 	return false
 }
 
+// maxCharacterNameLength is MAX_PLAYER_NAME of the game server without the terminator.
+const maxCharacterNameLength = 24
+
 func checkCharacterName(s string) bool {
+	if len(s) > maxCharacterNameLength {
+		return false
+	}
+
 	sep := strings.Split(s, "_")
 	if len(sep) != 2 {
 		return false
@@ -43,6 +93,9 @@ func checkCharacterName(s string) bool {
 
 	ok := true
 	for _, str := range sep {
+		if str == "" {
+			return false
+		}
 		firstChar := rune(str[0])
 		if containsDigit(str) || containsSpecialChar(str) || unicode.IsLower(firstChar) {
 			ok = false
@@ -50,3 +103,15 @@ func checkCharacterName(s string) bool {
 	}
 	return ok
 }
+
+// Truncate cuts s to at most limit bytes without splitting a character, MySQL refuses
+// invalid utf8mb4.
+func Truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
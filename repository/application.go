@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"sarp_backend/model"
+	"time"
+)
+
+// lockApplicant returns the Created value of a character of username that was never
+// accepted and its latest application, nil for characters waiting from before the
+// applications were stored. Both rows stay locked until the transaction ends.
+func lockApplicant(ctx context.Context, tx *sqlx.Tx, username, character string) (int, *ApplicationDB, error) {
+	var created int
+	query := "SELECT Created FROM characters WHERE `Character` = ? AND Username = ? AND Status = 0 FOR UPDATE"
+	if err := tx.GetContext(ctx, &created, query, character, username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil, model.ErrCharacterNotFound
+		}
+		return 0, nil, err
+	}
+	if created != 0 && created != CharacterRejected {
+		return 0, nil, model.ErrCharacterNotFound
+	}
+
+	application, err := lockApplication(ctx, tx, character)
+	if errors.Is(err, model.ErrCharacterNotFound) {
+		return created, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return created, application, nil
+}
+
+// FetchOpenApplication returns a waiting or rejected character of username with its
+// latest application.
+func (r *UserRepository) FetchOpenApplication(ctx context.Context, username, character string) (*CharacterDB, *ApplicationDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var data CharacterDB
+	query := "SELECT Username, `Character`, Age, Gender, Origin FROM characters WHERE `Character` = ? AND Username = ? AND Status = 0 AND Created IN (0, ?)"
+	if err := r.DB.GetContext(ctx, &data, query, character, username, CharacterRejected); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, model.ErrCharacterNotFound
+		}
+		return nil, nil, err
+	}
+
+	applications, err := r.FetchApplications(ctx, []string{data.Character})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(applications) == 0 {
+		return &data, nil, nil
+	}
+	return &data, &applications[0], nil
+}
+
+// UpdateApplication replaces the details and the answers of a waiting character. It
+// is refused while a reviewer holds the claim, they would decide on a moving target.
+func (r *UserRepository) UpdateApplication(ctx context.Context, data *CharacterDB, application *ApplicationDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		created, current, err := lockApplicant(ctx, tx, data.Username, data.Character)
+		if err != nil {
+			return err
+		}
+		if created != 0 {
+			return model.ErrCharacterNotFound
+		}
+		if current != nil {
+			holder, errClaim := activeClaim(ctx, tx, current.ID)
+			if errClaim != nil {
+				return errClaim
+			}
+			if holder != "" {
+				return model.ErrApplicationInReview
+			}
+		}
+
+		query := "UPDATE characters SET Age = ?, Gender = ?, Origin = ?, Skin = ? WHERE `Character` = ?"
+		if _, err = tx.ExecContext(ctx, query, data.Age, data.Gender, data.Origin, data.Skin, data.Character); err != nil {
+			return err
+		}
+
+		if current == nil {
+			return insertApplication(ctx, tx, application)
+		}
+
+		if _, err = tx.ExecContext(ctx, "UPDATE ucp_applications SET Version = ? WHERE ID = ?", application.Version, current.ID); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "DELETE FROM ucp_application_answers WHERE ApplicationID = ?", current.ID); err != nil {
+			return err
+		}
+		if err = insertAnswers(ctx, tx, current.ID, application.Answers); err != nil {
+			return err
+		}
+		return insertReviewEvent(ctx, tx, current, data.Username, ReviewEdit, "")
+	})
+}
+
+// WithdrawCharacter deletes a waiting or rejected character of username so the name
+// and the slot are free again. The applications stay for the review history.
+func (r *UserRepository) WithdrawCharacter(ctx context.Context, username, character string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		_, current, err := lockApplicant(ctx, tx, username, character)
+		if err != nil {
+			return err
+		}
+
+		if current != nil && current.Status == ApplicationPending {
+			query := "UPDATE ucp_applications SET Status = ?, ClaimedBy = '', ClaimExpiresAt = NULL WHERE ID = ?"
+			if _, err = tx.ExecContext(ctx, query, ApplicationWithdrawn, current.ID); err != nil {
+				return err
+			}
+			if err = insertReviewEvent(ctx, tx, current, username, ReviewWithdraw, ""); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM characters WHERE `Character` = ? AND Status = 0", character)
+		return err
+	})
+}
+
+// ResubmitCharacter puts a rejected character back in the waiting list with a new
+// application. The previous one must be older than cooldown.
+func (r *UserRepository) ResubmitCharacter(ctx context.Context, data *CharacterDB, application *ApplicationDB, cooldown time.Duration) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		created, current, err := lockApplicant(ctx, tx, data.Username, data.Character)
+		if err != nil {
+			return err
+		}
+		if created != CharacterRejected || current == nil || current.Status != ApplicationRejected {
+			return model.ErrCharacterNotFound
+		}
+
+		var waiting bool
+		query := "SELECT CreatedAt > DATE_SUB(NOW(), INTERVAL ? SECOND) FROM ucp_applications WHERE ID = ?"
+		if err = tx.GetContext(ctx, &waiting, query, int(cooldown.Seconds()), current.ID); err != nil {
+			return err
+		}
+		if waiting {
+			return model.ErrResubmitCooldown
+		}
+
+		query = "UPDATE characters SET Created = 0, Age = ?, Gender = ?, Origin = ?, Skin = ? WHERE `Character` = ?"
+		if _, err = tx.ExecContext(ctx, query, data.Age, data.Gender, data.Origin, data.Skin, data.Character); err != nil {
+			return err
+		}
+		return insertApplication(ctx, tx, application)
+	})
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+type AuditFilterDB struct {
+	Actor  string
+	Action string
+	Target string
+	From   string
+	To     string
+	Limit  int
+	Offset int
+}
+
+// insertAudit is called from inside the transaction that makes the change, so an
+// action is recorded only if it actually happened. A nil entry records nothing.
+func insertAudit(ctx context.Context, tx *sqlx.Tx, data *AuditDB) error {
+	if data == nil {
+		return nil
+	}
+
+	query := "INSERT INTO ucp_audit (Actor, Action, Target, Payload, IP) VALUES (?, ?, ?, ?, ?)"
+	result, err := tx.ExecContext(ctx, query, data.Actor, data.Action, data.Target, data.Payload, data.IP)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return errors.New("no rows affected, expected one")
+	}
+	return nil
+}
+
+func (r *UserRepository) CreateAudit(ctx context.Context, data *AuditDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		return insertAudit(ctx, tx, data)
+	})
+}
+
+func (r *UserRepository) FetchAudit(ctx context.Context, filter *AuditFilterDB) ([]AuditDB, int, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var where []string
+	var args []interface{}
+
+	if filter.Actor != "" {
+		where = append(where, "Actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		where = append(where, "Action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Target != "" {
+		where = append(where, "Target = ?")
+		args = append(args, filter.Target)
+	}
+	if filter.From != "" {
+		where = append(where, "CreatedAt >= ?")
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		where = append(where, "CreatedAt < DATE_ADD(?, INTERVAL 1 DAY)")
+		args = append(args, filter.To)
+	}
+
+	cond := ""
+	if len(where) > 0 {
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := r.DB.GetContext(ctx, &total, "SELECT COUNT(*) FROM ucp_audit"+cond, args...); err != nil {
+		return nil, 0, err
+	}
+
+	var entries []AuditDB
+	query := "SELECT ID, Actor, Action, Target, Payload, IP, CreatedAt FROM ucp_audit" + cond + " ORDER BY ID DESC LIMIT ? OFFSET ?"
+	if err := r.DB.SelectContext(ctx, &entries, query, append(args, filter.Limit, filter.Offset)...); err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"sarp_backend/model"
+)
+
+const (
+	CKPending  = "pending"
+	CKApproved = "approved"
+	CKDenied   = "denied"
+)
+
+// CharacterDead marks a character killed by an approved CK, DeleteExp purges it.
+const CharacterDead = -1
+
+const ckColumns = "ID, Requester, Staff, `Character`, Owner, Evidence, Description, Status, ReviewedBy, Reason, CreatedAt, ReviewedAt"
+
+// CreateCKRequest files a CK against an accepted character. Requests of players need a
+// CK voucher of the requester for each pending one, it is spent on approval. Staff
+// requests are free.
+func (r *UserRepository) CreateCKRequest(ctx context.Context, data *CKRequestDB) (int64, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var id int64
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		vouchers := 0
+		if !data.Staff {
+			if err := tx.GetContext(ctx, &vouchers, "SELECT VoucherCK FROM accounts WHERE Username = ? FOR UPDATE", data.Requester); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return model.ErrAccountNotFound
+				}
+				return err
+			}
+		}
+
+		var owner string
+		query := "SELECT Username FROM characters WHERE `Character` = ? AND Created = 1 FOR UPDATE"
+		if err := tx.GetContext(ctx, &owner, query, data.Character); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.ErrCharacterNotFound
+			}
+			return err
+		}
+
+		var pending int
+		query = "SELECT COUNT(*) FROM ucp_ck_requests WHERE `Character` = ? AND Status = ?"
+		if err := tx.GetContext(ctx, &pending, query, data.Character, CKPending); err != nil {
+			return err
+		}
+		if pending > 0 {
+			return model.ErrCKPending
+		}
+
+		if !data.Staff {
+			var requests int
+			query = "SELECT COUNT(*) FROM ucp_ck_requests WHERE Requester = ? AND Staff = 0 AND Status = ?"
+			if err := tx.GetContext(ctx, &requests, query, data.Requester, CKPending); err != nil {
+				return err
+			}
+			if requests >= vouchers {
+				return model.ErrNoCKVoucher
+			}
+		}
+
+		query = "INSERT INTO ucp_ck_requests (Requester, Staff, `Character`, Owner, Evidence, Description) VALUES (?, ?, ?, ?, ?, ?)"
+		result, err := tx.ExecContext(ctx, query, data.Requester, data.Staff, data.Character, owner, data.Evidence, data.Description)
+		if err != nil {
+			return err
+		}
+		id, err = result.LastInsertId()
+		return err
+	})
+
+	return id, err
+}
+
+func (r *UserRepository) FetchCKRequests(ctx context.Context, filter *CKRequestFilterDB) ([]CKRequestDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "SELECT " + ckColumns + " FROM ucp_ck_requests WHERE 1 = 1"
+	var args []interface{}
+	if filter.Requester != "" {
+		query += " AND Requester = ?"
+		args = append(args, filter.Requester)
+	}
+	if filter.Status != "" {
+		query += " AND Status = ?"
+		args = append(args, filter.Status)
+	}
+	query += " ORDER BY ID DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	var requests []CKRequestDB
+	if err := r.DB.SelectContext(ctx, &requests, query, args...); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func lockCKRequest(ctx context.Context, tx *sqlx.Tx, id int64) (*CKRequestDB, error) {
+	var data CKRequestDB
+	query := "SELECT " + ckColumns + " FROM ucp_ck_requests WHERE ID = ? FOR UPDATE"
+	if err := tx.GetContext(ctx, &data, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrCKNotFound
+		}
+		return nil, err
+	}
+	if data.Status != CKPending {
+		return nil, model.ErrCKNotFound
+	}
+	return &data, nil
+}
+
+// ApproveCKRequest marks the character dead, spends the voucher of a player request
+// and writes the logs_ck row in one transaction. It returns the approved request.
+func (r *UserRepository) ApproveCKRequest(ctx context.Context, id int64, admin string, audit *AuditDB) (*CKRequestDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var approved CKRequestDB
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		request, err := lockCKRequest(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		query := "UPDATE characters SET Created = ? WHERE `Character` = ? AND Username = ? AND Created = 1"
+		result, err := tx.ExecContext(ctx, query, CharacterDead, request.Character, request.Owner)
+		if err != nil {
+			return err
+		}
+		if rows, errRows := result.RowsAffected(); errRows != nil || rows == 0 {
+			return model.ErrCharacterNotFound
+		}
+
+		if !request.Staff {
+			query = "UPDATE accounts SET VoucherCK = VoucherCK - 1 WHERE Username = ? AND VoucherCK > 0"
+			if result, err = tx.ExecContext(ctx, query, request.Requester); err != nil {
+				return err
+			}
+			if rows, errRows := result.RowsAffected(); errRows != nil || rows == 0 {
+				return model.ErrNoCKVoucher
+			}
+		}
+
+		query = "INSERT INTO logs_ck (Admin, Player, Reason) VALUES (?, ?, ?)"
+		if _, err = tx.ExecContext(ctx, query, admin, request.Character, model.Truncate(request.Description, 128)); err != nil {
+			return err
+		}
+
+		query = "UPDATE ucp_ck_requests SET Status = ?, ReviewedBy = ?, ReviewedAt = NOW() WHERE ID = ?"
+		if _, err = tx.ExecContext(ctx, query, CKApproved, admin, id); err != nil {
+			return err
+		}
+		if err = insertAudit(ctx, tx, audit); err != nil {
+			return err
+		}
+
+		return tx.GetContext(ctx, &approved, "SELECT "+ckColumns+" FROM ucp_ck_requests WHERE ID = ?", id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &approved, nil
+}
+
+func (r *UserRepository) DenyCKRequest(ctx context.Context, id int64, admin, reason string, audit *AuditDB) (*CKRequestDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var denied CKRequestDB
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		if _, err := lockCKRequest(ctx, tx, id); err != nil {
+			return err
+		}
+
+		query := "UPDATE ucp_ck_requests SET Status = ?, ReviewedBy = ?, Reason = ?, ReviewedAt = NOW() WHERE ID = ?"
+		if _, err := tx.ExecContext(ctx, query, CKDenied, admin, model.Truncate(reason, 500), id); err != nil {
+			return err
+		}
+		if err := insertAudit(ctx, tx, audit); err != nil {
+			return err
+		}
+
+		return tx.GetContext(ctx, &denied, "SELECT "+ckColumns+" FROM ucp_ck_requests WHERE ID = ?", id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &denied, nil
+}
@@ -1,5 +1,10 @@
 package repository
 
+import (
+	"database/sql"
+	"time"
+)
+
 type UserDB struct {
 	Username      string `db:"Username"`
 	Email         string `db:"Email"`
@@ -14,6 +19,12 @@ type UserDB struct {
 	DonateExpired int    `db:"DonateExpired"`
 }
 
+type ProfileDB struct {
+	ForumName   string `db:"NumeForum"`
+	Avatar      string `db:"Avatar"`
+	Description string `db:"DescriereaMea"`
+}
+
 type CharacterDB struct {
 	Username     string `db:"Username"`
 	Character    string `db:"Character"`
@@ -66,3 +77,173 @@ type AjailDB struct {
 	Prisoned  int    `db:"Prisoned"`
 	JailTime  int    `db:"JailTime"`
 }
+
+type TwoFactorDB struct {
+	Username string `db:"Username"`
+	Secret   string `db:"Secret"`
+	Enabled  bool   `db:"Enabled"`
+	LastStep int64  `db:"LastStep"`
+}
+
+type SessionDB struct {
+	ID        string    `db:"ID"`
+	Username  string    `db:"Username"`
+	IP        string    `db:"IP"`
+	UserAgent string    `db:"UserAgent"`
+	CreatedAt time.Time `db:"CreatedAt"`
+	LastSeen  time.Time `db:"LastSeen"`
+}
+
+type LoginAttemptDB struct {
+	Scope       string       `db:"Scope"`
+	Subject     string       `db:"Subject"`
+	Failures    int          `db:"Failures"`
+	LockedUntil sql.NullTime `db:"LockedUntil"`
+}
+
+type TokenDB struct {
+	ID         int64        `db:"ID"`
+	Purpose    string       `db:"Purpose"`
+	TokenHash  string       `db:"TokenHash"`
+	Username   string       `db:"Username"`
+	Email      string       `db:"Email"`
+	ExpiresAt  time.Time    `db:"ExpiresAt"`
+	ConsumedAt sql.NullTime `db:"ConsumedAt"`
+	CreatedAt  time.Time    `db:"CreatedAt"`
+}
+
+type AuditDB struct {
+	ID        int64     `db:"ID"`
+	Actor     string    `db:"Actor"`
+	Action    string    `db:"Action"`
+	Target    string    `db:"Target"`
+	Payload   string    `db:"Payload"`
+	IP        string    `db:"IP"`
+	CreatedAt time.Time `db:"CreatedAt"`
+}
+
+// LogTableDB describes which columns of a game log table can be filtered.
+// NameColumns hold account or character names, SearchColumns are free text.
+type LogTableDB struct {
+	Name          string
+	TimeColumn    string
+	NameColumns   []string
+	SearchColumns []string
+}
+
+type LogsFilterDB struct {
+	Cursor    int64
+	Limit     int
+	From      string
+	To        string
+	Account   string
+	Character string
+	Search    string
+}
+
+type LogsPageDB struct {
+	Rows       []map[string]interface{}
+	NextCursor int64
+	Total      int
+	Estimated  bool
+}
+
+type EmailDB struct {
+	ID             int64        `db:"ID"`
+	IdempotencyKey string       `db:"IdempotencyKey"`
+	Recipient      string       `db:"Recipient"`
+	Subject        string       `db:"Subject"`
+	TextBody       string       `db:"TextBody"`
+	Body           string       `db:"Body"`
+	Status         string       `db:"Status"`
+	Attempts       int          `db:"Attempts"`
+	LastError      string       `db:"LastError"`
+	NextAttemptAt  time.Time    `db:"NextAttemptAt"`
+	SentAt         sql.NullTime `db:"SentAt"`
+	CreatedAt      time.Time    `db:"CreatedAt"`
+}
+
+type QuestionDB struct {
+	Version   int    `db:"Version"`
+	Position  int    `db:"Position"`
+	Text      string `db:"Text"`
+	MinLength int    `db:"MinLength"`
+	MaxLength int    `db:"MaxLength"`
+}
+
+// QuestionnaireDB is one version of the application questions, versions are never
+// edited so an application can always be shown with the questions it answered.
+type QuestionnaireDB struct {
+	Version   int       `db:"Version"`
+	CreatedBy string    `db:"CreatedBy"`
+	CreatedAt time.Time `db:"CreatedAt"`
+	Questions []QuestionDB
+}
+
+// ApplicationDB is a submitted character application, Answers are in question order.
+// A claim is held while ClaimExpiresAt is in the future.
+type ApplicationDB struct {
+	ID             int64        `db:"ID"`
+	Username       string       `db:"Username"`
+	Character      string       `db:"Character"`
+	Version        int          `db:"Version"`
+	Status         string       `db:"Status"`
+	ClaimedBy      string       `db:"ClaimedBy"`
+	ClaimExpiresAt sql.NullTime `db:"ClaimExpiresAt"`
+	ReviewedBy     string       `db:"ReviewedBy"`
+	Reason         string       `db:"Reason"`
+	ReviewedAt     sql.NullTime `db:"ReviewedAt"`
+	CreatedAt      time.Time    `db:"CreatedAt"`
+	Answers        []string
+}
+
+type ReviewEventDB struct {
+	ID            int64     `db:"ID"`
+	ApplicationID int64     `db:"ApplicationID"`
+	Character     string    `db:"Character"`
+	Reviewer      string    `db:"Reviewer"`
+	Action        string    `db:"Action"`
+	Note          string    `db:"Note"`
+	CreatedAt     time.Time `db:"CreatedAt"`
+}
+
+// NameChangeDB is a rename requested by the owner of an accepted character, Reason is
+// filled when staff deny it.
+type NameChangeDB struct {
+	ID         int64        `db:"ID"`
+	Username   string       `db:"Username"`
+	Character  string       `db:"Character"`
+	NewName    string       `db:"NewName"`
+	Status     string       `db:"Status"`
+	ReviewedBy string       `db:"ReviewedBy"`
+	Reason     string       `db:"Reason"`
+	CreatedAt  time.Time    `db:"CreatedAt"`
+	ReviewedAt sql.NullTime `db:"ReviewedAt"`
+}
+
+type NameChangeFilterDB struct {
+	Username string
+	Status   string
+	Limit    int
+}
+
+type CKRequestDB struct {
+	ID          int64        `db:"ID"`
+	Requester   string       `db:"Requester"`
+	Staff       bool         `db:"Staff"`
+	Character   string       `db:"Character"`
+	Owner       string       `db:"Owner"`
+	Evidence    string       `db:"Evidence"`
+	Description string       `db:"Description"`
+	Status      string       `db:"Status"`
+	ReviewedBy  string       `db:"ReviewedBy"`
+	Reason      string       `db:"Reason"`
+	CreatedAt   time.Time    `db:"CreatedAt"`
+	ReviewedAt  sql.NullTime `db:"ReviewedAt"`
+}
+
+type CKRequestFilterDB struct {
+	Requester string
+	Status    string
+	Limit     int
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type AccountRepository interface {
+	Create(ctx context.Context, data *UserDB) error
+	Activate(ctx context.Context, email string) error
+	CheckActivation(ctx context.Context, name string) (bool, error)
+	FetchPassword(ctx context.Context, name string) (string, error)
+	UpdatePassword(ctx context.Context, email, password string) error
+	UpdatePasswordByName(ctx context.Context, name, password string) error
+	UpdateEmail(ctx context.Context, name, email string) error
+	FetchProfile(ctx context.Context, name string) (*ProfileDB, error)
+	UpdateProfile(ctx context.Context, name string, data *ProfileDB) error
+	Fetch(ctx context.Context, name string, email string) (bool, error)
+	FetchMail(ctx context.Context, name string) (string, error)
+	FetchUsername(ctx context.Context, email string) (string, error)
+	FetchTesterLevel(ctx context.Context, name string) (int, error)
+	FetchAdminLevel(ctx context.Context, name string) (int, error)
+	UpdateStaffLevel(ctx context.Context, name string, admin, tester int, audit *AuditDB) error
+	ResetPassword(ctx context.Context, name, password string, audit *AuditDB) error
+	ChangePassword(ctx context.Context, name, password, keepSession string) error
+}
+
+type PreferenceRepository interface {
+	FetchLocale(ctx context.Context, name string) (string, error)
+	SaveLocale(ctx context.Context, name, locale string) error
+}
+
+type CharacterRepository interface {
+	CreateCharacter(ctx context.Context, data *CharacterDB, application *ApplicationDB) error
+	FetchWaitingCharacters(ctx context.Context) ([]CharacterDB, error)
+	FetchApplications(ctx context.Context, characters []string) ([]ApplicationDB, error)
+	AcceptCharacter(ctx context.Context, characterName, acceptedBy string, audit *AuditDB) (string, error)
+	DeclineCharacter(ctx context.Context, characterName, reviewer, reason string, audit *AuditDB) (string, error)
+	ClaimApplication(ctx context.Context, character, reviewer string, lease time.Duration) (*ApplicationDB, error)
+	ReleaseApplication(ctx context.Context, character, reviewer string) error
+	AddReviewNote(ctx context.Context, character, reviewer, note string) error
+	FetchReviewHistory(ctx context.Context, character string) ([]ApplicationDB, []ReviewEventDB, error)
+	FetchOpenApplication(ctx context.Context, username, character string) (*CharacterDB, *ApplicationDB, error)
+	UpdateApplication(ctx context.Context, data *CharacterDB, application *ApplicationDB) error
+	WithdrawCharacter(ctx context.Context, username, character string) error
+	ResubmitCharacter(ctx context.Context, data *CharacterDB, application *ApplicationDB, cooldown time.Duration) error
+	FetchCharacter(ctx context.Context, character string) (*CharacterDB, error)
+	DeleteExp(ctx context.Context) error
+}
+
+type QuestionnaireRepository interface {
+	FetchQuestionnaire(ctx context.Context, version int) (*QuestionnaireDB, error)
+	FetchLatestQuestionnaire(ctx context.Context) (*QuestionnaireDB, error)
+	CreateQuestionnaire(ctx context.Context, data *QuestionnaireDB, audit *AuditDB) (int, error)
+}
+
+type NameChangeRepository interface {
+	CreateNameChange(ctx context.Context, data *NameChangeDB) (int64, error)
+	FetchNameChanges(ctx context.Context, filter *NameChangeFilterDB) ([]NameChangeDB, error)
+	ApproveNameChange(ctx context.Context, id int64, admin string, audit *AuditDB) (*NameChangeDB, error)
+	DenyNameChange(ctx context.Context, id int64, admin, reason string, audit *AuditDB) (*NameChangeDB, error)
+}
+
+type CKRepository interface {
+	CreateCKRequest(ctx context.Context, data *CKRequestDB) (int64, error)
+	FetchCKRequests(ctx context.Context, filter *CKRequestFilterDB) ([]CKRequestDB, error)
+	ApproveCKRequest(ctx context.Context, id int64, admin string, audit *AuditDB) (*CKRequestDB, error)
+	DenyCKRequest(ctx context.Context, id int64, admin, reason string, audit *AuditDB) (*CKRequestDB, error)
+}
+
+type BanRepository interface {
+	CheckForBan(ctx context.Context, name string) (bool, error)
+	AddBan(ctx context.Context, data *BlacklistDB, audit *AuditDB) error
+	FetchBans(ctx context.Context) ([]BlacklistDB, error)
+	Unban(ctx context.Context, name string, audit *AuditDB) error
+	Ajail(ctx context.Context, data *AjailDB, audit *AuditDB) error
+}
+
+type LogRepository interface {
+	FetchLogs(ctx context.Context, table LogTableDB, filter *LogsFilterDB) (*LogsPageDB, error)
+	CreateAudit(ctx context.Context, data *AuditDB) error
+	FetchAudit(ctx context.Context, filter *AuditFilterDB) ([]AuditDB, int, error)
+}
+
+type StatsRepository interface {
+	FetchStats(ctx context.Context, name string) (*GetStatsDB, error)
+	FetchStaff(ctx context.Context) ([]GetStatsDB, error)
+	FetchServerStats(ctx context.Context) (*GetServerStatsDB, error)
+}
+
+type SessionRepository interface {
+	FetchSessionData(ctx context.Context, id string) ([]byte, error)
+	SaveSessionData(ctx context.Context, id string, data []byte, expiresAt int64) error
+	DeleteSessionData(ctx context.Context, id string) error
+	DeleteAllSessions(ctx context.Context) error
+	DeleteExpiredSessions(ctx context.Context, now int64) error
+	TouchSession(ctx context.Context, id, name, ip, userAgent string) error
+	FetchSessions(ctx context.Context, name string) ([]SessionDB, error)
+	DeleteSession(ctx context.Context, name, id string) error
+	DeleteOtherSessions(ctx context.Context, name, keepID string) error
+	DeleteUserSessions(ctx context.Context, name string, audit *AuditDB) error
+}
+
+type TwoFactorRepository interface {
+	FetchTwoFactor(ctx context.Context, name string) (*TwoFactorDB, error)
+	SaveTwoFactorSecret(ctx context.Context, name, secret string) error
+	EnableTwoFactor(ctx context.Context, name string, step int64, recoveryHashes []string) error
+	UseTwoFactorStep(ctx context.Context, name string, step int64) error
+	UseRecoveryCode(ctx context.Context, name, hash string) error
+	DisableTwoFactor(ctx context.Context, name string) error
+}
+
+type TokenRepository interface {
+	CreateToken(ctx context.Context, data *TokenDB) error
+	FetchToken(ctx context.Context, purpose, hash string) (*TokenDB, error)
+	ConsumeToken(ctx context.Context, id int64) error
+	CountTokensSince(ctx context.Context, purpose, email string, since time.Time) (int, error)
+	DeleteExpiredTokens(ctx context.Context) error
+}
+
+type LoginAttemptRepository interface {
+	FetchLoginAttempt(ctx context.Context, scope, subject string) (*LoginAttemptDB, error)
+	AddLoginFailure(ctx context.Context, scope, subject string, resetAfter time.Duration) (int, error)
+	LockLogin(ctx context.Context, scope, subject string, until time.Time) error
+	ClearLoginAttempts(ctx context.Context, scope, subject string, audit *AuditDB) error
+}
+
+type OutboxRepository interface {
+	EnqueueEmail(ctx context.Context, data *EmailDB) (bool, error)
+	ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]EmailDB, error)
+	MarkEmailSent(ctx context.Context, id int64) error
+	MarkEmailFailed(ctx context.Context, id int64, status, lastError string, next time.Time) error
+	FetchEmails(ctx context.Context, filter *EmailFilterDB) ([]EmailDB, int, error)
+	RetryEmail(ctx context.Context, id int64, audit *AuditDB) error
+}
+
+// Repository is everything the services need from the storage, it is implemented
+// by UserRepository on top of MySQL and by MemoryRepository for tests.
+type Repository interface {
+	AccountRepository
+	PreferenceRepository
+	CharacterRepository
+	QuestionnaireRepository
+	NameChangeRepository
+	CKRepository
+	BanRepository
+	LogRepository
+	StatsRepository
+	SessionRepository
+	TwoFactorRepository
+	TokenRepository
+	LoginAttemptRepository
+	OutboxRepository
+}
+
+var (
+	_ Repository = (*UserRepository)(nil)
+	_ Repository = (*MemoryRepository)(nil)
+)
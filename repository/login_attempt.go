@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+const (
+	AttemptScopeUser = "user"
+	AttemptScopeIP   = "ip"
+)
+
+func (r *UserRepository) FetchLoginAttempt(ctx context.Context, scope, subject string) (*LoginAttemptDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var data LoginAttemptDB
+	query := "SELECT Scope, Subject, Failures, LockedUntil FROM ucp_login_attempts WHERE Scope = ? AND Subject = ?"
+	if err := r.DB.GetContext(ctx, &data, query, scope, subject); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// AddLoginFailure increments the failure counter and returns the new value, failures
+// older than resetAfter are forgotten and the counter starts again from one.
+func (r *UserRepository) AddLoginFailure(ctx context.Context, scope, subject string, resetAfter time.Duration) (int, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var failures int
+
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		query := "INSERT INTO ucp_login_attempts (Scope, Subject, Failures, LastFailure) VALUES (?, ?, 1, NOW()) " +
+			"ON DUPLICATE KEY UPDATE Failures = IF(LastFailure < DATE_SUB(NOW(), INTERVAL ? SECOND), 1, Failures + 1), LastFailure = NOW()"
+		if _, err := tx.ExecContext(ctx, query, scope, subject, int(resetAfter.Seconds())); err != nil {
+			return err
+		}
+
+		return tx.GetContext(ctx, &failures, "SELECT Failures FROM ucp_login_attempts WHERE Scope = ? AND Subject = ?", scope, subject)
+	})
+
+	return failures, err
+}
+
+func (r *UserRepository) LockLogin(ctx context.Context, scope, subject string, until time.Time) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "UPDATE ucp_login_attempts SET LockedUntil = ? WHERE Scope = ? AND Subject = ?"
+	_, err := r.DB.ExecContext(ctx, query, until, scope, subject)
+	return err
+}
+
+func (r *UserRepository) ClearLoginAttempts(ctx context.Context, scope, subject string, audit *AuditDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM ucp_login_attempts WHERE Scope = ? AND Subject = ?", scope, subject); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, audit)
+	})
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// logsCountCap bounds the count query, past it the total is only an estimate.
+const logsCountCap = 10000
+
+func (r *UserRepository) FetchLogs(ctx context.Context, table LogTableDB, filter *LogsFilterDB) (*LogsPageDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var where []string
+	var args []interface{}
+
+	if filter.From != "" {
+		where = append(where, fmt.Sprintf("`%s` >= ?", table.TimeColumn))
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		where = append(where, fmt.Sprintf("`%s` < DATE_ADD(?, INTERVAL 1 DAY)", table.TimeColumn))
+		args = append(args, filter.To)
+	}
+	if filter.Character != "" {
+		var cond []string
+		for _, col := range table.NameColumns {
+			cond = append(cond, fmt.Sprintf("`%s` = ?", col))
+			args = append(args, filter.Character)
+		}
+		where = append(where, "("+strings.Join(cond, " OR ")+")")
+	}
+	if filter.Account != "" {
+		var cond []string
+		for _, col := range table.NameColumns {
+			cond = append(cond, fmt.Sprintf("`%s` IN (SELECT `Character` FROM characters WHERE Username = ?) OR `%s` = ?", col, col))
+			args = append(args, filter.Account, filter.Account)
+		}
+		where = append(where, "("+strings.Join(cond, " OR ")+")")
+	}
+	if filter.Search != "" && len(table.SearchColumns) > 0 {
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		var cond []string
+		for _, col := range table.SearchColumns {
+			cond = append(cond, fmt.Sprintf("`%s` LIKE ?", col))
+			args = append(args, pattern)
+		}
+		where = append(where, "("+strings.Join(cond, " OR ")+")")
+	}
+
+	cond := ""
+	if len(where) > 0 {
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (SELECT 1 FROM `%s`%s LIMIT %d) counted", table.Name, cond, logsCountCap+1)
+	if err := r.DB.GetContext(ctx, &total, countQuery, args...); err != nil {
+		return nil, err
+	}
+	page := &LogsPageDB{Total: total}
+	if total > logsCountCap {
+		page.Total = logsCountCap
+		page.Estimated = true
+	}
+
+	pageCond := cond
+	pageArgs := append([]interface{}{}, args...)
+	if filter.Cursor > 0 {
+		if pageCond == "" {
+			pageCond = " WHERE ID < ?"
+		} else {
+			pageCond += " AND ID < ?"
+		}
+		pageArgs = append(pageArgs, filter.Cursor)
+	}
+
+	// One extra row tells whether there is a next page.
+	q := fmt.Sprintf("SELECT * FROM `%s`%s ORDER BY ID DESC LIMIT ?", table.Name, pageCond)
+	rows, err := r.DB.QueryxContext(ctx, q, append(pageArgs, filter.Limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := make(map[string]interface{})
+		if err = rows.MapScan(row); err != nil {
+			return nil, err
+		}
+		for key, value := range row {
+			if b, ok := value.([]byte); ok {
+				row[key] = string(b)
+			}
+		}
+		if _, ok := row["IP"]; ok {
+			row["IP"] = "-"
+		}
+		page.Rows = append(page.Rows, row)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Rows) > filter.Limit {
+		page.Rows = page.Rows[:filter.Limit]
+		if id, ok := page.Rows[len(page.Rows)-1]["ID"].(int64); ok {
+			page.NextCursor = id
+		}
+	}
+
+	return page, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,2042 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sarp_backend/model"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryRepository keeps everything in maps guarded by a single mutex. It follows
+// the semantics of the MySQL queries (Activated = 2 for confirmed accounts,
+// Created 0 waiting / 1 accepted / -1 expired / -2 rejected, bans active until Expire,
+// case-insensitive names) so the handlers can be tested without a database.
+type MemoryRepository struct {
+	mu sync.Mutex
+
+	accounts      map[string]*memoryAccount
+	locales       map[string]string
+	characters    map[string]*memoryCharacter
+	bans          []*memoryBan
+	twoFactor     map[string]*TwoFactorDB
+	recoveryCodes map[string][]*memoryRecoveryCode
+	sessions      map[string]*memorySession
+	loginAttempts map[[2]string]*memoryLoginAttempt
+	tokens        []*TokenDB
+	emails        []*EmailDB
+	questions     []*QuestionnaireDB
+	applications  []*ApplicationDB
+	nameChanges   []*NameChangeDB
+	ckRequests    []*CKRequestDB
+	reviewEvents  []ReviewEventDB
+	audit         []AuditDB
+	logs          map[string][]map[string]interface{}
+
+	lastID int64
+}
+
+type memoryAccount struct {
+	UserDB
+	ProfileDB
+	Activated  int
+	Count      int
+	Accepted   int
+	AcceptedBy string
+	// Vouchers are bought in game, GrantVouchers stands in for the game server.
+	VoucherName int
+	VoucherCK   int
+}
+
+type memoryCharacter struct {
+	CharacterDB
+	ID         int64
+	Created    int
+	Status     int
+	Online     int
+	Prisoned   int
+	JailTime   int
+	AcceptedBy string
+}
+
+type memoryBan struct {
+	BlacklistDB
+	Perm      int
+	ExpiresAt time.Time
+}
+
+type memoryRecoveryCode struct {
+	Hash string
+	Used bool
+}
+
+type memorySession struct {
+	SessionDB
+	Data      []byte
+	ExpiresAt int64
+}
+
+type memoryLoginAttempt struct {
+	LoginAttemptDB
+	LastFailure time.Time
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		accounts:      make(map[string]*memoryAccount),
+		locales:       make(map[string]string),
+		characters:    make(map[string]*memoryCharacter),
+		twoFactor:     make(map[string]*TwoFactorDB),
+		recoveryCodes: make(map[string][]*memoryRecoveryCode),
+		sessions:      make(map[string]*memorySession),
+		loginAttempts: make(map[[2]string]*memoryLoginAttempt),
+		logs:          make(map[string][]map[string]interface{}),
+	}
+}
+
+// lock fails like a cancelled query would when the caller already gave up.
+func (m *MemoryRepository) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	return nil
+}
+
+func memoryKey(name string) string {
+	return strings.ToLower(name)
+}
+
+func (m *MemoryRepository) nextID() int64 {
+	m.lastID++
+	return m.lastID
+}
+
+func (m *MemoryRepository) insertAudit(data *AuditDB) {
+	if data == nil {
+		return
+	}
+
+	entry := *data
+	entry.ID = m.nextID()
+	entry.CreatedAt = time.Now()
+	m.audit = append(m.audit, entry)
+}
+
+func (m *MemoryRepository) accountByEmail(email string) *memoryAccount {
+	for _, a := range m.accounts {
+		if strings.EqualFold(a.Email, email) {
+			return a
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) Create(ctx context.Context, data *UserDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if m.accounts[memoryKey(data.Username)] != nil || m.accountByEmail(data.Email) != nil {
+		return model.ErrAccountExists
+	}
+
+	account := &memoryAccount{UserDB: *data, Accepted: 2}
+	account.IP = "n/a"
+	account.ProfileDB = ProfileDB{ForumName: data.Username, Avatar: "https://i.imgur.com/LXXYhKw.png", Description: "-"}
+	m.accounts[memoryKey(data.Username)] = account
+	return nil
+}
+
+func (m *MemoryRepository) Activate(ctx context.Context, email string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accountByEmail(email)
+	if account == nil || account.Activated != 0 {
+		return errors.New("no rows affected, expected one")
+	}
+
+	account.Activated = 2
+	return nil
+}
+
+func (m *MemoryRepository) CheckActivation(ctx context.Context, name string) (bool, error) {
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return false, sql.ErrNoRows
+	}
+	return account.Activated == 2, nil
+}
+
+func (m *MemoryRepository) FetchPassword(ctx context.Context, name string) (string, error) {
+	if err := m.lock(ctx); err != nil {
+		return "", err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return "", sql.ErrNoRows
+	}
+	return account.Password, nil
+}
+
+func (m *MemoryRepository) UpdatePassword(ctx context.Context, email, password string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accountByEmail(email)
+	if account == nil {
+		return errors.New("no rows affected, expected one")
+	}
+
+	account.Password = password
+	m.invalidateTokens(TokenPurposeReset, email)
+	return nil
+}
+
+func (m *MemoryRepository) UpdatePasswordByName(ctx context.Context, name, password string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return errors.New("no rows affected, expected one")
+	}
+
+	account.Password = password
+	return nil
+}
+
+func (m *MemoryRepository) UpdateEmail(ctx context.Context, name, email string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return model.ErrAccountNotFound
+	}
+
+	if other := m.accountByEmail(email); other != nil && other != account {
+		return model.ErrEmailTaken
+	}
+
+	m.invalidateTokens(TokenPurposeReset, account.Email)
+	account.Email = email
+	return nil
+}
+
+func (m *MemoryRepository) FetchProfile(ctx context.Context, name string) (*ProfileDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return nil, model.ErrAccountNotFound
+	}
+
+	profile := account.ProfileDB
+	return &profile, nil
+}
+
+func (m *MemoryRepository) UpdateProfile(ctx context.Context, name string, data *ProfileDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return model.ErrAccountNotFound
+	}
+
+	account.ProfileDB = *data
+	return nil
+}
+
+func (m *MemoryRepository) Fetch(ctx context.Context, name string, email string) (bool, error) {
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mu.Unlock()
+
+	return m.accounts[memoryKey(name)] != nil || m.accountByEmail(email) != nil, nil
+}
+
+func (m *MemoryRepository) FetchMail(ctx context.Context, name string) (string, error) {
+	if err := m.lock(ctx); err != nil {
+		return "", err
+	}
+	defer m.mu.Unlock()
+
+	if account := m.accounts[memoryKey(name)]; account != nil {
+		return account.Email, nil
+	}
+	return "", nil
+}
+
+func (m *MemoryRepository) FetchUsername(ctx context.Context, email string) (string, error) {
+	if err := m.lock(ctx); err != nil {
+		return "", err
+	}
+	defer m.mu.Unlock()
+
+	if account := m.accountByEmail(email); account != nil {
+		return account.Username, nil
+	}
+	return "", nil
+}
+
+func (m *MemoryRepository) FetchTesterLevel(ctx context.Context, name string) (int, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return 0, sql.ErrNoRows
+	}
+	return account.Tester, nil
+}
+
+func (m *MemoryRepository) FetchAdminLevel(ctx context.Context, name string) (int, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return 0, sql.ErrNoRows
+	}
+	return account.Admin, nil
+}
+
+func (m *MemoryRepository) UpdateStaffLevel(ctx context.Context, name string, admin, tester int, audit *AuditDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return model.ErrAccountNotFound
+	}
+
+	account.Admin = admin
+	account.Tester = tester
+	m.deleteSessions(name)
+	m.insertAudit(audit)
+	return nil
+}
+
+func (m *MemoryRepository) ResetPassword(ctx context.Context, name, password string, audit *AuditDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return model.ErrAccountNotFound
+	}
+
+	account.Password = password
+	m.invalidateTokens(TokenPurposeReset, account.Email)
+	m.deleteSessions(name)
+	m.insertAudit(audit)
+	return nil
+}
+
+func (m *MemoryRepository) ChangePassword(ctx context.Context, name, password, keepSession string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return model.ErrAccountNotFound
+	}
+
+	account.Password = password
+	m.invalidateTokens(TokenPurposeReset, account.Email)
+	for id, s := range m.sessions {
+		if strings.EqualFold(s.Username, name) && id != keepSession {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) FetchStats(ctx context.Context, name string) (*GetStatsDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(name)]
+	if account == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	ret := &GetStatsDB{
+		Username:   name,
+		Admin:      account.Admin,
+		Tester:     account.Tester,
+		DonateRank: account.DonateRank,
+	}
+	for _, c := range m.sortedCharacters() {
+		if strings.EqualFold(c.Username, name) && (c.Created >= 0 || c.Created == CharacterRejected) {
+			ret.CharactersData = append(ret.CharactersData, CharacterStatsDB{
+				Name:         c.Character,
+				Created:      c.Created,
+				Level:        c.Level,
+				PlayingHours: c.PlayingHours,
+			})
+		}
+	}
+	ret.Characters = characterSlots(ret.CharactersData)
+
+	return ret, nil
+}
+
+func (m *MemoryRepository) FetchStaff(ctx context.Context) ([]GetStatsDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	var ret []GetStatsDB
+	for _, a := range m.accounts {
+		if a.Admin > 0 || a.Tester > 0 {
+			ret = append(ret, GetStatsDB{Username: a.Username, Admin: a.Admin, Tester: a.Tester})
+		}
+	}
+
+	sortStaff(ret)
+
+	return ret, nil
+}
+
+func (m *MemoryRepository) FetchServerStats(ctx context.Context) (*GetServerStatsDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	var ret GetServerStatsDB
+	now := time.Now()
+
+	for _, c := range m.characters {
+		if c.Online == 1 {
+			ret.Online++
+		}
+		if c.Created > 0 {
+			ret.Characters++
+		}
+	}
+	for _, b := range m.bans {
+		if b.Perm == 1 || !b.ExpiresAt.Before(now) {
+			ret.Bans++
+		}
+	}
+	for _, a := range m.accounts {
+		if a.Admin > 0 || a.Tester > 0 {
+			ret.Staff++
+		}
+		if a.Activated == 2 {
+			ret.Accounts++
+		}
+	}
+
+	return &ret, nil
+}
+
+func (m *MemoryRepository) sortedCharacters() []*memoryCharacter {
+	list := make([]*memoryCharacter, 0, len(m.characters))
+	for _, c := range m.characters {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+func (m *MemoryRepository) FetchLocale(ctx context.Context, name string) (string, error) {
+	if err := m.lock(ctx); err != nil {
+		return "", err
+	}
+	defer m.mu.Unlock()
+
+	return m.locales[memoryKey(name)], nil
+}
+
+func (m *MemoryRepository) SaveLocale(ctx context.Context, name, locale string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.locales[memoryKey(name)] = locale
+	return nil
+}
+
+func (m *MemoryRepository) CreateCharacter(ctx context.Context, data *CharacterDB, application *ApplicationDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if m.characters[memoryKey(data.Character)] != nil {
+		return fmt.Errorf("%w: %s", model.ErrCharacterNameTaken, data.Character)
+	}
+
+	character := &memoryCharacter{CharacterDB: *data, ID: m.nextID(), AcceptedBy: "N/A"}
+	character.Level = 1
+	m.characters[memoryKey(data.Character)] = character
+
+	m.insertApplication(application)
+	return nil
+}
+
+func (m *MemoryRepository) insertApplication(application *ApplicationDB) {
+	if application == nil {
+		return
+	}
+
+	stored := *application
+	stored.ID = m.nextID()
+	stored.Status = ApplicationPending
+	stored.CreatedAt = time.Now()
+	stored.Answers = append([]string(nil), application.Answers...)
+	m.applications = append(m.applications, &stored)
+}
+
+func (m *MemoryRepository) FetchApplications(ctx context.Context, characters []string) ([]ApplicationDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	latest := make(map[string]*ApplicationDB)
+	for _, a := range m.applications {
+		latest[memoryKey(a.Character)] = a
+	}
+
+	var ret []ApplicationDB
+	for _, name := range characters {
+		if a := latest[memoryKey(name)]; a != nil {
+			found := *a
+			found.Answers = append([]string(nil), a.Answers...)
+			ret = append(ret, found)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret, nil
+}
+
+func (m *MemoryRepository) FetchQuestionnaire(ctx context.Context, version int) (*QuestionnaireDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	if version < 1 || version > len(m.questions) {
+		return nil, model.ErrNotFound
+	}
+	return copyQuestionnaire(m.questions[version-1]), nil
+}
+
+func (m *MemoryRepository) FetchLatestQuestionnaire(ctx context.Context) (*QuestionnaireDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	if len(m.questions) == 0 {
+		return &QuestionnaireDB{}, nil
+	}
+	return copyQuestionnaire(m.questions[len(m.questions)-1]), nil
+}
+
+func (m *MemoryRepository) CreateQuestionnaire(ctx context.Context, data *QuestionnaireDB, audit *AuditDB) (int, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mu.Unlock()
+
+	stored := copyQuestionnaire(data)
+	stored.Version = len(m.questions) + 1
+	stored.CreatedAt = time.Now()
+	for i := range stored.Questions {
+		stored.Questions[i].Version = stored.Version
+		stored.Questions[i].Position = i + 1
+	}
+	m.questions = append(m.questions, stored)
+	m.insertAudit(audit)
+	return stored.Version, nil
+}
+
+func copyQuestionnaire(q *QuestionnaireDB) *QuestionnaireDB {
+	ret := *q
+	ret.Questions = append([]QuestionDB(nil), q.Questions...)
+	return &ret
+}
+
+func (m *MemoryRepository) FetchWaitingCharacters(ctx context.Context) ([]CharacterDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	var ret []CharacterDB
+	for _, c := range m.sortedCharacters() {
+		if c.Created == 0 {
+			ret = append(ret, CharacterDB{
+				Username:  c.Username,
+				Character: c.Character,
+				Age:       c.Age,
+				Gender:    c.Gender,
+				Origin:    c.Origin,
+			})
+		}
+	}
+	return ret, nil
+}
+
+func (m *MemoryRepository) AcceptCharacter(ctx context.Context, characterName, acceptedBy string, audit *AuditDB) (string, error) {
+	if err := m.lock(ctx); err != nil {
+		return "", err
+	}
+	defer m.mu.Unlock()
+
+	character := m.characters[memoryKey(characterName)]
+	if character == nil || character.Created != 0 {
+		return "", model.ErrCharacterNotFound
+	}
+	account := m.accounts[memoryKey(character.Username)]
+	if account == nil {
+		return "", model.ErrAccountNotFound
+	}
+	if err := m.reviewApplication(characterName, acceptedBy, ApplicationAccepted, ""); err != nil {
+		return "", err
+	}
+
+	character.Created = 1
+	character.Status = 1
+	account.Count++
+	account.AcceptedBy = acceptedBy
+	account.Accepted = 2
+	m.insertAudit(audit)
+	return character.Username, nil
+}
+
+func (m *MemoryRepository) DeclineCharacter(ctx context.Context, characterName, reviewer, reason string, audit *AuditDB) (string, error) {
+	if err := m.lock(ctx); err != nil {
+		return "", err
+	}
+	defer m.mu.Unlock()
+
+	character := m.characters[memoryKey(characterName)]
+	if character == nil || character.Status != 0 || character.Created != 0 {
+		return "", model.ErrCharacterNotFound
+	}
+	if err := m.reviewApplication(characterName, reviewer, ApplicationRejected, reason); err != nil {
+		return "", err
+	}
+
+	character.Created = CharacterRejected
+	m.insertAudit(audit)
+	return character.Username, nil
+}
+
+// latestApplication returns the stored application of character so callers can change
+// it in place, nil when it never applied.
+func (m *MemoryRepository) latestApplication(character string) *ApplicationDB {
+	for i := len(m.applications) - 1; i >= 0; i-- {
+		if memoryKey(m.applications[i].Character) == memoryKey(character) {
+			return m.applications[i]
+		}
+	}
+	return nil
+}
+
+// claimHolder mirrors activeClaim, the claim lapses once ClaimExpiresAt passed.
+func claimHolder(application *ApplicationDB) string {
+	if application.ClaimExpiresAt.Valid && application.ClaimExpiresAt.Time.After(time.Now()) {
+		return application.ClaimedBy
+	}
+	return ""
+}
+
+func (m *MemoryRepository) insertReviewEvent(application *ApplicationDB, reviewer, action, note string) {
+	m.reviewEvents = append(m.reviewEvents, ReviewEventDB{
+		ID:            m.nextID(),
+		ApplicationID: application.ID,
+		Character:     application.Character,
+		Reviewer:      reviewer,
+		Action:        action,
+		Note:          model.Truncate(note, 1000),
+		CreatedAt:     time.Now(),
+	})
+}
+
+func (m *MemoryRepository) reviewApplication(character, reviewer, status, reason string) error {
+	application := m.latestApplication(character)
+	if application == nil {
+		return nil
+	}
+	if application.Status != ApplicationPending {
+		return model.ErrCharacterNotFound
+	}
+	if holder := claimHolder(application); holder != "" && holder != reviewer {
+		return model.ErrApplicationClaimed
+	}
+
+	application.Status = status
+	application.ReviewedBy = reviewer
+	application.Reason = model.Truncate(reason, 500)
+	application.ReviewedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	application.ClaimedBy = ""
+	application.ClaimExpiresAt = sql.NullTime{}
+
+	action := ReviewAccept
+	if status == ApplicationRejected {
+		action = ReviewReject
+	}
+	m.insertReviewEvent(application, reviewer, action, reason)
+	return nil
+}
+
+func (m *MemoryRepository) ClaimApplication(ctx context.Context, character, reviewer string, lease time.Duration) (*ApplicationDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	application := m.latestApplication(character)
+	if application == nil || application.Status != ApplicationPending {
+		return nil, model.ErrCharacterNotFound
+	}
+	if holder := claimHolder(application); holder != "" && holder != reviewer {
+		return nil, model.ErrApplicationClaimed
+	}
+
+	application.ClaimedBy = reviewer
+	application.ClaimExpiresAt = sql.NullTime{Time: time.Now().Add(lease), Valid: true}
+	m.insertReviewEvent(application, reviewer, ReviewClaim, "")
+
+	claimed := *application
+	claimed.Answers = append([]string(nil), application.Answers...)
+	return &claimed, nil
+}
+
+func (m *MemoryRepository) ReleaseApplication(ctx context.Context, character, reviewer string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	application := m.latestApplication(character)
+	if application == nil {
+		return model.ErrCharacterNotFound
+	}
+	if application.Status != ApplicationPending || claimHolder(application) != reviewer {
+		return model.ErrApplicationNotClaimed
+	}
+
+	application.ClaimedBy = ""
+	application.ClaimExpiresAt = sql.NullTime{}
+	m.insertReviewEvent(application, reviewer, ReviewRelease, "")
+	return nil
+}
+
+func (m *MemoryRepository) AddReviewNote(ctx context.Context, character, reviewer, note string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	application := m.latestApplication(character)
+	if application == nil {
+		return model.ErrCharacterNotFound
+	}
+	m.insertReviewEvent(application, reviewer, ReviewNote, note)
+	return nil
+}
+
+func (m *MemoryRepository) FetchReviewHistory(ctx context.Context, character string) ([]ApplicationDB, []ReviewEventDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, nil, err
+	}
+	defer m.mu.Unlock()
+
+	var applications []ApplicationDB
+	for _, a := range m.applications {
+		if memoryKey(a.Character) == memoryKey(character) {
+			found := *a
+			found.Answers = append([]string(nil), a.Answers...)
+			applications = append(applications, found)
+		}
+	}
+
+	var events []ReviewEventDB
+	for _, e := range m.reviewEvents {
+		if memoryKey(e.Character) == memoryKey(character) {
+			events = append(events, e)
+		}
+	}
+	return applications, events, nil
+}
+
+// applicant mirrors lockApplicant, it returns the stored character so callers can
+// change it in place.
+func (m *MemoryRepository) applicant(username, character string) (*memoryCharacter, *ApplicationDB, error) {
+	c := m.characters[memoryKey(character)]
+	if c == nil || !strings.EqualFold(c.Username, username) || c.Status != 0 || (c.Created != 0 && c.Created != CharacterRejected) {
+		return nil, nil, model.ErrCharacterNotFound
+	}
+	return c, m.latestApplication(character), nil
+}
+
+func (m *MemoryRepository) FetchOpenApplication(ctx context.Context, username, character string) (*CharacterDB, *ApplicationDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, nil, err
+	}
+	defer m.mu.Unlock()
+
+	c, application, err := m.applicant(username, character)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := CharacterDB{Username: c.Username, Character: c.Character, Age: c.Age, Gender: c.Gender, Origin: c.Origin}
+	if application == nil {
+		return &data, nil, nil
+	}
+	found := *application
+	found.Answers = append([]string(nil), application.Answers...)
+	return &data, &found, nil
+}
+
+func (m *MemoryRepository) UpdateApplication(ctx context.Context, data *CharacterDB, application *ApplicationDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	c, current, err := m.applicant(data.Username, data.Character)
+	if err != nil {
+		return err
+	}
+	if c.Created != 0 {
+		return model.ErrCharacterNotFound
+	}
+	if current != nil && claimHolder(current) != "" {
+		return model.ErrApplicationInReview
+	}
+
+	c.Age, c.Gender, c.Origin, c.Skin = data.Age, data.Gender, data.Origin, data.Skin
+	if current == nil {
+		m.insertApplication(application)
+		return nil
+	}
+
+	current.Version = application.Version
+	current.Answers = append([]string(nil), application.Answers...)
+	m.insertReviewEvent(current, data.Username, ReviewEdit, "")
+	return nil
+}
+
+func (m *MemoryRepository) WithdrawCharacter(ctx context.Context, username, character string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	_, current, err := m.applicant(username, character)
+	if err != nil {
+		return err
+	}
+
+	if current != nil && current.Status == ApplicationPending {
+		current.Status = ApplicationWithdrawn
+		current.ClaimedBy = ""
+		current.ClaimExpiresAt = sql.NullTime{}
+		m.insertReviewEvent(current, username, ReviewWithdraw, "")
+	}
+
+	delete(m.characters, memoryKey(character))
+	return nil
+}
+
+func (m *MemoryRepository) ResubmitCharacter(ctx context.Context, data *CharacterDB, application *ApplicationDB, cooldown time.Duration) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	c, current, err := m.applicant(data.Username, data.Character)
+	if err != nil {
+		return err
+	}
+	if c.Created != CharacterRejected || current == nil || current.Status != ApplicationRejected {
+		return model.ErrCharacterNotFound
+	}
+	if current.CreatedAt.After(time.Now().Add(-cooldown)) {
+		return model.ErrResubmitCooldown
+	}
+
+	c.Created = 0
+	c.Age, c.Gender, c.Origin, c.Skin = data.Age, data.Gender, data.Origin, data.Skin
+	m.insertApplication(application)
+	return nil
+}
+
+func (m *MemoryRepository) CreateNameChange(ctx context.Context, data *NameChangeDB) (int64, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(data.Username)]
+	if account == nil {
+		return 0, model.ErrAccountNotFound
+	}
+
+	c := m.characters[memoryKey(data.Character)]
+	if c == nil || !strings.EqualFold(c.Username, data.Username) || c.Created != 1 {
+		return 0, model.ErrCharacterNotFound
+	}
+
+	requests := 0
+	for _, p := range m.nameChanges {
+		if p.Status != NameChangePending {
+			continue
+		}
+		switch {
+		case strings.EqualFold(p.Character, data.Character):
+			return 0, model.ErrNameChangePending
+		case strings.EqualFold(p.NewName, data.NewName):
+			return 0, model.ErrCharacterNameTaken
+		case strings.EqualFold(p.Username, data.Username):
+			requests++
+		}
+	}
+	if requests >= account.VoucherName {
+		return 0, model.ErrNoNameVoucher
+	}
+	if m.characters[memoryKey(data.NewName)] != nil {
+		return 0, model.ErrCharacterNameTaken
+	}
+
+	stored := &NameChangeDB{
+		ID:        m.nextID(),
+		Username:  data.Username,
+		Character: data.Character,
+		NewName:   data.NewName,
+		Status:    NameChangePending,
+		CreatedAt: time.Now(),
+	}
+	m.nameChanges = append(m.nameChanges, stored)
+	return stored.ID, nil
+}
+
+func (m *MemoryRepository) FetchNameChanges(ctx context.Context, filter *NameChangeFilterDB) ([]NameChangeDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	var ret []NameChangeDB
+	for i := len(m.nameChanges) - 1; i >= 0 && len(ret) < filter.Limit; i-- {
+		p := m.nameChanges[i]
+		if (filter.Username == "" || strings.EqualFold(p.Username, filter.Username)) && (filter.Status == "" || p.Status == filter.Status) {
+			ret = append(ret, *p)
+		}
+	}
+	return ret, nil
+}
+
+func (m *MemoryRepository) pendingNameChange(id int64) (*NameChangeDB, error) {
+	for _, p := range m.nameChanges {
+		if p.ID == id && p.Status == NameChangePending {
+			return p, nil
+		}
+	}
+	return nil, model.ErrNameChangeNotFound
+}
+
+func (m *MemoryRepository) ApproveNameChange(ctx context.Context, id int64, admin string, audit *AuditDB) (*NameChangeDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	request, err := m.pendingNameChange(id)
+	if err != nil {
+		return nil, err
+	}
+	if m.characters[memoryKey(request.NewName)] != nil {
+		return nil, model.ErrCharacterNameTaken
+	}
+
+	c := m.characters[memoryKey(request.Character)]
+	if c == nil || !strings.EqualFold(c.Username, request.Username) || c.Created != 1 {
+		return nil, model.ErrCharacterNotFound
+	}
+	account := m.accounts[memoryKey(request.Username)]
+	if account == nil || account.VoucherName < 1 {
+		return nil, model.ErrNoNameVoucher
+	}
+
+	delete(m.characters, memoryKey(request.Character))
+	c.Character = request.NewName
+	m.characters[memoryKey(request.NewName)] = c
+	account.VoucherName--
+	m.insertLog("namechanges", map[string]interface{}{"OldName": request.Character, "NewName": request.NewName, "Admin": admin})
+
+	request.Status = NameChangeApproved
+	request.ReviewedBy = admin
+	request.ReviewedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	m.insertAudit(audit)
+
+	approved := *request
+	return &approved, nil
+}
+
+func (m *MemoryRepository) DenyNameChange(ctx context.Context, id int64, admin, reason string, audit *AuditDB) (*NameChangeDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	request, err := m.pendingNameChange(id)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Status = NameChangeDenied
+	request.ReviewedBy = admin
+	request.Reason = model.Truncate(reason, 500)
+	request.ReviewedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	m.insertAudit(audit)
+
+	denied := *request
+	return &denied, nil
+}
+
+func (m *MemoryRepository) CreateCKRequest(ctx context.Context, data *CKRequestDB) (int64, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mu.Unlock()
+
+	vouchers := 0
+	if !data.Staff {
+		account := m.accounts[memoryKey(data.Requester)]
+		if account == nil {
+			return 0, model.ErrAccountNotFound
+		}
+		vouchers = account.VoucherCK
+	}
+
+	c := m.characters[memoryKey(data.Character)]
+	if c == nil || c.Created != 1 {
+		return 0, model.ErrCharacterNotFound
+	}
+
+	requests := 0
+	for _, p := range m.ckRequests {
+		if p.Status != CKPending {
+			continue
+		}
+		if strings.EqualFold(p.Character, data.Character) {
+			return 0, model.ErrCKPending
+		}
+		if !p.Staff && strings.EqualFold(p.Requester, data.Requester) {
+			requests++
+		}
+	}
+	if !data.Staff && requests >= vouchers {
+		return 0, model.ErrNoCKVoucher
+	}
+
+	stored := &CKRequestDB{
+		ID:          m.nextID(),
+		Requester:   data.Requester,
+		Staff:       data.Staff,
+		Character:   c.Character,
+		Owner:       c.Username,
+		Evidence:    data.Evidence,
+		Description: data.Description,
+		Status:      CKPending,
+		CreatedAt:   time.Now(),
+	}
+	m.ckRequests = append(m.ckRequests, stored)
+	return stored.ID, nil
+}
+
+func (m *MemoryRepository) FetchCKRequests(ctx context.Context, filter *CKRequestFilterDB) ([]CKRequestDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	var ret []CKRequestDB
+	for i := len(m.ckRequests) - 1; i >= 0 && len(ret) < filter.Limit; i-- {
+		p := m.ckRequests[i]
+		if (filter.Requester == "" || strings.EqualFold(p.Requester, filter.Requester)) && (filter.Status == "" || p.Status == filter.Status) {
+			ret = append(ret, *p)
+		}
+	}
+	return ret, nil
+}
+
+func (m *MemoryRepository) pendingCKRequest(id int64) (*CKRequestDB, error) {
+	for _, p := range m.ckRequests {
+		if p.ID == id && p.Status == CKPending {
+			return p, nil
+		}
+	}
+	return nil, model.ErrCKNotFound
+}
+
+func (m *MemoryRepository) ApproveCKRequest(ctx context.Context, id int64, admin string, audit *AuditDB) (*CKRequestDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	request, err := m.pendingCKRequest(id)
+	if err != nil {
+		return nil, err
+	}
+
+	c := m.characters[memoryKey(request.Character)]
+	if c == nil || !strings.EqualFold(c.Username, request.Owner) || c.Created != 1 {
+		return nil, model.ErrCharacterNotFound
+	}
+	var account *memoryAccount
+	if !request.Staff {
+		account = m.accounts[memoryKey(request.Requester)]
+		if account == nil || account.VoucherCK < 1 {
+			return nil, model.ErrNoCKVoucher
+		}
+	}
+
+	c.Created = CharacterDead
+	if account != nil {
+		account.VoucherCK--
+	}
+	m.insertLog("logs_ck", map[string]interface{}{"Admin": admin, "Player": request.Character, "Reason": model.Truncate(request.Description, 128)})
+
+	request.Status = CKApproved
+	request.ReviewedBy = admin
+	request.ReviewedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	m.insertAudit(audit)
+
+	approved := *request
+	return &approved, nil
+}
+
+func (m *MemoryRepository) DenyCKRequest(ctx context.Context, id int64, admin, reason string, audit *AuditDB) (*CKRequestDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	request, err := m.pendingCKRequest(id)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Status = CKDenied
+	request.ReviewedBy = admin
+	request.Reason = model.Truncate(reason, 500)
+	request.ReviewedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	m.insertAudit(audit)
+
+	denied := *request
+	return &denied, nil
+}
+
+func (m *MemoryRepository) FetchCharacter(ctx context.Context, name string) (*CharacterDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	character := m.characters[memoryKey(name)]
+	if character == nil {
+		return nil, model.ErrCharacterNotFound
+	}
+	return &CharacterDB{Username: character.Username, Character: character.Character}, nil
+}
+
+func (m *MemoryRepository) Ajail(ctx context.Context, data *AjailDB, audit *AuditDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	character := m.characters[memoryKey(data.Character)]
+	if character == nil {
+		return model.ErrCharacterNotFound
+	}
+
+	character.JailTime = data.JailTime
+	character.Prisoned = data.Prisoned
+	m.insertAudit(audit)
+	return nil
+}
+
+func (m *MemoryRepository) DeleteExp(ctx context.Context) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	limit := time.Now().Add(-rejectedRetention)
+	for k, c := range m.characters {
+		if c.Created == CharacterDead {
+			delete(m.characters, k)
+			continue
+		}
+		if c.Created == CharacterRejected {
+			if a := m.latestApplication(c.Character); a == nil || (a.ReviewedAt.Valid && a.ReviewedAt.Time.Before(limit)) {
+				delete(m.characters, k)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) CheckForBan(ctx context.Context, name string) (bool, error) {
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, b := range m.bans {
+		if strings.EqualFold(b.Username, name) && !b.ExpiresAt.Before(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryRepository) AddBan(ctx context.Context, data *BlacklistDB, audit *AuditDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	account := m.accounts[memoryKey(data.Username)]
+	if account == nil {
+		return model.ErrAccountNotFound
+	}
+	data.IP = account.IP
+	if data.IP == "" {
+		return errors.New("can't get IP")
+	}
+
+	ban := &memoryBan{BlacklistDB: *data, ExpiresAt: time.Now().AddDate(0, 0, int(data.Expire))}
+	m.bans = append(m.bans, ban)
+	m.deleteSessions(data.Username)
+	m.insertAudit(audit)
+	return nil
+}
+
+// FetchBans reports Expire as the number of days left, rounded up.
+func (m *MemoryRepository) FetchBans(ctx context.Context) ([]BlacklistDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	var ret []BlacklistDB
+	now := time.Now()
+	for _, b := range m.bans {
+		if b.ExpiresAt.Before(now) {
+			continue
+		}
+
+		ban := BlacklistDB{
+			Username: b.Username,
+			BannedBy: b.BannedBy,
+			Reason:   b.Reason,
+			Expire:   uint((b.ExpiresAt.Sub(now) + 24*time.Hour - 1) / (24 * time.Hour)),
+		}
+		for _, c := range m.sortedCharacters() {
+			if strings.EqualFold(c.Username, b.Username) && c.Status == 1 {
+				ban.Characters = append(ban.Characters, CharacterDB{Character: c.Character})
+			}
+		}
+		ret = append(ret, ban)
+	}
+	return ret, nil
+}
+
+func (m *MemoryRepository) Unban(ctx context.Context, name string, audit *AuditDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	expired := time.Now().Add(-time.Second)
+	var rows int
+	for _, b := range m.bans {
+		if strings.EqualFold(b.Username, name) {
+			b.ExpiresAt = expired
+			rows++
+		}
+	}
+	if rows == 0 {
+		return model.ErrNotBanned
+	}
+
+	m.insertAudit(audit)
+	return nil
+}
+
+// GrantVouchers adds name change and CK vouchers to the account, the game server sells
+// them in production.
+func (m *MemoryRepository) GrantVouchers(name string, nameChanges, ck int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if account := m.accounts[memoryKey(name)]; account != nil {
+		account.VoucherName += nameChanges
+		account.VoucherCK += ck
+	}
+}
+
+// InsertLog adds a row to a game log table, the game server writes these in production.
+// ID is assigned and Date defaults to now like the table definition does.
+func (m *MemoryRepository) InsertLog(table string, row map[string]interface{}) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.insertLog(table, row)
+}
+
+func (m *MemoryRepository) insertLog(table string, row map[string]interface{}) int64 {
+	stored := make(map[string]interface{}, len(row)+2)
+	for k, v := range row {
+		stored[k] = v
+	}
+	stored["ID"] = m.nextID()
+	if _, ok := stored["Date"]; !ok {
+		stored["Date"] = time.Now()
+	}
+	if _, ok := stored["IP"]; !ok {
+		stored["IP"] = ""
+	}
+
+	m.logs[table] = append(m.logs[table], stored)
+	return stored["ID"].(int64)
+}
+
+func (m *MemoryRepository) FetchLogs(ctx context.Context, table LogTableDB, filter *LogsFilterDB) (*LogsPageDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	var from, to time.Time
+	var err error
+	if filter.From != "" {
+		if from, err = time.ParseInLocation("2006-01-02", filter.From, time.Local); err != nil {
+			return nil, err
+		}
+	}
+	if filter.To != "" {
+		if to, err = time.ParseInLocation("2006-01-02", filter.To, time.Local); err != nil {
+			return nil, err
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+
+	var owned map[string]bool
+	if filter.Account != "" {
+		owned = make(map[string]bool)
+		for _, c := range m.characters {
+			if strings.EqualFold(c.Username, filter.Account) {
+				owned[memoryKey(c.Character)] = true
+			}
+		}
+	}
+
+	match := func(row map[string]interface{}) bool {
+		if t, ok := row[table.TimeColumn].(time.Time); ok {
+			if !from.IsZero() && t.Before(from) {
+				return false
+			}
+			if !to.IsZero() && !t.Before(to) {
+				return false
+			}
+		}
+		if filter.Character != "" && !anyColumn(row, table.NameColumns, func(v string) bool {
+			return strings.EqualFold(v, filter.Character)
+		}) {
+			return false
+		}
+		if filter.Account != "" && !anyColumn(row, table.NameColumns, func(v string) bool {
+			return owned[memoryKey(v)] || strings.EqualFold(v, filter.Account)
+		}) {
+			return false
+		}
+		if filter.Search != "" && len(table.SearchColumns) > 0 && !anyColumn(row, table.SearchColumns, func(v string) bool {
+			return strings.Contains(strings.ToLower(v), strings.ToLower(filter.Search))
+		}) {
+			return false
+		}
+		return true
+	}
+
+	rows := m.logs[table.Name]
+	page := &LogsPageDB{}
+	for i := len(rows) - 1; i >= 0; i-- {
+		row := rows[i]
+		if !match(row) {
+			continue
+		}
+
+		page.Total++
+		if filter.Cursor > 0 && row["ID"].(int64) >= filter.Cursor {
+			continue
+		}
+		if len(page.Rows) <= filter.Limit {
+			copied := make(map[string]interface{}, len(row))
+			for k, v := range row {
+				copied[k] = v
+			}
+			copied["IP"] = "-"
+			page.Rows = append(page.Rows, copied)
+		}
+	}
+
+	if page.Total > logsCountCap {
+		page.Total = logsCountCap
+		page.Estimated = true
+	}
+	if len(page.Rows) > filter.Limit {
+		page.Rows = page.Rows[:filter.Limit]
+		page.NextCursor = page.Rows[len(page.Rows)-1]["ID"].(int64)
+	}
+
+	return page, nil
+}
+
+func anyColumn(row map[string]interface{}, columns []string, fn func(string) bool) bool {
+	for _, col := range columns {
+		if v, ok := row[col]; ok && fn(fmt.Sprint(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryRepository) CreateAudit(ctx context.Context, data *AuditDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.insertAudit(data)
+	return nil
+}
+
+func (m *MemoryRepository) FetchAudit(ctx context.Context, filter *AuditFilterDB) ([]AuditDB, int, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, 0, err
+	}
+	defer m.mu.Unlock()
+
+	var from, to time.Time
+	var err error
+	if filter.From != "" {
+		if from, err = time.ParseInLocation("2006-01-02", filter.From, time.Local); err != nil {
+			return nil, 0, err
+		}
+	}
+	if filter.To != "" {
+		if to, err = time.ParseInLocation("2006-01-02", filter.To, time.Local); err != nil {
+			return nil, 0, err
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+
+	var matched []AuditDB
+	for i := len(m.audit) - 1; i >= 0; i-- {
+		e := m.audit[i]
+		if filter.Actor != "" && !strings.EqualFold(e.Actor, filter.Actor) {
+			continue
+		}
+		if filter.Action != "" && !strings.EqualFold(e.Action, filter.Action) {
+			continue
+		}
+		if filter.Target != "" && !strings.EqualFold(e.Target, filter.Target) {
+			continue
+		}
+		if !from.IsZero() && e.CreatedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !e.CreatedAt.Before(to) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+
+	total := len(matched)
+	if filter.Offset >= total {
+		return nil, total, nil
+	}
+	matched = matched[filter.Offset:]
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+
+	return matched, total, nil
+}
+
+func (m *MemoryRepository) FetchSessionData(ctx context.Context, id string) ([]byte, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	s := m.sessions[id]
+	if s == nil || (s.ExpiresAt != 0 && s.ExpiresAt <= time.Now().Unix()) {
+		return nil, nil
+	}
+	return append([]byte(nil), s.Data...), nil
+}
+
+func (m *MemoryRepository) SaveSessionData(ctx context.Context, id string, data []byte, expiresAt int64) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	s := m.sessions[id]
+	if s == nil {
+		now := time.Now()
+		s = &memorySession{SessionDB: SessionDB{ID: id, CreatedAt: now, LastSeen: now}}
+		m.sessions[id] = s
+	}
+	s.Data = append([]byte(nil), data...)
+	s.ExpiresAt = expiresAt
+	return nil
+}
+
+func (m *MemoryRepository) DeleteSessionData(ctx context.Context, id string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *MemoryRepository) DeleteAllSessions(ctx context.Context) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.sessions = make(map[string]*memorySession)
+	return nil
+}
+
+func (m *MemoryRepository) DeleteExpiredSessions(ctx context.Context, now int64) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	for id, s := range m.sessions {
+		if s.ExpiresAt != 0 && s.ExpiresAt <= now {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) TouchSession(ctx context.Context, id, name, ip, userAgent string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	s := m.sessions[id]
+	if s == nil {
+		return nil
+	}
+	if s.Username == name && !s.LastSeen.Before(time.Now().Add(-time.Minute)) {
+		return nil
+	}
+
+	s.Username = name
+	s.IP = ip
+	s.UserAgent = userAgent
+	s.LastSeen = time.Now()
+	return nil
+}
+
+func (m *MemoryRepository) FetchSessions(ctx context.Context, name string) ([]SessionDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	var ret []SessionDB
+	now := time.Now().Unix()
+	for _, s := range m.sessions {
+		if strings.EqualFold(s.Username, name) && (s.ExpiresAt == 0 || s.ExpiresAt > now) {
+			ret = append(ret, s.SessionDB)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].LastSeen.After(ret[j].LastSeen) })
+	return ret, nil
+}
+
+func (m *MemoryRepository) DeleteSession(ctx context.Context, name, id string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	s := m.sessions[id]
+	if s == nil || !strings.EqualFold(s.Username, name) {
+		return errors.New("no rows affected, expected one")
+	}
+
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *MemoryRepository) DeleteOtherSessions(ctx context.Context, name, keepID string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	for id, s := range m.sessions {
+		if strings.EqualFold(s.Username, name) && id != keepID {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) DeleteUserSessions(ctx context.Context, name string, audit *AuditDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.deleteSessions(name)
+	m.insertAudit(audit)
+	return nil
+}
+
+func (m *MemoryRepository) deleteSessions(name string) {
+	for id, s := range m.sessions {
+		if strings.EqualFold(s.Username, name) {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+func (m *MemoryRepository) FetchTwoFactor(ctx context.Context, name string) (*TwoFactorDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	data := m.twoFactor[memoryKey(name)]
+	if data == nil {
+		return nil, nil
+	}
+	copied := *data
+	return &copied, nil
+}
+
+func (m *MemoryRepository) SaveTwoFactorSecret(ctx context.Context, name, secret string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.twoFactor[memoryKey(name)] = &TwoFactorDB{Username: name, Secret: secret}
+	return nil
+}
+
+func (m *MemoryRepository) EnableTwoFactor(ctx context.Context, name string, step int64, recoveryHashes []string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	data := m.twoFactor[memoryKey(name)]
+	if data == nil || data.Enabled {
+		return errors.New("no rows affected, expected one")
+	}
+
+	data.Enabled = true
+	data.LastStep = step
+
+	codes := make([]*memoryRecoveryCode, 0, len(recoveryHashes))
+	for _, hash := range recoveryHashes {
+		codes = append(codes, &memoryRecoveryCode{Hash: hash})
+	}
+	m.recoveryCodes[memoryKey(name)] = codes
+	return nil
+}
+
+func (m *MemoryRepository) UseTwoFactorStep(ctx context.Context, name string, step int64) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	data := m.twoFactor[memoryKey(name)]
+	if data == nil || !data.Enabled || data.LastStep >= step {
+		return fmt.Errorf("%w: code already used", model.ErrInvalidTwoFactorCode)
+	}
+
+	data.LastStep = step
+	return nil
+}
+
+func (m *MemoryRepository) UseRecoveryCode(ctx context.Context, name, hash string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	for _, code := range m.recoveryCodes[memoryKey(name)] {
+		if code.Hash == hash && !code.Used {
+			code.Used = true
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: invalid recovery code", model.ErrInvalidTwoFactorCode)
+}
+
+func (m *MemoryRepository) DisableTwoFactor(ctx context.Context, name string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	delete(m.recoveryCodes, memoryKey(name))
+	if m.twoFactor[memoryKey(name)] == nil {
+		return errors.New("no rows affected, expected one")
+	}
+
+	delete(m.twoFactor, memoryKey(name))
+	return nil
+}
+
+func (m *MemoryRepository) CreateToken(ctx context.Context, data *TokenDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	token := *data
+	token.ID = m.nextID()
+	token.ConsumedAt = sql.NullTime{}
+	token.CreatedAt = time.Now()
+	m.tokens = append(m.tokens, &token)
+	return nil
+}
+
+func (m *MemoryRepository) FetchToken(ctx context.Context, purpose, hash string) (*TokenDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	for _, t := range m.tokens {
+		if t.Purpose == purpose && t.TokenHash == hash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MemoryRepository) ConsumeToken(ctx context.Context, id int64) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, t := range m.tokens {
+		if t.ID == id && !t.ConsumedAt.Valid && t.ExpiresAt.After(now) {
+			t.ConsumedAt = sql.NullTime{Time: now, Valid: true}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: already consumed", model.ErrTokenInvalid)
+}
+
+func (m *MemoryRepository) CountTokensSince(ctx context.Context, purpose, email string, since time.Time) (int, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, t := range m.tokens {
+		if t.Purpose == purpose && strings.EqualFold(t.Email, email) && t.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MemoryRepository) invalidateTokens(purpose, email string) {
+	now := time.Now()
+	for _, t := range m.tokens {
+		if t.Purpose == purpose && strings.EqualFold(t.Email, email) && !t.ConsumedAt.Valid {
+			t.ConsumedAt = sql.NullTime{Time: now, Valid: true}
+		}
+	}
+}
+
+func (m *MemoryRepository) DeleteExpiredTokens(ctx context.Context) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	limit := time.Now().AddDate(0, 0, -7)
+	kept := m.tokens[:0]
+	for _, t := range m.tokens {
+		if !t.ExpiresAt.Before(limit) {
+			kept = append(kept, t)
+		}
+	}
+	m.tokens = kept
+	return nil
+}
+
+func (m *MemoryRepository) FetchLoginAttempt(ctx context.Context, scope, subject string) (*LoginAttemptDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	data := m.loginAttempts[[2]string{scope, memoryKey(subject)}]
+	if data == nil {
+		return nil, nil
+	}
+	copied := data.LoginAttemptDB
+	return &copied, nil
+}
+
+func (m *MemoryRepository) AddLoginFailure(ctx context.Context, scope, subject string, resetAfter time.Duration) (int, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	k := [2]string{scope, memoryKey(subject)}
+	data := m.loginAttempts[k]
+	if data == nil {
+		data = &memoryLoginAttempt{LoginAttemptDB: LoginAttemptDB{Scope: scope, Subject: subject}}
+		m.loginAttempts[k] = data
+	}
+
+	if data.Failures > 0 && data.LastFailure.Before(now.Add(-resetAfter)) {
+		data.Failures = 1
+	} else {
+		data.Failures++
+	}
+	data.LastFailure = now
+
+	return data.Failures, nil
+}
+
+func (m *MemoryRepository) LockLogin(ctx context.Context, scope, subject string, until time.Time) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if data := m.loginAttempts[[2]string{scope, memoryKey(subject)}]; data != nil {
+		data.LockedUntil = sql.NullTime{Time: until, Valid: true}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) ClearLoginAttempts(ctx context.Context, scope, subject string, audit *AuditDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	delete(m.loginAttempts, [2]string{scope, memoryKey(subject)})
+	m.insertAudit(audit)
+	return nil
+}
+
+func (m *MemoryRepository) EnqueueEmail(ctx context.Context, data *EmailDB) (bool, error) {
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mu.Unlock()
+
+	for _, e := range m.emails {
+		if e.IdempotencyKey == data.IdempotencyKey {
+			return false, nil
+		}
+	}
+
+	now := time.Now()
+	m.emails = append(m.emails, &EmailDB{
+		ID:             m.nextID(),
+		IdempotencyKey: data.IdempotencyKey,
+		Recipient:      data.Recipient,
+		Subject:        data.Subject,
+		TextBody:       data.TextBody,
+		Body:           data.Body,
+		Status:         EmailPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	})
+	return true, nil
+}
+
+func (m *MemoryRepository) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]EmailDB, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var due []*EmailDB
+	for _, e := range m.emails {
+		if e.Status == EmailPending && !e.NextAttemptAt.After(now) {
+			due = append(due, e)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+
+	var ret []EmailDB
+	for _, e := range due {
+		if len(ret) == limit {
+			break
+		}
+		ret = append(ret, *e)
+		e.NextAttemptAt = now.Add(lease)
+	}
+	return ret, nil
+}
+
+func (m *MemoryRepository) MarkEmailSent(ctx context.Context, id int64) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	for _, e := range m.emails {
+		if e.ID == id {
+			e.Status = EmailSent
+			e.Attempts++
+			e.LastError = ""
+			e.SentAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) MarkEmailFailed(ctx context.Context, id int64, status, lastError string, next time.Time) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	for _, e := range m.emails {
+		if e.ID == id {
+			e.Status = status
+			e.Attempts++
+			e.LastError = model.Truncate(lastError, 1000)
+			e.NextAttemptAt = next
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) FetchEmails(ctx context.Context, filter *EmailFilterDB) ([]EmailDB, int, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, 0, err
+	}
+	defer m.mu.Unlock()
+
+	var matched []EmailDB
+	for i := len(m.emails) - 1; i >= 0; i-- {
+		if filter.Status == "" || m.emails[i].Status == filter.Status {
+			matched = append(matched, *m.emails[i])
+		}
+	}
+
+	total := len(matched)
+	if filter.Offset >= total {
+		return nil, total, nil
+	}
+	matched = matched[filter.Offset:]
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total, nil
+}
+
+func (m *MemoryRepository) RetryEmail(ctx context.Context, id int64, audit *AuditDB) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	for _, e := range m.emails {
+		if e.ID == id && e.Status != EmailSent {
+			e.Status = EmailPending
+			e.Attempts = 0
+			e.NextAttemptAt = time.Now()
+			m.insertAudit(audit)
+			return nil
+		}
+	}
+	return model.ErrEmailNotFound
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"sarp_backend/model"
+	"strings"
+)
+
+const (
+	NameChangePending  = "pending"
+	NameChangeApproved = "approved"
+	NameChangeDenied   = "denied"
+)
+
+const nameChangeColumns = "ID, Username, `Character`, NewName, Status, ReviewedBy, Reason, CreatedAt, ReviewedAt"
+
+// CreateNameChange queues a rename of an accepted character of data.Username. Every
+// pending request of the account must be covered by a name change voucher, the voucher
+// is only spent once the request is approved.
+func (r *UserRepository) CreateNameChange(ctx context.Context, data *NameChangeDB) (int64, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var id int64
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		var vouchers int
+		if err := tx.GetContext(ctx, &vouchers, "SELECT VoucherName FROM accounts WHERE Username = ? FOR UPDATE", data.Username); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.ErrAccountNotFound
+			}
+			return err
+		}
+
+		var owned int
+		query := "SELECT COUNT(*) FROM characters WHERE `Character` = ? AND Username = ? AND Created = 1"
+		if err := tx.GetContext(ctx, &owned, query, data.Character, data.Username); err != nil {
+			return err
+		}
+		if owned == 0 {
+			return model.ErrCharacterNotFound
+		}
+
+		var pending []NameChangeDB
+		query = "SELECT " + nameChangeColumns + " FROM ucp_name_changes WHERE Status = ? AND (Username = ? OR `Character` = ? OR NewName = ?)"
+		if err := tx.SelectContext(ctx, &pending, query, NameChangePending, data.Username, data.Character, data.NewName); err != nil {
+			return err
+		}
+		requests := 0
+		for _, p := range pending {
+			switch {
+			case strings.EqualFold(p.Character, data.Character):
+				return model.ErrNameChangePending
+			case strings.EqualFold(p.NewName, data.NewName):
+				return model.ErrCharacterNameTaken
+			case strings.EqualFold(p.Username, data.Username):
+				requests++
+			}
+		}
+		if requests >= vouchers {
+			return model.ErrNoNameVoucher
+		}
+
+		var taken int
+		if err := tx.GetContext(ctx, &taken, "SELECT COUNT(*) FROM characters WHERE `Character` = ?", data.NewName); err != nil {
+			return err
+		}
+		if taken > 0 {
+			return model.ErrCharacterNameTaken
+		}
+
+		query = "INSERT INTO ucp_name_changes (Username, `Character`, NewName) VALUES (?, ?, ?)"
+		result, err := tx.ExecContext(ctx, query, data.Username, data.Character, data.NewName)
+		if err != nil {
+			return err
+		}
+		id, err = result.LastInsertId()
+		return err
+	})
+
+	return id, err
+}
+
+func (r *UserRepository) FetchNameChanges(ctx context.Context, filter *NameChangeFilterDB) ([]NameChangeDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "SELECT " + nameChangeColumns + " FROM ucp_name_changes WHERE 1 = 1"
+	var args []interface{}
+	if filter.Username != "" {
+		query += " AND Username = ?"
+		args = append(args, filter.Username)
+	}
+	if filter.Status != "" {
+		query += " AND Status = ?"
+		args = append(args, filter.Status)
+	}
+	query += " ORDER BY ID DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	var requests []NameChangeDB
+	if err := r.DB.SelectContext(ctx, &requests, query, args...); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func lockNameChange(ctx context.Context, tx *sqlx.Tx, id int64) (*NameChangeDB, error) {
+	var data NameChangeDB
+	query := "SELECT " + nameChangeColumns + " FROM ucp_name_changes WHERE ID = ? FOR UPDATE"
+	if err := tx.GetContext(ctx, &data, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrNameChangeNotFound
+		}
+		return nil, err
+	}
+	if data.Status != NameChangePending {
+		return nil, model.ErrNameChangeNotFound
+	}
+	return &data, nil
+}
+
+// ApproveNameChange renames the character, spends a voucher of the owner and writes
+// the namechanges log row in one transaction. It returns the approved request.
+func (r *UserRepository) ApproveNameChange(ctx context.Context, id int64, admin string, audit *AuditDB) (*NameChangeDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var approved NameChangeDB
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		request, err := lockNameChange(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		var taken int
+		if err = tx.GetContext(ctx, &taken, "SELECT COUNT(*) FROM characters WHERE `Character` = ?", request.NewName); err != nil {
+			return err
+		}
+		if taken > 0 {
+			return model.ErrCharacterNameTaken
+		}
+
+		query := "UPDATE characters SET `Character` = ? WHERE `Character` = ? AND Username = ? AND Created = 1"
+		result, err := tx.ExecContext(ctx, query, request.NewName, request.Character, request.Username)
+		if err != nil {
+			return err
+		}
+		if rows, errRows := result.RowsAffected(); errRows != nil || rows == 0 {
+			return model.ErrCharacterNotFound
+		}
+
+		query = "UPDATE accounts SET VoucherName = VoucherName - 1 WHERE Username = ? AND VoucherName > 0"
+		if result, err = tx.ExecContext(ctx, query, request.Username); err != nil {
+			return err
+		}
+		if rows, errRows := result.RowsAffected(); errRows != nil || rows == 0 {
+			return model.ErrNoNameVoucher
+		}
+
+		query = "INSERT INTO namechanges (OldName, NewName, Admin) VALUES (?, ?, ?)"
+		if _, err = tx.ExecContext(ctx, query, request.Character, request.NewName, admin); err != nil {
+			return err
+		}
+
+		query = "UPDATE ucp_name_changes SET Status = ?, ReviewedBy = ?, ReviewedAt = NOW() WHERE ID = ?"
+		if _, err = tx.ExecContext(ctx, query, NameChangeApproved, admin, id); err != nil {
+			return err
+		}
+		if err = insertAudit(ctx, tx, audit); err != nil {
+			return err
+		}
+
+		return tx.GetContext(ctx, &approved, "SELECT "+nameChangeColumns+" FROM ucp_name_changes WHERE ID = ?", id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &approved, nil
+}
+
+func (r *UserRepository) DenyNameChange(ctx context.Context, id int64, admin, reason string, audit *AuditDB) (*NameChangeDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var denied NameChangeDB
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		if _, err := lockNameChange(ctx, tx, id); err != nil {
+			return err
+		}
+
+		query := "UPDATE ucp_name_changes SET Status = ?, ReviewedBy = ?, Reason = ?, ReviewedAt = NOW() WHERE ID = ?"
+		if _, err := tx.ExecContext(ctx, query, NameChangeDenied, admin, model.Truncate(reason, 500), id); err != nil {
+			return err
+		}
+		if err := insertAudit(ctx, tx, audit); err != nil {
+			return err
+		}
+
+		return tx.GetContext(ctx, &denied, "SELECT "+nameChangeColumns+" FROM ucp_name_changes WHERE ID = ?", id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &denied, nil
+}
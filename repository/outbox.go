@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"sarp_backend/model"
+	"strings"
+	"time"
+)
+
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailDead    = "dead"
+)
+
+type EmailFilterDB struct {
+	Status string
+	Limit  int
+	Offset int
+}
+
+// Rows queued before the text alternative existed have no TextBody.
+const emailColumns = "ID, IdempotencyKey, Recipient, Subject, IFNULL(TextBody, '') AS TextBody, Body, Status, Attempts, LastError, NextAttemptAt, SentAt, CreatedAt"
+
+// EnqueueEmail stores the message unless one with the same idempotency key is already
+// queued, it reports whether a new row was created.
+func (r *UserRepository) EnqueueEmail(ctx context.Context, data *EmailDB) (bool, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "INSERT INTO email_outbox (IdempotencyKey, Recipient, Subject, TextBody, Body) VALUES (?, ?, ?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE ID = ID"
+	result, err := r.DB.ExecContext(ctx, query, data.IdempotencyKey, data.Recipient, data.Subject, data.TextBody, data.Body)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ClaimEmails leases up to limit due messages. Their next attempt is moved past the
+// lease, so a worker dying in the middle of a send only delays the message and other
+// workers skip the rows while they are locked.
+func (r *UserRepository) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]EmailDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var emails []EmailDB
+
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		query := "SELECT " + emailColumns + " FROM email_outbox WHERE Status = ? AND NextAttemptAt <= NOW() " +
+			"ORDER BY NextAttemptAt, ID LIMIT ? FOR UPDATE SKIP LOCKED"
+		if err := tx.SelectContext(ctx, &emails, query, EmailPending, limit); err != nil {
+			return err
+		}
+		if len(emails) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, 0, len(emails))
+		for _, e := range emails {
+			ids = append(ids, e.ID)
+		}
+
+		update, args, err := sqlx.In("UPDATE email_outbox SET NextAttemptAt = DATE_ADD(NOW(), INTERVAL ? SECOND) WHERE ID IN (?)", int(lease.Seconds()), ids)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, update, args...)
+		return err
+	})
+
+	return emails, err
+}
+
+func (r *UserRepository) MarkEmailSent(ctx context.Context, id int64) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "UPDATE email_outbox SET Status = ?, Attempts = Attempts + 1, LastError = '', SentAt = NOW() WHERE ID = ?"
+	_, err := r.DB.ExecContext(ctx, query, EmailSent, id)
+	return err
+}
+
+// MarkEmailFailed records a failed attempt, status is EmailPending to try again at
+// next or EmailDead to stop retrying.
+func (r *UserRepository) MarkEmailFailed(ctx context.Context, id int64, status, lastError string, next time.Time) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "UPDATE email_outbox SET Status = ?, Attempts = Attempts + 1, LastError = ?, NextAttemptAt = ? WHERE ID = ?"
+	_, err := r.DB.ExecContext(ctx, query, status, model.Truncate(lastError, 1000), next, id)
+	return err
+}
+
+func (r *UserRepository) FetchEmails(ctx context.Context, filter *EmailFilterDB) ([]EmailDB, int, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var where []string
+	var args []interface{}
+
+	if filter.Status != "" {
+		where = append(where, "Status = ?")
+		args = append(args, filter.Status)
+	}
+
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := r.DB.GetContext(ctx, &total, "SELECT COUNT(*) FROM email_outbox"+clause, args...); err != nil {
+		return nil, 0, err
+	}
+
+	var emails []EmailDB
+	query := "SELECT " + emailColumns + " FROM email_outbox" + clause + " ORDER BY ID DESC LIMIT ? OFFSET ?"
+	if err := r.DB.SelectContext(ctx, &emails, query, append(args, filter.Limit, filter.Offset)...); err != nil {
+		return nil, 0, err
+	}
+
+	return emails, total, nil
+}
+
+// RetryEmail queues a message that wasn't delivered yet for an immediate attempt and
+// gives it a fresh set of attempts.
+func (r *UserRepository) RetryEmail(ctx context.Context, id int64, audit *AuditDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		query := "UPDATE email_outbox SET Status = ?, Attempts = 0, NextAttemptAt = NOW() WHERE ID = ? AND Status <> ?"
+		result, err := tx.ExecContext(ctx, query, EmailPending, id, EmailSent)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return model.ErrEmailNotFound
+		}
+		return insertAudit(ctx, tx, audit)
+	})
+}
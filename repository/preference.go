@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+)
+
+// FetchLocale returns an empty string for accounts that never picked a language.
+func (r *UserRepository) FetchLocale(ctx context.Context, name string) (string, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var locale string
+	query := "SELECT Locale FROM ucp_preferences WHERE Username = ?"
+	if err := r.DB.GetContext(ctx, &locale, query, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return locale, nil
+}
+
+func (r *UserRepository) SaveLocale(ctx context.Context, name, locale string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "INSERT INTO ucp_preferences (Username, Locale) VALUES (?, ?) ON DUPLICATE KEY UPDATE Locale = VALUES(Locale)"
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, query, name, locale); err != nil {
+			return err
+		}
+		return nil
+	})
+}
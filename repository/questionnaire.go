@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"sarp_backend/model"
+)
+
+// insertApplication stores the answers in the transaction creating the character, a
+// nil application stores nothing.
+func insertApplication(ctx context.Context, tx *sqlx.Tx, data *ApplicationDB) error {
+	if data == nil {
+		return nil
+	}
+
+	query := "INSERT INTO ucp_applications (Username, `Character`, Version) VALUES (?, ?, ?)"
+	result, err := tx.ExecContext(ctx, query, data.Username, data.Character, data.Version)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	return insertAnswers(ctx, tx, id, data.Answers)
+}
+
+func insertAnswers(ctx context.Context, tx *sqlx.Tx, id int64, answers []string) error {
+	query := "INSERT INTO ucp_application_answers (ApplicationID, Position, Answer) VALUES (?, ?, ?)"
+	for i, answer := range answers {
+		if _, err := tx.ExecContext(ctx, query, id, i+1, answer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FetchApplications returns the latest application of each character.
+func (r *UserRepository) FetchApplications(ctx context.Context, characters []string) ([]ApplicationDB, error) {
+	if len(characters) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query, args, err := sqlx.In("SELECT "+applicationColumns+" FROM ucp_applications "+
+		"WHERE ID IN (SELECT MAX(ID) FROM ucp_applications WHERE `Character` IN (?) GROUP BY `Character`) ORDER BY ID", characters)
+	if err != nil {
+		return nil, err
+	}
+
+	var applications []ApplicationDB
+	if err = r.DB.SelectContext(ctx, &applications, query, args...); err != nil {
+		return nil, err
+	}
+	if err = r.fetchAnswers(ctx, applications); err != nil {
+		return nil, err
+	}
+
+	return applications, nil
+}
+
+// fetchAnswers fills the answers of every application in one query.
+func (r *UserRepository) fetchAnswers(ctx context.Context, applications []ApplicationDB) error {
+	if len(applications) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(applications))
+	byID := make(map[int64]*ApplicationDB, len(applications))
+	for i := range applications {
+		ids = append(ids, applications[i].ID)
+		byID[applications[i].ID] = &applications[i]
+	}
+
+	var answers []struct {
+		ApplicationID int64  `db:"ApplicationID"`
+		Answer        string `db:"Answer"`
+	}
+	query, args, err := sqlx.In("SELECT ApplicationID, Answer FROM ucp_application_answers WHERE ApplicationID IN (?) ORDER BY ApplicationID, Position", ids)
+	if err != nil {
+		return err
+	}
+	if err = r.DB.SelectContext(ctx, &answers, query, args...); err != nil {
+		return err
+	}
+	for _, a := range answers {
+		byID[a.ApplicationID].Answers = append(byID[a.ApplicationID].Answers, a.Answer)
+	}
+	return nil
+}
+
+func (r *UserRepository) FetchQuestionnaire(ctx context.Context, version int) (*QuestionnaireDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var data QuestionnaireDB
+	query := "SELECT Version, CreatedBy, CreatedAt FROM ucp_questionnaires WHERE Version = ?"
+	if err := r.DB.GetContext(ctx, &data, query, version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+
+	query = "SELECT Version, Position, Text, MinLength, MaxLength FROM ucp_questions WHERE Version = ? ORDER BY Position"
+	if err := r.DB.SelectContext(ctx, &data.Questions, query, version); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// FetchLatestQuestionnaire returns the questions new applications answer, version 0
+// without questions when staff never configured any.
+func (r *UserRepository) FetchLatestQuestionnaire(ctx context.Context) (*QuestionnaireDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var version int
+	if err := r.DB.GetContext(ctx, &version, "SELECT IFNULL(MAX(Version), 0) FROM ucp_questionnaires"); err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		return &QuestionnaireDB{}, nil
+	}
+
+	return r.FetchQuestionnaire(ctx, version)
+}
+
+// CreateQuestionnaire saves a new version, it is used for every application created
+// after it.
+func (r *UserRepository) CreateQuestionnaire(ctx context.Context, data *QuestionnaireDB, audit *AuditDB) (int, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var version int
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "INSERT INTO ucp_questionnaires (CreatedBy) VALUES (?)", data.CreatedBy)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		version = int(id)
+
+		query := "INSERT INTO ucp_questions (Version, Position, Text, MinLength, MaxLength) VALUES (?, ?, ?, ?, ?)"
+		for i, q := range data.Questions {
+			if _, err = tx.ExecContext(ctx, query, version, i+1, q.Text, q.MinLength, q.MaxLength); err != nil {
+				return err
+			}
+		}
+		return insertAudit(ctx, tx, audit)
+	})
+
+	return version, err
+}
@@ -1,27 +1,41 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	"sarp_backend/model"
 	"sort"
+	"time"
 )
 
 type UserRepository struct {
-	DB *sqlx.DB
+	DB           *sqlx.DB
+	QueryTimeout time.Duration
 }
 
-func New(dsn string) (*UserRepository, error) {
+func New(dsn string, queryTimeout time.Duration) (*UserRepository, error) {
 	db, err := sqlx.Connect("mysql", dsn)
 	if err != nil {
 		return nil, err
 	}
-	return &UserRepository{DB: db}, nil
+	return &UserRepository{DB: db, QueryTimeout: queryTimeout}, nil
 }
 
-func withTransaction(db *sqlx.DB, txFunc func(*sqlx.Tx) error) error {
-	tx, err := db.Beginx()
+// queryContext bounds every repository call, the caller's context still cancels
+// it earlier when the request is aborted.
+func (r *UserRepository) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.QueryTimeout)
+}
+
+func withTransaction(ctx context.Context, db *sqlx.DB, txFunc func(*sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -35,22 +49,25 @@ func withTransaction(db *sqlx.DB, txFunc func(*sqlx.Tx) error) error {
 	return tx.Commit()
 }
 
-func (r *UserRepository) valueExists(query string, args ...interface{}) (bool, error) {
+func (r *UserRepository) valueExists(ctx context.Context, query string, args ...interface{}) (bool, error) {
 	var count int
-	if err := r.DB.Get(&count, query, args...); err != nil {
+	if err := r.DB.GetContext(ctx, &count, query, args...); err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-func (r *UserRepository) Create(data *UserDB) error {
+func (r *UserRepository) Create(ctx context.Context, data *UserDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	existsQuery := "SELECT COUNT(*) FROM accounts WHERE Username = ? OR Email = ?"
-	exists, err := r.valueExists(existsQuery, data.Username, data.Email)
+	exists, err := r.valueExists(ctx, existsQuery, data.Username, data.Email)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return errors.New("account already exists")
+		return model.ErrAccountExists
 	}
 
 	insertQuery := `
@@ -58,8 +75,8 @@ func (r *UserRepository) Create(data *UserDB) error {
 		VALUES (?, ?, ?, 'N/A', ?, '000000', ?, 0, 0, 0, '000000', 2, 0)
 	`
 
-	return withTransaction(r.DB, func(tx *sqlx.Tx) error {
-		result, errTx := tx.Exec(insertQuery, data.Username, data.Email, data.Username, data.Password, data.RegisterDate)
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		result, errTx := tx.ExecContext(ctx, insertQuery, data.Username, data.Email, data.Username, data.Password, data.RegisterDate)
 		if errTx != nil {
 			return errTx
 		}
@@ -71,11 +88,14 @@ func (r *UserRepository) Create(data *UserDB) error {
 	})
 }
 
-func (r *UserRepository) Activate(email string) error {
+func (r *UserRepository) Activate(ctx context.Context, email string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	query := "UPDATE accounts SET Activated = 2 WHERE Email = ? AND Activated = 0"
 
-	return withTransaction(r.DB, func(tx *sqlx.Tx) error {
-		result, errTx := tx.Exec(query, email)
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		result, errTx := tx.ExecContext(ctx, query, email)
 		if errTx != nil {
 			return errTx
 		}
@@ -87,28 +107,59 @@ func (r *UserRepository) Activate(email string) error {
 	})
 }
 
-func (r *UserRepository) CheckActivation(name string) (bool, error) {
+func (r *UserRepository) CheckActivation(ctx context.Context, name string) (bool, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var activation int
 	query := "SELECT Activated FROM accounts WHERE Username = ?"
-	if err := r.DB.Get(&activation, query, name); err != nil {
+	if err := r.DB.GetContext(ctx, &activation, query, name); err != nil {
 		return false, err
 	}
 
 	return activation == 2, nil
 }
 
-func (r *UserRepository) Verify(data *UserDB) error {
-	var name string
-	query := "SELECT Username FROM accounts WHERE Username = ? AND Password = ?"
+func (r *UserRepository) FetchPassword(ctx context.Context, name string) (string, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
 
-	return r.DB.Get(&name, query, data.Username, data.Password)
+	var password sql.NullString
+	query := "SELECT Password FROM accounts WHERE Username = ?"
+	if err := r.DB.GetContext(ctx, &password, query, name); err != nil {
+		return "", err
+	}
+
+	return password.String, nil
 }
 
-func (r *UserRepository) UpdatePassword(email, password string) error {
+func (r *UserRepository) UpdatePassword(ctx context.Context, email, password string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	query := "UPDATE accounts SET Password = ? WHERE Email = ?"
 
-	return withTransaction(r.DB, func(tx *sqlx.Tx) error {
-		result, errTx := tx.Exec(query, password, email)
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		result, errTx := tx.ExecContext(ctx, query, password, email)
+		if errTx != nil {
+			return errTx
+		}
+		rows, errRows := result.RowsAffected()
+		if errRows != nil || rows == 0 {
+			return errors.New("no rows affected, expected one")
+		}
+		return invalidateTokens(ctx, tx, TokenPurposeReset, email)
+	})
+}
+
+func (r *UserRepository) UpdatePasswordByName(ctx context.Context, name, password string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "UPDATE accounts SET Password = ? WHERE Username = ?"
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		result, errTx := tx.ExecContext(ctx, query, password, name)
 		if errTx != nil {
 			return errTx
 		}
@@ -120,10 +171,81 @@ func (r *UserRepository) UpdatePassword(email, password string) error {
 	})
 }
 
-func (r *UserRepository) Fetch(name string, email string) (bool, error) {
+// UpdateEmail moves the account to a confirmed new address. Reset links sent to the
+// old address stop working.
+func (r *UserRepository) UpdateEmail(ctx context.Context, name, email string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		var oldEmail string
+		if err := tx.GetContext(ctx, &oldEmail, "SELECT Email FROM accounts WHERE Username = ? FOR UPDATE", name); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.ErrAccountNotFound
+			}
+			return err
+		}
+
+		// The address may have been registered since the change was requested.
+		var taken int
+		if err := tx.GetContext(ctx, &taken, "SELECT COUNT(*) FROM accounts WHERE Email = ? AND Username <> ?", email, name); err != nil {
+			return err
+		}
+		if taken > 0 {
+			return model.ErrEmailTaken
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE accounts SET Email = ? WHERE Username = ?", email, name); err != nil {
+			return err
+		}
+		return invalidateTokens(ctx, tx, TokenPurposeReset, oldEmail)
+	})
+}
+
+func (r *UserRepository) FetchProfile(ctx context.Context, name string) (*ProfileDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var ret ProfileDB
+	query := "SELECT NumeForum, Avatar, DescriereaMea FROM accounts WHERE Username = ?"
+	if err := r.DB.GetContext(ctx, &ret, query, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+func (r *UserRepository) UpdateProfile(ctx context.Context, name string, data *ProfileDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "UPDATE accounts SET NumeForum = ?, Avatar = ?, DescriereaMea = ? WHERE Username = ?"
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		var count int
+		if err := tx.GetContext(ctx, &count, "SELECT COUNT(*) FROM accounts WHERE Username = ? FOR UPDATE", name); err != nil {
+			return err
+		}
+		if count == 0 {
+			return model.ErrAccountNotFound
+		}
+
+		// RowsAffected is 0 when nothing changed, so it can't tell a missing account apart.
+		_, errTx := tx.ExecContext(ctx, query, data.ForumName, data.Avatar, data.Description, name)
+		return errTx
+	})
+}
+
+func (r *UserRepository) Fetch(ctx context.Context, name string, email string) (bool, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var fetch string
 	query := "SELECT Username FROM accounts WHERE Username = ? OR Email = ?"
-	if err := r.DB.Get(&fetch, query, name, email); err != nil {
+	if err := r.DB.GetContext(ctx, &fetch, query, name, email); err != nil {
 		if err.Error() == "sql: no rows in result set" {
 			return false, nil
 		}
@@ -132,29 +254,35 @@ func (r *UserRepository) Fetch(name string, email string) (bool, error) {
 	return len(fetch) != 0, nil
 }
 
-func (r *UserRepository) FetchStats(name string) (*GetStatsDB, error) {
+func (r *UserRepository) FetchStats(ctx context.Context, name string) (*GetStatsDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var ret GetStatsDB
 	query := "SELECT Admin, Tester, DonateRank, `Characters`, LoginDate FROM accounts WHERE Username = ?"
-	if err := r.DB.Get(&ret, query, name); err != nil {
+	if err := r.DB.GetContext(ctx, &ret, query, name); err != nil {
 		return nil, err
 	}
 
 	var characters []CharacterStatsDB
-	query = "SELECT `Character`, Created, Level, PlayingHours FROM characters WHERE Username = ? AND Created >= 0"
-	if err := r.DB.Select(&characters, query, name); err != nil {
+	query = "SELECT `Character`, Created, Level, PlayingHours FROM characters WHERE Username = ? AND (Created >= 0 OR Created = ?)"
+	if err := r.DB.SelectContext(ctx, &characters, query, name, CharacterRejected); err != nil {
 		return nil, err
 	}
 
 	ret.CharactersData = characters
-	ret.Characters = len(characters)
+	ret.Characters = characterSlots(characters)
 	ret.Username = name
 	return &ret, nil
 }
 
-func (r *UserRepository) FetchMail(name string) (string, error) {
+func (r *UserRepository) FetchMail(ctx context.Context, name string) (string, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var mail string
 	query := "SELECT Email FROM accounts WHERE Username = ?"
-	if err := r.DB.Get(&mail, query, name); err != nil {
+	if err := r.DB.GetContext(ctx, &mail, query, name); err != nil {
 		if err.Error() == "sql: no rows in result set" {
 			return "", nil
 		}
@@ -164,13 +292,22 @@ func (r *UserRepository) FetchMail(name string) (string, error) {
 	return mail, nil
 }
 
-func (r *UserRepository) FetchStaff() ([]GetStatsDB, error) {
+func (r *UserRepository) FetchStaff(ctx context.Context) ([]GetStatsDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var ret []GetStatsDB
 	query := "SELECT Username, Admin, Tester FROM accounts WHERE Admin > 0 OR Tester > 0"
-	if err := r.DB.Select(&ret, query); err != nil {
+	if err := r.DB.SelectContext(ctx, &ret, query); err != nil {
 		return nil, err
 	}
 
+	sortStaff(ret)
+
+	return ret, nil
+}
+
+func sortStaff(ret []GetStatsDB) {
 	sort.Slice(ret, func(i, j int) bool {
 		if ret[i].Tester < ret[j].Tester {
 			return true
@@ -187,80 +324,170 @@ func (r *UserRepository) FetchStaff() ([]GetStatsDB, error) {
 		// Dacă sunt ambii Tester sau Admin cu același nivel, sortăm alfabetic după Username
 		return ret[i].Username < ret[j].Username
 	})
-
-	return ret, nil
 }
 
-func (r *UserRepository) FetchServerStats() (*GetServerStatsDB, error) {
+func (r *UserRepository) FetchServerStats(ctx context.Context) (*GetServerStatsDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var ret GetServerStatsDB
 	query := "SELECT COUNT(*) FROM characters WHERE Online = 1"
-	if err := r.DB.Get(&ret.Online, query); err != nil {
+	if err := r.DB.GetContext(ctx, &ret.Online, query); err != nil {
 		return nil, err
 	}
 
 	query = "SELECT COUNT(*) FROM blacklist WHERE perm = 1 OR Expire >= NOW()"
-	if err := r.DB.Get(&ret.Bans, query); err != nil {
+	if err := r.DB.GetContext(ctx, &ret.Bans, query); err != nil {
 		return nil, err
 	}
 
 	query = "SELECT COUNT(*) FROM houses"
-	if err := r.DB.Get(&ret.Houses, query); err != nil {
+	if err := r.DB.GetContext(ctx, &ret.Houses, query); err != nil {
 		return nil, err
 	}
 
 	query = "SELECT COUNT(*) FROM accounts WHERE Admin > 0 OR Tester > 0"
-	if err := r.DB.Get(&ret.Staff, query); err != nil {
+	if err := r.DB.GetContext(ctx, &ret.Staff, query); err != nil {
 		return nil, err
 	}
 
 	query = "SELECT COUNT(*) FROM  accounts WHERE Activated = 2"
-	if err := r.DB.Get(&ret.Accounts, query); err != nil {
+	if err := r.DB.GetContext(ctx, &ret.Accounts, query); err != nil {
 		return nil, err
 	}
 
 	query = "SELECT COUNT(*) FROM characters WHERE Created > 0"
-	if err := r.DB.Get(&ret.Characters, query); err != nil {
+	if err := r.DB.GetContext(ctx, &ret.Characters, query); err != nil {
 		return nil, err
 	}
 
 	return &ret, nil
 }
 
-func (r *UserRepository) FetchTesterLevel(name string) (bool, error) {
+func (r *UserRepository) FetchTesterLevel(ctx context.Context, name string) (int, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var testerLevel int
 	query := "SELECT Tester FROM accounts WHERE Username = ?"
-	if err := r.DB.Get(&testerLevel, query, name); err != nil {
-		return false, err
+	if err := r.DB.GetContext(ctx, &testerLevel, query, name); err != nil {
+		return 0, err
 	}
 
-	return testerLevel > 0, nil
+	return testerLevel, nil
 }
 
-func (r *UserRepository) FetchAdminLevel(name string) (bool, error) {
+func (r *UserRepository) FetchAdminLevel(ctx context.Context, name string) (int, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var adminLevel int
 	query := "SELECT Admin FROM accounts WHERE Username = ?"
-	if err := r.DB.Get(&adminLevel, query, name); err != nil {
-		return false, err
+	if err := r.DB.GetContext(ctx, &adminLevel, query, name); err != nil {
+		return 0, err
 	}
 
-	return adminLevel > 0, nil
+	return adminLevel, nil
+}
+
+// UpdateStaffLevel logs the account out everywhere, the levels are cached in the
+// session and would otherwise only change at the next login.
+func (r *UserRepository) UpdateStaffLevel(ctx context.Context, name string, admin, tester int, audit *AuditDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		var count int
+		if err := tx.GetContext(ctx, &count, "SELECT COUNT(*) FROM accounts WHERE Username = ? FOR UPDATE", name); err != nil {
+			return err
+		}
+		if count == 0 {
+			return model.ErrAccountNotFound
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE accounts SET Admin = ?, Tester = ? WHERE Username = ?", admin, tester, name); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM ucp_sessions WHERE Username = ?", name); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, audit)
+	})
+}
+
+// ResetPassword is the operator side of a reset: pending reset links stop working
+// and every session of the account is closed.
+func (r *UserRepository) ResetPassword(ctx context.Context, name, password string, audit *AuditDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		var email string
+		if err := tx.GetContext(ctx, &email, "SELECT Email FROM accounts WHERE Username = ? FOR UPDATE", name); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.ErrAccountNotFound
+			}
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE accounts SET Password = ? WHERE Username = ?", password, name); err != nil {
+			return err
+		}
+		if err := invalidateTokens(ctx, tx, TokenPurposeReset, email); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM ucp_sessions WHERE Username = ?", name); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, audit)
+	})
 }
 
-func (r *UserRepository) CreateCharacter(data *CharacterDB) error {
+// ChangePassword saves the new password of a logged in account. Pending reset links
+// stop working and every session of the account except keepSession is closed in the
+// same transaction.
+func (r *UserRepository) ChangePassword(ctx context.Context, name, password, keepSession string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		var email string
+		if err := tx.GetContext(ctx, &email, "SELECT Email FROM accounts WHERE Username = ? FOR UPDATE", name); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.ErrAccountNotFound
+			}
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE accounts SET Password = ? WHERE Username = ?", password, name); err != nil {
+			return err
+		}
+		if err := invalidateTokens(ctx, tx, TokenPurposeReset, email); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM ucp_sessions WHERE Username = ? AND ID <> ?", name, keepSession)
+		return err
+	})
+}
+
+func (r *UserRepository) CreateCharacter(ctx context.Context, data *CharacterDB, application *ApplicationDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var ret string
 	query := "SELECT `Character` FROM characters WHERE `Character` = ?"
-	if err := r.DB.Get(&ret, query, data.Character); err != nil && err.Error() != "sql: no rows in result set" {
+	if err := r.DB.GetContext(ctx, &ret, query, data.Character); err != nil && err.Error() != "sql: no rows in result set" {
 		return err
 	}
 	if len(ret) > 0 {
-		return errors.New(fmt.Sprintf("character with name %s already exists", data.Character))
+		return fmt.Errorf("%w: %s", model.ErrCharacterNameTaken, data.Character)
 	}
 
 	insertQuery := "INSERT INTO characters(Username, `Character`, Level, Created, Age, Gender, Origin, Skin, Status, AcceptedBy) " +
 		"VALUES (?, ?, 1, 0, ?, ?, ?, ?, 0, 'N/A');"
 
-	return withTransaction(r.DB, func(tx *sqlx.Tx) error {
-		result, errTx := tx.Exec(insertQuery, data.Username, data.Character, data.Age, data.Gender, data.Origin, data.Skin)
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		result, errTx := tx.ExecContext(ctx, insertQuery, data.Username, data.Character, data.Age, data.Gender, data.Origin, data.Skin)
 		if errTx != nil {
 			return errTx
 		}
@@ -268,40 +495,64 @@ func (r *UserRepository) CreateCharacter(data *CharacterDB) error {
 		if errRows != nil || rows == 0 {
 			return errors.New("no rows affected, expected one")
 		}
-		return nil
+		return insertApplication(ctx, tx, application)
 	})
 }
 
-func (r *UserRepository) FetchWaitingCharacters() ([]CharacterDB, error) {
+func (r *UserRepository) FetchWaitingCharacters(ctx context.Context) ([]CharacterDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var characters []CharacterDB
 	query := "SELECT Username, `Character`, Age, Gender, Origin FROM characters WHERE Created = 0"
 
-	if err := r.DB.Select(&characters, query); err != nil {
+	if err := r.DB.SelectContext(ctx, &characters, query); err != nil {
 		return nil, err
 	}
 	return characters, nil
 }
 
-func (r *UserRepository) AcceptCharacter(username, characterName, acceptedBy string) error {
-	return withTransaction(r.DB, func(tx *sqlx.Tx) error {
-		charCountQuery := "SELECT Characters FROM accounts WHERE Username = ?"
+// AcceptCharacter accepts a waiting character and returns the account owning it, the
+// one whose character count goes up.
+func (r *UserRepository) AcceptCharacter(ctx context.Context, characterName, acceptedBy string, audit *AuditDB) (string, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var username string
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		query := "SELECT Username FROM characters WHERE `Character` = ? AND Created = 0 FOR UPDATE"
+		if err := tx.GetContext(ctx, &username, query, characterName); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.ErrCharacterNotFound
+			}
+			return err
+		}
+
+		if err := reviewApplication(ctx, tx, characterName, acceptedBy, ApplicationAccepted, ""); err != nil {
+			return err
+		}
+
+		charCountQuery := "SELECT Characters FROM accounts WHERE Username = ? FOR UPDATE"
 		var charCount int
-		if err := tx.Get(&charCount, charCountQuery, username); err != nil {
+		if err := tx.GetContext(ctx, &charCount, charCountQuery, username); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.ErrAccountNotFound
+			}
 			return err
 		}
 
-		updateCharQuery := "UPDATE characters SET Created = 1, Status = 1 WHERE `Character` = ?"
-		result, errTx := tx.Exec(updateCharQuery, characterName)
+		updateCharQuery := "UPDATE characters SET Created = 1, Status = 1 WHERE `Character` = ? AND Created = 0"
+		result, errTx := tx.ExecContext(ctx, updateCharQuery, characterName)
 		if errTx != nil {
 			return errTx
 		}
 		rows, errRows := result.RowsAffected()
 		if errRows != nil || rows == 0 {
-			return errors.New("no rows affected, expected one")
+			return model.ErrCharacterNotFound
 		}
 
 		updateUserQuery := "UPDATE accounts SET Characters = ?, AcceptedBy = ?, Accepted = 2 WHERE Username = ?"
-		result, errTx = tx.Exec(updateUserQuery, charCount+1, acceptedBy, username)
+		result, errTx = tx.ExecContext(ctx, updateUserQuery, charCount+1, acceptedBy, username)
 		if errTx != nil {
 			return errTx
 		}
@@ -309,29 +560,63 @@ func (r *UserRepository) AcceptCharacter(username, characterName, acceptedBy str
 		if errRows != nil || rows == 0 {
 			return errors.New("no rows affected, expected one")
 		}
-		return nil
+		return insertAudit(ctx, tx, audit)
 	})
+	if err != nil {
+		return "", err
+	}
+
+	return username, nil
 }
 
-func (r *UserRepository) DeclineCharacter(characterName string) error {
-	return withTransaction(r.DB, func(tx *sqlx.Tx) error {
-		query := "DELETE FROM characters WHERE `Character` = ? AND Status = 0"
-		result, err := tx.Exec(query, characterName)
+// DeclineCharacter marks the waiting character as rejected and returns the account
+// owning it, the row and its application are kept for the review history.
+func (r *UserRepository) DeclineCharacter(ctx context.Context, characterName, reviewer, reason string, audit *AuditDB) (string, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var username string
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		query := "SELECT Username FROM characters WHERE `Character` = ? AND Status = 0 AND Created = 0 FOR UPDATE"
+		if err := tx.GetContext(ctx, &username, query, characterName); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.ErrCharacterNotFound
+			}
+			return err
+		}
+
+		if err := reviewApplication(ctx, tx, characterName, reviewer, ApplicationRejected, reason); err != nil {
+			return err
+		}
+
+		query = "UPDATE characters SET Created = ? WHERE `Character` = ? AND Status = 0 AND Created = 0"
+		result, err := tx.ExecContext(ctx, query, CharacterRejected, characterName)
 		if err != nil {
 			return err
 		}
 		rows, errRows := result.RowsAffected()
 		if errRows != nil || rows == 0 {
-			return errors.New("no rows affected, expected one")
+			return model.ErrCharacterNotFound
 		}
-		return nil
+		return insertAudit(ctx, tx, audit)
 	})
+	if err != nil {
+		return "", err
+	}
+
+	return username, nil
 }
 
-func (r *UserRepository) FetchCharacter(character string) (*CharacterDB, error) {
+func (r *UserRepository) FetchCharacter(ctx context.Context, character string) (*CharacterDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var data CharacterDB
 	query := "SELECT Username, `Character` FROM characters WHERE `Character` = ?"
-	if err := r.DB.Get(&data, query, character); err != nil {
+	if err := r.DB.GetContext(ctx, &data, query, character); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrCharacterNotFound
+		}
 		return nil, err
 	}
 	if data.Username == "" || data.Character == "" {
@@ -341,30 +626,40 @@ func (r *UserRepository) FetchCharacter(character string) (*CharacterDB, error)
 	return &data, nil
 }
 
-func (r *UserRepository) CheckForBan(name string) (bool, error) {
+func (r *UserRepository) CheckForBan(ctx context.Context, name string) (bool, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var ret string
 	query := "SELECT Username FROM blacklist WHERE Username = ? AND Expire >= NOW()"
-	if err := r.DB.Get(&ret, query, name); err != nil && err.Error() != "sql: no rows in result set" {
+	if err := r.DB.GetContext(ctx, &ret, query, name); err != nil && err.Error() != "sql: no rows in result set" {
 		return false, err
 	}
 
 	return len(ret) > 0, nil
 }
 
-func (r *UserRepository) AddBan(data *BlacklistDB) error {
+// AddBan bans the account for Expire days and closes every session it has.
+func (r *UserRepository) AddBan(ctx context.Context, data *BlacklistDB, audit *AuditDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	selectIP := "SELECT IP FROM accounts WHERE Username = ?"
-	if err := r.DB.Get(&data.IP, selectIP, data.Username); err != nil {
+	if err := r.DB.GetContext(ctx, &data.IP, selectIP, data.Username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ErrAccountNotFound
+		}
 		return err
 	}
 	if data.IP == "" {
 		return errors.New("can't get IP")
 	}
 
-	return withTransaction(r.DB, func(tx *sqlx.Tx) error {
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
 		query := "INSERT INTO `blacklist` (`IP`,`Username`,`BannedBy`,`Reason`,`perm`, `Date`, `expire`) " +
 			"VALUES (?, ?, ?, ?, 0, ?, DATE_ADD(NOW(),INTERVAL ? DAY))"
 
-		result, err := tx.Exec(query, data.IP, data.Username, data.BannedBy, data.Reason, data.Date, data.Expire)
+		result, err := tx.ExecContext(ctx, query, data.IP, data.Username, data.BannedBy, data.Reason, data.Date, data.Expire)
 		if err != nil {
 			return err
 		}
@@ -372,21 +667,27 @@ func (r *UserRepository) AddBan(data *BlacklistDB) error {
 		if err != nil || rows == 0 {
 			return errors.New("no rows affected, expected one")
 		}
-		return nil
+		if _, err = tx.ExecContext(ctx, "DELETE FROM ucp_sessions WHERE Username = ?", data.Username); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, audit)
 	})
 }
 
-func (r *UserRepository) FetchBans() ([]BlacklistDB, error) {
+func (r *UserRepository) FetchBans(ctx context.Context) ([]BlacklistDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
 	var bans []BlacklistDB
 	queryBans := "SELECT Username, BannedBy, Reason, Expire FROM blacklist WHERE Expire >= NOW()"
-	if err := r.DB.Select(&bans, queryBans); err != nil {
+	if err := r.DB.SelectContext(ctx, &bans, queryBans); err != nil {
 		return nil, err
 	}
 
 	for _, ban := range bans {
 		var name string
 		queryChar := "SELECT `Character` FROM characters WHERE Username = ? AND Status = 1"
-		if err := r.DB.Select(&name, queryChar); err != nil {
+		if err := r.DB.SelectContext(ctx, &name, queryChar); err != nil {
 			return nil, err
 		}
 		ban.Characters = append(ban.Characters, CharacterDB{Character: name})
@@ -395,66 +696,58 @@ func (r *UserRepository) FetchBans() ([]BlacklistDB, error) {
 	return bans, nil
 }
 
-func (r *UserRepository) Unban(name string) error {
-	return withTransaction(r.DB, func(tx *sqlx.Tx) error {
+func (r *UserRepository) Unban(ctx context.Context, name string, audit *AuditDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
 		query := "UPDATE blacklist SET expire = DATE_SUB(NOW(), INTERVAL 1 SECOND) WHERE Username = ?"
-		result, err := tx.Exec(query, name)
+		result, err := tx.ExecContext(ctx, query, name)
 		if err != nil {
 			return err
 		}
 		rows, err := result.RowsAffected()
 		if err != nil || rows == 0 {
-			return errors.New("no rows affected, expected one")
+			return model.ErrNotBanned
 		}
-		return nil
+		return insertAudit(ctx, tx, audit)
 	})
 }
 
-func (r *UserRepository) Ajail(data *AjailDB) error {
-	return withTransaction(r.DB, func(tx *sqlx.Tx) error {
+func (r *UserRepository) Ajail(ctx context.Context, data *AjailDB, audit *AuditDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
 		query := "UPDATE characters SET JailTime = ?, Prisoned = ? WHERE `Character` = ?"
-		result, err := tx.Exec(query, data.JailTime, data.Prisoned, data.Character)
+		result, err := tx.ExecContext(ctx, query, data.JailTime, data.Prisoned, data.Character)
 		if err != nil {
 			return err
 		}
 		rows, err := result.RowsAffected()
 		if err != nil || rows == 0 {
-			return errors.New("no rows affected, expected one")
+			return model.ErrCharacterNotFound
 		}
-		return nil
+		return insertAudit(ctx, tx, audit)
 	})
 }
 
-func (r *UserRepository) FetchLogs(logsType string) ([]map[string]interface{}, error) {
-	q := fmt.Sprintf("SELECT * FROM %s ORDER BY ID DESC LIMIT 100", logsType)
-	rows, err := r.DB.Queryx(q)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// DeleteExp purges dead characters and the rejected ones whose last review is older
+// than rejectedRetention, which frees their names. The applications stay as history.
+func (r *UserRepository) DeleteExp(ctx context.Context) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
 
-	var logs []map[string]interface{}
-	for rows.Next() {
-		row := make(map[string]interface{})
-		if err = rows.MapScan(row); err != nil {
-			return nil, err
-		}
-		if row["IP"] != "" {
-			row["IP"] = "-"
-		}
-		logs = append(logs, row)
-	}
-
-	return logs, nil
-}
-
-func (r *UserRepository) DeleteExp() error {
-	return withTransaction(r.DB, func(tx *sqlx.Tx) error {
-		query := "DELETE FROM characters WHERE Created = -1"
-		_, err := tx.Exec(query)
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		query := "DELETE FROM characters WHERE Created = ?"
+		_, err := tx.ExecContext(ctx, query, CharacterDead)
 		if err != nil {
 			return err
 		}
-		return nil
+
+		query = "DELETE FROM characters WHERE Created = ? AND `Character` NOT IN " +
+			"(SELECT `Character` FROM ucp_applications WHERE ReviewedAt IS NULL OR ReviewedAt > ?)"
+		_, err = tx.ExecContext(ctx, query, CharacterRejected, time.Now().Add(-rejectedRetention))
+		return err
 	})
 }
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"sarp_backend/model"
+	"time"
+)
+
+const (
+	ApplicationPending   = "pending"
+	ApplicationAccepted  = "accepted"
+	ApplicationRejected  = "rejected"
+	ApplicationWithdrawn = "withdrawn"
+)
+
+const (
+	ReviewClaim   = "claim"
+	ReviewRelease = "release"
+	ReviewNote    = "note"
+	ReviewAccept  = "accept"
+	ReviewReject  = "reject"
+	// Edits and withdrawals are made by the player, Reviewer holds their account name.
+	ReviewEdit     = "edit"
+	ReviewWithdraw = "withdraw"
+)
+
+// CharacterRejected is the Created value of a rejected character, the row is kept so
+// the application and its review stay attached to it.
+const CharacterRejected = -2
+
+// rejectedRetention is how long a rejected character keeps its name for a resubmit
+// before DeleteExp purges it.
+const rejectedRetention = 30 * 24 * time.Hour
+
+// characterSlots counts the characters taking one of the account's slots, rejected
+// ones are only listed so they can be resubmitted.
+func characterSlots(characters []CharacterStatsDB) int {
+	slots := 0
+	for _, c := range characters {
+		if c.Created != CharacterRejected {
+			slots++
+		}
+	}
+	return slots
+}
+
+const applicationColumns = "ID, Username, `Character`, Version, Status, ClaimedBy, ClaimExpiresAt, ReviewedBy, Reason, ReviewedAt, CreatedAt"
+
+// lockApplication returns the latest application of character, locked until the
+// transaction ends.
+func lockApplication(ctx context.Context, tx *sqlx.Tx, character string) (*ApplicationDB, error) {
+	var data ApplicationDB
+	query := "SELECT " + applicationColumns + " FROM ucp_applications WHERE `Character` = ? ORDER BY ID DESC LIMIT 1 FOR UPDATE"
+	if err := tx.GetContext(ctx, &data, query, character); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrCharacterNotFound
+		}
+		return nil, err
+	}
+	return &data, nil
+}
+
+// activeClaim is the reviewer holding the application, empty once the lease ran out.
+// The database clock decides so every instance agrees on the expiry.
+func activeClaim(ctx context.Context, tx *sqlx.Tx, id int64) (string, error) {
+	var holder string
+	query := "SELECT IF(ClaimExpiresAt > NOW(), ClaimedBy, '') FROM ucp_applications WHERE ID = ?"
+	if err := tx.GetContext(ctx, &holder, query, id); err != nil {
+		return "", err
+	}
+	return holder, nil
+}
+
+func insertReviewEvent(ctx context.Context, tx *sqlx.Tx, application *ApplicationDB, reviewer, action, note string) error {
+	query := "INSERT INTO ucp_review_events (ApplicationID, `Character`, Reviewer, Action, Note) VALUES (?, ?, ?, ?, ?)"
+	_, err := tx.ExecContext(ctx, query, application.ID, application.Character, reviewer, action, model.Truncate(note, 1000))
+	return err
+}
+
+// reviewApplication closes the pending application of character with status. It fails
+// while another reviewer holds the claim, characters without an application have
+// nothing to close.
+func reviewApplication(ctx context.Context, tx *sqlx.Tx, character, reviewer, status, reason string) error {
+	application, err := lockApplication(ctx, tx, character)
+	if errors.Is(err, model.ErrCharacterNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if application.Status != ApplicationPending {
+		return model.ErrCharacterNotFound
+	}
+
+	holder, err := activeClaim(ctx, tx, application.ID)
+	if err != nil {
+		return err
+	}
+	if holder != "" && holder != reviewer {
+		return model.ErrApplicationClaimed
+	}
+
+	query := "UPDATE ucp_applications SET Status = ?, ReviewedBy = ?, Reason = ?, ReviewedAt = NOW(), ClaimedBy = '', ClaimExpiresAt = NULL WHERE ID = ?"
+	if _, err = tx.ExecContext(ctx, query, status, reviewer, model.Truncate(reason, 500), application.ID); err != nil {
+		return err
+	}
+
+	action := ReviewAccept
+	if status == ApplicationRejected {
+		action = ReviewReject
+	}
+	return insertReviewEvent(ctx, tx, application, reviewer, action, reason)
+}
+
+// ClaimApplication gives reviewer the pending application of character for lease.
+// Claiming again before the lease runs out extends it.
+func (r *UserRepository) ClaimApplication(ctx context.Context, character, reviewer string, lease time.Duration) (*ApplicationDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var claimed ApplicationDB
+	err := withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		application, err := lockApplication(ctx, tx, character)
+		if err != nil {
+			return err
+		}
+		if application.Status != ApplicationPending {
+			return model.ErrCharacterNotFound
+		}
+
+		holder, err := activeClaim(ctx, tx, application.ID)
+		if err != nil {
+			return err
+		}
+		if holder != "" && holder != reviewer {
+			return model.ErrApplicationClaimed
+		}
+
+		query := "UPDATE ucp_applications SET ClaimedBy = ?, ClaimExpiresAt = DATE_ADD(NOW(), INTERVAL ? SECOND) WHERE ID = ?"
+		if _, err = tx.ExecContext(ctx, query, reviewer, int(lease.Seconds()), application.ID); err != nil {
+			return err
+		}
+		if err = insertReviewEvent(ctx, tx, application, reviewer, ReviewClaim, ""); err != nil {
+			return err
+		}
+
+		return tx.GetContext(ctx, &claimed, "SELECT "+applicationColumns+" FROM ucp_applications WHERE ID = ?", application.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &claimed, nil
+}
+
+func (r *UserRepository) ReleaseApplication(ctx context.Context, character, reviewer string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		application, err := lockApplication(ctx, tx, character)
+		if err != nil {
+			return err
+		}
+
+		holder, err := activeClaim(ctx, tx, application.ID)
+		if err != nil {
+			return err
+		}
+		if application.Status != ApplicationPending || holder != reviewer {
+			return model.ErrApplicationNotClaimed
+		}
+
+		query := "UPDATE ucp_applications SET ClaimedBy = '', ClaimExpiresAt = NULL WHERE ID = ?"
+		if _, err = tx.ExecContext(ctx, query, application.ID); err != nil {
+			return err
+		}
+		return insertReviewEvent(ctx, tx, application, reviewer, ReviewRelease, "")
+	})
+}
+
+// AddReviewNote attaches an internal note to the latest application of character,
+// whatever its status and whoever holds the claim.
+func (r *UserRepository) AddReviewNote(ctx context.Context, character, reviewer, note string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		application, err := lockApplication(ctx, tx, character)
+		if err != nil {
+			return err
+		}
+		return insertReviewEvent(ctx, tx, application, reviewer, ReviewNote, note)
+	})
+}
+
+// FetchReviewHistory returns every application of character and the review events
+// of all of them, oldest first.
+func (r *UserRepository) FetchReviewHistory(ctx context.Context, character string) ([]ApplicationDB, []ReviewEventDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var applications []ApplicationDB
+	query := "SELECT " + applicationColumns + " FROM ucp_applications WHERE `Character` = ? ORDER BY ID"
+	if err := r.DB.SelectContext(ctx, &applications, query, character); err != nil {
+		return nil, nil, err
+	}
+	if err := r.fetchAnswers(ctx, applications); err != nil {
+		return nil, nil, err
+	}
+
+	var events []ReviewEventDB
+	query = "SELECT ID, ApplicationID, `Character`, Reviewer, Action, Note, CreatedAt FROM ucp_review_events WHERE `Character` = ? ORDER BY ID"
+	if err := r.DB.SelectContext(ctx, &events, query, character); err != nil {
+		return nil, nil, err
+	}
+
+	return applications, events, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+func (r *UserRepository) FetchSessionData(ctx context.Context, id string) ([]byte, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var data []byte
+	query := "SELECT Data FROM ucp_sessions WHERE ID = ? AND (ExpiresAt = 0 OR ExpiresAt > ?)"
+	if err := r.DB.GetContext(ctx, &data, query, id, time.Now().Unix()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (r *UserRepository) SaveSessionData(ctx context.Context, id string, data []byte, expiresAt int64) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "INSERT INTO ucp_sessions (ID, Data, ExpiresAt) VALUES (?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE Data = VALUES(Data), ExpiresAt = VALUES(ExpiresAt)"
+	_, err := r.DB.ExecContext(ctx, query, id, data, expiresAt)
+	return err
+}
+
+func (r *UserRepository) DeleteSessionData(ctx context.Context, id string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, "DELETE FROM ucp_sessions WHERE ID = ?", id)
+	return err
+}
+
+func (r *UserRepository) DeleteAllSessions(ctx context.Context) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, "DELETE FROM ucp_sessions")
+	return err
+}
+
+func (r *UserRepository) DeleteExpiredSessions(ctx context.Context, now int64) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, "DELETE FROM ucp_sessions WHERE ExpiresAt <> 0 AND ExpiresAt <= ?", now)
+	return err
+}
+
+// TouchSession stores the metadata shown in the active sessions list, last
+// seen is only refreshed once per minute to avoid a write on every request.
+func (r *UserRepository) TouchSession(ctx context.Context, id, name, ip, userAgent string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "UPDATE ucp_sessions SET Username = ?, IP = ?, UserAgent = ?, LastSeen = NOW() " +
+		"WHERE ID = ? AND (Username <> ? OR LastSeen < DATE_SUB(NOW(), INTERVAL 1 MINUTE))"
+	_, err := r.DB.ExecContext(ctx, query, name, ip, userAgent, id, name)
+	return err
+}
+
+func (r *UserRepository) FetchSessions(ctx context.Context, name string) ([]SessionDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var sessions []SessionDB
+	query := "SELECT ID, Username, IP, UserAgent, CreatedAt, LastSeen FROM ucp_sessions " +
+		"WHERE Username = ? AND (ExpiresAt = 0 OR ExpiresAt > ?) ORDER BY LastSeen DESC"
+	if err := r.DB.SelectContext(ctx, &sessions, query, name, time.Now().Unix()); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (r *UserRepository) DeleteSession(ctx context.Context, name, id string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM ucp_sessions WHERE Username = ? AND ID = ?", name, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return errors.New("no rows affected, expected one")
+	}
+	return nil
+}
+
+func (r *UserRepository) DeleteOtherSessions(ctx context.Context, name, keepID string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, "DELETE FROM ucp_sessions WHERE Username = ? AND ID <> ?", name, keepID)
+	return err
+}
+
+func (r *UserRepository) DeleteUserSessions(ctx context.Context, name string, audit *AuditDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM ucp_sessions WHERE Username = ?", name); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, audit)
+	})
+}
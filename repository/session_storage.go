@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// SessionStorage implements fiber.Storage on top of the ucp_sessions table so
+// sessions survive restarts of the service. fiber.Storage has no context, the
+// queries are only bounded by the repository timeout.
+type SessionStorage struct {
+	repo       SessionRepository
+	gcInterval time.Duration
+	done       chan struct{}
+}
+
+func NewSessionStorage(repo SessionRepository, gcInterval time.Duration) *SessionStorage {
+	s := &SessionStorage{
+		repo:       repo,
+		gcInterval: gcInterval,
+		done:       make(chan struct{}),
+	}
+
+	go s.gc()
+
+	return s
+}
+
+func (s *SessionStorage) Get(key string) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+
+	return s.repo.FetchSessionData(context.Background(), key)
+}
+
+func (s *SessionStorage) Set(key string, val []byte, exp time.Duration) error {
+	if len(key) == 0 || len(val) == 0 {
+		return nil
+	}
+
+	var expiresAt int64
+	if exp != 0 {
+		expiresAt = time.Now().Add(exp).Unix()
+	}
+
+	return s.repo.SaveSessionData(context.Background(), key, val, expiresAt)
+}
+
+func (s *SessionStorage) Delete(key string) error {
+	if len(key) == 0 {
+		return nil
+	}
+
+	return s.repo.DeleteSessionData(context.Background(), key)
+}
+
+func (s *SessionStorage) Reset() error {
+	return s.repo.DeleteAllSessions(context.Background())
+}
+
+func (s *SessionStorage) Close() error {
+	close(s.done)
+	return nil
+}
+
+func (s *SessionStorage) gc() {
+	ticker := time.NewTicker(s.gcInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case t := <-ticker.C:
+			_ = s.repo.DeleteExpiredSessions(context.Background(), t.Unix())
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"sarp_backend/model"
+	"time"
+)
+
+const (
+	TokenPurposeConfirm = "confirm"
+	TokenPurposeReset   = "reset"
+	TokenPurposeEmail   = "email_change"
+)
+
+func (r *UserRepository) CreateToken(ctx context.Context, data *TokenDB) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "INSERT INTO ucp_tokens (Purpose, TokenHash, Username, Email, ExpiresAt) VALUES (?, ?, ?, ?, ?)"
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, query, data.Purpose, data.TokenHash, data.Username, data.Email, data.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil || rows == 0 {
+			return errors.New("no rows affected, expected one")
+		}
+		return nil
+	})
+}
+
+func (r *UserRepository) FetchToken(ctx context.Context, purpose, hash string) (*TokenDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var data TokenDB
+	query := "SELECT ID, Purpose, TokenHash, Username, Email, ExpiresAt, ConsumedAt, CreatedAt FROM ucp_tokens WHERE Purpose = ? AND TokenHash = ?"
+	if err := r.DB.GetContext(ctx, &data, query, purpose, hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// ConsumeToken marks the token as used, it fails if someone else consumed it in the meantime.
+func (r *UserRepository) ConsumeToken(ctx context.Context, id int64) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		query := "UPDATE ucp_tokens SET ConsumedAt = NOW() WHERE ID = ? AND ConsumedAt IS NULL AND ExpiresAt > NOW()"
+		result, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil || rows == 0 {
+			return fmt.Errorf("%w: already consumed", model.ErrTokenInvalid)
+		}
+		return nil
+	})
+}
+
+// CountTokensSince counts the tokens issued for the address after since, used or not.
+func (r *UserRepository) CountTokensSince(ctx context.Context, purpose, email string, since time.Time) (int, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var count int
+	query := "SELECT COUNT(*) FROM ucp_tokens WHERE Purpose = ? AND Email = ? AND CreatedAt > ?"
+	if err := r.DB.GetContext(ctx, &count, query, purpose, email, since); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func invalidateTokens(ctx context.Context, tx *sqlx.Tx, purpose, email string) error {
+	query := "UPDATE ucp_tokens SET ConsumedAt = NOW() WHERE Purpose = ? AND Email = ? AND ConsumedAt IS NULL"
+	_, err := tx.ExecContext(ctx, query, purpose, email)
+	return err
+}
+
+func (r *UserRepository) FetchUsername(ctx context.Context, email string) (string, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var name string
+	query := "SELECT Username FROM accounts WHERE Email = ?"
+	if err := r.DB.GetContext(ctx, &name, query, email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return name, nil
+}
+
+func (r *UserRepository) DeleteExpiredTokens(ctx context.Context) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, "DELETE FROM ucp_tokens WHERE ExpiresAt < DATE_SUB(NOW(), INTERVAL 7 DAY)")
+	return err
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"sarp_backend/model"
+)
+
+func (r *UserRepository) FetchTwoFactor(ctx context.Context, name string) (*TwoFactorDB, error) {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	var data TwoFactorDB
+	query := "SELECT Username, Secret, Enabled, LastStep FROM ucp_two_factor WHERE Username = ?"
+	if err := r.DB.GetContext(ctx, &data, query, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+func (r *UserRepository) SaveTwoFactorSecret(ctx context.Context, name, secret string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	query := "INSERT INTO ucp_two_factor (Username, Secret, Enabled, LastStep) VALUES (?, ?, 0, 0) " +
+		"ON DUPLICATE KEY UPDATE Secret = VALUES(Secret), Enabled = 0, LastStep = 0"
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, query, name, secret); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *UserRepository) EnableTwoFactor(ctx context.Context, name string, step int64, recoveryHashes []string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		query := "UPDATE ucp_two_factor SET Enabled = 1, LastStep = ? WHERE Username = ? AND Enabled = 0"
+		result, err := tx.ExecContext(ctx, query, step, name)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil || rows == 0 {
+			return errors.New("no rows affected, expected one")
+		}
+
+		if _, err = tx.ExecContext(ctx, "DELETE FROM ucp_recovery_codes WHERE Username = ?", name); err != nil {
+			return err
+		}
+
+		for _, hash := range recoveryHashes {
+			if _, err = tx.ExecContext(ctx, "INSERT INTO ucp_recovery_codes (Username, CodeHash) VALUES (?, ?)", name, hash); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UseTwoFactorStep only advances the last used step, so a code can't be replayed inside its window.
+func (r *UserRepository) UseTwoFactorStep(ctx context.Context, name string, step int64) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		query := "UPDATE ucp_two_factor SET LastStep = ? WHERE Username = ? AND Enabled = 1 AND LastStep < ?"
+		result, err := tx.ExecContext(ctx, query, step, name, step)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil || rows == 0 {
+			return fmt.Errorf("%w: code already used", model.ErrInvalidTwoFactorCode)
+		}
+		return nil
+	})
+}
+
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, name, hash string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		query := "UPDATE ucp_recovery_codes SET UsedAt = NOW() WHERE Username = ? AND CodeHash = ? AND UsedAt IS NULL"
+		result, err := tx.ExecContext(ctx, query, name, hash)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil || rows == 0 {
+			return fmt.Errorf("%w: invalid recovery code", model.ErrInvalidTwoFactorCode)
+		}
+		return nil
+	})
+}
+
+func (r *UserRepository) DisableTwoFactor(ctx context.Context, name string) error {
+	ctx, cancel := r.queryContext(ctx)
+	defer cancel()
+
+	return withTransaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM ucp_recovery_codes WHERE Username = ?", name); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, "DELETE FROM ucp_two_factor WHERE Username = ?", name)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil || rows == 0 {
+			return errors.New("no rows affected, expected one")
+		}
+		return nil
+	})
+}
@@ -8,21 +8,24 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/session"
+	"io"
 	"log"
 	"os"
 	"os/signal"
 	config "sarp_backend/config"
 	"sarp_backend/handler"
+	"sarp_backend/i18n"
 	"sarp_backend/repository"
 	"sarp_backend/service"
+	"strings"
 	"syscall"
 	"time"
 )
 
-func StartServer() {
-	cfg, errRead := config.Read("./cfg.json")
+func StartServer(configPath string) {
+	cfg, errRead := config.Read(configPath)
 	if errRead != nil {
-		log.Fatalf("error reading cfg.json: %v", errRead)
+		log.Fatalf("error reading %s:\n%v", configPath, errRead)
 	}
 
 	logFileName := "log_" + time.Now().Format("2006-01-02_15-04-05") + ".log"
@@ -32,23 +35,92 @@ func StartServer() {
 	}
 	defer loggerService.Shutdown()
 
-	ucpRepo, errRepo := repository.New(cfg.Dsn)
+	ucpRepo, errRepo := repository.New(cfg.Dsn, time.Duration(cfg.QueryTimeout)*time.Second)
 	if errRepo != nil {
 		log.Fatalf("error creating repository: %v", errRepo)
 		return
 	}
 
-	userService := service.NewUserService(ucpRepo)
-	charService := service.NewCharacterService(ucpRepo)
-	emailService := service.NewEmailService(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPFrom)
+	passwordHasher, errHasher := service.NewPasswordHasher(cfg.PasswordHash)
+	if errHasher != nil {
+		log.Fatalf("error creating password hasher: %v", errHasher)
+	}
+
+	catalog, errCatalog := i18n.Load()
+	if errCatalog != nil {
+		log.Fatalf("error loading message catalog: %v", errCatalog)
+	}
+
+	emailTemplates, errTemplates := service.NewEmailTemplates(catalog, cfg.EmailTemplatesDir)
+	if errTemplates != nil {
+		log.Fatalf("error loading email templates: %v", errTemplates)
+	}
+
+	userService := service.NewUserService(ucpRepo, passwordHasher)
+	charService := service.NewCharacterService(ucpRepo, service.CharacterConfig{
+		ClaimLease:       time.Duration(cfg.ReviewClaimMinutes) * time.Minute,
+		ResubmitCooldown: time.Duration(cfg.ResubmitCooldownHours) * time.Hour,
+	})
+	emailService, errEmail := service.NewEmailTransport(service.EmailTransportConfig{
+		Transport:    cfg.EmailTransport,
+		From:         cfg.SMTPFrom,
+		SMTPHost:     cfg.SMTPHost,
+		SMTPPort:     cfg.SMTPPort,
+		SMTPUser:     cfg.SMTPUser,
+		SMTPPassword: cfg.SMTPPassword,
+		SMTPSecurity: cfg.SMTPSecurity,
+		SMTPIdle:     time.Duration(cfg.SMTPIdle) * time.Second,
+		FileDir:      cfg.EmailFileDir,
+	})
+	if errEmail != nil {
+		log.Fatalf("error creating email transport: %v", errEmail)
+	}
+	sessionStorage := repository.NewSessionStorage(ucpRepo, 10*time.Minute)
+	defer sessionStorage.Close()
+
 	authService := service.NewAuthService(session.New(session.Config{
+		Storage:        sessionStorage,
+		Expiration:     time.Duration(cfg.SessionExpiryHours) * time.Hour,
 		CookieSecure:   true,
 		CookieHTTPOnly: true,
 		CookieSameSite: "Strict",
-	}))
-	authMiddleware := service.NewMiddleware(authService)
+	}), ucpRepo)
+	twoFactorService := service.NewTwoFactorService(ucpRepo, cfg.TwoFactorIssuer)
+	permissionService := service.NewPermissionService(cfg.AdminPermissions, cfg.TesterPermissions)
+	authMiddleware := service.NewMiddleware(authService, userService, permissionService, catalog, cfg.TwoFactorRequiredStaff)
+
+	outboxService := service.NewOutboxService(ucpRepo, emailService, service.OutboxConfig{
+		Workers:      cfg.OutboxWorkers,
+		BatchSize:    cfg.OutboxBatchSize,
+		PollInterval: time.Duration(cfg.OutboxPoll) * time.Second,
+		BaseDelay:    time.Duration(cfg.OutboxBaseDelay) * time.Second,
+		MaxDelay:     time.Duration(cfg.OutboxMaxDelay) * time.Second,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+		Lease:        time.Duration(cfg.OutboxLease) * time.Second,
+	})
+	outboxService.Start()
+
+	guardService := service.NewLoginGuardService(ucpRepo, outboxService, emailTemplates, service.LoginGuardConfig{
+		FreeAttempts:   cfg.LoginFreeAttempts,
+		LockAttempts:   cfg.LoginLockAttempts,
+		IPLockAttempts: cfg.LoginIPLockAttempts,
+		BaseDelay:      time.Duration(cfg.LoginBaseDelay) * time.Second,
+		MaxDelay:       time.Duration(cfg.LoginMaxDelay) * time.Second,
+		LockDuration:   time.Duration(cfg.LoginLockMinutes) * time.Minute,
+		ResetAfter:     time.Duration(cfg.LoginResetMinutes) * time.Minute,
+	})
 
-	ucpHandler := handler.New(userService, charService, authService, loggerService, emailService)
+	tokenService := service.NewTokenService(ucpRepo, cfg.TokenSecret, map[string]time.Duration{
+		service.TokenConfirm: time.Duration(cfg.ConfirmTokenMinutes) * time.Minute,
+		service.TokenReset:   time.Duration(cfg.ResetTokenMinutes) * time.Minute,
+		service.TokenEmail:   time.Duration(cfg.EmailTokenMinutes) * time.Minute,
+	})
+
+	ucpHandler := handler.New(userService, charService, authService, loggerService, outboxService, twoFactorService, guardService, tokenService, permissionService, service.NewAuditService(ucpRepo), catalog, emailTemplates, handler.Settings{
+		PublicURL:           cfg.PublicURL,
+		ConfirmEmailsLimit:  cfg.ConfirmEmailsPerMail,
+		ConfirmEmailsWindow: time.Duration(cfg.ConfirmEmailsPerWindow) * time.Second,
+	})
 
 	fiberConfig := fiber.Config{
 		BodyLimit:               4 * 1024 * 10,
@@ -59,7 +131,8 @@ func StartServer() {
 		WriteBufferSize:         4 * 1024 * 10,
 		Prefork:                 false,
 		EnableTrustedProxyCheck: true,
-		TrustedProxies:          []string{"127.0.0.1", "::1"},
+		TrustedProxies:          cfg.TrustedProxies,
+		ProxyHeader:             cfg.ProxyHeader,
 	}
 	app := fiber.New(fiberConfig)
 	app.Use(logger.New(), compress.New())
@@ -67,32 +140,24 @@ func StartServer() {
 	app.Use(cors.New(cors.Config{
 		AllowMethods: "GET,POST",
 		AllowHeaders: "Origin, Content-Type, Accept",
-		AllowOrigins: "https://app.ro",
+		AllowOrigins: strings.Join(cfg.CORSOrigins, ","),
 	}))
 
 	app.Use(limiter.New(limiter.Config{
-		Max:        500,
-		Expiration: 1 * time.Hour,
-		KeyGenerator: func(ctx *fiber.Ctx) string {
-			realIP := ctx.Get("X-Real-IP")
-			if realIP == "" {
-				realIP = ctx.IP()
-			}
-			return realIP
-		},
+		Max:          cfg.RateLimitMax,
+		Expiration:   time.Duration(cfg.RateLimitWindow) * time.Second,
+		KeyGenerator: service.ClientIP,
 		LimitReached: func(ctx *fiber.Ctx) error {
-			ip := ctx.Get("X-Real-IP")
-			if ip == "" {
-				ip = ctx.IP()
-			}
-			loggerService.Info(fmt.Sprintf("Rate limit reached for IP: %s", ip))
+			loggerService.Info(fmt.Sprintf("Rate limit reached for IP: %s", service.ClientIP(ctx)))
 			return ctx.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
 				"error":   true,
-				"message": "You've reached the limit of HTTP requests. Try again later.",
+				"message": catalog.T(catalog.Match(ctx.Get(fiber.HeaderAcceptLanguage)), "rate_limit.reached"),
 			})
 		},
 	}))
 
+	app.Use(service.RequestContext(time.Duration(cfg.RequestTimeout) * time.Second))
+
 	// Serve static files from the "build" directory
 	app.Static("/", cfg.FEPath)
 
@@ -112,7 +177,13 @@ func StartServer() {
 		return ctx.Type("html").SendString(html)
 	})
 
-	SetupRoutes(app, authMiddleware, ucpHandler)
+	SetupRoutes(app, cfg, authMiddleware, ucpHandler)
+
+	if mailbox, ok := emailService.(*service.MemoryTransport); ok && cfg.DevMode {
+		mailboxHandler := handler.NewMailboxHandler(mailbox)
+		app.Get("/dev/mailbox", mailboxHandler.List)
+		app.Get("/dev/mailbox/:id", mailboxHandler.Show)
+	}
 
 	// Route for 404
 	app.Get("/*", func(c *fiber.Ctx) error {
@@ -160,14 +231,19 @@ func StartServer() {
 		loggerService.Exception(fmt.Sprintf("error during shutdown: %v", err))
 	}
 
+	outboxService.Close()
+	if closer, ok := emailService.(io.Closer); ok {
+		_ = closer.Close()
+	}
 	close(done)
 	os.Exit(1)
 }
 
-func SetupRoutes(app *fiber.App, authMiddleware *service.Middleware, ucpHandler *handler.UserHandler) {
+func SetupRoutes(app *fiber.App, cfg *config.Config, authMiddleware *service.Middleware, ucpHandler *handler.UserHandler) {
 	api := app.Group("internal-ucp-api")
 
 	v1 := api.Group("v1")
+	v1.Use(authMiddleware.Localize)
 
 	v1.Use("/register", authMiddleware.EnsureLoggedOut)
 	v1.Post("/register", ucpHandler.Register) // Apel direct la handler
@@ -175,6 +251,12 @@ func SetupRoutes(app *fiber.App, authMiddleware *service.Middleware, ucpHandler
 	v1.Use("/confirm", authMiddleware.EnsureLoggedOut)
 	v1.Get("/confirm", ucpHandler.Confirm)
 
+	v1.Use("/resend-confirmation", authMiddleware.EnsureLoggedOut)
+	v1.Get("/resend-confirmation", authMiddleware.RateLimit(cfg.ResendConfirmMax, time.Duration(cfg.ResendConfirmWindow)*time.Second), ucpHandler.ResendConfirmation)
+
+	// Opened from the new mailbox, with or without a session.
+	v1.Get("/verify-email", ucpHandler.VerifyEmail)
+
 	v1.Use("/reset-request", authMiddleware.EnsureLoggedOut)
 	v1.Get("/reset-request", ucpHandler.ResetRequest)
 
@@ -187,6 +269,20 @@ func SetupRoutes(app *fiber.App, authMiddleware *service.Middleware, ucpHandler
 	v1.Use("/login", authMiddleware.EnsureLoggedOut)
 	v1.Post("/login", ucpHandler.Login)
 
+	v1.Use("/verify-two-factor", authMiddleware.EnsureTwoFactorPending)
+	v1.Post("/verify-two-factor", ucpHandler.VerifyTwoFactor)
+
+	v1.Use("/two-factor", authMiddleware.EnsureAuthenticated)
+	v1.Get("/two-factor/status", ucpHandler.TwoFactorStatus)
+	v1.Post("/two-factor/enroll", ucpHandler.EnrollTwoFactor)
+	v1.Post("/two-factor/confirm", ucpHandler.ConfirmTwoFactor)
+	v1.Post("/two-factor/disable", ucpHandler.DisableTwoFactor)
+
+	v1.Use("/sessions", authMiddleware.EnsureAuthenticated)
+	v1.Get("/sessions", ucpHandler.Sessions)
+	v1.Post("/sessions/revoke", ucpHandler.RevokeSession)
+	v1.Post("/sessions/revoke-others", ucpHandler.RevokeOtherSessions)
+
 	v1.Use("/logout", authMiddleware.EnsureAuthenticated)
 	v1.Post("/logout", ucpHandler.Logout)
 
@@ -202,21 +298,66 @@ func SetupRoutes(app *fiber.App, authMiddleware *service.Middleware, ucpHandler
 	v1.Use("/server-stats", authMiddleware.EnsureAuthenticated)
 	v1.Get("/server-stats", ucpHandler.ServerStats)
 
+	v1.Use("/account", authMiddleware.EnsureAuthenticated)
+	v1.Post("/account/locale", ucpHandler.SetLocale)
+	v1.Post("/account/email", ucpHandler.ChangeEmail)
+	v1.Post("/account/password", ucpHandler.ChangePassword)
+	v1.Get("/account/profile", ucpHandler.Profile)
+	v1.Post("/account/profile", ucpHandler.UpdateProfile)
+
 	v1.Use("/create-character", authMiddleware.EnsureAuthenticated)
 	v1.Post("/create-character", ucpHandler.CreateCharacter)
 
-	v1.Use("/restricted", func(ctx *fiber.Ctx) error {
-		return authMiddleware.EnsurePrivilege(ctx)
-	})
+	v1.Use("/character-application", authMiddleware.EnsureAuthenticated)
+	v1.Get("/character-application", ucpHandler.CharacterApplication)
+
+	v1.Use("/edit-character", authMiddleware.EnsureAuthenticated)
+	v1.Post("/edit-character", ucpHandler.EditCharacter)
+
+	v1.Use("/withdraw-character", authMiddleware.EnsureAuthenticated)
+	v1.Post("/withdraw-character", ucpHandler.WithdrawCharacter)
+
+	v1.Use("/resubmit-character", authMiddleware.EnsureAuthenticated)
+	v1.Post("/resubmit-character", ucpHandler.ResubmitCharacter)
+
+	v1.Use("/name-changes", authMiddleware.EnsureAuthenticated)
+	v1.Get("/name-changes", ucpHandler.NameChanges)
+	v1.Post("/name-changes/request", ucpHandler.RequestNameChange)
+
+	v1.Use("/ck-requests", authMiddleware.EnsureAuthenticated)
+	v1.Get("/ck-requests", ucpHandler.CKRequests)
+	v1.Post("/ck-requests/request", ucpHandler.RequestCK)
+
+	v1.Use("/questionnaire", authMiddleware.EnsureAuthenticated)
+	v1.Get("/questionnaire", ucpHandler.Questionnaire)
+
+	v1.Use("/restricted", authMiddleware.RequirePermission(service.PermStaffPanel))
 
 	v1.Get("/restricted/check", ucpHandler.CheckAdmin)
-	v1.Get("/restricted/waiting-list", ucpHandler.WaitingList)
-	v1.Post("/restricted/accept-character", ucpHandler.AcceptCharacter)
-	v1.Post("/restricted/reject-character", ucpHandler.RejectCharacter)
-	v1.Post("/restricted/fetch-character", ucpHandler.FetchCharacter)
-	v1.Get("/restricted/ban-list", ucpHandler.BanList)
-	v1.Post("/restricted/ban", ucpHandler.Ban)
-	v1.Post("/restricted/unban", ucpHandler.Unban)
-	v1.Post("/restricted/ajail", ucpHandler.Ajail)
+	v1.Get("/restricted/waiting-list", authMiddleware.RequirePermission(service.PermCharacterReview), ucpHandler.WaitingList)
+	v1.Post("/restricted/accept-character", authMiddleware.RequirePermission(service.PermCharacterReview), ucpHandler.AcceptCharacter)
+	v1.Post("/restricted/reject-character", authMiddleware.RequirePermission(service.PermCharacterReview), ucpHandler.RejectCharacter)
+	v1.Post("/restricted/reviews/claim", authMiddleware.RequirePermission(service.PermCharacterReview), ucpHandler.ClaimApplication)
+	v1.Post("/restricted/reviews/release", authMiddleware.RequirePermission(service.PermCharacterReview), ucpHandler.ReleaseApplication)
+	v1.Post("/restricted/reviews/note", authMiddleware.RequirePermission(service.PermCharacterReview), ucpHandler.AddReviewNote)
+	v1.Get("/restricted/reviews/history", authMiddleware.RequirePermission(service.PermCharacterReview), ucpHandler.ReviewHistory)
+	v1.Get("/restricted/name-changes", authMiddleware.RequirePermission(service.PermNameChangeReview), ucpHandler.NameChangeQueue)
+	v1.Post("/restricted/name-changes/approve", authMiddleware.RequirePermission(service.PermNameChangeReview), ucpHandler.ApproveNameChange)
+	v1.Post("/restricted/name-changes/deny", authMiddleware.RequirePermission(service.PermNameChangeReview), ucpHandler.DenyNameChange)
+	v1.Get("/restricted/ck-requests", authMiddleware.RequirePermission(service.PermCKReview), ucpHandler.CKQueue)
+	v1.Post("/restricted/ck-requests/approve", authMiddleware.RequirePermission(service.PermCKReview), ucpHandler.ApproveCK)
+	v1.Post("/restricted/ck-requests/deny", authMiddleware.RequirePermission(service.PermCKReview), ucpHandler.DenyCK)
+	v1.Post("/restricted/questionnaire", authMiddleware.RequirePermission(service.PermQuestionnaireManage), ucpHandler.UpdateQuestionnaire)
+	v1.Post("/restricted/fetch-character", authMiddleware.RequirePermission(service.PermCharacterRead), ucpHandler.FetchCharacter)
+	v1.Get("/restricted/ban-list", authMiddleware.RequirePermission(service.PermBanList), ucpHandler.BanList)
+	v1.Post("/restricted/ban", authMiddleware.RequirePermission(service.PermBanCreate), ucpHandler.Ban)
+	v1.Post("/restricted/unban", authMiddleware.RequirePermission(service.PermBanRevoke), ucpHandler.Unban)
+	v1.Post("/restricted/ajail", authMiddleware.RequirePermission(service.PermAjail), ucpHandler.Ajail)
+	v1.Post("/restricted/force-logout", authMiddleware.RequirePermission(service.PermSessionRevoke), ucpHandler.ForceLogout)
+	v1.Post("/restricted/clear-lockout", authMiddleware.RequirePermission(service.PermLockoutClear), ucpHandler.ClearLockout)
+	// The table is only known from the body, Logs checks logs.read:<table> itself.
 	v1.Post("/restricted/logs", ucpHandler.Logs)
+	v1.Get("/restricted/audit", authMiddleware.RequirePermission(service.PermAuditRead), ucpHandler.AuditLog)
+	v1.Get("/restricted/email-outbox", authMiddleware.RequirePermission(service.PermEmailManage), ucpHandler.EmailOutbox)
+	v1.Post("/restricted/email-outbox/retry", authMiddleware.RequirePermission(service.PermEmailManage), ucpHandler.RetryEmail)
 }
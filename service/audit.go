@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+)
+
+const (
+	AuditCharacterAccept   = "character.accept"
+	AuditCharacterReject   = "character.reject"
+	AuditBanCreate         = "ban.create"
+	AuditBanRevoke         = "ban.revoke"
+	AuditAjail             = "ajail"
+	AuditLogsRead          = "logs.read"
+	AuditSessionRevoke     = "session.revoke"
+	AuditLockoutClear      = "lockout.clear"
+	AuditEmailRetry        = "email.retry"
+	AuditUserPromote       = "user.promote"
+	AuditUserDemote        = "user.demote"
+	AuditPasswordReset     = "user.password_reset"
+	AuditQuestionnaire     = "questionnaire.update"
+	AuditNameChangeApprove = "namechange.approve"
+	AuditNameChangeDeny    = "namechange.deny"
+	AuditCKApprove         = "ck.approve"
+	AuditCKDeny            = "ck.deny"
+)
+
+type AuditService struct {
+	userRepository repository.LogRepository
+}
+
+func NewAuditService(repo repository.LogRepository) *AuditService {
+	return &AuditService{userRepository: repo}
+}
+
+// Record is for actions that don't change anything, mutations pass their entry
+// down to the repository so it is written in the same transaction.
+func (a *AuditService) Record(ctx context.Context, data *model.AuditAPI) error {
+	return a.userRepository.CreateAudit(ctx, auditRecord(data))
+}
+
+func (a *AuditService) List(ctx context.Context, filter *model.AuditFilterAPI) (*model.AuditPageAPI, error) {
+	entries, total, err := a.userRepository.FetchAudit(ctx, &repository.AuditFilterDB{
+		Actor:  filter.Actor,
+		Action: filter.Action,
+		Target: filter.Target,
+		From:   filter.From,
+		To:     filter.To,
+		Limit:  filter.PerPage,
+		Offset: (filter.Page - 1) * filter.PerPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.AuditPageAPI{
+		Entries: []model.AuditAPI{},
+		Page:    filter.Page,
+		PerPage: filter.PerPage,
+		Total:   total,
+	}
+	for _, e := range entries {
+		page.Entries = append(page.Entries, model.AuditAPI{
+			ID:        e.ID,
+			Actor:     e.Actor,
+			Action:    e.Action,
+			Target:    e.Target,
+			Payload:   e.Payload,
+			IP:        e.IP,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+
+	return page, nil
+}
+
+func auditRecord(data *model.AuditAPI) *repository.AuditDB {
+	if data == nil {
+		return nil
+	}
+
+	return &repository.AuditDB{
+		Actor:   data.Actor,
+		Action:  data.Action,
+		Target:  data.Target,
+		Payload: data.Payload,
+		IP:      data.IP,
+	}
+}
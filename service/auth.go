@@ -1,29 +1,34 @@
 package service
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
-	"time"
+	"sarp_backend/model"
+	"sarp_backend/repository"
 )
 
 type AuthService struct {
-	Store *session.Store
+	Store          *session.Store
+	userRepository repository.SessionRepository
 }
 
-func NewAuthService(store *session.Store) *AuthService {
-	return &AuthService{Store: store}
+func NewAuthService(store *session.Store, repo repository.SessionRepository) *AuthService {
+	return &AuthService{Store: store, userRepository: repo}
 }
 
-func (a *AuthService) CheckSession(ctx *fiber.Ctx) (string, bool, bool, error) {
+func (a *AuthService) CheckSession(ctx *fiber.Ctx) (string, int, int, error) {
 	var name string
-	var isAdmin, isTester bool
+	var adminLevel, testerLevel int
 
 	sess, err := a.Store.Get(ctx)
 	if err != nil {
 		globalLogger.Exception(err.Error())
-		return name, isAdmin, isTester, err
+		return name, adminLevel, testerLevel, err
 	}
 
 	if r := sess.Get("name"); r != nil {
@@ -32,44 +37,87 @@ func (a *AuthService) CheckSession(ctx *fiber.Ctx) (string, bool, bool, error) {
 		if !ok {
 			errMsg := fmt.Sprintf("can't type cast to string session for user %s", name)
 			globalLogger.Exception(errMsg)
-			return name, isAdmin, isTester, errors.New(errMsg)
+			return name, adminLevel, testerLevel, errors.New(errMsg)
 		}
 	}
 
-	if r := sess.Get("is_admin"); r != nil {
+	if r := sess.Get("admin_level"); r != nil {
 		var ok bool
-		isAdmin, ok = r.(bool)
+		adminLevel, ok = r.(int)
 		if !ok {
-			errMsg := fmt.Sprintf("can't type cast to bool is_admin for user %s", name)
+			errMsg := fmt.Sprintf("can't type cast to int admin_level for user %s", name)
 			globalLogger.Exception(errMsg)
-			return name, isAdmin, isTester, errors.New(errMsg)
+			return name, adminLevel, testerLevel, errors.New(errMsg)
 		}
 	}
 
-	if r := sess.Get("is_tester"); r != nil {
+	if r := sess.Get("tester_level"); r != nil {
 		var ok bool
-		isTester, ok = r.(bool)
+		testerLevel, ok = r.(int)
 		if !ok {
-			errMsg := fmt.Sprintf("can't type cast to bool is_tester for user %s", name)
+			errMsg := fmt.Sprintf("can't type cast to int tester_level for user %s", name)
 			globalLogger.Exception(errMsg)
-			return name, isAdmin, isTester, errors.New(errMsg)
+			return name, adminLevel, testerLevel, errors.New(errMsg)
 		}
 	}
 
-	return name, isAdmin, isTester, nil
+	if name != "" && ctx.Locals("session_touched") == nil {
+		ctx.Locals("session_touched", true)
+		if err = a.userRepository.TouchSession(ctx.UserContext(), sess.ID(), name, ClientIP(ctx), userAgent(ctx)); err != nil {
+			globalLogger.Exception(fmt.Sprintf("can't update session metadata for user %s: %v", name, err))
+		}
+	}
+
+	return name, adminLevel, testerLevel, nil
 }
 
-func (a *AuthService) SaveSession(ctx *fiber.Ctx, name string, isTester bool, isAdmin bool) error {
+func (a *AuthService) CheckTwoFactor(ctx *fiber.Ctx) (string, error) {
+	sess, err := a.Store.Get(ctx)
+	if err != nil {
+		globalLogger.Exception(err.Error())
+		return TwoFactorNone, err
+	}
+
+	r := sess.Get("two_factor")
+	if r == nil {
+		return TwoFactorNone, nil
+	}
+
+	state, ok := r.(string)
+	if !ok {
+		errMsg := "can't type cast to string two_factor session state"
+		globalLogger.Exception(errMsg)
+		return TwoFactorNone, errors.New(errMsg)
+	}
+
+	return state, nil
+}
+
+// SaveSession stores the login state under a new session id, so an id planted before
+// the login or the second factor step can't be used to ride the authenticated session.
+func (a *AuthService) SaveSession(ctx *fiber.Ctx, name string, adminLevel int, testerLevel int, twoFactor string) error {
 	sess, err := a.Store.Get(ctx)
 	if err != nil {
 		globalLogger.Exception(err.Error())
 		return err
 	}
+	if err = sess.Regenerate(); err != nil {
+		return err
+	}
 	sess.Set("name", name)
-	sess.Set("is_tester", isTester)
-	sess.Set("is_admin", isAdmin)
-	sess.SetExpiry(time.Hour * 24)
-	return sess.Save()
+	sess.Set("admin_level", adminLevel)
+	sess.Set("tester_level", testerLevel)
+	sess.Set("two_factor", twoFactor)
+	sess.SetExpiry(a.Store.Expiration)
+
+	// Save releases the session, so the id has to be read before.
+	id := sess.ID()
+	if err = sess.Save(); err != nil {
+		return err
+	}
+
+	ctx.Locals("session_touched", true)
+	return a.userRepository.TouchSession(ctx.UserContext(), id, name, ClientIP(ctx), userAgent(ctx))
 }
 
 func (a *AuthService) DestroySession(ctx *fiber.Ctx) error {
@@ -82,18 +130,127 @@ func (a *AuthService) DestroySession(ctx *fiber.Ctx) error {
 	return sess.Destroy()
 }
 
+func (a *AuthService) ListSessions(ctx *fiber.Ctx) ([]model.SessionAPI, error) {
+	name, current, err := a.currentSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := a.userRepository.FetchSessions(ctx.UserContext(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []model.SessionAPI
+	for _, s := range sessions {
+		list = append(list, model.SessionAPI{
+			ID:        publicSessionID(s.ID),
+			IP:        s.IP,
+			UserAgent: s.UserAgent,
+			CreatedAt: s.CreatedAt,
+			LastSeen:  s.LastSeen,
+			Current:   s.ID == current,
+		})
+	}
+
+	return list, nil
+}
+
+func (a *AuthService) RevokeSession(ctx *fiber.Ctx, id string) error {
+	name, current, err := a.currentSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := a.userRepository.FetchSessions(ctx.UserContext(), name)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		if publicSessionID(s.ID) != id {
+			continue
+		}
+		if s.ID == current {
+			return model.ErrCurrentSession
+		}
+		return a.userRepository.DeleteSession(ctx.UserContext(), name, s.ID)
+	}
+
+	return model.ErrSessionNotFound
+}
+
+func (a *AuthService) RevokeOtherSessions(ctx *fiber.Ctx) error {
+	name, current, err := a.currentSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.userRepository.DeleteOtherSessions(ctx.UserContext(), name, current)
+}
+
+// SessionID returns the id of the session of the caller, it never leaves the server.
+func (a *AuthService) SessionID(ctx *fiber.Ctx) (string, error) {
+	_, current, err := a.currentSession(ctx)
+	return current, err
+}
+
+func (a *AuthService) RevokeUserSessions(ctx context.Context, name string, audit *model.AuditAPI) error {
+	if name == "" {
+		return model.ErrMissingFields
+	}
+
+	return a.userRepository.DeleteUserSessions(ctx, name, auditRecord(audit))
+}
+
+func (a *AuthService) currentSession(ctx *fiber.Ctx) (string, string, error) {
+	sess, err := a.Store.Get(ctx)
+	if err != nil {
+		globalLogger.Exception(err.Error())
+		return "", "", err
+	}
+
+	name, ok := sess.Get("name").(string)
+	if !ok || name == "" {
+		return "", "", model.ErrNotAuthenticated
+	}
+
+	return name, sess.ID(), nil
+}
+
+// publicSessionID is what the client sees, the real id is the session cookie and never leaves the server.
+func publicSessionID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:12])
+}
+
+// ClientIP is the address of the caller. Fiber only takes it from the proxy header
+// when the request comes from one of the trusted proxies, anyone else could rotate
+// the header to dodge the lockouts and the rate limits.
+func ClientIP(ctx *fiber.Ctx) string {
+	return ctx.IP()
+}
+
+func userAgent(ctx *fiber.Ctx) string {
+	ua := ctx.Get(fiber.HeaderUserAgent)
+	if len(ua) > 255 {
+		ua = ua[:255]
+	}
+	return ua
+}
+
 func (a *AuthService) Authenticate(ctx *fiber.Ctx) error {
-	name, isAdmin, isTester, err := a.CheckSession(ctx)
+	name, adminLevel, testerLevel, err := a.CheckSession(ctx)
 	if err != nil {
 		return err
 	}
 
 	if name == "" {
-		return errors.New("WaitingList(): session doesn't exist: user is not logged in")
+		return model.ErrNotAuthenticated
 	}
 
-	if !isAdmin || !isTester {
-		return errors.New(fmt.Sprintf("WaitingList(): user %s doesn't have admin rights", name))
+	if adminLevel == 0 && testerLevel == 0 {
+		return fmt.Errorf("%w: user %s doesn't have staff rights", model.ErrForbidden, name)
 	}
 
 	return nil
@@ -1,32 +1,71 @@
 package service
 
 import (
+	"fmt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"sarp_backend/i18n"
 	"sarp_backend/model"
+	"time"
 )
 
 type Middleware struct {
-	AuthService *AuthService
+	AuthService           *AuthService
+	Users                 *UserService
+	Permissions           *PermissionService
+	Catalog               *i18n.Catalog
+	StaffTwoFactorEnforce bool
 }
 
-func NewMiddleware(authService *AuthService) *Middleware {
-	return &Middleware{AuthService: authService}
+func NewMiddleware(authService *AuthService, userService *UserService, permissions *PermissionService, catalog *i18n.Catalog, staffTwoFactorEnforce bool) *Middleware {
+	return &Middleware{AuthService: authService, Users: userService, Permissions: permissions, Catalog: catalog, StaffTwoFactorEnforce: staffTwoFactorEnforce}
 }
 
+// Localize picks the language of the request. The lang query parameter wins, then the
+// language saved on the account and at last the Accept-Language header.
+func (m *Middleware) Localize(ctx *fiber.Ctx) error {
+	locale := m.Catalog.Normalize(ctx.Query("lang"))
+
+	if locale == "" {
+		if name, _, _, err := m.AuthService.CheckSession(ctx); err == nil && name != "" {
+			saved, errLocale := m.Users.Locale(ctx.UserContext(), name)
+			if errLocale != nil && globalLogger != nil {
+				globalLogger.Exception(fmt.Sprintf("Localize(): can't fetch the locale of %s: %v", name, errLocale))
+			}
+			locale = m.Catalog.Normalize(saved)
+		}
+	}
+
+	if locale == "" {
+		locale = m.Catalog.Match(ctx.Get(fiber.HeaderAcceptLanguage))
+	}
+
+	ctx.Set(fiber.HeaderContentLanguage, locale)
+	ctx.SetUserContext(i18n.WithLocale(ctx.UserContext(), locale))
+	return ctx.Next()
+}
+
+func (m *Middleware) reject(ctx *fiber.Ctx, status int, id string) error {
+	return ctx.Status(status).JSON(model.BaseResponse{
+		Error:   true,
+		Message: m.Catalog.T(i18n.FromContext(ctx.UserContext()), id),
+	})
+}
+
+// EnsureLoggedOut lets half-authenticated sessions through so a user can restart the login.
 func (m *Middleware) EnsureLoggedOut(ctx *fiber.Ctx) error {
 	name, _, _, err := m.AuthService.CheckSession(ctx)
 	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(model.BaseResponse{
-			Error:   true,
-			Message: "Error checking for session",
-		})
+		return m.reject(ctx, fiber.StatusInternalServerError, "session.check_failed")
 	}
 
-	if name != "" {
-		return ctx.Status(fiber.StatusOK).JSON(model.BaseResponse{
-			Error:   true,
-			Message: "You are already logged in",
-		})
+	twoFactor, err := m.AuthService.CheckTwoFactor(ctx)
+	if err != nil {
+		return m.reject(ctx, fiber.StatusInternalServerError, "session.check_failed")
+	}
+
+	if name != "" && twoFactor != TwoFactorPending {
+		return m.reject(ctx, fiber.StatusOK, "session.logged_in")
 	}
 	return ctx.Next()
 }
@@ -34,36 +73,70 @@ func (m *Middleware) EnsureLoggedOut(ctx *fiber.Ctx) error {
 func (m *Middleware) EnsureAuthenticated(ctx *fiber.Ctx) error {
 	name, _, _, err := m.AuthService.CheckSession(ctx)
 	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(model.BaseResponse{
-			Error:   true,
-			Message: "Error checking for session",
-		})
+		return m.reject(ctx, fiber.StatusInternalServerError, "session.check_failed")
+	}
+
+	twoFactor, err := m.AuthService.CheckTwoFactor(ctx)
+	if err != nil {
+		return m.reject(ctx, fiber.StatusInternalServerError, "session.check_failed")
 	}
 
-	if name == "" {
-		return ctx.Status(fiber.StatusOK).JSON(model.BaseResponse{
-			Error:   true,
-			Message: "You must be logged in to access this resource",
-		})
+	if name == "" || twoFactor == TwoFactorPending {
+		return m.reject(ctx, fiber.StatusOK, "session.login_required")
 	}
 	return ctx.Next()
 }
 
-func (m *Middleware) EnsurePrivilege(ctx *fiber.Ctx) error {
-	name, admin, tester, err := m.AuthService.CheckSession(ctx)
+// RequirePermission only lets staff through whose Admin or Tester level grants the permission.
+func (m *Middleware) RequirePermission(permission string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		name, adminLevel, testerLevel, err := m.AuthService.CheckSession(ctx)
+		if err != nil {
+			return m.reject(ctx, fiber.StatusInternalServerError, "session.check_failed")
+		}
+
+		if name == "" || !m.Permissions.Allowed(adminLevel, testerLevel, permission) {
+			return m.reject(ctx, fiber.StatusOK, "session.permission_required")
+		}
+
+		twoFactor, err := m.AuthService.CheckTwoFactor(ctx)
+		if err != nil {
+			return m.reject(ctx, fiber.StatusInternalServerError, "session.check_failed")
+		}
+
+		if twoFactor == TwoFactorPending || (m.StaffTwoFactorEnforce && twoFactor != TwoFactorVerified) {
+			return m.reject(ctx, fiber.StatusOK, "session.two_factor_required")
+		}
+
+		return ctx.Next()
+	}
+}
+
+func (m *Middleware) EnsureTwoFactorPending(ctx *fiber.Ctx) error {
+	name, _, _, err := m.AuthService.CheckSession(ctx)
 	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(model.BaseResponse{
-			Error:   true,
-			Message: "Error checking for session",
-		})
+		return m.reject(ctx, fiber.StatusInternalServerError, "session.check_failed")
 	}
 
-	if name == "" || (!admin && !tester) {
-		return ctx.Status(fiber.StatusOK).JSON(model.BaseResponse{
-			Error:   true,
-			Message: "You must have correct privileges to access this resource",
-		})
+	twoFactor, err := m.AuthService.CheckTwoFactor(ctx)
+	if err != nil {
+		return m.reject(ctx, fiber.StatusInternalServerError, "session.check_failed")
 	}
 
+	if name == "" || twoFactor != TwoFactorPending {
+		return m.reject(ctx, fiber.StatusOK, "session.two_factor_not_pending")
+	}
 	return ctx.Next()
 }
+
+// RateLimit allows max requests per client IP in every window, on top of the global limiter.
+func (m *Middleware) RateLimit(max int, window time.Duration) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:          max,
+		Expiration:   window,
+		KeyGenerator: ClientIP,
+		LimitReached: func(ctx *fiber.Ctx) error {
+			return m.reject(ctx, fiber.StatusTooManyRequests, "rate_limit.reached")
+		},
+	})
+}
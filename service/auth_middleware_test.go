@@ -0,0 +1,71 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sarp_backend/i18n"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"testing"
+)
+
+func TestRequirePermission(t *testing.T) {
+	catalog, err := i18n.Load()
+	if err != nil {
+		t.Fatalf("Error loading catalog: %v", err)
+	}
+
+	auth := NewAuthService(session.New(), repository.NewMemoryRepository())
+	perms := NewPermissionService(map[int][]string{3: {PermBanCreate}}, map[int][]string{1: {PermCharacterReview}})
+	middleware := NewMiddleware(auth, nil, perms, catalog, false)
+
+	app := fiber.New()
+	app.Post("/login/:admin", func(ctx *fiber.Ctx) error {
+		admin, err := ctx.ParamsInt("admin")
+		if err != nil {
+			return err
+		}
+		if err = auth.SaveSession(ctx, "Staff_Member", admin, 0, TwoFactorNone); err != nil {
+			return err
+		}
+		return ctx.SendStatus(http.StatusOK)
+	})
+	app.Post("/ban", middleware.RequirePermission(PermBanCreate), func(ctx *fiber.Ctx) error {
+		return ctx.JSON(model.BaseResponse{Message: "banned"})
+	})
+
+	ban := func(admin int) model.BaseResponse {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodPost, "/ban", nil)
+		if admin >= 0 {
+			login, err := app.Test(httptest.NewRequest(http.MethodPost, fmt.Sprintf("/login/%d", admin), nil), -1)
+			if err != nil {
+				t.Fatalf("Error logging in: %v", err)
+			}
+			for _, c := range login.Cookies() {
+				req.AddCookie(c)
+			}
+		}
+
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("Error sending request: %v", err)
+		}
+		var body model.BaseResponse
+		if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Error decoding response body: %v", err)
+		}
+		return body
+	}
+
+	denied := model.BaseResponse{Error: true, Message: catalog.T(i18n.Default, "session.permission_required")}
+	assert.Equal(t, denied, ban(-1), "Guests must be turned away")
+	assert.Equal(t, denied, ban(1), "Staff without the permission must be turned away")
+	assert.Equal(t, model.BaseResponse{Message: "banned"}, ban(3))
+}
@@ -1,29 +1,61 @@
 package service
 
 import (
+	"context"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/mock"
+	"sarp_backend/model"
 )
 
 type MockAuthService struct {
 	mock.Mock
 }
 
-func (a *MockAuthService) CheckSession(ctx *fiber.Ctx) (string, bool, bool, error) {
+func (a *MockAuthService) CheckSession(ctx *fiber.Ctx) (string, int, int, error) {
 	args := a.Called(ctx)
-	return args.String(0), args.Bool(1), args.Bool(2), args.Error(3)
+	return args.String(0), args.Int(1), args.Int(2), args.Error(3)
 }
 
-func (a *MockAuthService) SaveSession(ctx *fiber.Ctx, name string, isTester bool, isAdmin bool) error {
-	args := a.Called(ctx, name, isTester, isAdmin)
+func (a *MockAuthService) SaveSession(ctx *fiber.Ctx, name string, adminLevel int, testerLevel int, twoFactor string) error {
+	args := a.Called(ctx, name, adminLevel, testerLevel, twoFactor)
 	return args.Error(0)
 }
 
+func (a *MockAuthService) CheckTwoFactor(ctx *fiber.Ctx) (string, error) {
+	args := a.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
 func (a *MockAuthService) DestroySession(ctx *fiber.Ctx) error {
 	args := a.Called(ctx)
 	return args.Error(0)
 }
 
+func (a *MockAuthService) ListSessions(ctx *fiber.Ctx) ([]model.SessionAPI, error) {
+	args := a.Called(ctx)
+	return args.Get(0).([]model.SessionAPI), args.Error(1)
+}
+
+func (a *MockAuthService) RevokeSession(ctx *fiber.Ctx, id string) error {
+	args := a.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (a *MockAuthService) RevokeOtherSessions(ctx *fiber.Ctx) error {
+	args := a.Called(ctx)
+	return args.Error(0)
+}
+
+func (a *MockAuthService) SessionID(ctx *fiber.Ctx) (string, error) {
+	args := a.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
+func (a *MockAuthService) RevokeUserSessions(ctx context.Context, name string, audit *model.AuditAPI) error {
+	args := a.Called(name, audit)
+	return args.Error(0)
+}
+
 func (a *MockAuthService) Authenticate(ctx *fiber.Ctx) error {
 	return nil
 }
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sarp_backend/repository"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	clientIP := func(trusted []string) string {
+		t.Helper()
+
+		app := fiber.New(fiber.Config{
+			EnableTrustedProxyCheck: true,
+			TrustedProxies:          trusted,
+			ProxyHeader:             "X-Real-IP",
+		})
+		app.Get("/", func(ctx *fiber.Ctx) error {
+			return ctx.SendString(ClientIP(ctx))
+		})
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Real-IP", "203.0.113.7")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("Error sending request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// app.Test connects from 0.0.0.0.
+	assert.Equal(t, "0.0.0.0", clientIP([]string{"127.0.0.1"}), "The header of an untrusted caller must be ignored")
+	assert.Equal(t, "203.0.113.7", clientIP([]string{"0.0.0.0"}), "The header of a trusted proxy must be used")
+}
+
+func TestSaveSessionRegenerates(t *testing.T) {
+	auth := NewAuthService(session.New(), repository.NewMemoryRepository())
+
+	app := fiber.New()
+	app.Post("/login", func(ctx *fiber.Ctx) error {
+		return auth.SaveSession(ctx, "Test_Player", 0, 0, TwoFactorPending)
+	})
+	app.Post("/verify", func(ctx *fiber.Ctx) error {
+		return auth.SaveSession(ctx, "Test_Player", 0, 0, TwoFactorVerified)
+	})
+	app.Get("/whoami", func(ctx *fiber.Ctx) error {
+		name, _, _, err := auth.CheckSession(ctx)
+		if err != nil {
+			return err
+		}
+		return ctx.SendString(name)
+	})
+
+	send := func(method, path string, cookie *http.Cookie) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(method, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("Error sending request: %v", err)
+		}
+		return resp
+	}
+	sessionCookie := func(resp *http.Response) *http.Cookie {
+		t.Helper()
+
+		for _, c := range resp.Cookies() {
+			if c.Name == "session_id" {
+				return c
+			}
+		}
+		t.Fatalf("No session cookie was set")
+		return nil
+	}
+	whoami := func(cookie *http.Cookie) string {
+		t.Helper()
+
+		body, _ := io.ReadAll(send(http.MethodGet, "/whoami", cookie).Body)
+		return string(body)
+	}
+
+	pending := sessionCookie(send(http.MethodPost, "/login", nil))
+	verified := sessionCookie(send(http.MethodPost, "/verify", pending))
+
+	assert.NotEqual(t, pending.Value, verified.Value, "The second factor step must issue a new session id")
+	assert.Equal(t, "", whoami(pending), "The id used before the step must be dropped")
+	assert.Equal(t, "Test_Player", whoami(verified))
+}
@@ -1,20 +1,45 @@
 package service
 
 import (
-	"errors"
+	"context"
 	"sarp_backend/model"
 	"sarp_backend/repository"
+	"strings"
+	"time"
 )
 
+type CharacterConfig struct {
+	// ClaimLease is how long a reviewer keeps an application before others can take it.
+	ClaimLease time.Duration
+	// ResubmitCooldown is the time between two submissions of a rejected character.
+	ResubmitCooldown time.Duration
+}
+
+// CharacterStore is the storage behind CharacterService: the applications, the
+// questionnaire and the name change and CK queues.
+type CharacterStore interface {
+	repository.CharacterRepository
+	repository.QuestionnaireRepository
+	repository.NameChangeRepository
+	repository.CKRepository
+}
+
 type CharacterService struct {
-	userRepository *repository.UserRepository
+	userRepository CharacterStore
+	config         CharacterConfig
 }
 
-func NewCharacterService(repo *repository.UserRepository) *CharacterService {
-	return &CharacterService{userRepository: repo}
+func NewCharacterService(repo CharacterStore, config CharacterConfig) *CharacterService {
+	return &CharacterService{userRepository: repo, config: config}
 }
 
-func (c *CharacterService) Create(data *model.CharacterDataAPI) error {
+func (c *CharacterService) Create(ctx context.Context, data *model.CharacterDataAPI) error {
+	character, application := characterRecords(data)
+	return c.userRepository.CreateCharacter(ctx, character, application)
+}
+
+// characterRecords converts a submitted form into the character row and its application.
+func characterRecords(data *model.CharacterDataAPI) (*repository.CharacterDB, *repository.ApplicationDB) {
 	dto := &repository.CharacterDB{
 		Username:  data.Username,
 		Character: data.CharacterName,
@@ -29,40 +54,309 @@ func (c *CharacterService) Create(data *model.CharacterDataAPI) error {
 		dto.Skin = 93
 	}
 
-	return c.userRepository.CreateCharacter(dto)
+	application := &repository.ApplicationDB{
+		Username:  data.Username,
+		Character: data.CharacterName,
+		Version:   data.QuestionnaireVersion,
+		Answers:   data.Answers,
+	}
+
+	return dto, application
 }
 
-func (c *CharacterService) FetchWaiting() ([]model.CharacterDataAPI, error) {
-	dataList, err := c.userRepository.FetchWaitingCharacters()
+// Application returns the last submission of a waiting or rejected character of username.
+func (c *CharacterService) Application(ctx context.Context, username, character string) (*model.ApplicationDraftAPI, error) {
+	if character == "" {
+		return nil, model.ErrCharacterNameRequired
+	}
+
+	data, application, err := c.userRepository.FetchOpenApplication(ctx, username, character)
 	if err != nil {
 		return nil, err
 	}
 
+	draft := &model.ApplicationDraftAPI{
+		CharacterName:   data.Character,
+		CharacterAge:    data.Age,
+		CharacterGender: data.Gender,
+		CharacterOrigin: data.Origin,
+		Status:          repository.ApplicationPending,
+		Questions:       []model.QuestionAPI{},
+		Answers:         []string{},
+	}
+	if application == nil {
+		return draft, nil
+	}
+
+	draft.Status = application.Status
+	draft.Reason = application.Reason
+	draft.QuestionnaireVersion = application.Version
+	if application.Answers != nil {
+		draft.Answers = application.Answers
+	}
+	if application.Version > 0 {
+		if draft.Questions, err = c.questions(ctx, map[int][]model.QuestionAPI{}, application.Version); err != nil {
+			return nil, err
+		}
+	}
+	if application.Status == repository.ApplicationRejected {
+		after := application.CreatedAt.Add(c.config.ResubmitCooldown)
+		draft.ResubmitAfter = &after
+	}
+
+	return draft, nil
+}
+
+// Edit replaces the details and answers of a waiting character of data.Username.
+func (c *CharacterService) Edit(ctx context.Context, data *model.CharacterDataAPI) error {
+	character, application := characterRecords(data)
+	return c.userRepository.UpdateApplication(ctx, character, application)
+}
+
+func (c *CharacterService) Withdraw(ctx context.Context, username, character string) error {
+	if character == "" {
+		return model.ErrCharacterNameRequired
+	}
+	return c.userRepository.WithdrawCharacter(ctx, username, character)
+}
+
+// Resubmit sends a rejected character of data.Username back to the waiting list.
+func (c *CharacterService) Resubmit(ctx context.Context, data *model.CharacterDataAPI) error {
+	character, application := characterRecords(data)
+	return c.userRepository.ResubmitCharacter(ctx, character, application, c.config.ResubmitCooldown)
+}
+
+func (c *CharacterService) FetchWaiting(ctx context.Context) ([]model.CharacterDataAPI, error) {
+	dataList, err := c.userRepository.FetchWaitingCharacters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(dataList))
+	for _, data := range dataList {
+		names = append(names, data.Character)
+	}
+
+	applications, err := c.userRepository.FetchApplications(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	byCharacter := make(map[string]repository.ApplicationDB, len(applications))
+	for _, a := range applications {
+		byCharacter[strings.ToLower(a.Character)] = a
+	}
+
+	// Applications are shown with the questions they answered, not the current ones.
+	questionnaires := make(map[int][]model.QuestionAPI)
+
 	var dto []model.CharacterDataAPI
 
 	for _, data := range dataList {
-		dto = append(dto, model.CharacterDataAPI{
+		character := model.CharacterDataAPI{
 			Username:        data.Username,
 			CharacterName:   data.Character,
 			CharacterAge:    data.Age,
 			CharacterGender: data.Gender,
 			CharacterOrigin: data.Origin,
-		})
+		}
+
+		a, ok := byCharacter[strings.ToLower(data.Character)]
+		if ok && a.Version > 0 {
+			questions, errQ := c.questions(ctx, questionnaires, a.Version)
+			if errQ != nil {
+				return nil, errQ
+			}
+
+			character.QuestionnaireVersion = a.Version
+			character.Answers = a.Answers
+			character.Questions = questions
+		}
+		if ok && a.ClaimedBy != "" && a.ClaimExpiresAt.Valid && a.ClaimExpiresAt.Time.After(time.Now()) {
+			expires := a.ClaimExpiresAt.Time
+			character.ClaimedBy = a.ClaimedBy
+			character.ClaimExpiresAt = &expires
+		}
+
+		dto = append(dto, character)
 	}
 
 	return dto, nil
 }
 
-func (c *CharacterService) AcceptCharacter(data model.CharacterAPI) error {
-	if data.CharacterName == "" {
-		return errors.New("unexpected character name data")
+func (c *CharacterService) Questionnaire(ctx context.Context) (*model.QuestionnaireAPI, error) {
+	q, err := c.userRepository.FetchLatestQuestionnaire(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return c.userRepository.AcceptCharacter(data.Username, data.CharacterName, data.AcceptedBy)
+
+	return &model.QuestionnaireAPI{
+		Version:   q.Version,
+		Questions: questionsAPI(q),
+		CreatedBy: q.CreatedBy,
+		CreatedAt: q.CreatedAt,
+	}, nil
 }
 
-func (c *CharacterService) DeclineCharacter(data model.RejectCharacterAPI) error {
+// UpdateQuestionnaire saves data as a new version and returns it, pending applications
+// keep the version they were written against.
+func (c *CharacterService) UpdateQuestionnaire(ctx context.Context, data *model.QuestionnaireAPI, audit *model.AuditAPI) (*model.QuestionnaireAPI, error) {
+	dto := &repository.QuestionnaireDB{CreatedBy: data.CreatedBy}
+	for _, q := range data.Questions {
+		dto.Questions = append(dto.Questions, repository.QuestionDB{
+			Text:      q.Text,
+			MinLength: q.MinLength,
+			MaxLength: q.MaxLength,
+		})
+	}
+
+	version, err := c.userRepository.CreateQuestionnaire(ctx, dto, auditRecord(audit))
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := c.userRepository.FetchQuestionnaire(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.QuestionnaireAPI{
+		Version:   q.Version,
+		Questions: questionsAPI(q),
+		CreatedBy: q.CreatedBy,
+		CreatedAt: q.CreatedAt,
+	}, nil
+}
+
+// questions returns the questions of version, cache keeps the versions already fetched.
+func (c *CharacterService) questions(ctx context.Context, cache map[int][]model.QuestionAPI, version int) ([]model.QuestionAPI, error) {
+	if questions, found := cache[version]; found {
+		return questions, nil
+	}
+
+	q, err := c.userRepository.FetchQuestionnaire(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	cache[version] = questionsAPI(q)
+	return cache[version], nil
+}
+
+func questionsAPI(q *repository.QuestionnaireDB) []model.QuestionAPI {
+	questions := []model.QuestionAPI{}
+	for _, question := range q.Questions {
+		questions = append(questions, model.QuestionAPI{
+			Text:      question.Text,
+			MinLength: question.MinLength,
+			MaxLength: question.MaxLength,
+		})
+	}
+	return questions
+}
+
+// AcceptCharacter accepts the waiting character and returns the account owning it, the
+// Username of data is not trusted for that.
+func (c *CharacterService) AcceptCharacter(ctx context.Context, data model.CharacterAPI, audit *model.AuditAPI) (string, error) {
 	if data.CharacterName == "" {
-		return errors.New("unexpected character name data")
+		return "", model.ErrCharacterNameRequired
+	}
+	return c.userRepository.AcceptCharacter(ctx, data.CharacterName, data.AcceptedBy, auditRecord(audit))
+}
+
+// DeclineCharacter rejects the waiting character and returns the account owning it.
+func (c *CharacterService) DeclineCharacter(ctx context.Context, data model.RejectCharacterAPI, audit *model.AuditAPI) (string, error) {
+	if err := data.Validate(); err != nil {
+		return "", err
+	}
+	return c.userRepository.DeclineCharacter(ctx, data.CharacterName, data.RejectedBy, data.Reason, auditRecord(audit))
+}
+
+// Claim reserves the application of character for reviewer until the lease runs out,
+// accepting or rejecting it is refused to everyone else meanwhile.
+func (c *CharacterService) Claim(ctx context.Context, character, reviewer string) (*model.ClaimResultAPI, error) {
+	if character == "" {
+		return nil, model.ErrCharacterNameRequired
 	}
-	return c.userRepository.DeclineCharacter(data.CharacterName)
+
+	application, err := c.userRepository.ClaimApplication(ctx, character, reviewer, c.config.ClaimLease)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ClaimResultAPI{
+		CharacterName:  application.Character,
+		ClaimedBy:      application.ClaimedBy,
+		ClaimExpiresAt: application.ClaimExpiresAt.Time,
+	}, nil
+}
+
+func (c *CharacterService) Release(ctx context.Context, character, reviewer string) error {
+	if character == "" {
+		return model.ErrCharacterNameRequired
+	}
+	return c.userRepository.ReleaseApplication(ctx, character, reviewer)
+}
+
+func (c *CharacterService) AddNote(ctx context.Context, data *model.ReviewNoteAPI, reviewer string) error {
+	return c.userRepository.AddReviewNote(ctx, data.CharacterName, reviewer, data.Note)
+}
+
+// History returns every application of character with the questions it answered and
+// the review events in the order they happened.
+func (c *CharacterService) History(ctx context.Context, character string) (*model.ReviewHistoryAPI, error) {
+	if character == "" {
+		return nil, model.ErrCharacterNameRequired
+	}
+
+	applications, events, err := c.userRepository.FetchReviewHistory(ctx, character)
+	if err != nil {
+		return nil, err
+	}
+	if len(applications) == 0 {
+		return nil, model.ErrCharacterNotFound
+	}
+
+	history := &model.ReviewHistoryAPI{
+		CharacterName: applications[0].Character,
+		Applications:  []model.ApplicationReviewAPI{},
+		Events:        []model.ReviewEventAPI{},
+	}
+
+	questionnaires := make(map[int][]model.QuestionAPI)
+	for _, a := range applications {
+		application := model.ApplicationReviewAPI{
+			ID:                   a.ID,
+			QuestionnaireVersion: a.Version,
+			Questions:            []model.QuestionAPI{},
+			Answers:              a.Answers,
+			Status:               a.Status,
+			ReviewedBy:           a.ReviewedBy,
+			Reason:               a.Reason,
+			CreatedAt:            a.CreatedAt,
+		}
+		if application.Answers == nil {
+			application.Answers = []string{}
+		}
+		if a.Version > 0 {
+			if application.Questions, err = c.questions(ctx, questionnaires, a.Version); err != nil {
+				return nil, err
+			}
+		}
+		if a.ReviewedAt.Valid {
+			reviewed := a.ReviewedAt.Time
+			application.ReviewedAt = &reviewed
+		}
+		history.Applications = append(history.Applications, application)
+	}
+
+	for _, e := range events {
+		history.Events = append(history.Events, model.ReviewEventAPI{
+			Application: e.ApplicationID,
+			Reviewer:    e.Reviewer,
+			Action:      e.Action,
+			Note:        e.Note,
+			CreatedAt:   e.CreatedAt,
+		})
+	}
+
+	return history, nil
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"github.com/stretchr/testify/mock"
 	"sarp_backend/model"
 )
@@ -9,18 +10,90 @@ type MockCharacterService struct {
 	mock.Mock
 }
 
-func (c *MockCharacterService) Create(data *model.CharacterDataAPI) error {
+func (c *MockCharacterService) Create(ctx context.Context, data *model.CharacterDataAPI) error {
 	return nil
 }
 
-func (c *MockCharacterService) FetchWaiting() ([]model.CharacterDataAPI, error) {
+func (c *MockCharacterService) FetchWaiting(ctx context.Context) ([]model.CharacterDataAPI, error) {
 	return nil, nil
 }
 
-func (c *MockCharacterService) AcceptCharacter(data model.CharacterAPI) error {
+func (c *MockCharacterService) AcceptCharacter(ctx context.Context, data model.CharacterAPI, audit *model.AuditAPI) (string, error) {
+	return data.Username, nil
+}
+
+func (c *MockCharacterService) DeclineCharacter(ctx context.Context, data model.RejectCharacterAPI, audit *model.AuditAPI) (string, error) {
+	return data.Username, nil
+}
+
+func (c *MockCharacterService) Questionnaire(ctx context.Context) (*model.QuestionnaireAPI, error) {
+	return &model.QuestionnaireAPI{Questions: []model.QuestionAPI{}}, nil
+}
+
+func (c *MockCharacterService) UpdateQuestionnaire(ctx context.Context, data *model.QuestionnaireAPI, audit *model.AuditAPI) (*model.QuestionnaireAPI, error) {
+	return data, nil
+}
+
+func (c *MockCharacterService) Claim(ctx context.Context, character, reviewer string) (*model.ClaimResultAPI, error) {
+	return &model.ClaimResultAPI{CharacterName: character, ClaimedBy: reviewer}, nil
+}
+
+func (c *MockCharacterService) Release(ctx context.Context, character, reviewer string) error {
 	return nil
 }
 
-func (c *MockCharacterService) DeclineCharacter(data model.RejectCharacterAPI) error {
+func (c *MockCharacterService) AddNote(ctx context.Context, data *model.ReviewNoteAPI, reviewer string) error {
 	return nil
 }
+
+func (c *MockCharacterService) History(ctx context.Context, character string) (*model.ReviewHistoryAPI, error) {
+	return &model.ReviewHistoryAPI{CharacterName: character}, nil
+}
+
+func (c *MockCharacterService) Application(ctx context.Context, username, character string) (*model.ApplicationDraftAPI, error) {
+	return &model.ApplicationDraftAPI{CharacterName: character}, nil
+}
+
+func (c *MockCharacterService) Edit(ctx context.Context, data *model.CharacterDataAPI) error {
+	return nil
+}
+
+func (c *MockCharacterService) Withdraw(ctx context.Context, username, character string) error {
+	return nil
+}
+
+func (c *MockCharacterService) Resubmit(ctx context.Context, data *model.CharacterDataAPI) error {
+	return nil
+}
+
+func (c *MockCharacterService) RequestNameChange(ctx context.Context, username string, data *model.NameChangeRequestAPI) (int64, error) {
+	return 1, nil
+}
+
+func (c *MockCharacterService) NameChanges(ctx context.Context, username, status string) ([]model.NameChangeAPI, error) {
+	return nil, nil
+}
+
+func (c *MockCharacterService) ApproveNameChange(ctx context.Context, id int64, admin string, audit *model.AuditAPI) (*model.NameChangeAPI, error) {
+	return &model.NameChangeAPI{ID: id, Status: "approved", ReviewedBy: admin}, nil
+}
+
+func (c *MockCharacterService) DenyNameChange(ctx context.Context, data *model.NameChangeDecisionAPI, admin string, audit *model.AuditAPI) (*model.NameChangeAPI, error) {
+	return &model.NameChangeAPI{ID: data.ID, Status: "denied", ReviewedBy: admin, Reason: data.Reason}, nil
+}
+
+func (c *MockCharacterService) RequestCK(ctx context.Context, requester string, staff bool, data *model.CKRequestAPI) (int64, error) {
+	return 1, nil
+}
+
+func (c *MockCharacterService) CKRequests(ctx context.Context, requester, status string) ([]model.CKAPI, error) {
+	return nil, nil
+}
+
+func (c *MockCharacterService) ApproveCK(ctx context.Context, id int64, admin string, audit *model.AuditAPI) (*model.CKAPI, error) {
+	return &model.CKAPI{ID: id, Status: "approved", ReviewedBy: admin}, nil
+}
+
+func (c *MockCharacterService) DenyCK(ctx context.Context, data *model.CKDecisionAPI, admin string, audit *model.AuditAPI) (*model.CKAPI, error) {
+	return &model.CKAPI{ID: data.ID, Status: "denied", ReviewedBy: admin, Reason: data.Reason}, nil
+}
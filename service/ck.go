@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"strings"
+)
+
+// ckLimit caps the queue and the history of a player, the oldest requests are left out.
+const ckLimit = 200
+
+func ckRecord(data *repository.CKRequestDB) model.CKAPI {
+	ret := model.CKAPI{
+		ID:            data.ID,
+		Requester:     data.Requester,
+		Staff:         data.Staff,
+		CharacterName: data.Character,
+		Owner:         data.Owner,
+		Evidence:      strings.Split(data.Evidence, "\n"),
+		Description:   data.Description,
+		Status:        data.Status,
+		ReviewedBy:    data.ReviewedBy,
+		Reason:        data.Reason,
+		CreatedAt:     data.CreatedAt,
+	}
+	if data.ReviewedAt.Valid {
+		reviewedAt := data.ReviewedAt.Time
+		ret.ReviewedAt = &reviewedAt
+	}
+	return ret
+}
+
+// RequestCK files a CK against an accepted character. Requests of players need a CK
+// voucher, staff file them for free.
+func (c *CharacterService) RequestCK(ctx context.Context, requester string, staff bool, data *model.CKRequestAPI) (int64, error) {
+	if err := data.Validate(); err != nil {
+		return 0, err
+	}
+
+	return c.userRepository.CreateCKRequest(ctx, &repository.CKRequestDB{
+		Requester:   requester,
+		Staff:       staff,
+		Character:   data.CharacterName,
+		Evidence:    strings.Join(data.Evidence, "\n"),
+		Description: data.Description,
+	})
+}
+
+// CKRequests returns the latest requests, filed by requester only when it isn't empty.
+func (c *CharacterService) CKRequests(ctx context.Context, requester, status string) ([]model.CKAPI, error) {
+	requests, err := c.userRepository.FetchCKRequests(ctx, &repository.CKRequestFilterDB{
+		Requester: requester,
+		Status:    status,
+		Limit:     ckLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]model.CKAPI, 0, len(requests))
+	for i := range requests {
+		ret = append(ret, ckRecord(&requests[i]))
+	}
+	return ret, nil
+}
+
+func (c *CharacterService) ApproveCK(ctx context.Context, id int64, admin string, audit *model.AuditAPI) (*model.CKAPI, error) {
+	request, err := c.userRepository.ApproveCKRequest(ctx, id, admin, auditRecord(audit))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := ckRecord(request)
+	return &ret, nil
+}
+
+func (c *CharacterService) DenyCK(ctx context.Context, data *model.CKDecisionAPI, admin string, audit *model.AuditAPI) (*model.CKAPI, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	request, err := c.userRepository.DenyCKRequest(ctx, data.ID, admin, data.Reason, auditRecord(audit))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := ckRecord(request)
+	return &ret, nil
+}
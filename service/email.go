@@ -1,53 +1,60 @@
 package service
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"gopkg.in/gomail.v2"
+	"time"
 )
 
-type EmailService struct {
-	SMTPHost string
-	SMTPPort int
-	Username string
-	Password string
-	From     string
-}
+const (
+	EmailTransportSMTP   = "smtp"
+	EmailTransportFile   = "file"
+	EmailTransportMemory = "memory"
 
-func NewEmailService(host string, port int, username, password string, from string) *EmailService {
-	return &EmailService{
-		SMTPHost: host,
-		SMTPPort: port,
-		Username: username,
-		Password: password,
-		From:     from,
-	}
-}
+	SMTPImplicitTLS = "tls"
+	SMTPStartTLS    = "starttls"
+)
 
-func (e *EmailService) SendEmail(to, subject, body string) error {
-	mail := gomail.NewMessage()
-	mail.SetHeader("From", e.From)
-	mail.SetHeader("To", to)
-	mail.SetHeader("Subject", subject)
-	mail.SetBody("text/html", body)
+type EmailTransportConfig struct {
+	Transport string
+	From      string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUser     string
+	SMTPPassword string
+	SMTPSecurity string
+	SMTPIdle     time.Duration
 
-	dialer := gomail.NewDialer(e.SMTPHost, e.SMTPPort, e.Username, e.Password)
-	dialer.SSL = true
+	FileDir string
 
-	return dialer.DialAndSend(mail)
+	// MemoryLimit is how many messages the capture transport keeps.
+	MemoryLimit int
 }
 
-func GenerateToken(email string, timestamp int64) string {
-	secretKey := "$ecretKey_).r0@+"
-	data := fmt.Sprintf("%s:%d", email, timestamp)
-	h := hmac.New(sha256.New, []byte(secretKey))
-	h.Write([]byte(data))
-	return hex.EncodeToString(h.Sum(nil))
+// NewEmailTransport builds the EmailInterface picked in the config.
+func NewEmailTransport(config EmailTransportConfig) (EmailInterface, error) {
+	switch config.Transport {
+	case EmailTransportSMTP:
+		return NewSMTPTransport(config.SMTPHost, config.SMTPPort, config.SMTPUser, config.SMTPPassword, config.From, config.SMTPSecurity, config.SMTPIdle)
+	case EmailTransportFile:
+		return NewFileTransport(config.FileDir, config.From)
+	case EmailTransportMemory:
+		return NewMemoryTransport(config.From, config.MemoryLimit), nil
+	default:
+		return nil, fmt.Errorf("unknown email transport %q", config.Transport)
+	}
 }
 
-func ValidateToken(email, token string, timestamp int64) bool {
-	expectedToken := GenerateToken(email, timestamp)
-	return hmac.Equal([]byte(expectedToken), []byte(token))
+// newMessage builds a multipart/alternative message, clients that can't show HTML
+// fall back to the text part.
+func newMessage(from, to, subject, text, html string) *gomail.Message {
+	mail := gomail.NewMessage()
+	mail.SetHeader("From", from)
+	mail.SetHeader("To", to)
+	mail.SetHeader("Subject", subject)
+	mail.SetDateHeader("Date", time.Now())
+	mail.SetBody("text/plain", text)
+	mail.AddAlternative("text/html", html)
+	return mail
 }
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileTransport writes every email as an .eml file in a maildir, nothing leaves the
+// machine. Files are written to tmp and moved to new once complete, so a mail client
+// reading the directory never sees half of a message.
+type FileTransport struct {
+	dir  string
+	from string
+}
+
+func NewFileTransport(dir, from string) (*FileTransport, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("email file transport needs a directory")
+	}
+
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o750); err != nil {
+			return nil, err
+		}
+	}
+
+	return &FileTransport{dir: dir, from: from}, nil
+}
+
+func (f *FileTransport) SendEmail(to, subject, text, html string) error {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d.%s.eml", time.Now().UnixNano(), hex.EncodeToString(suffix))
+
+	tmp := filepath.Join(f.dir, "tmp", name)
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+
+	if _, err = newMessage(f.from, to, subject, text, html).WriteTo(file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = file.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(f.dir, "new", name))
+}
@@ -1,57 +0,0 @@
-package service
-
-const ConfirmAccountEmail = `
-<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-</head>
-	Confirm %s %s
-<body>
-    
-</body>
-</html>
-`
-
-const ResetPasswordEmail = `
-<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-</head>
-
-<body>
-    Reset %s
-</body>
-</html>
-`
-
-const AcceptCharacterEmail = `
-<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-</head>
-
-<body>
-    Accept %s %s %s
-</body>
-</html>
-`
-
-const DeclineCharacterEmail = `
-<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-</head>
-
-<body>
-    Decline %s %s %s %s %s
-</body>
-</html>
-`
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+type CapturedEmail struct {
+	ID      int64
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	SentAt  time.Time
+}
+
+// MemoryTransport keeps the last emails in memory instead of sending them, it backs the
+// development mailbox.
+type MemoryTransport struct {
+	from  string
+	limit int
+
+	mu       sync.Mutex
+	lastID   int64
+	messages []CapturedEmail
+}
+
+func NewMemoryTransport(from string, limit int) *MemoryTransport {
+	if limit <= 0 {
+		limit = 100
+	}
+	return &MemoryTransport{from: from, limit: limit}
+}
+
+func (m *MemoryTransport) SendEmail(to, subject, text, html string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastID++
+	m.messages = append(m.messages, CapturedEmail{
+		ID:      m.lastID,
+		From:    m.from,
+		To:      to,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+		SentAt:  time.Now(),
+	})
+	if len(m.messages) > m.limit {
+		m.messages = m.messages[len(m.messages)-m.limit:]
+	}
+	return nil
+}
+
+// Messages returns the captured emails, newest first.
+func (m *MemoryTransport) Messages() []CapturedEmail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := make([]CapturedEmail, 0, len(m.messages))
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		messages = append(messages, m.messages[i])
+	}
+	return messages
+}
+
+func (m *MemoryTransport) Message(id int64) (CapturedEmail, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, message := range m.messages {
+		if message.ID == id {
+			return message, true
+		}
+	}
+	return CapturedEmail{}, false
+}
@@ -6,7 +6,7 @@ type MockEmailService struct {
 	mock.Mock
 }
 
-func (m *MockEmailService) SendEmail(to, subject, body string) error {
-	args := m.Called(to, subject, body)
+func (m *MockEmailService) SendEmail(to, subject, text, html string) error {
+	args := m.Called(to, subject, text, html)
 	return args.Error(0)
 }
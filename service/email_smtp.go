@@ -0,0 +1,165 @@
+package service
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SMTPTransport keeps one connection open between emails, it is reset before every
+// message and dialed again when the server dropped it or it was idle for too long.
+type SMTPTransport struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	security string
+	idle     time.Duration
+
+	tlsConfig *tls.Config
+
+	mu       sync.Mutex
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+func NewSMTPTransport(host string, port int, username, password, from, security string, idle time.Duration) (*SMTPTransport, error) {
+	if security != SMTPImplicitTLS && security != SMTPStartTLS {
+		return nil, fmt.Errorf("unknown smtp security %q", security)
+	}
+
+	return &SMTPTransport{
+		host:      host,
+		port:      port,
+		username:  username,
+		password:  password,
+		from:      from,
+		security:  security,
+		idle:      idle,
+		tlsConfig: &tls.Config{ServerName: host},
+	}, nil
+}
+
+func (s *SMTPTransport) SendEmail(to, subject, text, html string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.connection(); err != nil {
+		return err
+	}
+
+	if err := s.send(to, subject, text, html); err != nil {
+		// The state of the session is unknown, start with a new one next time.
+		s.closeClient()
+		return err
+	}
+
+	s.lastUsed = time.Now()
+	return nil
+}
+
+func (s *SMTPTransport) send(to, subject, text, html string) error {
+	if err := s.client.Mail(s.from); err != nil {
+		return err
+	}
+	if err := s.client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := s.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = newMessage(s.from, to, subject, text, html).WriteTo(w); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// connection makes sure s.client is a usable session.
+func (s *SMTPTransport) connection() error {
+	if s.client != nil {
+		if s.idle > 0 && time.Since(s.lastUsed) > s.idle {
+			s.closeClient()
+		} else if err := s.client.Reset(); err != nil {
+			s.closeClient()
+		} else {
+			return nil
+		}
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return err
+	}
+	s.client = client
+	return nil
+}
+
+func (s *SMTPTransport) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if s.security == SMTPImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, s.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if s.security == SMTPStartTLS {
+		// Never fall back to plain text, the credentials would go out in clear.
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			_ = client.Close()
+			return nil, errors.New("smtp server doesn't support STARTTLS")
+		}
+		if err = client.StartTLS(s.tlsConfig); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
+
+	if s.username != "" {
+		if err = client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+func (s *SMTPTransport) closeClient() {
+	if s.client == nil {
+		return
+	}
+	if err := s.client.Quit(); err != nil {
+		_ = s.client.Close()
+	}
+	s.client = nil
+}
+
+func (s *SMTPTransport) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeClient()
+	return nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sarp_backend/i18n"
+	texttemplate "text/template"
+)
+
+//go:embed templates/email/*.tmpl
+var emailFiles embed.FS
+
+// Every email has an HTML and a plain text template, both wrapped in the matching
+// layout. The subject lives in the message catalog under "email.<name>.subject".
+const (
+	ConfirmAccountEmail     = "confirm_account"
+	ResetPasswordEmail      = "reset_password"
+	AcceptCharacterEmail    = "character_accepted"
+	DeclineCharacterEmail   = "character_rejected"
+	AccountLockedEmail      = "account_locked"
+	ChangeEmailEmail        = "email_change_confirm"
+	EmailChangedEmail       = "email_change_notice"
+	NameChangeApprovedEmail = "name_change_approved"
+	NameChangeDeniedEmail   = "name_change_denied"
+	CKApprovedEmail         = "ck_approved"
+	CKDeniedEmail           = "ck_denied"
+)
+
+var emailNames = []string{ConfirmAccountEmail, ResetPasswordEmail, AcceptCharacterEmail, DeclineCharacterEmail, AccountLockedEmail, ChangeEmailEmail, EmailChangedEmail, NameChangeApprovedEmail, NameChangeDeniedEmail, CKApprovedEmail, CKDeniedEmail}
+
+type ConfirmAccountData struct {
+	Username string
+	Link     string
+}
+
+type ResetPasswordData struct {
+	Link string
+}
+
+type CharacterAcceptedData struct {
+	Username  string
+	Character string
+	Date      string
+}
+
+type CharacterRejectedData struct {
+	Username  string
+	Character string
+	Date      string
+	Reason    string
+	Admin     string
+}
+
+type AccountLockedData struct {
+	Username string
+	Until    string
+	IP       string
+}
+
+// ChangeEmailData is used for both the link sent to the new address and the notice
+// sent to the old one, Email is the new address.
+type ChangeEmailData struct {
+	Username string
+	Email    string
+	Link     string
+}
+
+// NameChangeData is used for both decisions, Reason and Admin are only shown on denial.
+type NameChangeData struct {
+	Username string
+	OldName  string
+	NewName  string
+	Date     string
+	Reason   string
+	Admin    string
+}
+
+// CKData is sent to the requester and, once approved, to the owner of the character.
+// Reason is only shown on denial.
+type CKData struct {
+	Username  string
+	Character string
+	Date      string
+	Reason    string
+	Admin     string
+}
+
+// Email is a rendered message ready to be queued.
+type Email struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type emailView struct {
+	Locale  string
+	Subject string
+	Data    interface{}
+}
+
+// EmailTemplates renders the transactional emails in the language of the recipient.
+// Values are escaped by html/template, translations are plain text and go through
+// the "t" function.
+type EmailTemplates struct {
+	catalog *i18n.Catalog
+	html    map[string]*htmltemplate.Template
+	text    map[string]*texttemplate.Template
+}
+
+// NewEmailTemplates parses the embedded templates, a file with the same name in
+// overrideDir replaces the embedded one.
+func NewEmailTemplates(catalog *i18n.Catalog, overrideDir string) (*EmailTemplates, error) {
+	e := &EmailTemplates{
+		catalog: catalog,
+		html:    make(map[string]*htmltemplate.Template),
+		text:    make(map[string]*texttemplate.Template),
+	}
+
+	// The real "t" is bound to the locale when rendering.
+	funcs := map[string]interface{}{"t": func(string, ...interface{}) string { return "" }}
+
+	htmlLayout, err := readEmailTemplate(overrideDir, "layout.html.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	textLayout, err := readEmailTemplate(overrideDir, "layout.txt.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range emailNames {
+		htmlContent, errRead := readEmailTemplate(overrideDir, name+".html.tmpl")
+		if errRead != nil {
+			return nil, errRead
+		}
+		textContent, errRead := readEmailTemplate(overrideDir, name+".txt.tmpl")
+		if errRead != nil {
+			return nil, errRead
+		}
+
+		htmlTmpl, errParse := htmltemplate.New("layout").Funcs(funcs).Parse(htmlLayout)
+		if errParse == nil {
+			htmlTmpl, errParse = htmlTmpl.New(name).Parse(htmlContent)
+		}
+		if errParse != nil {
+			return nil, fmt.Errorf("email %s: %w", name, errParse)
+		}
+
+		textTmpl, errParse := texttemplate.New("layout").Funcs(funcs).Parse(textLayout)
+		if errParse == nil {
+			textTmpl, errParse = textTmpl.New(name).Parse(textContent)
+		}
+		if errParse != nil {
+			return nil, fmt.Errorf("email %s: %w", name, errParse)
+		}
+
+		e.html[name] = htmlTmpl
+		e.text[name] = textTmpl
+	}
+
+	return e, nil
+}
+
+func readEmailTemplate(overrideDir, file string) (string, error) {
+	if overrideDir != "" {
+		data, err := os.ReadFile(filepath.Join(overrideDir, file))
+		if err == nil {
+			return string(data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	data, err := fs.ReadFile(emailFiles, "templates/email/"+file)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (e *EmailTemplates) Render(locale, name string, data interface{}) (*Email, error) {
+	htmlTmpl, ok := e.html[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email %s", name)
+	}
+
+	funcs := map[string]interface{}{
+		"t": func(id string, args ...interface{}) string {
+			return e.catalog.T(locale, id, args...)
+		},
+	}
+	view := emailView{
+		Locale:  locale,
+		Subject: e.catalog.T(locale, "email."+name+".subject"),
+		Data:    data,
+	}
+
+	htmlTmpl, err := htmlTmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	var html bytes.Buffer
+	if err = htmlTmpl.Funcs(funcs).ExecuteTemplate(&html, "layout", view); err != nil {
+		return nil, fmt.Errorf("email %s: %w", name, err)
+	}
+
+	textTmpl, err := e.text[name].Clone()
+	if err != nil {
+		return nil, err
+	}
+	var text bytes.Buffer
+	if err = textTmpl.Funcs(funcs).ExecuteTemplate(&text, "layout", view); err != nil {
+		return nil, fmt.Errorf("email %s: %w", name, err)
+	}
+
+	return &Email{Subject: view.Subject, Text: text.String(), HTML: html.String()}, nil
+}
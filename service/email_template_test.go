@@ -0,0 +1,113 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sarp_backend/i18n"
+	"strings"
+	"testing"
+)
+
+func testEmailTemplates(t *testing.T, overrideDir string) (*EmailTemplates, error) {
+	t.Helper()
+
+	catalog, err := i18n.Load()
+	if err != nil {
+		t.Fatalf("Error loading message catalog: %v", err)
+	}
+	return NewEmailTemplates(catalog, overrideDir)
+}
+
+func TestEmailTemplatesRender(t *testing.T) {
+	templates, err := testEmailTemplates(t, "")
+	if err != nil {
+		t.Fatalf("Error loading email templates: %v", err)
+	}
+
+	data := map[string]interface{}{
+		ConfirmAccountEmail:     ConfirmAccountData{Username: "test", Link: "https://app.ro/confirm?token=a&b"},
+		ResetPasswordEmail:      ResetPasswordData{Link: "https://app.ro/confirm-reset?token=a"},
+		AcceptCharacterEmail:    CharacterAcceptedData{Username: "test", Character: "Test_Test", Date: "01/01/2025, 10:00"},
+		DeclineCharacterEmail:   CharacterRejectedData{Username: "test", Character: "Test_Test", Date: "01/01/2025, 10:00", Reason: "test", Admin: "admin"},
+		AccountLockedEmail:      AccountLockedData{Username: "test", Until: "01/01/2025, 10:00", IP: "127.0.0.1"},
+		ChangeEmailEmail:        ChangeEmailData{Username: "test", Email: "new@app.ro", Link: "https://app.ro/confirm-email?token=a"},
+		EmailChangedEmail:       ChangeEmailData{Username: "test", Email: "new@app.ro"},
+		NameChangeApprovedEmail: NameChangeData{Username: "test", OldName: "Test_Test", NewName: "New_Name", Date: "01/01/2025, 10:00"},
+		CKApprovedEmail:         CKData{Username: "test", Character: "Test_Test", Date: "01/01/2025, 10:00", Admin: "admin"},
+		CKDeniedEmail:           CKData{Username: "test", Character: "Test_Test", Date: "01/01/2025, 10:00", Reason: "test", Admin: "admin"},
+		NameChangeDeniedEmail:   NameChangeData{Username: "test", OldName: "Test_Test", NewName: "New_Name", Date: "01/01/2025, 10:00", Reason: "test", Admin: "admin"},
+	}
+
+	for _, locale := range []string{i18n.Romanian, i18n.English} {
+		for _, name := range emailNames {
+			email, errRender := templates.Render(locale, name, data[name])
+			if !assert.NoError(t, errRender, "Error rendering %s in %s", name, locale) {
+				continue
+			}
+
+			assert.NotEmpty(t, email.Subject, "Missing subject for %s in %s", name, locale)
+			assert.Contains(t, email.HTML, `<html lang="`+locale+`">`)
+			assert.True(t, strings.HasSuffix(strings.TrimSpace(email.HTML), "</html>"), "Content outside of the layout for %s", name)
+			assert.NotContains(t, email.Text, "<", "HTML in the text part of %s", name)
+			assert.NotContains(t, email.HTML+email.Text, "%!", "Wrong arguments for %s in %s", name, locale)
+		}
+	}
+
+	email, err := templates.Render(i18n.Romanian, ConfirmAccountEmail, data[ConfirmAccountEmail])
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Confirmare cont UCP", email.Subject)
+		assert.Contains(t, email.HTML, `href="https://app.ro/confirm?token=a&amp;b"`)
+		assert.Contains(t, email.Text, "https://app.ro/confirm?token=a&b")
+	}
+
+	_, err = templates.Render(i18n.Romanian, "unknown", nil)
+	assert.Error(t, err)
+}
+
+func TestEmailTemplatesEscape(t *testing.T) {
+	templates, err := testEmailTemplates(t, "")
+	if err != nil {
+		t.Fatalf("Error loading email templates: %v", err)
+	}
+
+	email, err := templates.Render(i18n.English, DeclineCharacterEmail, CharacterRejectedData{
+		Username:  "test",
+		Character: "Test_Test",
+		Reason:    `<script>alert("x")</script>`,
+		Admin:     "admin",
+	})
+	if err != nil {
+		t.Fatalf("Error rendering email: %v", err)
+	}
+
+	assert.NotContains(t, email.HTML, "<script>")
+	assert.Contains(t, email.HTML, "&lt;script&gt;")
+	assert.Contains(t, email.Text, `Reason: <script>alert("x")</script>`, "The text part must keep the reason as written")
+}
+
+func TestEmailTemplatesOverride(t *testing.T) {
+	dir := t.TempDir()
+	content := `{{define "content"}}<p>Custom {{.Data.Username}}</p>{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "confirm_account.html.tmpl"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Error writing override template: %v", err)
+	}
+
+	templates, err := testEmailTemplates(t, dir)
+	if err != nil {
+		t.Fatalf("Error loading email templates: %v", err)
+	}
+
+	email, err := templates.Render(i18n.English, ConfirmAccountEmail, ConfirmAccountData{Username: "test", Link: "https://app.ro"})
+	if assert.NoError(t, err) {
+		assert.Contains(t, email.HTML, "<p>Custom test</p>")
+		assert.Contains(t, email.HTML, "<!DOCTYPE html>", "Overrides keep the embedded layout")
+		assert.Contains(t, email.Text, "https://app.ro", "Only the overridden file changes")
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "layout.txt.tmpl"), []byte(`{{define "layout"}`), 0o644); err != nil {
+		t.Fatalf("Error writing override template: %v", err)
+	}
+	_, err = testEmailTemplates(t, dir)
+	assert.Error(t, err, "A broken override must fail at startup")
+}
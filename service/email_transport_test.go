@@ -0,0 +1,299 @@
+package service
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileTransport(t *testing.T) {
+	dir := t.TempDir()
+	transport, err := NewFileTransport(dir, "ucp@app.ro")
+	if err != nil {
+		t.Fatalf("Error creating file transport: %v", err)
+	}
+
+	err = transport.SendEmail("test@app.ro", "Subject", "Text body", "<p>HTML body</p>")
+	assert.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "new", "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one .eml file in new, got %v (%v)", files, err)
+	}
+	tmp, _ := os.ReadDir(filepath.Join(dir, "tmp"))
+	assert.Empty(t, tmp, "Complete messages must be moved out of tmp")
+
+	file, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("Error opening .eml file: %v", err)
+	}
+	defer file.Close()
+
+	message, err := mail.ReadMessage(file)
+	if err != nil {
+		t.Fatalf("Error parsing .eml file: %v", err)
+	}
+	assert.Equal(t, "test@app.ro", message.Header.Get("To"))
+	assert.Equal(t, "ucp@app.ro", message.Header.Get("From"))
+	assert.Equal(t, "Subject", message.Header.Get("Subject"))
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("Error parsing content type: %v", err)
+	}
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	var types []string
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, errPart := reader.NextPart()
+		if errPart != nil {
+			break
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		types = append(types, partType)
+	}
+	assert.Equal(t, []string{"text/plain", "text/html"}, types)
+
+	_, err = NewFileTransport("", "ucp@app.ro")
+	assert.Error(t, err)
+}
+
+func TestMemoryTransport(t *testing.T) {
+	transport := NewMemoryTransport("ucp@app.ro", 2)
+
+	for _, subject := range []string{"first", "second", "third"} {
+		assert.NoError(t, transport.SendEmail("test@app.ro", subject, "text", "<p>html</p>"))
+	}
+
+	messages := transport.Messages()
+	if assert.Len(t, messages, 2, "Only the last messages are kept") {
+		assert.Equal(t, "third", messages[0].Subject)
+		assert.Equal(t, "second", messages[1].Subject)
+	}
+
+	message, ok := transport.Message(messages[0].ID)
+	assert.True(t, ok)
+	assert.Equal(t, "<p>html</p>", message.HTML)
+
+	_, ok = transport.Message(1)
+	assert.False(t, ok, "Dropped messages can't be opened")
+}
+
+func TestNewEmailTransport(t *testing.T) {
+	transport, err := NewEmailTransport(EmailTransportConfig{Transport: EmailTransportMemory})
+	assert.NoError(t, err)
+	assert.IsType(t, &MemoryTransport{}, transport)
+
+	transport, err = NewEmailTransport(EmailTransportConfig{Transport: EmailTransportFile, FileDir: t.TempDir()})
+	assert.NoError(t, err)
+	assert.IsType(t, &FileTransport{}, transport)
+
+	transport, err = NewEmailTransport(EmailTransportConfig{Transport: EmailTransportSMTP, SMTPHost: "localhost", SMTPPort: 587, SMTPSecurity: SMTPStartTLS})
+	assert.NoError(t, err)
+	assert.IsType(t, &SMTPTransport{}, transport)
+
+	_, err = NewEmailTransport(EmailTransportConfig{Transport: EmailTransportSMTP, SMTPSecurity: "none"})
+	assert.Error(t, err)
+
+	_, err = NewEmailTransport(EmailTransportConfig{Transport: "carrier-pigeon"})
+	assert.Error(t, err)
+}
+
+// fakeSMTP is a minimal SMTP server, it counts connections and the messages received.
+type fakeSMTP struct {
+	listener net.Listener
+	tls      *tls.Config
+	startTLS bool
+
+	mu          sync.Mutex
+	connections int
+	messages    []string
+}
+
+func newFakeSMTP(t *testing.T, startTLS bool) (*fakeSMTP, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Error parsing certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+
+	server := &fakeSMTP{
+		listener: listener,
+		tls:      &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+		startTLS: startTLS,
+	}
+	go server.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return server, pool
+}
+
+func (f *fakeSMTP) port() int {
+	return f.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.connections++
+		f.mu.Unlock()
+		go f.session(conn)
+	}
+}
+
+func (f *fakeSMTP) session(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	secure := false
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"):
+			extensions := []string{"250-localhost"}
+			if f.startTLS && !secure {
+				extensions = append(extensions, "250-STARTTLS")
+			}
+			if secure {
+				extensions = append(extensions, "250-AUTH PLAIN")
+			}
+			extensions = append(extensions, "250 8BITMIME")
+			for _, e := range extensions {
+				reply(e)
+			}
+		case command == "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, f.tls)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn = tlsConn
+			reader = bufio.NewReader(conn)
+			secure = true
+		case strings.HasPrefix(command, "AUTH"):
+			reply("235 ok")
+		case strings.HasPrefix(command, "MAIL"), strings.HasPrefix(command, "RCPT"), command == "RSET", command == "NOOP":
+			reply("250 ok")
+		case command == "DATA":
+			reply("354 go ahead")
+			var body strings.Builder
+			for {
+				data, errData := reader.ReadString('\n')
+				if errData != nil {
+					return
+				}
+				if data == ".\r\n" {
+					break
+				}
+				body.WriteString(data)
+			}
+			f.mu.Lock()
+			f.messages = append(f.messages, body.String())
+			f.mu.Unlock()
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command")
+		}
+	}
+}
+
+func TestSMTPTransportStartTLS(t *testing.T) {
+	server, pool := newFakeSMTP(t, true)
+
+	transport, err := NewSMTPTransport("localhost", server.port(), "user", "password", "ucp@app.ro", SMTPStartTLS, time.Minute)
+	if err != nil {
+		t.Fatalf("Error creating smtp transport: %v", err)
+	}
+	transport.tlsConfig.RootCAs = pool
+	defer transport.Close()
+
+	assert.NoError(t, transport.SendEmail("first@app.ro", "First", "text", "<p>html</p>"))
+	assert.NoError(t, transport.SendEmail("second@app.ro", "Second", "text", "<p>html</p>"))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, 1, server.connections, "The connection must be reused between emails")
+	if assert.Len(t, server.messages, 2) {
+		assert.Contains(t, server.messages[0], "Subject: First")
+		assert.Contains(t, server.messages[1], "To: second@app.ro")
+	}
+}
+
+func TestSMTPTransportRequiresStartTLS(t *testing.T) {
+	server, pool := newFakeSMTP(t, false)
+
+	transport, err := NewSMTPTransport("localhost", server.port(), "user", "password", "ucp@app.ro", SMTPStartTLS, time.Minute)
+	if err != nil {
+		t.Fatalf("Error creating smtp transport: %v", err)
+	}
+	transport.tlsConfig.RootCAs = pool
+
+	err = transport.SendEmail("test@app.ro", "Subject", "text", "<p>html</p>")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "STARTTLS")
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Empty(t, server.messages, "Nothing may be sent over a plain connection")
+}
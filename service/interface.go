@@ -1,45 +1,118 @@
 package service
 
 import (
+	"context"
 	"github.com/gofiber/fiber/v2"
 	"sarp_backend/model"
+	"time"
 )
 
 type UserServiceInterface interface {
-	Create(data *model.RegisterAPI) error
-	ActivateAccount(email string) error
-	CheckActivation(name string) (bool, error)
-	CheckForBan(name string) (bool, error)
-	Verify(data *model.LoginAPI) error
-	UpdatePassword(email string, password string) error
-	Fetch(name string, email string) (bool, error)
-	FetchMail(name string) (string, error)
-	IsTester(name string) (bool, error)
-	IsAdmin(name string) (bool, error)
-	GetStats(name string) (*model.GetStatsAPI, error)
-	GetStaff() ([]model.GetStaffAPI, error)
-	GetServerStats() (*model.ServerStatsAPI, error)
-	FetchCharacter(name string) (*model.CharacterAPI, error)
-	Ban(data *model.BanAPI) error
-	BanList() ([]model.BanAPI, error)
-	Unban(data *model.BanAPI) error
-	Ajail(data *model.AjailAPI) error
-	Logs(data *model.LogsAPI) ([]map[string]interface{}, error)
-	DeleteExpired() error
+	Create(ctx context.Context, data *model.RegisterAPI) error
+	ActivateAccount(ctx context.Context, email string) error
+	CheckActivation(ctx context.Context, name string) (bool, error)
+	CheckForBan(ctx context.Context, name string) (bool, error)
+	Verify(ctx context.Context, data *model.LoginAPI) error
+	UpdatePassword(ctx context.Context, email string, password string) error
+	ChangePassword(ctx context.Context, name, session string, data *model.ChangePasswordAPI) error
+	ResetPassword(ctx context.Context, data *model.ResetPasswordAPI, audit *model.AuditAPI) error
+	ChangeEmail(ctx context.Context, name, email string) error
+	Profile(ctx context.Context, name string) (*model.ProfileAPI, error)
+	UpdateProfile(ctx context.Context, name string, data *model.ProfileAPI) error
+	Fetch(ctx context.Context, name string, email string) (bool, error)
+	FetchMail(ctx context.Context, name string) (string, error)
+	FetchUsername(ctx context.Context, email string) (string, error)
+	Locale(ctx context.Context, name string) (string, error)
+	SetLocale(ctx context.Context, name, locale string) error
+	TesterLevel(ctx context.Context, name string) (int, error)
+	AdminLevel(ctx context.Context, name string) (int, error)
+	SetStaffLevel(ctx context.Context, data *model.StaffLevelAPI, audit *model.AuditAPI) error
+	GetStats(ctx context.Context, name string) (*model.GetStatsAPI, error)
+	GetStaff(ctx context.Context) ([]model.GetStaffAPI, error)
+	GetServerStats(ctx context.Context) (*model.ServerStatsAPI, error)
+	FetchCharacter(ctx context.Context, name string) (*model.CharacterAPI, error)
+	Ban(ctx context.Context, data *model.BanAPI, audit *model.AuditAPI) error
+	BanList(ctx context.Context) ([]model.BanAPI, error)
+	Unban(ctx context.Context, data *model.BanAPI, audit *model.AuditAPI) error
+	Ajail(ctx context.Context, data *model.AjailAPI, audit *model.AuditAPI) error
+	Logs(ctx context.Context, data *model.LogsAPI) (*model.LogsPageAPI, error)
+	DeleteExpired(ctx context.Context) error
 }
 
 type AuthServiceInterface interface {
-	CheckSession(ctx *fiber.Ctx) (string, bool, bool, error)
-	SaveSession(ctx *fiber.Ctx, name string, isTester bool, isAdmin bool) error
+	CheckSession(ctx *fiber.Ctx) (string, int, int, error)
+	SaveSession(ctx *fiber.Ctx, name string, adminLevel int, testerLevel int, twoFactor string) error
+	CheckTwoFactor(ctx *fiber.Ctx) (string, error)
 	DestroySession(ctx *fiber.Ctx) error
+	ListSessions(ctx *fiber.Ctx) ([]model.SessionAPI, error)
+	RevokeSession(ctx *fiber.Ctx, id string) error
+	RevokeOtherSessions(ctx *fiber.Ctx) error
+	SessionID(ctx *fiber.Ctx) (string, error)
+	RevokeUserSessions(ctx context.Context, name string, audit *model.AuditAPI) error
 	Authenticate(ctx *fiber.Ctx) error
 }
 
 type CharacterServiceInterface interface {
-	Create(data *model.CharacterDataAPI) error
-	FetchWaiting() ([]model.CharacterDataAPI, error)
-	AcceptCharacter(data model.CharacterAPI) error
-	DeclineCharacter(data model.RejectCharacterAPI) error
+	Create(ctx context.Context, data *model.CharacterDataAPI) error
+	FetchWaiting(ctx context.Context) ([]model.CharacterDataAPI, error)
+	Questionnaire(ctx context.Context) (*model.QuestionnaireAPI, error)
+	UpdateQuestionnaire(ctx context.Context, data *model.QuestionnaireAPI, audit *model.AuditAPI) (*model.QuestionnaireAPI, error)
+	AcceptCharacter(ctx context.Context, data model.CharacterAPI, audit *model.AuditAPI) (string, error)
+	DeclineCharacter(ctx context.Context, data model.RejectCharacterAPI, audit *model.AuditAPI) (string, error)
+	Claim(ctx context.Context, character, reviewer string) (*model.ClaimResultAPI, error)
+	Release(ctx context.Context, character, reviewer string) error
+	AddNote(ctx context.Context, data *model.ReviewNoteAPI, reviewer string) error
+	History(ctx context.Context, character string) (*model.ReviewHistoryAPI, error)
+	Application(ctx context.Context, username, character string) (*model.ApplicationDraftAPI, error)
+	Edit(ctx context.Context, data *model.CharacterDataAPI) error
+	Withdraw(ctx context.Context, username, character string) error
+	Resubmit(ctx context.Context, data *model.CharacterDataAPI) error
+	RequestNameChange(ctx context.Context, username string, data *model.NameChangeRequestAPI) (int64, error)
+	NameChanges(ctx context.Context, username, status string) ([]model.NameChangeAPI, error)
+	ApproveNameChange(ctx context.Context, id int64, admin string, audit *model.AuditAPI) (*model.NameChangeAPI, error)
+	DenyNameChange(ctx context.Context, data *model.NameChangeDecisionAPI, admin string, audit *model.AuditAPI) (*model.NameChangeAPI, error)
+	RequestCK(ctx context.Context, requester string, staff bool, data *model.CKRequestAPI) (int64, error)
+	CKRequests(ctx context.Context, requester, status string) ([]model.CKAPI, error)
+	ApproveCK(ctx context.Context, id int64, admin string, audit *model.AuditAPI) (*model.CKAPI, error)
+	DenyCK(ctx context.Context, data *model.CKDecisionAPI, admin string, audit *model.AuditAPI) (*model.CKAPI, error)
+}
+
+type TwoFactorServiceInterface interface {
+	Enabled(ctx context.Context, name string) (bool, error)
+	Enroll(ctx context.Context, name string) (*model.TwoFactorEnrollAPI, error)
+	Confirm(ctx context.Context, name string, code string) ([]string, error)
+	Verify(ctx context.Context, name string, code string) error
+	Disable(ctx context.Context, name string, code string) error
+}
+
+type TokenServiceInterface interface {
+	Issue(ctx context.Context, purpose, username, email string) (string, error)
+	Peek(ctx context.Context, purpose, token string) (*model.TokenAPI, error)
+	Consume(ctx context.Context, purpose, token string) (*model.TokenAPI, error)
+	Recent(ctx context.Context, purpose, email string, window time.Duration) (int, error)
+}
+
+type PermissionServiceInterface interface {
+	Allowed(adminLevel, testerLevel int, permission string) bool
+	List(adminLevel, testerLevel int) []string
+}
+
+type LoginGuardServiceInterface interface {
+	Check(ctx context.Context, name, ip string) (time.Duration, error)
+	Fail(ctx context.Context, name, ip string) error
+	Reset(ctx context.Context, name string) error
+	Unlock(ctx context.Context, name string, audit *model.AuditAPI) error
+}
+
+type AuditServiceInterface interface {
+	Record(ctx context.Context, data *model.AuditAPI) error
+	List(ctx context.Context, filter *model.AuditFilterAPI) (*model.AuditPageAPI, error)
+}
+
+type OutboxServiceInterface interface {
+	Enqueue(ctx context.Context, key, to string, email *Email) error
+	List(ctx context.Context, filter *model.EmailFilterAPI) (*model.EmailPageAPI, error)
+	Retry(ctx context.Context, id int64, audit *model.AuditAPI) error
 }
 
 type LoggerInterface interface {
@@ -51,5 +124,5 @@ type LoggerInterface interface {
 }
 
 type EmailInterface interface {
-	SendEmail(to, subject, body string) error
+	SendEmail(to, subject, text, html string) error
 }
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sarp_backend/i18n"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"strings"
+	"time"
+)
+
+type LoginGuardConfig struct {
+	FreeAttempts   int
+	LockAttempts   int
+	IPLockAttempts int
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	LockDuration   time.Duration
+	ResetAfter     time.Duration
+}
+
+// LoginGuardStore is the storage behind LoginGuardService, the attempts and the
+// address and locale of the lock notice.
+type LoginGuardStore interface {
+	repository.LoginAttemptRepository
+	FetchMail(ctx context.Context, name string) (string, error)
+	FetchLocale(ctx context.Context, name string) (string, error)
+}
+
+// LoginGuardService tracks failed logins per username and per IP. After the free
+// attempts every failure doubles the wait before the next try, once the lock
+// threshold is reached the account is locked and the owner is notified.
+type LoginGuardService struct {
+	userRepository LoginGuardStore
+	outbox         OutboxServiceInterface
+	templates      *EmailTemplates
+	config         LoginGuardConfig
+}
+
+func NewLoginGuardService(repo LoginGuardStore, outbox OutboxServiceInterface, templates *EmailTemplates, config LoginGuardConfig) *LoginGuardService {
+	return &LoginGuardService{userRepository: repo, outbox: outbox, templates: templates, config: config}
+}
+
+func (l *LoginGuardService) Check(ctx context.Context, name, ip string) (time.Duration, error) {
+	var retryAfter time.Duration
+
+	for _, key := range l.keys(name, ip) {
+		data, err := l.userRepository.FetchLoginAttempt(ctx, key[0], key[1])
+		if err != nil {
+			return 0, err
+		}
+		if data == nil || !data.LockedUntil.Valid {
+			continue
+		}
+
+		if wait := time.Until(data.LockedUntil.Time); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	return retryAfter, nil
+}
+
+func (l *LoginGuardService) Fail(ctx context.Context, name, ip string) error {
+	for _, key := range l.keys(name, ip) {
+		failures, err := l.userRepository.AddLoginFailure(ctx, key[0], key[1], l.config.ResetAfter)
+		if err != nil {
+			return err
+		}
+
+		lockAttempts := l.config.LockAttempts
+		if key[0] == repository.AttemptScopeIP {
+			lockAttempts = l.config.IPLockAttempts
+		}
+
+		delay := l.delay(failures, lockAttempts)
+		if delay == 0 {
+			continue
+		}
+
+		until := time.Now().Add(delay)
+		if err = l.userRepository.LockLogin(ctx, key[0], key[1], until); err != nil {
+			return err
+		}
+
+		if key[0] == repository.AttemptScopeUser && failures == lockAttempts {
+			l.notify(ctx, key[1], ip, until)
+		}
+	}
+
+	return nil
+}
+
+func (l *LoginGuardService) Reset(ctx context.Context, name string) error {
+	return l.userRepository.ClearLoginAttempts(ctx, repository.AttemptScopeUser, strings.ToLower(name), nil)
+}
+
+// Unlock is Reset done by a staff member on behalf of the player.
+func (l *LoginGuardService) Unlock(ctx context.Context, name string, audit *model.AuditAPI) error {
+	return l.userRepository.ClearLoginAttempts(ctx, repository.AttemptScopeUser, strings.ToLower(name), auditRecord(audit))
+}
+
+func (l *LoginGuardService) delay(failures, lockAttempts int) time.Duration {
+	if failures >= lockAttempts {
+		return l.config.LockDuration
+	}
+
+	if failures < l.config.FreeAttempts {
+		return 0
+	}
+
+	delay := l.config.BaseDelay << (failures - l.config.FreeAttempts)
+	if delay <= 0 || delay > l.config.MaxDelay {
+		delay = l.config.MaxDelay
+	}
+	return delay
+}
+
+func (l *LoginGuardService) keys(name, ip string) [][2]string {
+	var keys [][2]string
+	if name != "" {
+		keys = append(keys, [2]string{repository.AttemptScopeUser, strings.ToLower(name)})
+	}
+	if ip != "" {
+		keys = append(keys, [2]string{repository.AttemptScopeIP, ip})
+	}
+	return keys
+}
+
+func (l *LoginGuardService) notify(ctx context.Context, name, ip string, until time.Time) {
+	mail, err := l.userRepository.FetchMail(ctx, name)
+	if err != nil || mail == "" {
+		return
+	}
+
+	// The owner may not be the one trying to log in, so their own language wins.
+	locale, err := l.userRepository.FetchLocale(ctx, name)
+	if err != nil || locale == "" {
+		locale = i18n.FromContext(ctx)
+	}
+
+	email, err := l.templates.Render(locale, AccountLockedEmail, AccountLockedData{
+		Username: name,
+		Until:    until.Format("02/01/2006, 15:04"),
+		IP:       ip,
+	})
+	if err != nil {
+		if globalLogger != nil {
+			globalLogger.Exception(fmt.Sprintf("LoginGuard: can't render lockout email to %s: %v", name, err))
+		}
+		return
+	}
+
+	key := OutboxKey(AccountLockedEmail, name, until.Format(time.RFC3339))
+	if err = l.outbox.Enqueue(ctx, key, mail, email); err != nil && globalLogger != nil {
+		globalLogger.Exception(fmt.Sprintf("LoginGuard: can't queue lockout email to %s: %v", name, err))
+	}
+}
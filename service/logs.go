@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"strings"
+)
+
+// logTables whitelists the game log tables the UCP can browse. Every table can be
+// paged by ID, the date, name and search filters need the columns they run on to be
+// declared and are refused otherwise. Only tables whose definition is known declare
+// them, the other ones are owned by the game server and left unfiltered.
+var logTables = map[string]repository.LogTableDB{
+	"logs_ajail":    {},
+	"logs_ban":      {TimeColumn: "Date", NameColumns: []string{"Admin", "Player"}, SearchColumns: []string{"Reason"}},
+	"logs_warn":     {},
+	"logs_kick":     {},
+	"logs_unban":    {},
+	"logs_charity":  {},
+	"hit_logs":      {},
+	"logs_ck":       {TimeColumn: "Date", NameColumns: []string{"Admin", "Player"}, SearchColumns: []string{"Reason"}},
+	"logs_transfer": {},
+	"logs_givecash": {},
+	"logs_givedrug": {},
+	"logs_givegun":  {},
+	"logs_pay":      {},
+	"logs_ask":      {},
+	"logs_report":   {},
+	"namechanges":   {TimeColumn: "Date", NameColumns: []string{"OldName", "NewName", "Admin"}},
+}
+
+// checkLogFilter refuses the filters table has no columns declared for.
+func checkLogFilter(table repository.LogTableDB, data *model.LogsAPI) error {
+	if (data.From != "" || data.To != "") && table.TimeColumn == "" {
+		return fmt.Errorf("%w: %s can't be filtered by date", model.ErrInvalidFilter, table.Name)
+	}
+	if (data.Account != "" || data.Character != "") && len(table.NameColumns) == 0 {
+		return fmt.Errorf("%w: %s can't be filtered by name", model.ErrInvalidFilter, table.Name)
+	}
+	if data.Search != "" && len(table.SearchColumns) == 0 {
+		return fmt.Errorf("%w: %s can't be searched", model.ErrInvalidFilter, table.Name)
+	}
+	return nil
+}
+
+func (u *UserService) Logs(ctx context.Context, data *model.LogsAPI) (*model.LogsPageAPI, error) {
+	table, ok := logTables[strings.ToLower(data.Type)]
+	if !ok {
+		return nil, model.ErrInvalidLogType
+	}
+	table.Name = strings.ToLower(data.Type)
+	if err := checkLogFilter(table, data); err != nil {
+		return nil, err
+	}
+
+	page, err := u.userRepository.FetchLogs(ctx, table, &repository.LogsFilterDB{
+		Cursor:    data.Cursor,
+		Limit:     data.Limit,
+		From:      data.From,
+		To:        data.To,
+		Account:   data.Account,
+		Character: data.Character,
+		Search:    data.Search,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logs := page.Rows
+	if logs == nil {
+		logs = []map[string]interface{}{}
+	}
+
+	return &model.LogsPageAPI{
+		Logs:           logs,
+		NextCursor:     page.NextCursor,
+		Total:          page.Total,
+		TotalEstimated: page.Estimated,
+	}, nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"testing"
+)
+
+func TestLogsWithoutColumns(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	for i := 0; i < 5; i++ {
+		repo.InsertLog("logs_kick", map[string]interface{}{"Admin": "Admin_Test", "Player": fmt.Sprintf("Player_%d", i)})
+	}
+
+	users := NewUserService(repo, nil)
+
+	first, err := users.Logs(ctx, &model.LogsAPI{Type: "logs_kick", Limit: 3})
+	if assert.NoError(t, err) {
+		assert.Len(t, first.Logs, 3)
+		assert.Equal(t, 5, first.Total)
+		assert.NotZero(t, first.NextCursor)
+	}
+
+	second, err := users.Logs(ctx, &model.LogsAPI{Type: "LOGS_KICK", Limit: 3, Cursor: first.NextCursor})
+	if assert.NoError(t, err) {
+		assert.Len(t, second.Logs, 2)
+		assert.Zero(t, second.NextCursor)
+	}
+
+	_, err = users.Logs(ctx, &model.LogsAPI{Type: "logs_kick", Limit: 3, Account: "Admin_Test"})
+	assert.ErrorIs(t, err, model.ErrInvalidFilter)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+)
+
+// nameChangeLimit caps the queue and the history of a player, the oldest requests are
+// left out.
+const nameChangeLimit = 200
+
+func nameChangeRecord(data *repository.NameChangeDB) model.NameChangeAPI {
+	ret := model.NameChangeAPI{
+		ID:            data.ID,
+		Username:      data.Username,
+		CharacterName: data.Character,
+		NewName:       data.NewName,
+		Status:        data.Status,
+		ReviewedBy:    data.ReviewedBy,
+		Reason:        data.Reason,
+		CreatedAt:     data.CreatedAt,
+	}
+	if data.ReviewedAt.Valid {
+		reviewedAt := data.ReviewedAt.Time
+		ret.ReviewedAt = &reviewedAt
+	}
+	return ret
+}
+
+// RequestNameChange queues a rename of an accepted character of username. The request
+// needs a name change voucher, it is spent when staff approve it.
+func (c *CharacterService) RequestNameChange(ctx context.Context, username string, data *model.NameChangeRequestAPI) (int64, error) {
+	if err := data.Validate(); err != nil {
+		return 0, err
+	}
+
+	return c.userRepository.CreateNameChange(ctx, &repository.NameChangeDB{
+		Username:  username,
+		Character: data.CharacterName,
+		NewName:   data.NewName,
+	})
+}
+
+// NameChanges returns the latest requests, of username only when it isn't empty.
+func (c *CharacterService) NameChanges(ctx context.Context, username, status string) ([]model.NameChangeAPI, error) {
+	requests, err := c.userRepository.FetchNameChanges(ctx, &repository.NameChangeFilterDB{
+		Username: username,
+		Status:   status,
+		Limit:    nameChangeLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]model.NameChangeAPI, 0, len(requests))
+	for i := range requests {
+		ret = append(ret, nameChangeRecord(&requests[i]))
+	}
+	return ret, nil
+}
+
+func (c *CharacterService) ApproveNameChange(ctx context.Context, id int64, admin string, audit *model.AuditAPI) (*model.NameChangeAPI, error) {
+	request, err := c.userRepository.ApproveNameChange(ctx, id, admin, auditRecord(audit))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := nameChangeRecord(request)
+	return &ret, nil
+}
+
+func (c *CharacterService) DenyNameChange(ctx context.Context, data *model.NameChangeDecisionAPI, admin string, audit *model.AuditAPI) (*model.NameChangeAPI, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	request, err := c.userRepository.DenyNameChange(ctx, data.ID, admin, data.Reason, auditRecord(audit))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := nameChangeRecord(request)
+	return &ret, nil
+}
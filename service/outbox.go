@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"strings"
+	"sync"
+	"time"
+)
+
+type OutboxConfig struct {
+	Workers      int
+	BatchSize    int
+	PollInterval time.Duration
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	MaxAttempts  int
+	Lease        time.Duration
+}
+
+// OutboxService queues emails in the email_outbox table and delivers them from a
+// pool of background workers. A failed send is retried with exponential backoff,
+// after MaxAttempts the message is dead-lettered until a staff member retries it.
+type OutboxService struct {
+	userRepository repository.OutboxRepository
+	sender         EmailInterface
+	config         OutboxConfig
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewOutboxService(repo repository.OutboxRepository, sender EmailInterface, config OutboxConfig) *OutboxService {
+	return &OutboxService{userRepository: repo, sender: sender, config: config, done: make(chan struct{})}
+}
+
+// OutboxKey builds the idempotency key of an email from the parts that make it unique,
+// enqueueing twice with the same key sends a single email.
+func OutboxKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+func (o *OutboxService) Enqueue(ctx context.Context, key, to string, email *Email) error {
+	_, err := o.userRepository.EnqueueEmail(ctx, &repository.EmailDB{
+		IdempotencyKey: key,
+		Recipient:      to,
+		Subject:        email.Subject,
+		TextBody:       email.Text,
+		Body:           email.HTML,
+	})
+	return err
+}
+
+// Start launches the workers, Close stops them after the batch they are sending.
+func (o *OutboxService) Start() {
+	for i := 0; i < o.config.Workers; i++ {
+		o.wg.Add(1)
+		go o.work()
+	}
+}
+
+func (o *OutboxService) Close() {
+	close(o.done)
+	o.wg.Wait()
+}
+
+func (o *OutboxService) work() {
+	defer o.wg.Done()
+
+	for {
+		claimed, err := o.Dispatch(context.Background())
+		if err != nil && globalLogger != nil {
+			globalLogger.Exception(fmt.Sprintf("Outbox: can't dispatch emails: %v", err))
+		}
+
+		// A full batch means more mail is probably due, don't wait for the next poll.
+		if err == nil && claimed == o.config.BatchSize {
+			select {
+			case <-o.done:
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-o.done:
+			return
+		case <-time.After(o.config.PollInterval):
+		}
+	}
+}
+
+// Dispatch claims one batch of due emails and tries to send them, it returns how many
+// were claimed.
+func (o *OutboxService) Dispatch(ctx context.Context) (int, error) {
+	emails, err := o.userRepository.ClaimEmails(ctx, o.config.BatchSize, o.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range emails {
+		if errSend := o.sender.SendEmail(e.Recipient, e.Subject, e.TextBody, e.Body); errSend != nil {
+			status := repository.EmailPending
+			if e.Attempts+1 >= o.config.MaxAttempts {
+				status = repository.EmailDead
+				if globalLogger != nil {
+					globalLogger.Exception(fmt.Sprintf("Outbox: giving up on email %d to %s: %v", e.ID, e.Recipient, errSend))
+				}
+			}
+
+			if err = o.userRepository.MarkEmailFailed(ctx, e.ID, status, errSend.Error(), time.Now().Add(o.backoff(e.Attempts+1))); err != nil {
+				return len(emails), err
+			}
+			continue
+		}
+
+		if err = o.userRepository.MarkEmailSent(ctx, e.ID); err != nil {
+			return len(emails), err
+		}
+	}
+
+	return len(emails), nil
+}
+
+// backoff is the wait before the next try after the given number of failed attempts.
+func (o *OutboxService) backoff(attempts int) time.Duration {
+	delay := o.config.BaseDelay << (attempts - 1)
+	if delay <= 0 || delay > o.config.MaxDelay {
+		delay = o.config.MaxDelay
+	}
+	return delay
+}
+
+func (o *OutboxService) List(ctx context.Context, filter *model.EmailFilterAPI) (*model.EmailPageAPI, error) {
+	emails, total, err := o.userRepository.FetchEmails(ctx, &repository.EmailFilterDB{
+		Status: filter.Status,
+		Limit:  filter.PerPage,
+		Offset: (filter.Page - 1) * filter.PerPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.EmailPageAPI{
+		Entries: []model.EmailAPI{},
+		Page:    filter.Page,
+		PerPage: filter.PerPage,
+		Total:   total,
+	}
+	for _, e := range emails {
+		entry := model.EmailAPI{
+			ID:            e.ID,
+			Recipient:     e.Recipient,
+			Subject:       e.Subject,
+			Status:        e.Status,
+			Attempts:      e.Attempts,
+			LastError:     e.LastError,
+			NextAttemptAt: e.NextAttemptAt,
+			CreatedAt:     e.CreatedAt,
+		}
+		if e.SentAt.Valid {
+			entry.SentAt = &e.SentAt.Time
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+
+	return page, nil
+}
+
+func (o *OutboxService) Retry(ctx context.Context, id int64, audit *model.AuditAPI) error {
+	return o.userRepository.RetryEmail(ctx, id, auditRecord(audit))
+}
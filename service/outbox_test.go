@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"testing"
+	"time"
+)
+
+func outboxEntry(t *testing.T, outbox *OutboxService) model.EmailAPI {
+	t.Helper()
+
+	page, err := outbox.List(context.Background(), &model.EmailFilterAPI{Page: 1, PerPage: 10})
+	if err != nil {
+		t.Fatalf("Error listing the outbox: %v", err)
+	}
+	if len(page.Entries) != 1 {
+		t.Fatalf("Expected one email in the outbox, got %d", len(page.Entries))
+	}
+	return page.Entries[0]
+}
+
+func TestOutboxDispatch(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	email := new(MockEmailService)
+	email.On("SendEmail", "test@test.ro", "subject", "text", "html").Return(nil)
+
+	outbox := NewOutboxService(repo, email, OutboxConfig{BatchSize: 10, MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour})
+
+	key := OutboxKey("test", "1")
+	for i := 0; i < 2; i++ {
+		if err := outbox.Enqueue(ctx, key, "test@test.ro", &Email{Subject: "subject", Text: "text", HTML: "html"}); err != nil {
+			t.Fatalf("Error enqueueing email: %v", err)
+		}
+	}
+
+	claimed, err := outbox.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, claimed, "An idempotency key must only be queued once")
+	email.AssertNumberOfCalls(t, "SendEmail", 1)
+
+	entry := outboxEntry(t, outbox)
+	assert.Equal(t, repository.EmailSent, entry.Status)
+	assert.Equal(t, 1, entry.Attempts)
+	assert.NotNil(t, entry.SentAt)
+
+	claimed, err = outbox.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, claimed, "Sent emails must not be claimed again")
+}
+
+func TestOutboxDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	email := new(MockEmailService)
+	email.On("SendEmail", "test@test.ro", "subject", "text", "html").Return(errors.New("smtp is down"))
+
+	// Without delays every failed email is due again right away.
+	outbox := NewOutboxService(repo, email, OutboxConfig{BatchSize: 10, MaxAttempts: 3})
+
+	if err := outbox.Enqueue(ctx, OutboxKey("test"), "test@test.ro", &Email{Subject: "subject", Text: "text", HTML: "html"}); err != nil {
+		t.Fatalf("Error enqueueing email: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		claimed, err := outbox.Dispatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, claimed, "Unexpected claimed emails for attempt %d", i)
+	}
+
+	entry := outboxEntry(t, outbox)
+	assert.Equal(t, repository.EmailDead, entry.Status)
+	assert.Equal(t, 3, entry.Attempts)
+	assert.Equal(t, "smtp is down", entry.LastError)
+
+	claimed, err := outbox.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, claimed, "Dead emails must wait for a retry")
+
+	assert.NoError(t, outbox.Retry(ctx, entry.ID, nil))
+	entry = outboxEntry(t, outbox)
+	assert.Equal(t, repository.EmailPending, entry.Status)
+	assert.Zero(t, entry.Attempts)
+
+	claimed, err = outbox.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+
+	assert.ErrorIs(t, outbox.Retry(ctx, 999, nil), model.ErrEmailNotFound)
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	outbox := NewOutboxService(nil, nil, OutboxConfig{BaseDelay: time.Minute, MaxDelay: 10 * time.Minute})
+
+	assert.Equal(t, time.Minute, outbox.backoff(1))
+	assert.Equal(t, 2*time.Minute, outbox.backoff(2))
+	assert.Equal(t, 8*time.Minute, outbox.backoff(4))
+	assert.Equal(t, 10*time.Minute, outbox.backoff(5))
+	assert.Equal(t, 10*time.Minute, outbox.backoff(80), "Overflowing delays must be capped")
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jzelinskie/whirlpool"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const (
+	HashWhirlpool = "whirlpool"
+	HashArgon2id  = "argon2id"
+)
+
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password string, encoded string) bool
+	NeedsRehash(encoded string) bool
+}
+
+type WhirlpoolHasher struct{}
+
+func (w *WhirlpoolHasher) Hash(password string) (string, error) {
+	return hashWP(password), nil
+}
+
+// Verify compares case-insensitively because the game server stores upper case hex digests.
+func (w *WhirlpoolHasher) Verify(password string, encoded string) bool {
+	expected := []byte(hashWP(password))
+	actual := []byte(strings.ToLower(encoded))
+	return subtle.ConstantTimeCompare(expected, actual) == 1
+}
+
+func (w *WhirlpoolHasher) NeedsRehash(encoded string) bool {
+	return !isWhirlpoolHash(encoded)
+}
+
+type Argon2idHasher struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	KeyLen  uint32
+	SaltLen uint32
+}
+
+func NewArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 2,
+		KeyLen:  32,
+		SaltLen: 16,
+	}
+}
+
+func (a *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memory, a.Time, a.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *Argon2idHasher) Verify(password string, encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+func (a *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.Time != a.Time || params.Memory != a.Memory || params.Threads != a.Threads ||
+		uint32(len(salt)) != a.SaltLen || uint32(len(key)) != a.KeyLen
+}
+
+func decodeArgon2id(encoded string) (*Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return nil, nil, nil, errors.New("invalid argon2id hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, err
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, errors.New("incompatible argon2 version")
+	}
+
+	params := &Argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return nil, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	params.SaltLen = uint32(len(salt))
+	params.KeyLen = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// MultiHasher hashes with the configured algorithm but still verifies every
+// format found in the accounts table, so legacy passwords keep working until
+// they are rehashed on login.
+type MultiHasher struct {
+	preferred PasswordHasher
+	whirlpool *WhirlpoolHasher
+	argon2id  *Argon2idHasher
+}
+
+func NewPasswordHasher(algorithm string) (*MultiHasher, error) {
+	m := &MultiHasher{
+		whirlpool: &WhirlpoolHasher{},
+		argon2id:  NewArgon2idHasher(),
+	}
+
+	switch strings.ToLower(algorithm) {
+	case HashWhirlpool:
+		m.preferred = m.whirlpool
+	case HashArgon2id:
+		m.preferred = m.argon2id
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", algorithm)
+	}
+
+	return m, nil
+}
+
+func (m *MultiHasher) Hash(password string) (string, error) {
+	return m.preferred.Hash(password)
+}
+
+func (m *MultiHasher) Verify(password string, encoded string) bool {
+	if strings.HasPrefix(encoded, "$"+HashArgon2id+"$") {
+		return m.argon2id.Verify(password, encoded)
+	}
+	if isWhirlpoolHash(encoded) {
+		return m.whirlpool.Verify(password, encoded)
+	}
+	return false
+}
+
+// NeedsRehash only moves a password to a stronger format. An argon2id hash is kept when
+// whirlpool is configured, it is never rewritten as an unsalted whirlpool digest.
+func (m *MultiHasher) NeedsRehash(encoded string) bool {
+	if strings.HasPrefix(encoded, "$"+HashArgon2id+"$") && m.preferred != PasswordHasher(m.argon2id) {
+		return false
+	}
+	return m.preferred.NeedsRehash(encoded)
+}
+
+func isWhirlpoolHash(encoded string) bool {
+	if len(encoded) != 128 {
+		return false
+	}
+	_, err := hex.DecodeString(encoded)
+	return err == nil
+}
+
+func hashWP(payload string) string {
+	w := whirlpool.New()
+	w.Write([]byte(payload))
+	return hex.EncodeToString(w.Sum(nil))
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"strings"
+	"testing"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	const password = "test123."
+
+	legacy := hashWP(password)
+	modern, err := NewArgon2idHasher().Hash(password)
+	if err != nil {
+		t.Fatalf("Error hashing password: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		algorithm   string
+		stored      string
+		password    string
+		valid       bool
+		needsRehash bool
+	}{
+		{"Legacy hash is verified and marked for rehash", HashArgon2id, legacy, password, true, true},
+		{"Upper case legacy hash from the game server is verified", HashArgon2id, strings.ToUpper(legacy), password, true, true},
+		{"Legacy hash with wrong password is rejected", HashArgon2id, legacy, "wrong123.", false, true},
+		{"Legacy hash is kept when whirlpool is configured", HashWhirlpool, legacy, password, true, false},
+		{"Argon2id hash is not downgraded when whirlpool is configured", HashWhirlpool, modern, password, true, false},
+		{"Unknown hash format is rejected", HashArgon2id, "plain", password, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := NewPasswordHasher(tt.algorithm)
+			if err != nil {
+				t.Fatalf("Error creating hasher: %v", err)
+			}
+
+			assert.Equal(t, tt.valid, hasher.Verify(tt.password, tt.stored), "Unexpected verify result for test: %s", tt.name)
+			assert.Equal(t, tt.needsRehash, hasher.NeedsRehash(tt.stored), "Unexpected rehash result for test: %s", tt.name)
+		})
+	}
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher, err := NewPasswordHasher(HashArgon2id)
+	if err != nil {
+		t.Fatalf("Error creating hasher: %v", err)
+	}
+
+	first, err := hasher.Hash("test123.")
+	if err != nil {
+		t.Fatalf("Error hashing password: %v", err)
+	}
+	second, err := hasher.Hash("test123.")
+	if err != nil {
+		t.Fatalf("Error hashing password: %v", err)
+	}
+
+	assert.True(t, strings.HasPrefix(first, "$argon2id$v=19$"))
+	assert.NotEqual(t, first, second, "Hashes must be salted")
+	assert.LessOrEqual(t, len(first), 129, "Hash must fit the accounts.Password column")
+	assert.True(t, hasher.Verify("test123.", first))
+	assert.False(t, hasher.Verify("test123", first))
+	assert.False(t, hasher.NeedsRehash(first))
+
+	_, err = NewPasswordHasher("md5")
+	assert.Error(t, err)
+}
+
+type countingHasher struct {
+	WhirlpoolHasher
+	verified int
+}
+
+func (c *countingHasher) Verify(password string, encoded string) bool {
+	c.verified++
+	return c.WhirlpoolHasher.Verify(password, encoded)
+}
+
+func TestVerifyUnknownAccount(t *testing.T) {
+	hasher := &countingHasher{}
+	users := NewUserService(repository.NewMemoryRepository(), hasher)
+
+	err := users.Verify(context.Background(), &model.LoginAPI{Username: "Nobody", Password: "test123."})
+	assert.ErrorIs(t, err, model.ErrInvalidCredentials)
+	assert.Equal(t, 1, hasher.verified, "Unknown accounts must still pay for a hash")
+}
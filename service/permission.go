@@ -0,0 +1,73 @@
+package service
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	PermStaffPanel          = "staff.panel"
+	PermCharacterReview     = "character.review"
+	PermCharacterRead       = "character.read"
+	PermBanList             = "ban.list"
+	PermBanCreate           = "ban.create"
+	PermBanRevoke           = "ban.revoke"
+	PermAjail               = "ajail"
+	PermLogsRead            = "logs.read"
+	PermSessionRevoke       = "session.revoke"
+	PermLockoutClear        = "lockout.clear"
+	PermAuditRead           = "audit.read"
+	PermEmailManage         = "email.manage"
+	PermQuestionnaireManage = "questionnaire.manage"
+	PermNameChangeReview    = "namechange.review"
+	PermCKReview            = "ck.review"
+)
+
+// PermissionService maps Admin and Tester levels to named permissions. Levels are
+// cumulative, an admin of level 3 also has everything granted to levels 1 and 2.
+// A permission ending in ":*" grants every scoped variant, "logs.read:*" covers
+// "logs.read:logs_kills".
+type PermissionService struct {
+	admin  map[int][]string
+	tester map[int][]string
+}
+
+func NewPermissionService(admin, tester map[int][]string) *PermissionService {
+	return &PermissionService{admin: admin, tester: tester}
+}
+
+func (p *PermissionService) Allowed(adminLevel, testerLevel int, permission string) bool {
+	for _, granted := range p.List(adminLevel, testerLevel) {
+		if granted == permission {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(granted, "*"); ok && strings.HasPrefix(permission, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PermissionService) List(adminLevel, testerLevel int) []string {
+	set := make(map[string]struct{})
+	collect(set, p.admin, adminLevel)
+	collect(set, p.tester, testerLevel)
+
+	list := make([]string, 0, len(set))
+	for perm := range set {
+		list = append(list, perm)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func collect(set map[string]struct{}, levels map[int][]string, level int) {
+	for l, perms := range levels {
+		if l < 1 || l > level {
+			continue
+		}
+		for _, perm := range perms {
+			set[perm] = struct{}{}
+		}
+	}
+}
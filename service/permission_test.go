@@ -0,0 +1,28 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPermissionService(t *testing.T) {
+	perms := NewPermissionService(
+		map[int][]string{
+			1: {PermCharacterReview, PermLogsRead + ":*"},
+			3: {PermBanCreate},
+		},
+		map[int][]string{
+			1: {PermCharacterReview},
+		},
+	)
+
+	assert.False(t, perms.Allowed(0, 0, PermCharacterReview))
+	assert.True(t, perms.Allowed(0, 1, PermCharacterReview))
+	assert.False(t, perms.Allowed(0, 5, PermBanCreate))
+	assert.False(t, perms.Allowed(2, 0, PermBanCreate))
+	assert.True(t, perms.Allowed(3, 0, PermBanCreate))
+	assert.True(t, perms.Allowed(4, 0, PermBanCreate))
+	assert.True(t, perms.Allowed(1, 0, PermLogsRead+":logs_kills"))
+	assert.False(t, perms.Allowed(0, 1, PermLogsRead+":logs_kills"))
+	assert.Equal(t, []string{PermBanCreate, PermCharacterReview, PermLogsRead + ":*"}, perms.List(3, 1))
+}
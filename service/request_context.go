@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"time"
+)
+
+// RequestContext gives every request the context that handlers pass down to the
+// services and the repository. It is derived from the fasthttp request context,
+// so it ends when the server shuts down, when the timeout passes or when the
+// handler returns. fasthttp doesn't notice a client that disconnects while the
+// handler runs, such a request is bounded only by the timeout.
+func RequestContext(timeout time.Duration) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		c, cancel := context.WithTimeout(ctx.Context(), timeout)
+		defer cancel()
+
+		ctx.SetUserContext(c)
+		return ctx.Next()
+	}
+}
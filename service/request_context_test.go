@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestContext(t *testing.T) {
+	var seen context.Context
+
+	app := fiber.New()
+	app.Use(RequestContext(time.Minute))
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		seen = ctx.UserContext()
+
+		deadline, ok := seen.Deadline()
+		assert.True(t, ok, "Request context has no deadline")
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+		assert.NoError(t, seen.Err())
+		return nil
+	})
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/", nil), -1); err != nil {
+		t.Fatalf("Error sending test request: %v", err)
+	}
+
+	if assert.NotNil(t, seen) {
+		assert.ErrorIs(t, seen.Err(), context.Canceled, "Request context must end with the request")
+	}
+}
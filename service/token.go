@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"time"
+)
+
+const (
+	TokenConfirm = repository.TokenPurposeConfirm
+	TokenReset   = repository.TokenPurposeReset
+	TokenEmail   = repository.TokenPurposeEmail
+)
+
+// TokenService issues single-use tokens. Only the HMAC of a token is stored, so a
+// leaked table can't be used to confirm accounts or reset passwords.
+type TokenService struct {
+	userRepository repository.TokenRepository
+	secret         []byte
+	ttl            map[string]time.Duration
+}
+
+func NewTokenService(repo repository.TokenRepository, secret string, ttl map[string]time.Duration) *TokenService {
+	return &TokenService{userRepository: repo, secret: []byte(secret), ttl: ttl}
+}
+
+func (t *TokenService) Issue(ctx context.Context, purpose, username, email string) (string, error) {
+	ttl, ok := t.ttl[purpose]
+	if !ok {
+		return "", fmt.Errorf("unknown token purpose %s", purpose)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	err := t.userRepository.CreateToken(ctx, &repository.TokenDB{
+		Purpose:   purpose,
+		TokenHash: t.hash(token),
+		Username:  username,
+		Email:     email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Peek checks the token without using it up.
+func (t *TokenService) Peek(ctx context.Context, purpose, token string) (*model.TokenAPI, error) {
+	data, err := t.fetch(ctx, purpose, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenAPI{Username: data.Username, Email: data.Email}, nil
+}
+
+func (t *TokenService) Consume(ctx context.Context, purpose, token string) (*model.TokenAPI, error) {
+	data, err := t.fetch(ctx, purpose, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.userRepository.ConsumeToken(ctx, data.ID); err != nil {
+		return nil, model.ErrTokenInvalid
+	}
+
+	return &model.TokenAPI{Username: data.Username, Email: data.Email}, nil
+}
+
+// Recent counts the tokens issued for the address in the last window.
+func (t *TokenService) Recent(ctx context.Context, purpose, email string, window time.Duration) (int, error) {
+	return t.userRepository.CountTokensSince(ctx, purpose, email, time.Now().Add(-window))
+}
+
+func (t *TokenService) fetch(ctx context.Context, purpose, token string) (*repository.TokenDB, error) {
+	if token == "" {
+		return nil, model.ErrTokenInvalid
+	}
+
+	data, err := t.userRepository.FetchToken(ctx, purpose, t.hash(token))
+	if err != nil {
+		return nil, err
+	}
+	if data == nil || data.ConsumedAt.Valid {
+		return nil, model.ErrTokenInvalid
+	}
+	if time.Now().After(data.ExpiresAt) {
+		return nil, model.ErrTokenExpired
+	}
+
+	return data, nil
+}
+
+func (t *TokenService) hash(token string) string {
+	h := hmac.New(sha256.New, t.secret)
+	h.Write([]byte(token))
+	return hex.EncodeToString(h.Sum(nil))
+}
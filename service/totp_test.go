@@ -0,0 +1,51 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 SHA1 test vectors truncated to six digits.
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		name     string
+		unix     int64
+		expected string
+	}{
+		{"Code at T=59", 59, "287082"},
+		{"Code at T=1111111109", 1111111109, "081804"},
+		{"Code at T=1234567890", 1234567890, "005924"},
+		{"Code at T=2000000000", 2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := totpCode(secret, tt.unix/totpPeriod)
+			if err != nil {
+				t.Fatalf("Error generating code: %v", err)
+			}
+			assert.Equal(t, tt.expected, code)
+
+			step, ok := validateTOTP(secret, tt.expected, time.Unix(tt.unix+totpPeriod, 0))
+			assert.True(t, ok, "Code from the previous step must be accepted")
+			assert.Equal(t, tt.unix/totpPeriod, step)
+
+			_, ok = validateTOTP(secret, tt.expected, time.Unix(tt.unix+3*totpPeriod, 0))
+			assert.False(t, ok, "Code outside the skew window must be rejected")
+		})
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("Error generating recovery codes: %v", err)
+	}
+
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.Equal(t, hashRecoveryCode(codes[0]), hashRecoveryCode(" "+codes[0][:5]+codes[0][6:]+" "))
+	assert.NotEqual(t, hashRecoveryCode(codes[0]), hashRecoveryCode(codes[1]))
+}
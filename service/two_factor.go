@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"sarp_backend/model"
+	"sarp_backend/repository"
+	"time"
+)
+
+const (
+	TwoFactorNone     = "none"
+	TwoFactorPending  = "pending"
+	TwoFactorVerified = "verified"
+)
+
+type TwoFactorService struct {
+	userRepository repository.TwoFactorRepository
+	issuer         string
+}
+
+func NewTwoFactorService(repo repository.TwoFactorRepository, issuer string) *TwoFactorService {
+	return &TwoFactorService{userRepository: repo, issuer: issuer}
+}
+
+func (t *TwoFactorService) Enabled(ctx context.Context, name string) (bool, error) {
+	data, err := t.userRepository.FetchTwoFactor(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	return data != nil && data.Enabled, nil
+}
+
+func (t *TwoFactorService) Enroll(ctx context.Context, name string) (*model.TwoFactorEnrollAPI, error) {
+	enabled, err := t.Enabled(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, model.ErrTwoFactorEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.userRepository.SaveTwoFactorSecret(ctx, name, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorEnrollAPI{
+		Secret: secret,
+		URI:    totpURI(t.issuer, name, secret),
+	}, nil
+}
+
+func (t *TwoFactorService) Confirm(ctx context.Context, name string, code string) ([]string, error) {
+	data, err := t.userRepository.FetchTwoFactor(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil || data.Enabled {
+		return nil, model.ErrTwoFactorNotEnrolling
+	}
+
+	step, ok := validateTOTP(data.Secret, code, time.Now())
+	if !ok {
+		return nil, model.ErrInvalidTwoFactorCode
+	}
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, hashRecoveryCode(c))
+	}
+
+	if err = t.userRepository.EnableTwoFactor(ctx, name, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify accepts either a TOTP code or one of the unused recovery codes.
+func (t *TwoFactorService) Verify(ctx context.Context, name string, code string) error {
+	data, err := t.userRepository.FetchTwoFactor(ctx, name)
+	if err != nil {
+		return err
+	}
+	if data == nil || !data.Enabled {
+		return model.ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := validateTOTP(data.Secret, code, time.Now()); ok {
+		return t.userRepository.UseTwoFactorStep(ctx, name, step)
+	}
+
+	return t.userRepository.UseRecoveryCode(ctx, name, hashRecoveryCode(code))
+}
+
+func (t *TwoFactorService) Disable(ctx context.Context, name string, code string) error {
+	if err := t.Verify(ctx, name, code); err != nil {
+		return err
+	}
+
+	return t.userRepository.DisableTwoFactor(ctx, name)
+}
@@ -1,74 +1,200 @@
 package service
 
 import (
-	"encoding/hex"
+	"context"
+	"database/sql"
 	"errors"
-	"github.com/jzelinskie/whirlpool"
 	"sarp_backend/model"
 	"sarp_backend/repository"
-	"strings"
+	"sync"
 	"time"
 )
 
+// dummyPassword is hashed once and verified against for unknown accounts.
+const dummyPassword = "sarp-dummy-password"
+
+// UserStore is the storage behind UserService: the accounts, their punishments and
+// the stats, logs and characters shown to them.
+type UserStore interface {
+	repository.AccountRepository
+	repository.PreferenceRepository
+	repository.BanRepository
+	repository.StatsRepository
+	FetchLogs(ctx context.Context, table repository.LogTableDB, filter *repository.LogsFilterDB) (*repository.LogsPageDB, error)
+	FetchCharacter(ctx context.Context, character string) (*repository.CharacterDB, error)
+	DeleteExp(ctx context.Context) error
+	DeleteExpiredTokens(ctx context.Context) error
+}
+
 type UserService struct {
-	userRepository *repository.UserRepository
+	userRepository UserStore
+	hasher         PasswordHasher
+	dummyOnce      sync.Once
+	dummy          string
 }
 
-func NewUserService(repo *repository.UserRepository) *UserService {
-	return &UserService{userRepository: repo}
+func NewUserService(repo UserStore, hasher PasswordHasher) *UserService {
+	return &UserService{userRepository: repo, hasher: hasher}
 }
 
-func (u *UserService) Create(data *model.RegisterAPI) error {
+func (u *UserService) Create(ctx context.Context, data *model.RegisterAPI) error {
+	hashed, err := u.hasher.Hash(data.Password)
+	if err != nil {
+		return err
+	}
+
 	dto := &repository.UserDB{
 		Username:     data.Username,
 		Email:        data.Email,
-		Password:     hashWP(data.Password),
+		Password:     hashed,
 		RegisterDate: time.Now().Format("2006-01-02 15:04:05"),
 	}
 
-	return u.userRepository.Create(dto)
+	return u.userRepository.Create(ctx, dto)
 }
 
-func (u *UserService) ActivateAccount(email string) error {
-	return u.userRepository.Activate(email)
+func (u *UserService) ActivateAccount(ctx context.Context, email string) error {
+	return u.userRepository.Activate(ctx, email)
 }
 
-func (u *UserService) CheckActivation(name string) (bool, error) {
-	return u.userRepository.CheckActivation(name)
+func (u *UserService) CheckActivation(ctx context.Context, name string) (bool, error) {
+	return u.userRepository.CheckActivation(ctx, name)
 }
 
-func (u *UserService) Verify(data *model.LoginAPI) error {
-	dto := &repository.UserDB{
-		Username: data.Username,
-		Password: hashWP(data.Password),
+// Verify checks the password of data.Username. Unknown accounts are verified against a
+// dummy hash of the configured algorithm, so they answer as slowly as a wrong password.
+func (u *UserService) Verify(ctx context.Context, data *model.LoginAPI) error {
+	stored, err := u.userRepository.FetchPassword(ctx, data.Username)
+	if errors.Is(err, sql.ErrNoRows) {
+		u.hasher.Verify(data.Password, u.dummyHash())
+		return model.ErrInvalidCredentials
+	}
+	if err != nil {
+		return err
+	}
+
+	if !u.hasher.Verify(data.Password, stored) {
+		return model.ErrInvalidCredentials
 	}
 
-	return u.userRepository.Verify(dto)
+	if u.hasher.NeedsRehash(stored) {
+		// A failed upgrade must not block the login, the old hash is still valid
+		// and the rehash is retried on the next successful login.
+		if hashed, errHash := u.hasher.Hash(data.Password); errHash == nil {
+			_ = u.userRepository.UpdatePasswordByName(ctx, data.Username, hashed)
+		}
+	}
+
+	return nil
 }
 
-func (u *UserService) UpdatePassword(email string, password string) error {
-	hashed := hashWP(password)
-	return u.userRepository.UpdatePassword(email, hashed)
+func (u *UserService) dummyHash() string {
+	u.dummyOnce.Do(func() {
+		u.dummy, _ = u.hasher.Hash(dummyPassword)
+	})
+	return u.dummy
 }
 
-func (u *UserService) Fetch(name string, email string) (bool, error) {
-	return u.userRepository.Fetch(name, email)
+func (u *UserService) UpdatePassword(ctx context.Context, email string, password string) error {
+	hashed, err := u.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	return u.userRepository.UpdatePassword(ctx, email, hashed)
 }
 
-func (u *UserService) FetchMail(name string) (string, error) {
-	return u.userRepository.FetchMail(name)
+// ChangePassword replaces the password of a logged in account once the current one
+// is confirmed. Pending reset links stop working and every other session of the
+// account is closed, the one making the request stays logged in.
+func (u *UserService) ChangePassword(ctx context.Context, name, session string, data *model.ChangePasswordAPI) error {
+	stored, err := u.userRepository.FetchPassword(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if !u.hasher.Verify(data.CurrentPassword, stored) {
+		return model.ErrInvalidCredentials
+	}
+
+	hashed, err := u.hasher.Hash(data.NewPassword)
+	if err != nil {
+		return err
+	}
+	return u.userRepository.ChangePassword(ctx, name, hashed, session)
 }
 
-func (u *UserService) IsTester(name string) (bool, error) {
-	return u.userRepository.FetchTesterLevel(name)
+// ResetPassword sets a password chosen by an operator, the account is logged out and
+// any reset link still pending stops working.
+func (u *UserService) ResetPassword(ctx context.Context, data *model.ResetPasswordAPI, audit *model.AuditAPI) error {
+	hashed, err := u.hasher.Hash(data.NewPassword)
+	if err != nil {
+		return err
+	}
+	return u.userRepository.ResetPassword(ctx, data.Username, hashed, auditRecord(audit))
 }
 
-func (u *UserService) IsAdmin(name string) (bool, error) {
-	return u.userRepository.FetchAdminLevel(name)
+func (u *UserService) ChangeEmail(ctx context.Context, name, email string) error {
+	return u.userRepository.UpdateEmail(ctx, name, email)
 }
 
-func (u *UserService) GetStats(name string) (*model.GetStatsAPI, error) {
-	data, err := u.userRepository.FetchStats(name)
+func (u *UserService) Profile(ctx context.Context, name string) (*model.ProfileAPI, error) {
+	profile, err := u.userRepository.FetchProfile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ProfileAPI{
+		ForumName:   profile.ForumName,
+		Avatar:      profile.Avatar,
+		Description: profile.Description,
+	}, nil
+}
+
+func (u *UserService) UpdateProfile(ctx context.Context, name string, data *model.ProfileAPI) error {
+	return u.userRepository.UpdateProfile(ctx, name, &repository.ProfileDB{
+		ForumName:   data.ForumName,
+		Avatar:      data.Avatar,
+		Description: data.Description,
+	})
+}
+
+func (u *UserService) Fetch(ctx context.Context, name string, email string) (bool, error) {
+	return u.userRepository.Fetch(ctx, name, email)
+}
+
+func (u *UserService) FetchMail(ctx context.Context, name string) (string, error) {
+	return u.userRepository.FetchMail(ctx, name)
+}
+
+func (u *UserService) FetchUsername(ctx context.Context, email string) (string, error) {
+	return u.userRepository.FetchUsername(ctx, email)
+}
+
+// Locale is the language saved on the account, empty when the player never picked one.
+func (u *UserService) Locale(ctx context.Context, name string) (string, error) {
+	return u.userRepository.FetchLocale(ctx, name)
+}
+
+func (u *UserService) SetLocale(ctx context.Context, name, locale string) error {
+	return u.userRepository.SaveLocale(ctx, name, locale)
+}
+
+func (u *UserService) TesterLevel(ctx context.Context, name string) (int, error) {
+	return u.userRepository.FetchTesterLevel(ctx, name)
+}
+
+func (u *UserService) AdminLevel(ctx context.Context, name string) (int, error) {
+	return u.userRepository.FetchAdminLevel(ctx, name)
+}
+
+// SetStaffLevel replaces both ranks of the account. The sessions are closed because
+// they cache the levels read at login.
+func (u *UserService) SetStaffLevel(ctx context.Context, data *model.StaffLevelAPI, audit *model.AuditAPI) error {
+	return u.userRepository.UpdateStaffLevel(ctx, data.Username, data.Admin, data.Tester, auditRecord(audit))
+}
+
+func (u *UserService) GetStats(ctx context.Context, name string) (*model.GetStatsAPI, error) {
+	data, err := u.userRepository.FetchStats(ctx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -99,8 +225,8 @@ func (u *UserService) GetStats(name string) (*model.GetStatsAPI, error) {
 	}, nil
 }
 
-func (u *UserService) GetStaff() ([]model.GetStaffAPI, error) {
-	staff, err := u.userRepository.FetchStaff()
+func (u *UserService) GetStaff(ctx context.Context) ([]model.GetStaffAPI, error) {
+	staff, err := u.userRepository.FetchStaff(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -120,8 +246,8 @@ func (u *UserService) GetStaff() ([]model.GetStaffAPI, error) {
 	return list, nil
 }
 
-func (u *UserService) GetServerStats() (*model.ServerStatsAPI, error) {
-	data, err := u.userRepository.FetchServerStats()
+func (u *UserService) GetServerStats(ctx context.Context) (*model.ServerStatsAPI, error) {
+	data, err := u.userRepository.FetchServerStats(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -136,12 +262,12 @@ func (u *UserService) GetServerStats() (*model.ServerStatsAPI, error) {
 	}, nil
 }
 
-func (u *UserService) FetchCharacter(name string) (*model.CharacterAPI, error) {
+func (u *UserService) FetchCharacter(ctx context.Context, name string) (*model.CharacterAPI, error) {
 	if name == "" {
-		return nil, errors.New("character name can't be empty")
+		return nil, model.ErrCharacterNameRequired
 	}
 
-	fetch, err := u.userRepository.FetchCharacter(name)
+	fetch, err := u.userRepository.FetchCharacter(ctx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -152,17 +278,17 @@ func (u *UserService) FetchCharacter(name string) (*model.CharacterAPI, error) {
 	}, nil
 }
 
-func (u *UserService) CheckForBan(name string) (bool, error) {
-	return u.userRepository.CheckForBan(name)
+func (u *UserService) CheckForBan(ctx context.Context, name string) (bool, error) {
+	return u.userRepository.CheckForBan(ctx, name)
 }
 
-func (u *UserService) Ban(data *model.BanAPI) error {
+func (u *UserService) Ban(ctx context.Context, data *model.BanAPI, audit *model.AuditAPI) error {
 	if data.AdminName == "" || data.Username == "" || data.Reason == "" {
-		return errors.New("fields can't be empty")
+		return model.ErrMissingFields
 	}
 
 	if data.Expire <= 0 || data.Expire >= 30 {
-		return errors.New("invalid expire value")
+		return model.ErrInvalidExpire
 	}
 
 	ban := &repository.BlacklistDB{
@@ -173,11 +299,11 @@ func (u *UserService) Ban(data *model.BanAPI) error {
 		Expire:   data.Expire,
 	}
 
-	return u.userRepository.AddBan(ban)
+	return u.userRepository.AddBan(ctx, ban, auditRecord(audit))
 }
 
-func (u *UserService) BanList() ([]model.BanAPI, error) {
-	bans, err := u.userRepository.FetchBans()
+func (u *UserService) BanList(ctx context.Context) ([]model.BanAPI, error) {
+	bans, err := u.userRepository.FetchBans(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -201,46 +327,23 @@ func (u *UserService) BanList() ([]model.BanAPI, error) {
 	return ret, nil
 }
 
-func (u *UserService) Unban(data *model.BanAPI) error {
-	return u.userRepository.Unban(data.Username)
+func (u *UserService) Unban(ctx context.Context, data *model.BanAPI, audit *model.AuditAPI) error {
+	return u.userRepository.Unban(ctx, data.Username, auditRecord(audit))
 }
 
-func (u *UserService) Ajail(data *model.AjailAPI) error {
+func (u *UserService) Ajail(ctx context.Context, data *model.AjailAPI, audit *model.AuditAPI) error {
 	ajail := &repository.AjailDB{
 		Character: data.Character,
 		Prisoned:  0,
 		JailTime:  data.Time * 60, // minutes
 	}
 
-	return u.userRepository.Ajail(ajail)
+	return u.userRepository.Ajail(ctx, ajail, auditRecord(audit))
 }
 
-func (u *UserService) Logs(data *model.LogsAPI) ([]map[string]interface{}, error) {
-	var logsType = []string{
-		"logs_ajail", "logs_ban", "logs_warn", "logs_kick", "logs_unban",
-		"logs_charity", "hit_logs", "logs_ck", "logs_transfer",
-		"logs_givecash", "logs_givedrug", "logs_givegun", "logs_pay",
-		"logs_ask", "logs_report", "namechanges"}
-	var ok bool
-	for _, l := range logsType {
-		if strings.EqualFold(data.Type, l) {
-			ok = true
-			break
-		}
-	}
-	if !ok {
-		return nil, errors.New("invalid log type")
+func (u *UserService) DeleteExpired(ctx context.Context) error {
+	if err := u.userRepository.DeleteExpiredTokens(ctx); err != nil {
+		return err
 	}
-
-	return u.userRepository.FetchLogs(data.Type)
-}
-
-func (u *UserService) DeleteExpired() error {
-	return u.userRepository.DeleteExp()
-}
-
-func hashWP(payload string) string {
-	w := whirlpool.New()
-	w.Write([]byte(payload))
-	return hex.EncodeToString(w.Sum(nil))
+	return u.userRepository.DeleteExp(ctx)
 }
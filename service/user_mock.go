@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"github.com/stretchr/testify/mock"
 	"sarp_backend/model"
 )
@@ -9,57 +10,65 @@ type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserService) Create(data *model.RegisterAPI) error {
+func (m *MockUserService) Create(ctx context.Context, data *model.RegisterAPI) error {
 	args := m.Called(data)
 	return args.Error(0)
 }
 
-func (m *MockUserService) ActivateAccount(email string) error {
+func (m *MockUserService) ActivateAccount(ctx context.Context, email string) error {
 	return nil
 }
 
-func (m *MockUserService) CheckActivation(name string) (bool, error) {
+func (m *MockUserService) CheckActivation(ctx context.Context, name string) (bool, error) {
 	return false, nil
 }
 
-func (m *MockUserService) Verify(data *model.LoginAPI) error {
+func (m *MockUserService) Verify(ctx context.Context, data *model.LoginAPI) error {
 	args := m.Called(data)
 	return args.Error(0)
 }
 
-func (m *MockUserService) Fetch(name string) (bool, error) {
+func (m *MockUserService) Fetch(ctx context.Context, name string) (bool, error) {
 	return false, nil
 }
 
-func (m *MockUserService) IsTester(name string) (bool, error) {
-	return false, nil
+func (m *MockUserService) Locale(ctx context.Context, name string) (string, error) {
+	return "", nil
 }
 
-func (m *MockUserService) IsAdmin(name string) (bool, error) {
-	return false, nil
+func (m *MockUserService) SetLocale(ctx context.Context, name, locale string) error {
+	return nil
+}
+
+func (m *MockUserService) TesterLevel(ctx context.Context, name string) (int, error) {
+	return 0, nil
+}
+
+func (m *MockUserService) AdminLevel(ctx context.Context, name string) (int, error) {
+	return 0, nil
 }
 
-func (m *MockUserService) GetStats(name string) (*model.GetStatsAPI, error) {
+func (m *MockUserService) GetStats(ctx context.Context, name string) (*model.GetStatsAPI, error) {
 	args := m.Called(name)
 	return args.Get(0).(*model.GetStatsAPI), args.Error(1)
 }
 
-func (m *MockUserService) FetchCharacter(name string) (*model.CharacterAPI, error) {
+func (m *MockUserService) FetchCharacter(ctx context.Context, name string) (*model.CharacterAPI, error) {
 	return nil, nil
 }
 
-func (m *MockUserService) Ban(data *model.BanAPI) error {
+func (m *MockUserService) Ban(ctx context.Context, data *model.BanAPI, audit *model.AuditAPI) error {
 	return nil
 }
 
-func (m *MockUserService) BanList() ([]model.BanAPI, error) {
+func (m *MockUserService) BanList(ctx context.Context) ([]model.BanAPI, error) {
 	return nil, nil
 }
 
-func (m *MockUserService) Unban(data *model.BanAPI) error {
+func (m *MockUserService) Unban(ctx context.Context, data *model.BanAPI, audit *model.AuditAPI) error {
 	return nil
 }
 
-func (m *MockUserService) DeleteExpired() error {
+func (m *MockUserService) DeleteExpired(ctx context.Context) error {
 	return nil
 }
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"math/big"
+	"sarp_backend/model"
+	"sarp_backend/service"
+)
+
+const (
+	passwordLetters = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+	passwordDigits  = "23456789"
+	passwordSymbols = "!@#$%*-_"
+)
+
+func userActivate(fs *flag.FlagSet) func(c *console, args []string) error {
+	return func(c *console, args []string) error {
+		return c.activate(context.Background(), args[0])
+	}
+}
+
+func userPromote(fs *flag.FlagSet) func(c *console, args []string) error {
+	role := fs.String("role", "", "rank to give, admin or tester")
+	level := fs.Int("level", 1, "level of the rank")
+
+	return func(c *console, args []string) error {
+		if *level < 1 {
+			return errors.New("--level must be at least 1, use demote to remove a rank")
+		}
+		return c.setStaffLevel(context.Background(), args[0], *role, *level)
+	}
+}
+
+func userDemote(fs *flag.FlagSet) func(c *console, args []string) error {
+	role := fs.String("role", "", "rank to remove, admin or tester, both when omitted")
+
+	return func(c *console, args []string) error {
+		return c.setStaffLevel(context.Background(), args[0], *role, 0)
+	}
+}
+
+func userResetPassword(fs *flag.FlagSet) func(c *console, args []string) error {
+	password := fs.String("password", "", "new password, a random one is generated when omitted")
+
+	return func(c *console, args []string) error {
+		return c.resetPassword(context.Background(), args[0], *password)
+	}
+}
+
+func (c *console) activate(ctx context.Context, name string) error {
+	email, err := c.users.FetchMail(ctx, name)
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return model.ErrAccountNotFound
+	}
+
+	activated, err := c.users.CheckActivation(ctx, name)
+	if err != nil {
+		return err
+	}
+	if activated {
+		return c.done("account %s is already activated", name)
+	}
+
+	if err = c.users.ActivateAccount(ctx, email); err != nil {
+		return err
+	}
+	return c.done("account %s activated", name)
+}
+
+// setStaffLevel gives level on role, an empty role is only valid when removing both ranks.
+func (c *console) setStaffLevel(ctx context.Context, name, role string, level int) error {
+	email, err := c.users.FetchMail(ctx, name)
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return model.ErrAccountNotFound
+	}
+
+	data := &model.StaffLevelAPI{Username: name}
+	if data.Admin, err = c.users.AdminLevel(ctx, name); err != nil {
+		return err
+	}
+	if data.Tester, err = c.users.TesterLevel(ctx, name); err != nil {
+		return err
+	}
+
+	switch {
+	case role == "admin":
+		data.Admin = level
+	case role == "tester":
+		data.Tester = level
+	case role == "" && level == 0:
+		data.Admin, data.Tester = 0, 0
+	default:
+		return errors.New("--role must be admin or tester")
+	}
+
+	if err = data.Validate(); err != nil {
+		return err
+	}
+
+	action := service.AuditUserPromote
+	if level == 0 {
+		action = service.AuditUserDemote
+	}
+	if err = c.users.SetStaffLevel(ctx, data, c.audit(action, name, data)); err != nil {
+		return err
+	}
+
+	return c.print(data, "%s is now admin %d, tester %d", data.Username, data.Admin, data.Tester)
+}
+
+func (c *console) resetPassword(ctx context.Context, name, password string) error {
+	generated := password == ""
+	if generated {
+		var err error
+		if password, err = randomPassword(16); err != nil {
+			return err
+		}
+	}
+
+	data := &model.ResetPasswordAPI{Username: name, NewPassword: password}
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	audit := c.audit(service.AuditPasswordReset, name, map[string]bool{"generated": generated})
+	if err := c.users.ResetPassword(ctx, data, audit); err != nil {
+		return err
+	}
+
+	if !generated {
+		return c.done("password of %s changed", name)
+	}
+
+	type result struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	return c.print(result{Username: name, Password: password}, "password of %s changed to %s", name, password)
+}
+
+// randomPassword has at least one letter, digit and symbol so it passes the same
+// rules as a password picked by the player.
+func randomPassword(length int) (string, error) {
+	sets := []string{passwordLetters, passwordDigits, passwordSymbols}
+	all := passwordLetters + passwordDigits + passwordSymbols
+
+	password := make([]byte, length)
+	for i := range password {
+		set := all
+		if i < len(sets) {
+			set = sets[i]
+		}
+		c, err := randomChar(set)
+		if err != nil {
+			return "", err
+		}
+		password[i] = c
+	}
+
+	// Move the guaranteed characters away from the start.
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+
+	return string(password), nil
+}
+
+func randomChar(set string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+	if err != nil {
+		return 0, err
+	}
+	return set[n.Int64()], nil
+}